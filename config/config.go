@@ -0,0 +1,241 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the application configuration. Every field can be set in the YAML file and through the
+// environment variable in its env tag, the environment wins when both are set.
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Mongo     Mongo     `yaml:"mongo"`
+	JWT       JWT       `yaml:"jwt"`
+	Postmark  Postmark  `yaml:"postmark"`
+	Twilio    Twilio    `yaml:"twilio"`
+	Providers Providers `yaml:"providers"`
+	Features  Features  `yaml:"features"`
+	Float     Float     `yaml:"float"`
+	Tracing   Tracing   `yaml:"tracing"`
+}
+
+type Server struct {
+	Port         string        `yaml:"port" env:"PORT" validate:"required,numeric"`
+	Env          string        `yaml:"env" env:"APP_ENV"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" validate:"gt=0"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" validate:"gt=0"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" validate:"gt=0"`
+	// DrainPeriod is how long readiness fails before the listener is closed on shutdown, so load balancers
+	// stop routing to the instance. ShutdownTimeout bounds the time given to in-flight requests and workers.
+	DrainPeriod     time.Duration `yaml:"drain_period" env:"SHUTDOWN_DRAIN_PERIOD" validate:"gte=0"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" validate:"gt=0"`
+}
+
+type Mongo struct {
+	URL      string `yaml:"url" env:"MONGODB_URL" validate:"required"`
+	Database string `yaml:"database" env:"DB_NAME" validate:"required"`
+	// ReadTimeout bounds single document reads, WriteTimeout inserts and updates, and ScanTimeout listings
+	// and batch writes. The deadline of the request still applies when it is sooner.
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"MONGO_READ_TIMEOUT" validate:"gt=0"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"MONGO_WRITE_TIMEOUT" validate:"gt=0"`
+	ScanTimeout  time.Duration `yaml:"scan_timeout" env:"MONGO_SCAN_TIMEOUT" validate:"gt=0"`
+}
+
+type JWT struct {
+	PublicKey  string `yaml:"public_key" env:"JWT_PUBLIC_KEY" validate:"required"`
+	PrivateKey string `yaml:"private_key" env:"JWT_PRIVATE_KEY" validate:"required"`
+	// AuthTokenDuration and RefreshTokenDuration fall back to the token generator defaults when 0.
+	AuthTokenDuration    int `yaml:"auth_token_duration" env:"AUTH_TOKEN_DURATION" validate:"gte=0"`
+	RefreshTokenDuration int `yaml:"refresh_token_duration" env:"REFRESH_TOKEN_DURATION" validate:"gte=0"`
+}
+
+type Postmark struct {
+	Key           string `yaml:"key" env:"POSTMARK_KEY" validate:"required"`
+	PlatformEmail string `yaml:"platform_email" env:"PLATFORM_EMAIL" validate:"required,email"`
+}
+
+type Twilio struct {
+	AccountSID string `yaml:"account_sid" env:"TWILIO_ACCOUNT_SID" validate:"required"`
+	AuthToken  string `yaml:"auth_token" env:"TWILIO_AUTH_TOKEN" validate:"required"`
+	ServiceID  string `yaml:"service_id" env:"TWILIO_SERVICES_ID" validate:"required"`
+}
+
+type Providers struct {
+	VTpass     VTpass     `yaml:"vtpass"`
+	EasyAccess EasyAccess `yaml:"easyaccess"`
+	Dontech    Dontech    `yaml:"dontech"`
+	Anchor     Anchor     `yaml:"anchor"`
+}
+
+// Client tunes the http client of a provider, zero values use the client defaults. Retries of -1 makes
+// no retries.
+type Client struct {
+	Timeout          time.Duration `yaml:"timeout" env:"TIMEOUT" validate:"gte=0"`
+	Retries          int           `yaml:"retries" env:"RETRIES" validate:"gte=-1,lte=5"`
+	FailureThreshold int           `yaml:"failure_threshold" env:"FAILURE_THRESHOLD" validate:"gte=0"`
+	Cooldown         time.Duration `yaml:"cooldown" env:"COOLDOWN" validate:"gte=0"`
+}
+
+type VTpass struct {
+	BaseURL   string `yaml:"base_url" env:"VTPASS_SANDBOX" validate:"required,url"`
+	APIKey    string `yaml:"api_key" env:"APIKey" validate:"required"`
+	SecretKey string `yaml:"secret_key" env:"SK" validate:"required"`
+	// PublicKey authenticates the GET requests of the balance endpoint.
+	PublicKey string `yaml:"public_key" env:"VTPASS_PUBLIC_KEY"`
+	Client    Client `yaml:"client" envPrefix:"VTPASS_"`
+}
+
+type EasyAccess struct {
+	BaseURL string `yaml:"base_url" env:"EASYACCESS" validate:"required,url"`
+	Token   string `yaml:"token" env:"EASYACCESS_AUTH" validate:"required"`
+	Client  Client `yaml:"client" envPrefix:"EASYACCESS_"`
+}
+
+type Dontech struct {
+	BaseURL string `yaml:"base_url" env:"DONTECH" validate:"required,url"`
+	Token   string `yaml:"token" env:"DONTECH_AUTH" validate:"required"`
+	Client  Client `yaml:"client" envPrefix:"DONTECH_"`
+}
+
+type Anchor struct {
+	BaseURL string `yaml:"base_url" env:"ANCHOR_API" validate:"required,url"`
+	APIKey  string `yaml:"api_key" env:"ANCHORAPI_PROD" validate:"required"`
+	// CustomerID owns the deposit accounts, SettlementAccountID receives virtual nuban payments and
+	// DepositAccountID is the account transfers are paid from.
+	CustomerID          string `yaml:"customer_id" env:"CUSTOMER_ID_LIVE" validate:"required"`
+	SettlementAccountID string `yaml:"settlement_account_id" env:"DEPOSIT_ID_LIVE_2" validate:"required"`
+	DepositAccountID    string `yaml:"deposit_account_id" env:"DEPOSIT_ID_LIVE" validate:"required"`
+	// WebhookSecret signs the events anchor posts to us, events are refused while it is empty.
+	WebhookSecret string `yaml:"webhook_secret" env:"ANCHOR_WEBHOOK_SECRET"`
+	Client        Client `yaml:"client" envPrefix:"ANCHOR_"`
+}
+
+type Features struct {
+	Scheduler              bool          `yaml:"scheduler" env:"SCHEDULER_ENABLED"`
+	SchedulerInterval      time.Duration `yaml:"scheduler_interval" env:"SCHEDULER_INTERVAL" validate:"gte=0"`
+	PlanSync               bool          `yaml:"plan_sync" env:"DATA_PLAN_SYNC_ENABLED"`
+	PlanSyncInterval       time.Duration `yaml:"plan_sync_interval" env:"DATA_PLAN_SYNC_INTERVAL" validate:"gte=0"`
+	DepositSync            bool          `yaml:"deposit_sync" env:"DEPOSIT_SYNC_ENABLED"`
+	DepositSyncInterval    time.Duration `yaml:"deposit_sync_interval" env:"DEPOSIT_SYNC_INTERVAL" validate:"gte=0"`
+	Statements             bool          `yaml:"statements" env:"MONTHLY_STATEMENTS_ENABLED"`
+	StatementsInterval     time.Duration `yaml:"statements_interval" env:"MONTHLY_STATEMENTS_INTERVAL" validate:"gte=0"`
+	FloatMonitor           bool          `yaml:"float_monitor" env:"FLOAT_MONITOR_ENABLED"`
+	FloatMonitorInterval   time.Duration `yaml:"float_monitor_interval" env:"FLOAT_MONITOR_INTERVAL" validate:"gte=0"`
+	Reconciliation         bool          `yaml:"reconciliation" env:"RECONCILIATION_ENABLED"`
+	ReconciliationInterval time.Duration `yaml:"reconciliation_interval" env:"RECONCILIATION_INTERVAL" validate:"gte=0"`
+	Requery                bool          `yaml:"requery" env:"REQUERY_ENABLED"`
+	RequeryInterval        time.Duration `yaml:"requery_interval" env:"REQUERY_INTERVAL" validate:"gte=0"`
+	DataPlanMarkup         float64       `yaml:"data_plan_markup" env:"DATA_PLAN_MARKUP" validate:"gte=0,lte=100"`
+	TVPackageCacheTTL      time.Duration `yaml:"tv_package_cache_ttl" env:"TV_PACKAGE_CACHE_TTL" validate:"gte=0"`
+	BulkWorkers            int           `yaml:"bulk_workers" env:"BULK_WORKERS" validate:"gte=1,lte=50"`
+	BulkInterval           time.Duration `yaml:"bulk_interval" env:"BULK_INTERVAL" validate:"gte=0"`
+	KYCTransferLimit       float64       `yaml:"kyc_transfer_limit" env:"KYC_TRANSFER_LIMIT" validate:"gt=0"`
+	// RefundApprovalThreshold is the amount above which a refund waits for support to approve it.
+	RefundApprovalThreshold float64 `yaml:"refund_approval_threshold" env:"REFUND_APPROVAL_THRESHOLD" validate:"gte=0"`
+	// DisputeSLA is how long support has to resolve a dispute ticket.
+	DisputeSLA time.Duration `yaml:"dispute_sla" env:"DISPUTE_SLA" validate:"gt=0"`
+	// LowBalanceThreshold is the wallet balance below which a user is notified, 0 never notifies.
+	LowBalanceThreshold float64 `yaml:"low_balance_threshold" env:"LOW_BALANCE_THRESHOLD" validate:"gte=0"`
+}
+
+// Float holds the balances at the providers below which AlertEmails are told to top up, a threshold of 0
+// never alerts.
+type Float struct {
+	AlertEmails []string `yaml:"alert_emails" env:"FLOAT_ALERT_EMAILS" validate:"dive,email"`
+	VTpass      float64  `yaml:"vtpass" env:"FLOAT_THRESHOLD_VTPASS" validate:"gte=0"`
+	EasyAccess  float64  `yaml:"easyaccess" env:"FLOAT_THRESHOLD_EASYACCESS" validate:"gte=0"`
+	Dontech     float64  `yaml:"dontech" env:"FLOAT_THRESHOLD_DONTECH" validate:"gte=0"`
+	Anchor      float64  `yaml:"anchor" env:"FLOAT_THRESHOLD_ANCHOR" validate:"gte=0"`
+}
+
+// Tracing selects where spans are exported. stdout writes them to the process output so traces can be read
+// locally without a collector, none disables tracing.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" validate:"oneof=none stdout otlp"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"required_if=Exporter otlp,omitempty,url"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME" validate:"required"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLE_RATIO" validate:"gte=0,lte=1"`
+}
+
+// Default returns the configuration used for anything not set in the file or the environment.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:            "8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    90 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			DrainPeriod:     5 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Mongo: Mongo{
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			ScanTimeout:  30 * time.Second,
+		},
+		Features: Features{
+			Scheduler:               true,
+			SchedulerInterval:       time.Minute,
+			PlanSync:                true,
+			PlanSyncInterval:        6 * time.Hour,
+			DepositSync:             true,
+			DepositSyncInterval:     5 * time.Minute,
+			Statements:              true,
+			StatementsInterval:      time.Hour,
+			FloatMonitor:            true,
+			FloatMonitorInterval:    5 * time.Minute,
+			Reconciliation:          true,
+			ReconciliationInterval:  time.Hour,
+			Requery:                 true,
+			RequeryInterval:         15 * time.Minute,
+			TVPackageCacheTTL:       time.Hour,
+			BulkWorkers:             5,
+			BulkInterval:            time.Minute,
+			KYCTransferLimit:        50000,
+			RefundApprovalThreshold: 5000,
+			DisputeSLA:              48 * time.Hour,
+			LowBalanceThreshold:     1000,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "aremxyplug-be",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file at path when path is not empty, and the
+// environment, then validates it. Outside production a .env file is loaded into the environment first.
+func Load(path string) (*Config, error) {
+	if os.Getenv("APP_ENV") != "production" {
+		if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("config: loading .env: %w", err)
+		}
+	}
+
+	cfg := Default()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: reading %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(b, cfg); err != nil {
+			return nil, fmt.Errorf("config: parsing %s: %w", path, err)
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testYAML = `
+mongo:
+  url: mongodb://localhost:27017
+  database: aremxyplug
+jwt:
+  public_key: public
+  private_key: private
+postmark:
+  key: postmark
+  platform_email: hello@aremxyplug.com
+twilio:
+  account_sid: sid
+  auth_token: token
+  service_id: service
+providers:
+  vtpass:
+    base_url: https://sandbox.vtpass.com/api
+    api_key: pk
+    secret_key: sk
+    client:
+      timeout: 10s
+  easyaccess:
+    base_url: https://easyaccessapi.com.ng/api
+    token: easy
+  dontech:
+    base_url: https://dontech.com/api
+    token: don
+  anchor:
+    base_url: https://api.getanchor.co/api/v1
+    api_key: anchor
+    customer_id: customer
+    settlement_account_id: settlement
+    deposit_account_id: deposit
+features:
+  bulk_workers: 8
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, testYAML)
+
+	t.Run("Test file with defaults", func(t *testing.T) {
+		cfg, err := Load(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, "8080", cfg.Server.Port)
+		assert.Equal(t, "pk", cfg.Providers.VTpass.APIKey)
+		assert.Equal(t, 10*time.Second, cfg.Providers.VTpass.Client.Timeout)
+		assert.Equal(t, 8, cfg.Features.BulkWorkers)
+		assert.Equal(t, float64(50000), cfg.Features.KYCTransferLimit)
+		assert.True(t, cfg.Features.Scheduler)
+	})
+
+	t.Run("Test env overrides file", func(t *testing.T) {
+		t.Setenv("PORT", "9090")
+		t.Setenv("SK", "env-secret")
+		t.Setenv("VTPASS_RETRIES", "3")
+		t.Setenv("SCHEDULER_ENABLED", "false")
+		t.Setenv("DATA_PLAN_SYNC_INTERVAL", "30m")
+		t.Setenv("FLOAT_ALERT_EMAILS", "ops@example.com, finance@example.com")
+
+		cfg, err := Load(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, "9090", cfg.Server.Port)
+		assert.Equal(t, "env-secret", cfg.Providers.VTpass.SecretKey)
+		assert.Equal(t, 3, cfg.Providers.VTpass.Client.Retries)
+		assert.False(t, cfg.Features.Scheduler)
+		assert.Equal(t, 30*time.Minute, cfg.Features.PlanSyncInterval)
+		assert.Equal(t, []string{"ops@example.com", "finance@example.com"}, cfg.Float.AlertEmails)
+	})
+
+	t.Run("Test bad env value", func(t *testing.T) {
+		t.Setenv("BULK_WORKERS", "many")
+
+		_, err := Load(path)
+		assert.ErrorContains(t, err, "BULK_WORKERS")
+	})
+
+	t.Run("Test missing file", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Load(writeConfig(t, testYAML))
+	require.NoError(t, err)
+
+	cfg.Providers.VTpass.APIKey = ""
+	cfg.Providers.Anchor.BaseURL = "anchor"
+	cfg.Features.BulkWorkers = 0
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Equal(t, `config: invalid configuration:
+  providers.vtpass.api_key (APIKey) is required
+  providers.anchor.base_url (ANCHOR_API) must be a url
+  features.bulk_workers (BULK_WORKERS) must be at least 1`, err.Error())
+}
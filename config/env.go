@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv sets every field with an env tag whose variable is set. Nested structs are walked, an envPrefix
+// tag is prepended to the variable names of the struct below it.
+func loadEnv(cfg *Config) error {
+	return walkEnv(reflect.ValueOf(cfg).Elem(), "", func(field reflect.Value, name string) error {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return nil
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func walkEnv(v reflect.Value, prefix string, fn func(field reflect.Value, name string) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, sf := v.Field(i), t.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			if err := walkEnv(field, prefix+sf.Tag.Get("envPrefix"), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := sf.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if err := fn(field, prefix+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		// lists are comma separated
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
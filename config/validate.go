@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validate checks the configuration and returns every invalid setting in one error, each named by its
+// YAML path and environment variable.
+func (c *Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("yaml"), ",", 2)[0]
+	})
+
+	err := validate.Struct(c)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	names := envNames(c)
+	problems := make([]string, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		path := strings.TrimPrefix(fe.Namespace(), "Config.")
+		problems = append(problems, fmt.Sprintf("%s (%s) %s", path, names[path], describe(fe)))
+	}
+
+	return fmt.Errorf("config: invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+}
+
+func describe(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "oneof":
+		return "must be one of " + fe.Param()
+	case "url":
+		return "must be a url"
+	case "email":
+		return "must be an email address"
+	case "numeric":
+		return "must be a number"
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	}
+	return "failed " + fe.Tag()
+}
+
+// envNames maps the YAML path of every field to its environment variable.
+func envNames(c *Config) map[string]string {
+	names := map[string]string{}
+
+	var walk func(t reflect.Type, path, prefix string)
+	walk = func(t reflect.Type, path, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			p := path + strings.SplitN(sf.Tag.Get("yaml"), ",", 2)[0]
+			if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+				walk(sf.Type, p+".", prefix+sf.Tag.Get("envPrefix"))
+				continue
+			}
+			names[p] = prefix + sf.Tag.Get("env")
+		}
+	}
+	walk(reflect.TypeOf(*c), "", "")
+
+	return names
+}
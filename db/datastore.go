@@ -1,91 +1,243 @@
 package db
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/aremxyplug-be/db/models"
 	"github.com/aremxyplug-be/db/models/telcom"
 )
 
+// ErrDuplicate is returned when a write would break a uniqueness the store guarantees.
+var ErrDuplicate = errors.New("document already exists")
+
 type DataStore interface {
 	Extras
 	BankStore
 	UserStore
 	TelcomStore
 	UtilitiesStore
+	SchedulerStore
+	BulkStore
+	TransactionStore
+	FloatStore
+	ReconciliationStore
+	RefundStore
+	DisputeStore
+	NotificationStore
 }
 
 type Extras interface {
-	SaveOTP(data models.OTP) error
-	GetOTP(email string) (models.OTP, error)
-	GetPin(userID string) (string, error)
-	UpdatePin(data models.UserPin) error
-	SavePin(data models.UserPin) error
-	UpdateReferralCount(referralCode string) error
-	CreateUserReferral(userID, refcode string) error
-	GetReferral(userID string) (string, error)
-	UpdatePoint(userID string, points int) error
-	CreatePointDoc(userID string) error
-	CanRedeemPoints(userID string, points int) bool
-	GetPoint(userID string) (models.Points, error)
+	SaveOTP(ctx context.Context, data models.OTP) error
+	GetOTP(ctx context.Context, email string) (models.OTP, error)
+	GetPin(ctx context.Context, userID string) (string, error)
+	UpdatePin(ctx context.Context, data models.UserPin) error
+	SavePin(ctx context.Context, data models.UserPin) error
+	UpdateReferralCount(ctx context.Context, referralCode string) error
+	CreateUserReferral(ctx context.Context, userID, refcode string) error
+	GetReferral(ctx context.Context, userID string) (string, error)
+	UpdatePoint(ctx context.Context, userID string, points int) error
+	CreatePointDoc(ctx context.Context, userID string) error
+	CanRedeemPoints(ctx context.Context, userID string, points int) bool
+	GetPoint(ctx context.Context, userID string) (models.Points, error)
 }
 
 type BankStore interface {
-	SaveBankList(banklist models.BankDetails) error
-	GetBankDetail(bankName string) (models.BankDetails, error)
-	SaveVirtualAccount(account models.AccountDetails) error
-	GetVirtualNuban(name string) (models.AccountDetails, error)
-	SaveCounterParty(counterparty interface{}) error
-	SaveTransfer(transfer models.TransferResponse) error
-	GetCounterParty(accountNumber, bankname string) (models.CounterParty, error)
-	GetTransferDetails(id string) (models.TransferResponse, error)
-	GetAllTransferHistory(user string) ([]models.TransferResponse, error)
-	GetDepositDetails(id string) (models.DepositResponse, error)
-	GetAllDepositHistory(user string) ([]models.DepositResponse, error)
-	GetAllBankTransactions(user string) ([]interface{}, error)
-	SaveDeposit(detail models.DepositResponse) error
-	GetDepositID(virtualNuban string) (result interface{}, err error)
-	SaveDepositID(detail interface{}) error
-	GetBalance(virtualNuban string) (balance float64, err error)
-	SaveBalance(virtualNuban string, balance models.Balance) error
-	UpdateBalance(virtualNuban string, balance float64) error
+	Transactor
+	LedgerStore
+	SaveBankList(ctx context.Context, banklist models.BankDetails) error
+	GetBankDetail(ctx context.Context, bankName string) (models.BankDetails, error)
+	SaveVirtualAccount(ctx context.Context, account models.AccountDetails) error
+	GetVirtualNuban(ctx context.Context, name string) (models.AccountDetails, error)
+	GetVirtualAccounts(ctx context.Context) ([]models.AccountDetails, error)
+	SaveCounterParty(ctx context.Context, counterparty interface{}) error
+	SaveTransfer(ctx context.Context, transfer models.TransferResponse) error
+	UpdateTransferStatus(ctx context.Context, transactionID, status string) error
+	// SettleTransfer moves a pending transfer to status. It returns mongo.ErrNoDocuments when the transfer
+	// is no longer pending.
+	SettleTransfer(ctx context.Context, transactionID, status string) error
+	// GetPendingTransfers returns the transfers made before before that are still pending, oldest first.
+	GetPendingTransfers(ctx context.Context, before time.Time) ([]models.TransferResponse, error)
+	GetCounterParty(ctx context.Context, accountNumber, bankname string) (models.CounterParty, error)
+	GetTransferDetails(ctx context.Context, userID, id string) (models.TransferResponse, error)
+	// GetTransferByTransactionID finds a transfer by the transaction id anchor reports it under.
+	GetTransferByTransactionID(ctx context.Context, transactionID string) (models.TransferResponse, error)
+	GetAllTransferHistory(ctx context.Context, opts ListOptions) ([]models.TransferResponse, string, error)
+	GetDepositDetails(ctx context.Context, userID, id string) (models.DepositResponse, error)
+	GetAllDepositHistory(ctx context.Context, opts ListOptions) ([]models.DepositResponse, string, error)
+	GetAllBankTransactions(ctx context.Context, opts ListOptions) ([]interface{}, string, error)
+	SaveDeposit(ctx context.Context, detail models.DepositResponse) error
+	GetDepositID(ctx context.Context, virtualNuban string) (result interface{}, err error)
+	SaveDepositID(ctx context.Context, detail interface{}) error
+	SaveDepositAccount(ctx context.Context, account models.DepositAccount) error
+	GetBalance(ctx context.Context, virtualNuban string) (balance float64, err error)
+	SaveBalance(ctx context.Context, virtualNuban string, balance models.Balance) error
+	UpdateBalance(ctx context.Context, virtualNuban string, balance float64) error
+}
+
+// Transactor runs fn in a transaction, the writes fn makes with the ctx it is given are committed together
+// or not at all. fn may be run more than once when the transaction is retried, so it must not have side
+// effects outside the store.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// LedgerStore records the movements of the users' wallets.
+type LedgerStore interface {
+	SaveLedgerEntry(ctx context.Context, entry models.LedgerEntry) error
+	// GetLedgerEntries returns the entries of userID created from from to to, oldest first.
+	GetLedgerEntries(ctx context.Context, userID string, from, to time.Time) ([]models.LedgerEntry, error)
+	// GetLedgerEntriesByType returns the entries of every user of entryType created from from up to to,
+	// oldest first.
+	GetLedgerEntriesByType(ctx context.Context, entryType string, from, to time.Time) ([]models.LedgerEntry, error)
+	// GetLedgerEntriesByReference returns the entries of userID made for reference, oldest first.
+	GetLedgerEntriesByReference(ctx context.Context, userID, reference string) ([]models.LedgerEntry, error)
+	// GetBalanceAt returns the balance of userID left by its last entry before t, 0 when it has none.
+	GetBalanceAt(ctx context.Context, userID string, t time.Time) (float64, error)
+	// GetLedgerUsers returns the users with entries created from from to to.
+	GetLedgerUsers(ctx context.Context, from, to time.Time) ([]string, error)
+	// ClaimStatement records that the statement of userID for period is being sent, it returns false when
+	// it already was.
+	ClaimStatement(ctx context.Context, userID, period string) (bool, error)
 }
 
 type UserStore interface {
-	SaveUser(user models.User) error
-	GetUserByEmail(email string) (*models.User, error)
-	GetUserByUsername(username string) (*models.User, error)
-	GetUserByUsernameOrEmail(email string, username string) (*models.User, error)
-	GetUserByID(id string) (*models.User, error)
-	CreateMessage(message *models.Message) error
-	UpdateUserPassword(email string, password string) error
-	UpdateBVNField(user models.User) error
-	VerifyUser(email string) (*models.User, error)
+	SaveUser(ctx context.Context, user models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByUsernameOrEmail(ctx context.Context, email string, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	CreateMessage(ctx context.Context, message *models.Message) error
+	UpdateUserPassword(ctx context.Context, email string, password string) error
+	UpdateBVNField(ctx context.Context, user models.User) error
+	VerifyUser(ctx context.Context, email string) (*models.User, error)
 }
 
 type TelcomStore interface {
-	SaveDataTransaction(details interface{}) error
-	GetDataTransactionDetails(id string) (telcom.DataResult, error)
-	GetAllDataTransactions(username string) ([]telcom.DataResult, error)
-	GetSpecTransDetails(id string) (telcom.SpectranetResult, error)
-	GetAllSpecDataTransactions(username string) ([]telcom.SpectranetResult, error)
-	GetSmileTransDetails(id string) (telcom.SmileResult, error)
-	GetAllSmileDataTransactions(username string) ([]telcom.SmileResult, error)
-	SaveAirtimeTransaction(details *telcom.AirtimeResponse) error
-	GetAirtimeTransactionDetails(id string) (telcom.AirtimeResponse, error)
-	GetAllAirtimeTransactions(username string) ([]telcom.AirtimeResponse, error)
-	SaveTelcomRecipient(userID string, data telcom.Recipient) error
-	GetTelcomRecipients(username string) (telcom.TelcomRecipient, error)
-	EditTelcomRecipient(userID string, data telcom.Recipient) error
-	DeleteTelcomRecipient(recipientID int, userID string) error
+	SaveDataTransaction(ctx context.Context, details interface{}) error
+	GetDataTransactionDetails(ctx context.Context, userID, id string) (telcom.DataResult, error)
+	GetAllDataTransactions(ctx context.Context, opts ListOptions) ([]telcom.DataResult, string, error)
+	GetSpecTransDetails(ctx context.Context, userID, id string) (telcom.SpectranetResult, error)
+	GetAllSpecDataTransactions(ctx context.Context, opts ListOptions) ([]telcom.SpectranetResult, string, error)
+	GetSmileTransDetails(ctx context.Context, userID, id string) (telcom.SmileResult, error)
+	GetAllSmileDataTransactions(ctx context.Context, opts ListOptions) ([]telcom.SmileResult, string, error)
+	SaveAirtimeTransaction(ctx context.Context, details *telcom.AirtimeResponse) error
+	GetAirtimeTransactionDetails(ctx context.Context, userID, id string) (telcom.AirtimeResponse, error)
+	GetAllAirtimeTransactions(ctx context.Context, opts ListOptions) ([]telcom.AirtimeResponse, string, error)
+	SaveTelcomRecipient(ctx context.Context, userID string, data telcom.Recipient) error
+	GetTelcomRecipients(ctx context.Context, username string) (telcom.TelcomRecipient, error)
+	EditTelcomRecipient(ctx context.Context, userID string, data telcom.Recipient) error
+	DeleteTelcomRecipient(ctx context.Context, recipientID int, userID string) error
+	SaveDataPlans(ctx context.Context, provider string, plans []telcom.DataPlan) error
+	GetDataPlans(ctx context.Context, network string) ([]telcom.DataPlan, error)
+	GetDataPlan(ctx context.Context, provider, providerCode string) (telcom.DataPlan, error)
 }
 
 type UtilitiesStore interface {
-	SaveEduTransaction(details *models.EduResponse) error
-	GetEduTransactionDetails(id string) (models.EduResponse, error)
-	GetAllEduTransactions(user string) ([]models.EduResponse, error)
-	SaveTVSubcriptionTransaction(details *models.BillResult) error
-	GetTvSubscriptionDetails(id string) (models.BillResult, error)
-	GetAllTvSubTransactions(user string) ([]models.BillResult, error)
-	SaveElectricTransaction(details *models.ElectricResult) error
-	GetElectricSubDetails(id string) (models.ElectricResult, error)
-	GetAllElectricSubTransactions(user string) ([]models.ElectricResult, error)
+	SaveEduTransaction(ctx context.Context, details *models.EduResponse) error
+	GetEduTransactionDetails(ctx context.Context, userID, id string) (models.EduResponse, error)
+	GetAllEduTransactions(ctx context.Context, opts ListOptions) ([]models.EduResponse, string, error)
+	SaveTVSubcriptionTransaction(ctx context.Context, details *models.BillResult) error
+	GetTvSubscriptionDetails(ctx context.Context, userID, id string) (models.BillResult, error)
+	GetAllTvSubTransactions(ctx context.Context, opts ListOptions) ([]models.BillResult, string, error)
+	SaveElectricTransaction(ctx context.Context, details *models.ElectricResult) error
+	GetElectricSubDetails(ctx context.Context, userID, id string) (models.ElectricResult, error)
+	GetAllElectricSubTransactions(ctx context.Context, opts ListOptions) ([]models.ElectricResult, string, error)
+}
+
+type SchedulerStore interface {
+	SaveScheduledOrder(ctx context.Context, order models.ScheduledOrder) error
+	ClaimScheduledOrder(ctx context.Context, order models.ScheduledOrder, previousRun time.Time) error
+	UpdateScheduledOrderStatus(ctx context.Context, order models.ScheduledOrder, from string) error
+	RecordScheduledRun(ctx context.Context, run models.ScheduledRun) error
+	GetScheduledOrder(ctx context.Context, id string) (models.ScheduledOrder, error)
+	GetScheduledOrders(ctx context.Context, username string) ([]models.ScheduledOrder, error)
+	GetDueScheduledOrders(ctx context.Context, now time.Time) ([]models.ScheduledOrder, error)
+	SaveScheduledRun(ctx context.Context, run models.ScheduledRun) error
+	GetScheduledRuns(ctx context.Context, orderID string) ([]models.ScheduledRun, error)
+}
+
+// TransactionStore finds a transaction whatever product it belongs to.
+type TransactionStore interface {
+	// GetTransaction returns the transaction of userID with the order id as the model of its product, for
+	// example a telcom.DataResult or a models.TransferResponse.
+	GetTransaction(ctx context.Context, userID, id string) (interface{}, error)
+}
+
+type BulkStore interface {
+	SaveBulkBatch(ctx context.Context, batch models.BulkBatch) error
+	UpdateBulkRow(ctx context.Context, batchID string, row models.BulkRow) error
+	ClaimBulkRow(ctx context.Context, batchID string, row int) error
+	CompleteBulkBatch(ctx context.Context, batch models.BulkBatch) error
+	GetProcessingBulkBatches(ctx context.Context) ([]models.BulkBatch, error)
+	GetBulkBatch(ctx context.Context, id string) (models.BulkBatch, error)
+}
+
+// FloatStore keeps the balances of our wallets at the providers.
+type FloatStore interface {
+	SaveProviderBalance(ctx context.Context, balance models.ProviderBalance) error
+	// GetProviderBalances returns the balances of provider checked from from to to, oldest first.
+	GetProviderBalances(ctx context.Context, provider string, from, to time.Time) ([]models.ProviderBalance, error)
+	// GetLatestProviderBalances returns the last balance checked at every provider.
+	GetLatestProviderBalances(ctx context.Context) ([]models.ProviderBalance, error)
+}
+
+// ReconciliationStore keeps the reports of matching our transactions with the providers' and the
+// exceptions finance resolves.
+type ReconciliationStore interface {
+	// GetReconRecords returns the transactions we recorded with provider from from to to.
+	GetReconRecords(ctx context.Context, provider string, from, to time.Time) ([]models.ReconRecord, error)
+	// SaveReconciliation replaces the report of the provider and day of rec and its open exceptions.
+	// Resolved exceptions are kept.
+	SaveReconciliation(ctx context.Context, rec models.Reconciliation, exceptions []models.ReconException) error
+	GetReconciliations(ctx context.Context, opts ListOptions, provider string) ([]models.Reconciliation, string, error)
+	// GetReconExceptions returns the exceptions of provider, or of every provider when it is empty. The
+	// status of opts selects open or resolved ones.
+	GetReconExceptions(ctx context.Context, opts ListOptions, provider string) ([]models.ReconException, string, error)
+	// GetResolvedReconExceptions returns the exceptions of the provider and day that were resolved.
+	GetResolvedReconExceptions(ctx context.Context, provider, date string) ([]models.ReconException, error)
+	// ResolveReconException closes an open exception, it returns mongo.ErrNoDocuments when there is no open
+	// exception with the id.
+	ResolveReconException(ctx context.Context, id, resolution, resolvedBy string, at time.Time) error
+}
+
+// RefundStore keeps the refunds of the users' transactions.
+type RefundStore interface {
+	// CreateRefund saves a new refund, it returns ErrDuplicate when the transaction already has a refund
+	// that was not rejected or failed.
+	CreateRefund(ctx context.Context, refund models.Refund) error
+	GetRefund(ctx context.Context, id string) (models.Refund, error)
+	GetRefunds(ctx context.Context, opts ListOptions) ([]models.Refund, string, error)
+	// UpdateRefundStatus moves a refund from status from to event.Status and adds event to its trail. It
+	// returns mongo.ErrNoDocuments when the refund is not in status from.
+	UpdateRefundStatus(ctx context.Context, id, from string, event models.RefundEvent) error
+}
+
+// DisputeStore keeps the users' dispute tickets and their attachments.
+type DisputeStore interface {
+	// CreateDispute saves a new ticket with the content of its attachments, it returns ErrDuplicate when
+	// the transaction already has a ticket that is not closed.
+	CreateDispute(ctx context.Context, dispute models.Dispute, files []models.DisputeFile) error
+	// GetDispute returns the ticket with id, only when it belongs to userID if one is given.
+	GetDispute(ctx context.Context, userID, id string) (models.Dispute, error)
+	GetDisputes(ctx context.Context, opts ListOptions) ([]models.Dispute, string, error)
+	GetDisputeFile(ctx context.Context, disputeID, id string) (models.DisputeFile, error)
+	// AddDisputeEvent adds event to the ticket's timeline and applies the status or refund it carries. It
+	// returns mongo.ErrNoDocuments when the ticket is closed, or no longer in status from for a status change.
+	AddDisputeEvent(ctx context.Context, id, from string, event models.DisputeEvent) error
+}
+
+// NotificationStore keeps the users' notification inbox, saved with CreateMessage, and their channel
+// preferences.
+type NotificationStore interface {
+	GetNotifications(ctx context.Context, userID string, opts ListOptions) ([]models.Message, string, error)
+	CountUnreadNotifications(ctx context.Context, userID string) (int64, error)
+	// MarkNotificationRead returns mongo.ErrNoDocuments when the user has no notification with id.
+	MarkNotificationRead(ctx context.Context, userID, id string, at time.Time) error
+	// MarkAllNotificationsRead returns how many notifications were unread.
+	MarkAllNotificationsRead(ctx context.Context, userID string, at time.Time) (int64, error)
+	// GetNotificationPreferences returns mongo.ErrNoDocuments when the user has not set any.
+	GetNotificationPreferences(ctx context.Context, userID string) (models.NotificationPreferences, error)
+	SaveNotificationPreferences(ctx context.Context, prefs models.NotificationPreferences) error
 }
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListOptions selects a page of a list, newest first. The zero value is the first page of everything.
+type ListOptions struct {
+	// UserID restricts the list to the transactions of one user, it is empty for the admin lists.
+	UserID string
+	// Cursor is the next_cursor of the previous page, it is empty for the first page.
+	Cursor string
+	// Limit is the page size, it defaults to DefaultPageSize and is capped at MaxPageSize.
+	Limit int
+
+	From      time.Time
+	To        time.Time
+	Status    string
+	MinAmount float64
+	MaxAmount float64
+}
+
+// PageSize returns the number of items a page holds.
+func (o ListOptions) PageSize() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultPageSize
+	case o.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return o.Limit
+	}
+}
+
+// Cursor is the position of the last item of a page, the next page starts after it. Items are ordered by
+// creation time then id so items created at the same time are neither skipped nor repeated.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// Encode returns the cursor as an opaque string safe to use in a url.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor returned by Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListOptions_PageSize(t *testing.T) {
+	var tests = []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "Test default", limit: 0, want: DefaultPageSize},
+		{name: "Test negative", limit: -5, want: DefaultPageSize},
+		{name: "Test within range", limit: 50, want: 50},
+		{name: "Test capped", limit: MaxPageSize + 1, want: MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ListOptions{Limit: tt.limit}.PageSize())
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), ID: "6632176e9f1c2a0a4c8b4567"}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	assert.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+
+	var tests = []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Test not base64", cursor: "not a cursor!"},
+		{name: "Test not json", cursor: "bm90IGpzb24"},
+		{name: "Test missing id", cursor: Cursor{CreatedAt: cursor.CreatedAt}.Encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
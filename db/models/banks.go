@@ -1,24 +1,41 @@
 package models
 
+import "time"
+
+// statuses of a transfer, a transfer is pending from its debit until the bank accepts or rejects it. A
+// sent transfer is failed when anchor reports the bank did not pay it.
+const (
+	TransferPending  = "pending"
+	TransferSent     = "sent"
+	TransferRejected = "rejected"
+	TransferFailed   = "failed"
+)
+
 type TransferInfo struct {
 	Bank_name      string  `json:"bank_name"`
 	Account_Number string  `json:"account_number"`
 	Account_Name   string  `json:"account_name"`
 	Amount         float64 `json:"amount"`
 	Reason         string  `json:"message"`
+	UserID         string  `json:"-" bson:"-"`
 }
 
 type TransferResponse struct {
-	Bank_Name      string `json:"bank_name"`
-	Account_Name   string `json:"account_name"`
-	Account_No     string `json:"account_no"`
-	Name           string `json:"name"`
-	Product        string `json:"product"`
-	Description    string `json:"description"`
-	Reason         string `json:"message"`
-	Order_ID       int    `json:"order_id"`
-	Transaction_ID string `json:"transaction_id"`
-	Session_ID     string `json:"session_id"`
+	Bank_Name      string    `json:"bank_name"`
+	Account_Name   string    `json:"account_name"`
+	Account_No     string    `json:"account_no"`
+	Amount         float64   `json:"amount"`
+	Fee            float64   `json:"fee"`
+	Name           string    `json:"name"`
+	Product        string    `json:"product"`
+	Description    string    `json:"description"`
+	Reason         string    `json:"message"`
+	UserID         string    `json:"user_id" bson:"user_id"`
+	Order_ID       int       `json:"order_id"`
+	Transaction_ID string    `json:"transaction_id"`
+	Session_ID     string    `json:"session_id"`
+	Status         string    `json:"status,omitempty" bson:"status,omitempty"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
 }
 
 type AccountDetails struct {
@@ -29,6 +46,14 @@ type AccountDetails struct {
 	VirtualAccountID string `json:"virtualaccountid" bson:"virtualaccountid"`
 }
 
+// DepositAccount is a deposit account created for the business at anchor, DEPOSIT_ID_LIVE is set to the
+// id of the one transfers are paid from.
+type DepositAccount struct {
+	ID         string    `json:"id" bson:"id"`
+	CustomerID string    `json:"customer_id" bson:"customer_id"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+}
+
 type CounterParty struct {
 	ID            string `json:"id"`
 	AccountName   string `json:"account_name"`
@@ -42,21 +67,23 @@ type BankDetails struct {
 }
 
 type DepositResponse struct {
-	Amount         string `json:"amount"`         // amount recieved
-	WalletType     string `json:"walletType"`     // Nigerian NGN wallet
-	Bank_Name      string `json:"bank_name"`      // sender's bank name
-	Account_Name   string `json:"account_name"`   // sender's account name
-	Account_No     string `json:"account_no"`     // sender's account number
-	Product        string `json:"product"`        // *Virtual account
-	Description    string `json:"description"`    // description based on the method of deposit
-	Message        string `json:"message"`        // map to narration
-	Order_ID       int    `json:"order_id"`       // orderID created
-	Transaction_ID string `json:"transaction_id"` // transactionID created
-	Session_ID     string `json:"session_id"`     // map to paymentReference
+	Amount         string    `json:"amount"`         // amount recieved
+	WalletType     string    `json:"walletType"`     // Nigerian NGN wallet
+	Bank_Name      string    `json:"bank_name"`      // sender's bank name
+	Account_Name   string    `json:"account_name"`   // sender's account name
+	Account_No     string    `json:"account_no"`     // sender's account number
+	Product        string    `json:"product"`        // *Virtual account
+	Description    string    `json:"description"`    // description based on the method of deposit
+	Message        string    `json:"message"`        // map to narration
+	Order_ID       int       `json:"order_id"`       // orderID created
+	Transaction_ID string    `json:"transaction_id"` // transactionID created
+	Session_ID     string    `json:"session_id"`     // map to paymentReference
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+	UserID         string    `json:"user_id" bson:"user_id"` // owner of the virtual account credited
 }
 
 type Balance struct {
-	VirtualNuban string  `json:"virtualNuban"`
-	UserID       string  `json:"user_id"`
-	Balance      float64 `json:"balance"`
+	VirtualNuban string  `json:"virtualNuban" bson:"virtual_nuban"`
+	UserID       string  `json:"user_id" bson:"user_id"`
+	Balance      float64 `json:"balance" bson:"balance"`
 }
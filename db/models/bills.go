@@ -1,14 +1,20 @@
 package models
 
+import "time"
+
 type TvInfo struct {
-	DecoderType      string `json:"decoder_type"`
-	SmartCard_Number string `json:"iuc_number"`
-	Package          string `json:"package"`
-	Email            string `json:"email"`
+	DecoderType      string `json:"decoder_type" validate:"required,oneof=dstv gotv startimes showmax"`
+	SmartCard_Number string `json:"iuc_number" validate:"required,iuc"`
+	Package          string `json:"package" validate:"required_if=SubType change"`
+	Email            string `json:"email" validate:"omitempty,email"`
 	Amount           int    `json:"amount"`
 	Phone            string `json:"phone"`
-	SubType          string `json:"sub_type"`
+	SubType          string `json:"sub_type" validate:"omitempty,oneof=renew change"`
 	RequestID        string `json:"request_id"`
+	UserID           string `json:"-" bson:"-"`
+	// OrderID is the order the purchase is saved under, callers that debit the wallet for it set it to the
+	// reference of the debit. A new one is generated when it is 0.
+	OrderID int `json:"-" bson:"-"`
 }
 
 type TvAPI struct {
@@ -40,16 +46,38 @@ type Trans_Date struct {
 }
 
 type BillResult struct {
-	DecoderType   string `json:"decoder_type"`
-	Package       string `json:"package"`
-	IucNumber     string `json:"iuc_number"`
-	Phone         string `json:"phone"`
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	Amount        int    `json:"amount"`
-	Product       string `json:"product"`
-	Description   string `json:"description"`
-	OrderID       int    `json:"order_id"`
-	TranscationID string `json:"transcation_id"`
-	RequestID     string `json:"request_id"`
+	DecoderType   string    `json:"decoder_type"`
+	Package       string    `json:"package"`
+	IucNumber     string    `json:"iuc_number"`
+	Phone         string    `json:"phone"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	Amount        int       `json:"amount"`
+	Product       string    `json:"product"`
+	Description   string    `json:"description"`
+	UserID        string    `json:"user_id" bson:"user_id"`
+	OrderID       int       `json:"order_id" bson:"order_id"`
+	TranscationID string    `json:"transcation_id" bson:"transaction_id"`
+	RequestID     string    `json:"request_id" bson:"request_id"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+}
+
+// TvPackage is a bouquet offered by a tv provider.
+type TvPackage struct {
+	Provider string  `json:"provider"`
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+}
+
+// SmartCardInfo holds the customer details returned when a smartcard/IUC number is verified.
+type SmartCardInfo struct {
+	Provider           string  `json:"provider"`
+	SmartCard_Number   string  `json:"iuc_number"`
+	Customer_Name      string  `json:"customer_name"`
+	Status             string  `json:"status,omitempty"`
+	Current_Bouquet    string  `json:"current_bouquet,omitempty"`
+	Current_Bouquet_ID string  `json:"current_bouquet_code,omitempty"`
+	Due_Date           string  `json:"due_date,omitempty"`
+	Renewal_Amount     float64 `json:"renewal_amount,omitempty"`
 }
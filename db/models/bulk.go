@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	BulkProcessing = "processing"
+	BulkCompleted  = "completed"
+
+	RowPending    = "pending"
+	RowProcessing = "processing"
+	RowInvalid    = "invalid"
+	RowSuccess    = "success"
+	RowFailed     = "failed"
+	// RowUnconfirmed is a row the provider gave no answer for, or that was being bought when the service
+	// stopped. It is not refunded until a requery or support confirms the purchase failed.
+	RowUnconfirmed = "unconfirmed"
+)
+
+// BulkBatch is a set of airtime or data purchases paid for in one request.
+type BulkBatch struct {
+	ID          string     `json:"id" bson:"id"`
+	UserID      string     `json:"user_id" bson:"user_id"`
+	Username    string     `json:"username" bson:"username"`
+	Product     string     `json:"product" bson:"product"` // airtime or data
+	Status      string     `json:"status" bson:"status"`
+	Total       float64    `json:"total" bson:"total"`       // amount reserved from the wallet
+	Refunded    float64    `json:"refunded" bson:"refunded"` // amount returned for failed rows
+	Succeeded   int        `json:"succeeded" bson:"succeeded"`
+	Failed      int        `json:"failed" bson:"failed"`
+	Rows        []BulkRow  `json:"rows" bson:"rows"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// BulkRow is one line of a bulk purchase. Amount is used for airtime and Plan for data.
+type BulkRow struct {
+	Row       int     `json:"row" bson:"row"`
+	Phone     string  `json:"phone" bson:"phone"`
+	Network   string  `json:"network" bson:"network"`
+	Amount    string  `json:"amount,omitempty" bson:"amount,omitempty"`
+	Plan      int     `json:"plan,omitempty" bson:"plan,omitempty"`
+	Price     float64 `json:"price" bson:"price"`
+	Status    string  `json:"status" bson:"status"`
+	Error     string  `json:"error,omitempty" bson:"error,omitempty"`
+	Warning   string  `json:"warning,omitempty" bson:"warning,omitempty"`
+	Reference string  `json:"reference,omitempty" bson:"reference,omitempty"` // transaction id of the purchase
+	// OrderID is the order the row is debited and bought under, refunds are asked for it. Rows of batches
+	// made before it was kept are debited under the id of their batch.
+	OrderID int `json:"order_id,omitempty" bson:"order_id,omitempty"`
+}
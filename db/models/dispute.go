@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// reasons a user disputes a transaction.
+const (
+	DisputeNotReceived = "not_received"
+	DisputeWrongNumber = "wrong_number"
+	DisputeDoubleDebit = "double_debit"
+)
+
+// statuses of a dispute ticket. A resolved ticket can be reopened, a closed one is final.
+const (
+	DisputeOpen       = "open"
+	DisputeInProgress = "in_progress"
+	DisputeResolved   = "resolved"
+	DisputeClosed     = "closed"
+)
+
+// types of the events in a dispute's timeline.
+const (
+	DisputeEventComment = "comment"
+	DisputeEventStatus  = "status"
+	DisputeEventRequery = "requery"
+	DisputeEventRefund  = "refund"
+)
+
+// DisputeAttachment describes a file the user sent with a dispute, the content is kept as a DisputeFile.
+type DisputeAttachment struct {
+	ID          string `json:"id" bson:"id"`
+	Name        string `json:"name" bson:"name"`
+	ContentType string `json:"content_type" bson:"content_type"`
+	Size        int    `json:"size" bson:"size"`
+}
+
+// DisputeFile is the content of a dispute attachment.
+type DisputeFile struct {
+	ID          string    `json:"id" bson:"id"`
+	DisputeID   string    `json:"dispute_id" bson:"dispute_id"`
+	Name        string    `json:"name" bson:"name"`
+	ContentType string    `json:"content_type" bson:"content_type"`
+	Data        []byte    `json:"-" bson:"data"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+// DisputeEvent is a comment, status change, provider requery or refund on a dispute.
+type DisputeEvent struct {
+	Type     string    `json:"type" bson:"type"`
+	Actor    string    `json:"actor" bson:"actor"`
+	Staff    bool      `json:"staff" bson:"staff"`
+	Note     string    `json:"note,omitempty" bson:"note,omitempty"`
+	Status   string    `json:"status,omitempty" bson:"status,omitempty"`       // new status of a status change
+	RefundID string    `json:"refund_id,omitempty" bson:"refund_id,omitempty"` // refund requested from the ticket
+	At       time.Time `json:"at" bson:"at"`
+}
+
+// Dispute is a user's complaint about one of their transactions, worked by support until it is resolved.
+type Dispute struct {
+	ID            string              `json:"id" bson:"id"`
+	UserID        string              `json:"user_id" bson:"user_id"`
+	TransactionID string              `json:"transaction_id" bson:"transaction_id"` // order id of the transaction disputed
+	Product       string              `json:"product" bson:"product"`
+	Category      string              `json:"category" bson:"category"`
+	Description   string              `json:"description" bson:"description"`
+	Status        string              `json:"status" bson:"status"`
+	Attachments   []DisputeAttachment `json:"attachments" bson:"attachments"`
+	Events        []DisputeEvent      `json:"events" bson:"events"`
+	RefundID      string              `json:"refund_id,omitempty" bson:"refund_id,omitempty"`
+	// Active holds the transaction id until the ticket is closed, a unique index on it keeps one ticket
+	// per transaction.
+	Active string `json:"-" bson:"active,omitempty"`
+	// DueAt is when the SLA runs out, SLABreached is set on the way out when the ticket was not resolved
+	// by then.
+	DueAt       time.Time  `json:"due_at" bson:"due_at"`
+	SLABreached bool       `json:"sla_breached" bson:"-"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`
+}
@@ -1,12 +1,15 @@
 package models
 
+import "time"
+
 type EduInfo struct {
-	Exam_Type    string `json:"exam_type"`
-	Phone_Number string `json:"phone_no"`
-	Amount       string `json:"amount"`
-	Email        string `json:"email"`
-	Quantity     int    `json:"quantity"`
+	Exam_Type    string `json:"exam_type" validate:"required"`
+	Phone_Number string `json:"phone_no" validate:"required"`
+	Amount       string `json:"amount" validate:"omitempty,amount=1-100000"`
+	Email        string `json:"email" validate:"omitempty,email"`
+	Quantity     int    `json:"quantity" validate:"required,quantity=1-4 10"`
 	Wallet_Type  string `json:"wallet_type"`
+	UserID       string `json:"-" bson:"-"`
 }
 
 type EduApiResponse struct {
@@ -29,17 +32,18 @@ type EduApiResponse struct {
 }
 
 type EduResponse struct {
-	OrderID         int      `json:"order_id" bson:"order_id"`
-	Email           string   `json:"email" bson:"email"`
-	Phone           string   `json:"phone_no" bson:"phone_no"`
-	TransactionID   string   `json:"transaction_id"`
-	Name            string   `json:"name" bson:"name"`
-	ReferenceNumber string   `json:"reference_no" bson:"reference_no"`
-	Product         string   `json:"product" bson:"product"`
-	Amount          float64  `json:"amount" bson:"amount"`
-	Exam_Type       string   `json:"exam_type" bson:"exam_type"`
-	Description     string   `json:"description" bson:"description"`
-	Status          string   `json:"status" bson:"status"`
-	Pin_Generated   []string `json:"pins_generated" bson:"pins_generated"`
-	CreatedAt       string   `json:"created_at" bson:"created_at"`
+	UserID          string    `json:"user_id" bson:"user_id"`
+	OrderID         int       `json:"order_id" bson:"order_id"`
+	Email           string    `json:"email" bson:"email"`
+	Phone           string    `json:"phone_no" bson:"phone_no"`
+	TransactionID   string    `json:"transaction_id" bson:"transaction_id"`
+	Name            string    `json:"name" bson:"name"`
+	ReferenceNumber string    `json:"reference_no" bson:"reference_no"`
+	Product         string    `json:"product" bson:"product"`
+	Amount          float64   `json:"amount" bson:"amount"`
+	Exam_Type       string    `json:"exam_type" bson:"exam_type"`
+	Description     string    `json:"description" bson:"description"`
+	Status          string    `json:"status" bson:"status"`
+	Pin_Generated   []string  `json:"pins_generated" bson:"pins_generated"`
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
 }
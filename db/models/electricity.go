@@ -1,13 +1,22 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type ElectricInfo struct {
-	DiscoType  string `json:"disco_type"` // Name of service to buy
-	Meter_No   string `json:"meter_no"`   // meter number
-	Meter_Type string `json:"meter_type"` // meter type
-	Amount     int    `json:"amount"`
+	DiscoType  string `json:"disco_type" validate:"required"`                        // Name of service to buy
+	Meter_No   string `json:"meter_no" validate:"required,meter"`                    // meter number
+	Meter_Type string `json:"meter_type" validate:"required,oneof=prepaid postpaid"` // meter type
+	Amount     int    `json:"amount" validate:"required,amount=500-500000"`
 	Phone      string `json:"phone"`
-	Email      string `json:"email"`
+	Email      string `json:"email" validate:"omitempty,email"`
 	RequestID  string `json:"request_id"`
+	UserID     string `json:"-" bson:"-"`
+	// OrderID is the order the purchase is saved under, callers that debit the wallet for it set it to the
+	// reference of the debit. A new one is generated when it is 0.
+	OrderID int `json:"-" bson:"-"`
 }
 
 type ElectricAPI struct {
@@ -17,6 +26,13 @@ type ElectricAPI struct {
 	RequestID       string          `json:"requestId"`
 	Amount          string          `json:"amount"`
 	Purchased_Token string          `json:"purchased_code"`
+	// not every disco returns these, the token details are read from Purchased_Token when they are missing.
+	Token      string          `json:"token"`
+	MainToken  string          `json:"mainToken"`
+	Units      json.RawMessage `json:"units"`
+	TokenUnits json.RawMessage `json:"mainTokenUnits"`
+	Tariff     json.RawMessage `json:"tariff"`
+	Debt       json.RawMessage `json:"debtAmount"`
 }
 
 type Content struct {
@@ -45,23 +61,45 @@ type VerifyMeterResponse struct {
 }
 
 type verifyContent struct {
-	Name         string `json:"name"`
-	Meter_Number string `json:"meter_number"`
-	Err          string `json:"error,omitempty"`
+	Name                string      `json:"Customer_Name"`
+	Address             string      `json:"Address"`
+	Meter_Number        string      `json:"Meter_Number"`
+	Meter_Type          string      `json:"Meter_Type"`
+	Account_Type        string      `json:"Customer_Account_Type"`
+	Arrears             json.Number `json:"Customer_Arrears"`
+	Min_Purchase_Amount json.Number `json:"Min_Purchase_Amount"`
+	Err                 string      `json:"error,omitempty"`
+}
+
+// MeterInfo holds the customer details returned when a meter number is verified.
+type MeterInfo struct {
+	DiscoType           string  `json:"disco_type"`
+	Meter_Number        string  `json:"meter_no"`
+	Meter_Type          string  `json:"meter_type"`
+	Customer_Name       string  `json:"customer_name"`
+	Address             string  `json:"address"`
+	Arrears             float64 `json:"arrears,omitempty"`
+	Min_Purchase_Amount float64 `json:"min_purchase_amount,omitempty"`
 }
 
 type ElectricResult struct {
-	Amount        string `json:"amount"`
-	DiscoType     string `json:"disco_type" bson:"DiscoType"`
-	MeterType     string `json:"meter_type" bson:"meter_type"` // Prepaid
-	Name          string `json:"name" bson:"name"`
-	MeterNumber   string `json:"meter_number" bson:"meter_number"`
-	Phone         string `json:"phone" bson:"phone"`
-	Email         string `json:"email" bson:"email"`
-	Product       string `json:"product" bson:"product"`
-	Description   string `json:"description" bson:"description"` // append serviceID and variation code.
-	BillGenerated string `json:"bill_generated" bson:"bill_generated"`
-	OrderID       int    `json:"order_id" bson:"order_id"`
-	TransactionID string `json:"transaction_id" bson:"transaction_id"`
-	RequestID     string `json:"request_id" bson:"request_ID"`
+	Amount        string    `json:"amount"`
+	DiscoType     string    `json:"disco_type" bson:"disco_type"`
+	MeterType     string    `json:"meter_type" bson:"meter_type"` // Prepaid
+	Name          string    `json:"name" bson:"name"`
+	MeterNumber   string    `json:"meter_number" bson:"meter_number"`
+	Phone         string    `json:"phone" bson:"phone"`
+	Email         string    `json:"email" bson:"email"`
+	Product       string    `json:"product" bson:"product"`
+	Description   string    `json:"description" bson:"description"` // append serviceID and variation code.
+	BillGenerated string    `json:"bill_generated" bson:"bill_generated"`
+	Token         string    `json:"token,omitempty" bson:"token,omitempty"`
+	Units         string    `json:"units,omitempty" bson:"units,omitempty"`
+	Tariff        string    `json:"tariff,omitempty" bson:"tariff,omitempty"`
+	Debt          string    `json:"debt,omitempty" bson:"debt,omitempty"`
+	UserID        string    `json:"user_id" bson:"user_id"`
+	OrderID       int       `json:"order_id" bson:"order_id"`
+	TransactionID string    `json:"transaction_id" bson:"transaction_id"`
+	RequestID     string    `json:"request_id" bson:"request_id"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
 }
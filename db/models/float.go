@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ProviderBalance is our balance at a provider when the float monitor checked it, the checks of a
+// provider make up the time series of its float.
+type ProviderBalance struct {
+	Provider  string    `json:"provider" bson:"provider"`
+	Balance   float64   `json:"balance" bson:"balance"`
+	Threshold float64   `json:"threshold" bson:"threshold"` // balance below which an alert is sent, 0 when none is
+	Low       bool      `json:"low" bson:"low"`
+	CheckedAt time.Time `json:"checked_at" bson:"checked_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// types of the ledger entries, EntryPoints is for points redeemed against the wallet and EntryReversal
+// for a completed transaction refunded by the refunds workflow.
+const (
+	EntryDeposit  = "deposit"
+	EntryTransfer = "transfer"
+	EntryFee      = "fee"
+	EntryPurchase = "purchase"
+	EntryRefund   = "refund"
+	EntryPoints   = "points"
+	EntryReversal = "reversal"
+)
+
+// LedgerEntry is a movement of a user's wallet. Every change to a balance records one in the same
+// transaction, so the entries of a wallet replay its balance.
+type LedgerEntry struct {
+	ID          string    `json:"id" bson:"id"`
+	UserID      string    `json:"user_id" bson:"user_id"`
+	Type        string    `json:"type" bson:"type"`
+	Amount      float64   `json:"amount" bson:"amount"`       // credits are positive and debits negative
+	Balance     float64   `json:"balance" bson:"balance"`     // balance after the movement
+	Reference   string    `json:"reference" bson:"reference"` // order, batch or scheduled order that moved the balance
+	Description string    `json:"description" bson:"description"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Statement is the movements of a user's wallet over a period with the balances on either side of it.
+type Statement struct {
+	UserID         string        `json:"user_id"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	OpeningBalance float64       `json:"opening_balance"`
+	ClosingBalance float64       `json:"closing_balance"`
+	TotalCredits   float64       `json:"total_credits"`
+	TotalDebits    float64       `json:"total_debits"`
+	Entries        []LedgerEntry `json:"entries"`
+}
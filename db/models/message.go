@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Message model (Messages managed by ROAVA)
 type Message struct {
 	ID          string            `json:"id" bson:"id"`
@@ -13,6 +15,13 @@ type Message struct {
 	DataMap     map[string]string `json:"data_map" bson:"data_map"`
 	Attachments []Attachment      `json:"attachments" bson:"attachments"`
 	Ts          int64             `json:"ts" bson:"ts"`
+
+	// the fields of the notifications kept in the user's inbox, Status is MessageUnread or MessageRead.
+	Category   string     `json:"category,omitempty" bson:"category,omitempty"`
+	Status     string     `json:"status,omitempty" bson:"status,omitempty"`
+	Deliveries []Delivery `json:"deliveries,omitempty" bson:"deliveries,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty" bson:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
 }
 
 type Attachment struct {
@@ -28,4 +37,36 @@ const (
 	PUSH_MESSAGE_TYPE  MessageType = "PUSH"
 	EMAIL_MESSAGE_TYPE MessageType = "EMAIL"
 	SMS_MESSAGE_TYPE   MessageType = "SMS"
+	// INBOX_MESSAGE_TYPE is a notification kept for the user to read in the app, it is sent on the
+	// other types as the user's preferences allow.
+	INBOX_MESSAGE_TYPE MessageType = "INBOX"
+)
+
+const (
+	MessageUnread = "unread"
+	MessageRead   = "read"
+)
+
+// statuses of a delivery.
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
 )
+
+// Delivery is the send of a notification on one channel.
+type Delivery struct {
+	Channel MessageType `json:"channel" bson:"channel"`
+	Target  string      `json:"target" bson:"target"`
+	Status  string      `json:"status" bson:"status"`
+	Error   string      `json:"error,omitempty" bson:"error,omitempty"`
+	At      time.Time   `json:"at" bson:"at"`
+}
+
+// NotificationPreferences are the channels a user wants their notifications on, the inbox always has them.
+type NotificationPreferences struct {
+	UserID    string    `json:"user_id" bson:"user_id"`
+	Email     bool      `json:"email" bson:"email"`
+	SMS       bool      `json:"sms" bson:"sms"`
+	Push      bool      `json:"push" bson:"push"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
@@ -1,6 +1,6 @@
 package models
 
 type UserPin struct {
-	UserID string `json:"user_id"`
-	Pin    string `json:"pin"`
+	UserID string `json:"user_id" bson:"user_id"`
+	Pin    string `json:"pin" bson:"pin"`
 }
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// outcomes of matching a transaction with the provider's report of the same day.
+const (
+	ReconMatched           = "matched"
+	ReconMissingOnOurSide  = "missing_on_our_side"
+	ReconMissingAtProvider = "missing_at_provider"
+	ReconAmountMismatch    = "amount_mismatch"
+)
+
+// statuses of a reconciliation exception.
+const (
+	ExceptionOpen     = "open"
+	ExceptionResolved = "resolved"
+)
+
+// ReconRecord is a transaction as we or a provider recorded it, reduced to what reconciliation compares.
+type ReconRecord struct {
+	Reference string    `json:"reference" bson:"reference"` // request id or provider reference, the key records are matched on
+	Amount    float64   `json:"amount" bson:"amount"`
+	Status    string    `json:"status,omitempty" bson:"status,omitempty"`
+	Product   string    `json:"product,omitempty" bson:"product,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Reconciliation is the report of matching our transactions with a provider's for one day.
+type Reconciliation struct {
+	ID                string    `json:"id" bson:"id"`
+	Provider          string    `json:"provider" bson:"provider"`
+	Date              string    `json:"date" bson:"date"`     // day reconciled in Lagos time, 2006-01-02
+	Source            string    `json:"source" bson:"source"` // api or csv, where the provider's transactions came from
+	Matched           int       `json:"matched" bson:"matched"`
+	MissingOnOurSide  int       `json:"missing_on_our_side" bson:"missing_on_our_side"`
+	MissingAtProvider int       `json:"missing_at_provider" bson:"missing_at_provider"`
+	AmountMismatch    int       `json:"amount_mismatch" bson:"amount_mismatch"`
+	OurTotal          float64   `json:"our_total" bson:"our_total"`
+	ProviderTotal     float64   `json:"provider_total" bson:"provider_total"`
+	CreatedAt         time.Time `json:"created_at" bson:"created_at"`
+}
+
+// ReconException is a transaction that did not match, it stays open until finance resolves it.
+type ReconException struct {
+	ID               string       `json:"id" bson:"id"`
+	ReconciliationID string       `json:"reconciliation_id" bson:"reconciliation_id"`
+	Provider         string       `json:"provider" bson:"provider"`
+	Date             string       `json:"date" bson:"date"`
+	Type             string       `json:"type" bson:"type"`
+	Reference        string       `json:"reference" bson:"reference"`
+	Ours             *ReconRecord `json:"ours,omitempty" bson:"ours,omitempty"`
+	Theirs           *ReconRecord `json:"theirs,omitempty" bson:"theirs,omitempty"`
+	Status           string       `json:"status" bson:"status"`
+	Resolution       string       `json:"resolution,omitempty" bson:"resolution,omitempty"`
+	ResolvedBy       string       `json:"resolved_by,omitempty" bson:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time   `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	CreatedAt        time.Time    `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// statuses of a refund. A refund above the approval threshold starts pending approval, the others are
+// approved when they are requested.
+const (
+	RefundPending   = "pending_approval"
+	RefundApproved  = "approved"
+	RefundCompleted = "completed"
+	RefundRejected  = "rejected"
+	RefundFailed    = "failed"
+)
+
+// who asked for a refund.
+const (
+	RefundSourceAutomatic = "automatic"
+	RefundSourceSupport   = "support"
+)
+
+// RefundEvent is a change of the status of a refund.
+type RefundEvent struct {
+	Status string    `json:"status" bson:"status"`
+	Actor  string    `json:"actor" bson:"actor"` // support user or the system part that made the change
+	Note   string    `json:"note,omitempty" bson:"note,omitempty"`
+	At     time.Time `json:"at" bson:"at"`
+}
+
+// Refund returns the money paid for a transaction to the user's wallet.
+type Refund struct {
+	ID            string  `json:"id" bson:"id"`
+	UserID        string  `json:"user_id" bson:"user_id"`
+	Product       string  `json:"product" bson:"product"`
+	TransactionID string  `json:"transaction_id" bson:"transaction_id"` // order id of the transaction refunded
+	Amount        float64 `json:"amount" bson:"amount"`
+	Reason        string  `json:"reason" bson:"reason"`
+	Source        string  `json:"source" bson:"source"`
+	RequestedBy   string  `json:"requested_by" bson:"requested_by"`
+	Status        string  `json:"status" bson:"status"`
+	// Active holds the transaction id until the refund is rejected or fails, a unique index on it keeps a
+	// transaction from being refunded twice.
+	Active    string        `json:"-" bson:"active,omitempty"`
+	Trail     []RefundEvent `json:"trail" bson:"trail"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" bson:"updated_at"`
+}
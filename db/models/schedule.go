@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/aremxyplug-be/db/models/telcom"
+)
+
+const (
+	ScheduleActive    = "active"
+	SchedulePaused    = "paused"
+	ScheduleCancelled = "cancelled"
+	ScheduleCompleted = "completed"
+
+	RunSuccess = "success"
+	RunFailed  = "failed"
+	RunSkipped = "skipped"
+	// RunUnconfirmed is a run the provider gave no answer for, it is not refunded until a requery or
+	// support confirms the purchase failed.
+	RunUnconfirmed = "unconfirmed"
+)
+
+// ScheduledOrder is a recurring purchase created by a user. Only the details of its product are set.
+type ScheduledOrder struct {
+	ID          string              `json:"id" bson:"id"`
+	UserID      string              `json:"user_id" bson:"user_id"`
+	Username    string              `json:"username" bson:"username"`
+	Email       string              `json:"-" bson:"email"`
+	Phone       string              `json:"-" bson:"phone"`
+	Product     string              `json:"product" bson:"product"` // airtime, data, tv or electricity
+	Cadence     string              `json:"cadence" bson:"cadence"` // cron expression or @daily, @weekly, @monthly
+	StartDate   time.Time           `json:"start_date" bson:"start_date"`
+	EndDate     *time.Time          `json:"end_date,omitempty" bson:"end_date,omitempty"`
+	MaxAmount   float64             `json:"max_amount" bson:"max_amount"`
+	Airtime     *telcom.AirtimeInfo `json:"airtime,omitempty" bson:"airtime,omitempty"`
+	Data        *telcom.DataInfo    `json:"data,omitempty" bson:"data,omitempty"`
+	Tv          *TvInfo             `json:"tv,omitempty" bson:"tv,omitempty"`
+	Electricity *ElectricInfo       `json:"electricity,omitempty" bson:"electricity,omitempty"`
+	Status      string              `json:"status" bson:"status"`
+	NextRun     time.Time           `json:"next_run" bson:"next_run"`
+	LastRun     *time.Time          `json:"last_run,omitempty" bson:"last_run,omitempty"`
+	LastStatus  string              `json:"last_status,omitempty" bson:"last_status,omitempty"`
+	Runs        int                 `json:"runs" bson:"runs"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// ScheduledRun records one execution of a scheduled order.
+type ScheduledRun struct {
+	OrderID   string  `json:"order_id" bson:"order_id"`
+	Username  string  `json:"username" bson:"username"`
+	Product   string  `json:"product" bson:"product"`
+	Amount    float64 `json:"amount" bson:"amount"`
+	Status    string  `json:"status" bson:"status"`
+	Reference string  `json:"reference,omitempty" bson:"reference,omitempty"` // transaction id of the purchase
+	// PurchaseID is the order id the purchase of the run was debited and saved under, refunds are asked for it.
+	PurchaseID string    `json:"purchase_id,omitempty" bson:"purchase_id,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	RanAt      time.Time `json:"ran_at" bson:"ran_at"`
+}
@@ -1,13 +1,19 @@
 package telcom
 
+import "time"
+
 type AirtimeInfo struct {
-	Network     string `json:"network"`
-	Amount      string `json:"amount"`
-	Phone_no    string `json:"mobileno"`
+	Network     string `json:"network" validate:"required,network"`
+	Amount      string `json:"amount" validate:"required,amount=50-50000"`
+	Phone_no    string `json:"mobileno" validate:"required"`
 	Product     string `json:"product"`
 	Recipient   string `json:"recipient,omitempty"`
 	AirtimeType string `json:"airtime_type"`
 	Username    string
+	UserID      string `json:"-" bson:"-"`
+	// OrderID is the order the purchase is saved under, callers that debit the wallet for it set it to the
+	// reference of the debit. A new one is generated when it is 0.
+	OrderID int `json:"-" bson:"-"`
 }
 
 type AirtimeApiResponse struct {
@@ -23,15 +29,18 @@ type AirtimeApiResponse struct {
 }
 
 type AirtimeResponse struct {
-	Status          string `json:"status" bson:"status"`
-	Network         string `json:"network" bson:"network"`
-	Amount          string `json:"amount" bson:"amount"`
-	Phone_no        string `json:"phone_no" bson:"phone_no"`
-	Name            string `json:"name" bson:"name"`
-	Product         string `json:"product" bson:"product"`
-	Recipient       string `json:"recipient,omitempty" bson:"recipient,omitempty"`
-	OrderID         int    `json:"order_id" bson:"order_id"`
-	Description     string `json:"description" bson:"description"`
-	TransactionID   string `json:"transaction_id" bson:"transaction_id"`
-	ReferenceNumber string `json:"reference_number" bson:"reference_number"`
+	Status          string    `json:"status" bson:"status"`
+	Network         string    `json:"network" bson:"network"`
+	Amount          string    `json:"amount" bson:"amount"`
+	Phone_no        string    `json:"phone_no" bson:"phone_no"`
+	Name            string    `json:"name" bson:"name"`
+	Product         string    `json:"product" bson:"product"`
+	Recipient       string    `json:"recipient,omitempty" bson:"recipient,omitempty"`
+	UserID          string    `json:"user_id" bson:"user_id"`
+	OrderID         int       `json:"order_id" bson:"order_id"`
+	Description     string    `json:"description" bson:"description"`
+	TransactionID   string    `json:"transaction_id" bson:"transaction_id"`
+	ReferenceNumber string    `json:"reference_number" bson:"reference_number"`
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
+	Warning         string    `json:"warning,omitempty" bson:"-"`
 }
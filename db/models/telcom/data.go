@@ -1,29 +1,37 @@
 package telcom
 
+import "time"
+
 type DataInfo struct {
-	Network       int    `json:"network"`
+	Network       int    `json:"network" validate:"required,network"`
 	Network_id    int    `json:"newtork_id"`
-	Plan          int    `json:"plan"`
+	Plan          int    `json:"plan" validate:"required"`
 	Plan_id       string `json:"plan_id"`
-	Mobile_Num    string `json:"mobile_number"`
+	Mobile_Num    string `json:"mobile_number" validate:"required"`
 	Ported_number bool   `json:"Ported_number"`
 	Name          string `json:"name"`
 	Username      string
+	UserID        string `json:"-" bson:"-"`
+	// OrderID is the order the purchase is saved under, callers that debit the wallet for it set it to the
+	// reference of the debit. A new one is generated when it is 0.
+	OrderID int `json:"-" bson:"-"`
 }
 
 type DataResult struct {
-	OrderID         int    `json:"order_id" bson:"order_id"`
-	TransactionID   string `json:"transaction_id" bson:"transaction_id"`
-	ReferenceNumber string `json:"reference_number" bson:"reference_number"`
-	Network         string `json:"network" bson:"network"`
-	Username        string `json:"username" bson:"username"`
-	PlanName        string `json:"plan_name" bson:"plan_name"`
-	Plan_Amount     string `json:"plan_amount" bson:"plan_amount"`
-	Status          string `json:"Status" bson:"status"`
-	Name            string `json:"Name" bson:"name"`
-	Phone_Number    string `json:"Phone_Number" bson:"phone_number"`
-	CreatedAt       string `json:"CreatedAt" bson:"created_at"`
-	ApiID           int    `bson:"apiID"`
+	UserID          string    `json:"user_id" bson:"user_id"`
+	OrderID         int       `json:"order_id" bson:"order_id"`
+	TransactionID   string    `json:"transaction_id" bson:"transaction_id"`
+	ReferenceNumber string    `json:"reference_number" bson:"reference_number"`
+	Network         string    `json:"network" bson:"network"`
+	Username        string    `json:"username" bson:"username"`
+	PlanName        string    `json:"plan_name" bson:"plan_name"`
+	Plan_Amount     string    `json:"plan_amount" bson:"plan_amount"`
+	Status          string    `json:"Status" bson:"status"`
+	Name            string    `json:"Name" bson:"name"`
+	Phone_Number    string    `json:"Phone_Number" bson:"phone_number"`
+	CreatedAt       time.Time `json:"CreatedAt" bson:"created_at"`
+	ApiID           int       `bson:"apiID"`
+	Warning         string    `json:"warning,omitempty" bson:"-"`
 }
 
 type APIResponse struct {
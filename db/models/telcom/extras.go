@@ -1,13 +1,17 @@
 package telcom
 
+import "time"
+
 type SmileInfo struct {
 	Network      string `json:"network"`
-	Email        string `json:"email"`
-	Phone_Number string `json:"phone_no"`
-	AccountID    string `json:"accountID"` // Account ID, billlersCode
-	Product      string `json:"product"`   //serviceID
-	Product_plan string `json:"plan"`      // variation code
+	Email        string `json:"email" validate:"omitempty,email"`
+	Phone_Number string `json:"phone_no" validate:"required"`
+	AccountID    string `json:"accountID" validate:"required"` // Account ID, billlersCode
+	Product      string `json:"product" validate:"required"`   //serviceID
+	Product_plan string `json:"plan" validate:"required"`      // variation code
 	RequestID    string `json:"request_id"`
+	Amount       int    `json:"-" bson:"-"` // set from the plan catalogue
+	UserID       string `json:"-" bson:"-"`
 }
 
 type SmileAPIresponse struct {
@@ -39,30 +43,33 @@ type transaction_Date struct {
 }
 
 type SmileResult struct {
-	Network         string `json:"network" bson:"network"`
-	ProductPlan     string `json:"plan" bson:"product"`
-	Email           string `json:"email" bson:"email"`
-	AccountID       string `json:"account_id" bson:"account_id"`
-	Phone_Number    string `json:"phone_no" bson:"phone_no"`
-	Name            string `json:"name" bson:"name"`
-	Amount          int    `json:"amount" bson:"amount"`
-	Product         string `json:"product" bson:"product"`
-	Description     string `json:"description" bson:"description"`
-	OrderID         int    `json:"order_id" bson:"order_id"`
-	TranscationID   string `json:"transcation_id" bson:"transcation_id"`
-	ReferenceNumber string `json:"Reference_number" bson:"reference_number"` // map transactionid from api to this.
-	RequestID       string `json:"request_id" bson:"request_ID"`
+	Network         string    `json:"network" bson:"network"`
+	ProductPlan     string    `json:"plan" bson:"plan"`
+	Email           string    `json:"email" bson:"email"`
+	AccountID       string    `json:"account_id" bson:"account_id"`
+	Phone_Number    string    `json:"phone_no" bson:"phone_no"`
+	Name            string    `json:"name" bson:"name"`
+	Amount          int       `json:"amount" bson:"amount"`
+	Product         string    `json:"product" bson:"product"`
+	Description     string    `json:"description" bson:"description"`
+	UserID          string    `json:"user_id" bson:"user_id"`
+	OrderID         int       `json:"order_id" bson:"order_id"`
+	TranscationID   string    `json:"transcation_id" bson:"transaction_id"`
+	ReferenceNumber string    `json:"Reference_number" bson:"reference_number"` // map transactionid from api to this.
+	RequestID       string    `json:"request_id" bson:"request_id"`
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
 }
 
 type SpectranetInfo struct {
-	Network      string `json:"network"`  // ServiceID
-	Product      string `json:"product"`  //
-	Plan         string `json:"plan"`     // variation code?
-	Phone_Number string `json:"phone_no"` // billersCode &
+	Network      string `json:"network" validate:"required"`  // ServiceID
+	Product      string `json:"product"`                      //
+	Plan         string `json:"plan" validate:"required"`     // variation code?
+	Phone_Number string `json:"phone_no" validate:"required"` // billersCode &
 	Name         string `json:"name"`
-	No_of_Pins   string `json:"no_of_pins"` // quantity
-	Amount       int    `json:"amount"`
+	No_of_Pins   string `json:"no_of_pins" validate:"required,quantity=1-10"` // quantity
+	Amount       int    `json:"amount" validate:"omitempty,amount=100-100000"`
 	RequestID    string `json:"request_id"`
+	UserID       string `json:"-" bson:"-"`
 }
 
 type SpectranetApiResponse struct {
@@ -104,18 +111,20 @@ type card struct {
 }
 
 type SpectranetResult struct {
-	Network         string `json:"network" bson:"network"`
-	Product         string `json:"product" bson:"product"`
-	Plan            string `json:"plan" bson:"plan"`
-	Email           string `json:"email" bson:"email"`
-	Phone_Number    string `json:"phone_no" bson:"phone"`
-	Name            string `json:"name" bson:"name"`
-	No_of_Pins      int    `json:"no_of_pins" bson:"no_of_pins"`
-	Amount          int    `json:"amount" bson:"amount"`
-	ProductDesc     string `json:"product_desc" bson:"product_desc"`
-	Description     string `json:"description" bson:"description"`
-	OrderID         int    `json:"order_id" bson:"order_id"`
-	TranscationID   string `json:"transcation_id" bson:"transaction_id"`
-	ReferenceNumber string `json:"reference_number" bson:"reference_number"`
-	RequestID       string `json:"request_id" bson:"request_ID"`
+	Network         string    `json:"network" bson:"network"`
+	Product         string    `json:"product" bson:"product"`
+	Plan            string    `json:"plan" bson:"plan"`
+	Email           string    `json:"email" bson:"email"`
+	Phone_Number    string    `json:"phone_no" bson:"phone"`
+	Name            string    `json:"name" bson:"name"`
+	No_of_Pins      int       `json:"no_of_pins" bson:"no_of_pins"`
+	Amount          int       `json:"amount" bson:"amount"`
+	ProductDesc     string    `json:"product_desc" bson:"product_desc"`
+	Description     string    `json:"description" bson:"description"`
+	UserID          string    `json:"user_id" bson:"user_id"`
+	OrderID         int       `json:"order_id" bson:"order_id"`
+	TranscationID   string    `json:"transcation_id" bson:"transaction_id"`
+	ReferenceNumber string    `json:"reference_number" bson:"reference_number"`
+	RequestID       string    `json:"request_id" bson:"request_id"`
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
 }
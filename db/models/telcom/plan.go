@@ -0,0 +1,20 @@
+package telcom
+
+import "time"
+
+// DataPlan is a normalised data plan in the plan catalogue.
+type DataPlan struct {
+	ID           string    `json:"id" bson:"id"`                       // provider:provider_code
+	Network      string    `json:"network" bson:"network"`             // mtn, airtel, glo, 9mobile, smile, spectranet
+	Provider     string    `json:"provider" bson:"provider"`           // dontech or vtpass
+	ProviderCode string    `json:"provider_code" bson:"provider_code"` // dontech plan id or vtpass variation code
+	NetworkID    int       `json:"network_id,omitempty" bson:"network_id,omitempty"`
+	ServiceID    string    `json:"service_id,omitempty" bson:"service_id,omitempty"`
+	Name         string    `json:"name" bson:"name"`
+	PlanType     string    `json:"plan_type,omitempty" bson:"plan_type,omitempty"`
+	Size         string    `json:"size" bson:"size"`
+	Validity     string    `json:"validity" bson:"validity"`
+	CostPrice    float64   `json:"-" bson:"cost_price"`
+	Price        float64   `json:"price" bson:"price"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+}
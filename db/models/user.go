@@ -2,6 +2,12 @@ package models
 
 import "time"
 
+// roles of the staff, they are set on the user in the database. A user without a role is a customer.
+const (
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 // User is the model that governs all notes objects retrived or inserted into the DB
 type User struct {
 	ID             string    `json:"id" bson:"id"`
@@ -11,11 +17,17 @@ type User struct {
 	Password       string    `json:"password" bson:"password" validate:"required,min=6"`
 	PhoneNumber    string    `json:"phone_number" bson:"phonenumber" validate:"required"`
 	Country        string    `json:"country" bson:"country" validate:"required"`
-	InvitationCode string    `json:"invitation_code" bson:"invitation_Code"`
+	InvitationCode string    `json:"invitation_code" bson:"invitation_code"`
 	CreatedAt      int64     `json:"created_at" bson:"created_at"`
 	UpdatedAt      int64     `json:"updated_at" bson:"updated_at"`
 	BVN            string    `json:"bvn" bson:"bvn"`
 	IsVerified     bool      `json:"is_verified" bson:"is_verified"`
-	HasPin         bool      `json:"has_Pin" bson:"has_Pin"`
+	HasPin         bool      `json:"has_Pin" bson:"has_pin"`
 	ExpireAt       time.Time `bson:"expireAt"`
+	Role           string    `json:"role,omitempty" bson:"role,omitempty"`
+}
+
+// IsStaff reports whether the user works in support or administers the service.
+func (u *User) IsStaff() bool {
+	return u != nil && (u.Role == RoleSupport || u.Role == RoleAdmin)
 }
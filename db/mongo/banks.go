@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,41 +16,42 @@ import (
 )
 
 var (
-	bankTransColl = "bank-transactions"
-	balColl       = "balance"
-	bankColl      = "bank"
-	virtualColl   = "virtualAccount"
-	counterColl   = "counterParty"
-	deptColl      = "deposit"
+	bankTransColl  = "bank-transactions"
+	balColl        = "balance"
+	bankColl       = "bank"
+	virtualColl    = "virtualAccount"
+	counterColl    = "counterParty"
+	deptColl       = "deposit"
+	depositIDColl  = "deposit_IDs"
+	depositAccColl = "deposit_accounts"
+	pinColl        = "pin"
 )
 
-var (
-	ErrDepositIDExist = errors.New("deposit_id already exists")
+// the transfers and the deposits share a collection, they are told apart by their product.
+const (
+	transferProduct = "Money Transfer"
+	depositProduct  = "Virtual Account"
 )
 
-func (m *mongoStore) deptColl() (*mongo.Collection, error) {
-	col := m.mongoClient.Database(m.databaseName).Collection("deposit_IDs")
-	ctx := context.Background()
-	indexModel := mongo.IndexModel{
-		Keys:    bson.D{primitive.E{Key: "ID", Value: 1}},
-		Options: options.Index().SetUnique(true),
-	}
+func productFilter(product string) primitive.E {
+	return primitive.E{Key: "product", Value: product}
+}
 
-	_, err := col.Indexes().CreateOne(ctx, indexModel)
-	if err != nil {
-		return nil, err
-	}
+var (
+	ErrDepositIDExist = errors.New("deposit_id already exists")
+)
 
-	return col, nil
-}
+func (m *mongoStore) SaveBankList(ctx context.Context, banklist models.BankDetails) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
-func (m *mongoStore) SaveBankList(banklist models.BankDetails) error {
-	err := m.saveToDB(bankColl, banklist)
+	err := m.saveToDB(ctx, bankColl, banklist)
 	return err
 }
 
-func (m *mongoStore) GetBankDetail(name string) (models.BankDetails, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetBankDetail(ctx context.Context, name string) (models.BankDetails, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 	bankDetail := models.BankDetails{}
 
 	bankName := strings.ToUpper(name)
@@ -64,13 +66,17 @@ func (m *mongoStore) GetBankDetail(name string) (models.BankDetails, error) {
 	return bankDetail, nil
 }
 
-func (m *mongoStore) SaveVirtualAccount(account models.AccountDetails) error {
-	err := m.saveToDB(virtualColl, account)
+func (m *mongoStore) SaveVirtualAccount(ctx context.Context, account models.AccountDetails) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, virtualColl, account)
 	return err
 }
 
-func (m *mongoStore) GetVirtualNuban(name string) (models.AccountDetails, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetVirtualNuban(ctx context.Context, name string) (models.AccountDetails, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 	account_name := fmt.Sprintf("ANC(AREMXYPLUG/%s)", name)
 	fmt.Println(account_name)
 	filter := bson.D{primitive.E{Key: "account_name", Value: account_name}}
@@ -93,18 +99,111 @@ func (m *mongoStore) GetVirtualNuban(name string) (models.AccountDetails, error)
 	return acc_details, nil
 }
 
-func (m *mongoStore) SaveCounterParty(counterparty interface{}) error {
-	err := m.saveToDB(counterColl, counterparty)
+func (m *mongoStore) GetVirtualAccounts(ctx context.Context) ([]models.AccountDetails, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.AccountDetails{}
+
+	cur, err := m.col(virtualColl).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) SaveCounterParty(ctx context.Context, counterparty interface{}) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, counterColl, counterparty)
 	return err
 }
 
-func (m *mongoStore) SaveTransfer(transfer models.TransferResponse) error {
-	err := m.saveToDB(bankTransColl, transfer)
+func (m *mongoStore) SaveTransfer(ctx context.Context, transfer models.TransferResponse) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, bankTransColl, transfer)
 	return err
 }
 
-func (m *mongoStore) GetCounterParty(accountNumber, bankname string) (models.CounterParty, error) {
-	ctx := context.Background()
+func (m *mongoStore) UpdateTransferStatus(ctx context.Context, transactionID, status string) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "transaction_id", Value: transactionID},
+		productFilter(transferProduct),
+	}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "status", Value: status}}}}
+
+	res, err := m.col(bankTransColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (m *mongoStore) SettleTransfer(ctx context.Context, transactionID, status string) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "transaction_id", Value: transactionID},
+		productFilter(transferProduct),
+		primitive.E{Key: "status", Value: models.TransferPending},
+	}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "status", Value: status}}}}
+
+	res, err := m.col(bankTransColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (m *mongoStore) GetPendingTransfers(ctx context.Context, before time.Time) ([]models.TransferResponse, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.TransferResponse{}
+
+	filter := bson.D{
+		productFilter(transferProduct),
+		primitive.E{Key: "status", Value: models.TransferPending},
+		primitive.E{Key: "created_at", Value: bson.D{primitive.E{Key: "$lt", Value: before}}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cur, err := m.col(bankTransColl).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) GetCounterParty(ctx context.Context, accountNumber, bankname string) (models.CounterParty, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 	counterparty := models.CounterParty{}
 	bankName := strings.ToUpper(bankname)
 
@@ -120,8 +219,11 @@ func (m *mongoStore) GetCounterParty(accountNumber, bankname string) (models.Cou
 	return counterparty, nil
 }
 
-func (m *mongoStore) GetTransferDetails(id string) (models.TransferResponse, error) {
-	resp := m.getRecord(id, bankTransColl)
+func (m *mongoStore) GetTransferDetails(ctx context.Context, userID, id string) (models.TransferResponse, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	resp := m.getRecord(ctx, id, bankTransColl, ownedBy(userID)...)
 	result := models.TransferResponse{}
 	err := resp.Decode(&result)
 	if err != nil {
@@ -131,31 +233,52 @@ func (m *mongoStore) GetTransferDetails(id string) (models.TransferResponse, err
 	return result, nil
 }
 
-func (m *mongoStore) GetAllTransferHistory(user string) ([]models.TransferResponse, error) {
-	ctx := context.Background()
-	result := []models.TransferResponse{}
+func (m *mongoStore) GetTransferByTransactionID(ctx context.Context, transactionID string) (models.TransferResponse, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 
-	findResult, err := m.getAllRecords(bankTransColl, user)
+	filter := bson.D{
+		primitive.E{Key: "transaction_id", Value: transactionID},
+		productFilter(transferProduct),
+	}
+	result := models.TransferResponse{}
+	if err := m.col(bankTransColl).FindOne(ctx, filter).Decode(&result); err != nil {
+		return models.TransferResponse{}, err
+	}
+
+	return result, nil
+}
+
+func (m *mongoStore) GetAllTransferHistory(ctx context.Context, opts db.ListOptions) ([]models.TransferResponse, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.TransferResponse{}
+
+	cur, err := m.listRecords(ctx, bankTransColl, opts, productFilter(transferProduct))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	for findResult.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := models.TransferResponse{}
-		if err := findResult.Decode(&resp); err != nil {
-			return nil, err
+		if err := cur.Decode(&resp); err != nil {
+			return err
 		}
-
-		result = append(result, resp)
+		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer findResult.Close(ctx)
-
-	return result, nil
 
+	return res, next, nil
 }
 
-func (m *mongoStore) GetDepositDetails(id string) (models.DepositResponse, error) {
-	resp := m.getRecord(id, bankTransColl)
+func (m *mongoStore) GetDepositDetails(ctx context.Context, userID, id string) (models.DepositResponse, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	resp := m.getRecord(ctx, id, bankTransColl, ownedBy(userID)...)
 	result := models.DepositResponse{}
 	err := resp.Decode(&result)
 	if err != nil {
@@ -165,86 +288,87 @@ func (m *mongoStore) GetDepositDetails(id string) (models.DepositResponse, error
 	return result, nil
 }
 
-func (m *mongoStore) GetAllDepositHistory(user string) ([]models.DepositResponse, error) {
-	ctx := context.Background()
-	result := []models.DepositResponse{}
+func (m *mongoStore) GetAllDepositHistory(ctx context.Context, opts db.ListOptions) ([]models.DepositResponse, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.DepositResponse{}
 
-	findResult, err := m.getAllRecords(bankTransColl, user)
+	cur, err := m.listRecords(ctx, bankTransColl, opts, productFilter(depositProduct))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	for findResult.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := models.DepositResponse{}
-		if err := findResult.Decode(&resp); err != nil {
-			return nil, err
+		if err := cur.Decode(&resp); err != nil {
+			return err
 		}
-
-		result = append(result, resp)
+		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer findResult.Close(ctx)
 
-	return result, nil
+	return res, next, nil
 }
 
-func (m *mongoStore) GetAllBankTransactions(user string) ([]interface{}, error) {
-	ctx := context.Background()
-	cur, err := m.getAllRecords(bankTransColl, user)
+func (m *mongoStore) GetAllBankTransactions(ctx context.Context, opts db.ListOptions) ([]interface{}, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	transactionHistory := []interface{}{}
+
+	cur, err := m.listRecords(ctx, bankTransColl, opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	var transactionHistory []interface{}
-
-	for cur.Next(ctx) {
-
-		var raw bson.Raw
-
-		if err := cur.Decode(&raw); err != nil {
-			return nil, err
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
+		var product struct {
+			Product string `bson:"product"`
+		}
+		if err := cur.Decode(&product); err != nil {
+			return err
 		}
 
-		if raw.Lookup("").Type == bson.TypeString {
-			var deposit models.DepositResponse
-
-			if err := bson.Unmarshal(raw, &deposit); err != nil {
-				return nil, err
-			}
-
-			transactionHistory = append(transactionHistory, deposit)
-		} else if raw.Lookup("").Type == bson.TypeString {
-			var tranfer models.TransferResponse
-
-			if err := bson.Unmarshal(raw, &tranfer); err != nil {
-				return nil, err
+		if product.Product == transferProduct {
+			var transfer models.TransferResponse
+			if err := cur.Decode(&transfer); err != nil {
+				return err
 			}
-
-			transactionHistory = append(transactionHistory, tranfer)
+			transactionHistory = append(transactionHistory, transfer)
+			return nil
 		}
-	}
 
-	if err := cur.Err(); err != nil {
-		return nil, err
+		var deposit models.DepositResponse
+		if err := cur.Decode(&deposit); err != nil {
+			return err
+		}
+		transactionHistory = append(transactionHistory, deposit)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
-	return transactionHistory, nil
-
+	return transactionHistory, next, nil
 }
 
-func (m *mongoStore) SaveDeposit(detail models.DepositResponse) error {
-	err := m.saveToDB(bankTransColl, detail)
+func (m *mongoStore) SaveDeposit(ctx context.Context, detail models.DepositResponse) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, bankTransColl, detail)
 	return err
 }
 
-func (m *mongoStore) SaveDepositID(detail interface{}) error {
-	ctx := context.Background()
+func (m *mongoStore) SaveDepositID(ctx context.Context, detail interface{}) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
-	col, err := m.deptColl()
-	if err != nil {
-		return err
-	}
+	col := m.col(depositIDColl)
 
-	_, err = col.InsertOne(ctx, detail)
+	_, err := col.InsertOne(ctx, detail)
 	if err != nil {
 		if writeException, ok := err.(mongo.WriteException); ok {
 			for _, writeError := range writeException.WriteErrors {
@@ -264,8 +388,18 @@ func (m *mongoStore) SaveDepositID(detail interface{}) error {
 	return nil
 }
 
-func (m *mongoStore) GetDepositID(virtualNuban string) (result interface{}, err error) {
-	id_Result := m.getRecord(deptColl, virtualNuban)
+func (m *mongoStore) SaveDepositAccount(ctx context.Context, account models.DepositAccount) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	return m.saveToDB(ctx, depositAccColl, account)
+}
+
+func (m *mongoStore) GetDepositID(ctx context.Context, virtualNuban string) (result interface{}, err error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	id_Result := m.getRecord(ctx, deptColl, virtualNuban)
 
 	// change this result to struct
 	var resp interface{}
@@ -280,12 +414,12 @@ func (m *mongoStore) GetDepositID(virtualNuban string) (result interface{}, err
 	return resp, nil
 }
 
-func (m *mongoStore) GetBalance(virtualNuban string) (balance float64, err error) {
+func (m *mongoStore) GetBalance(ctx context.Context, virtualNuban string) (balance float64, err error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := m.readContext(ctx)
 	defer cancel()
 
-	filter := bson.D{primitive.E{Key: "virtualnuban", Value: virtualNuban}}
+	filter := bson.D{primitive.E{Key: "virtual_nuban", Value: virtualNuban}}
 
 	result := m.col(balColl).FindOne(ctx, filter)
 
@@ -300,11 +434,11 @@ func (m *mongoStore) GetBalance(virtualNuban string) (balance float64, err error
 	return bal.Balance, nil
 }
 
-func (m *mongoStore) SaveBalance(virtualNuban string, balance models.Balance) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (m *mongoStore) SaveBalance(ctx context.Context, virtualNuban string, balance models.Balance) error {
+	ctx, cancel := m.writeContext(ctx)
 	defer cancel()
 
-	filter := bson.D{primitive.E{Key: "virtualnuban", Value: virtualNuban}}
+	filter := bson.D{primitive.E{Key: "virtual_nuban", Value: virtualNuban}}
 
 	result := m.col(balColl).FindOne(ctx, filter)
 
@@ -333,11 +467,11 @@ func (m *mongoStore) SaveBalance(virtualNuban string, balance models.Balance) er
 	return nil
 }
 
-func (m *mongoStore) UpdateBalance(virtualNuban string, balance float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (m *mongoStore) UpdateBalance(ctx context.Context, virtualNuban string, balance float64) error {
+	ctx, cancel := m.writeContext(ctx)
 	defer cancel()
 
-	filter := bson.D{primitive.E{Key: "virtualNuban", Value: virtualNuban}}
+	filter := bson.D{primitive.E{Key: "virtual_nuban", Value: virtualNuban}}
 
 	updateFilter := bson.D{{Key: "$set", Value: bson.D{primitive.E{Key: "balance", Value: balance}}}}
 
@@ -351,20 +485,21 @@ func (m *mongoStore) UpdateBalance(virtualNuban string, balance float64) error {
 
 // first create the collection for pin
 // code to save pin to the database
-func (m *mongoStore) SavePin(data models.UserPin) error {
-	ctx := context.Background()
-	coll := m.col("pin")
-	userColl := m.col("user")
+func (m *mongoStore) SavePin(ctx context.Context, data models.UserPin) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+	coll := m.col(pinColl)
+	userColl := m.col(models.UserCollectionName)
 
 	_, err := coll.InsertOne(ctx, data)
 	if err != nil {
 		return err
 	}
 
-	filter := bson.M{"id": data.UserID, "has_Pin": false}
+	filter := bson.M{"id": data.UserID, "has_pin": false}
 	update := bson.M{
 		"$set": bson.M{
-			"has_Pin": true,
+			"has_pin": true,
 		},
 	}
 
@@ -381,11 +516,12 @@ func (m *mongoStore) SavePin(data models.UserPin) error {
 }
 
 // code to get the pin from the database
-func (m *mongoStore) GetPin(userID string) (string, error) {
-	ctx := context.Background()
-	filter := bson.D{primitive.E{Key: "userid", Value: userID}}
+func (m *mongoStore) GetPin(ctx context.Context, userID string) (string, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
 
-	result := m.col("pin").FindOne(ctx, filter)
+	result := m.col(pinColl).FindOne(ctx, filter)
 	var resp models.UserPin
 	err := result.Decode(&resp)
 	if err != nil {
@@ -401,15 +537,15 @@ func (m *mongoStore) GetPin(userID string) (string, error) {
 }
 
 // code to update the pin in the database
-func (m *mongoStore) UpdatePin(data models.UserPin) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (m *mongoStore) UpdatePin(ctx context.Context, data models.UserPin) error {
+	ctx, cancel := m.writeContext(ctx)
 	defer cancel()
 
-	filter := bson.D{primitive.E{Key: "userid", Value: data.UserID}}
+	filter := bson.D{primitive.E{Key: "user_id", Value: data.UserID}}
 
 	updateFilter := bson.D{{Key: "$set", Value: bson.D{primitive.E{Key: "pin", Value: data.Pin}}}}
 
-	_, err := m.col("pin").UpdateOne(ctx, filter, updateFilter)
+	_, err := m.col(pinColl).UpdateOne(ctx, filter, updateFilter)
 	if err != nil {
 		return err
 	}
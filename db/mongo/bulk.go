@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var bulkColl = "bulk-batches"
+
+func (m *mongoStore) SaveBulkBatch(ctx context.Context, batch models.BulkBatch) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	_, err := m.col(bulkColl).InsertOne(ctx, batch)
+	return err
+}
+
+func (m *mongoStore) UpdateBulkRow(ctx context.Context, batchID string, row models.BulkRow) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "id", Value: batchID}}
+	// rows are numbered from 1
+	update := bson.D{{Key: "$set", Value: bson.D{primitive.E{Key: fmt.Sprintf("rows.%d", row.Row-1), Value: row}}}}
+
+	_, err := m.col(bulkColl).UpdateOne(ctx, filter, update)
+	return err
+}
+
+// ClaimBulkRow marks a pending row of a batch processing, so only one worker buys it. It returns
+// mongo.ErrNoDocuments when the row is no longer pending.
+func (m *mongoStore) ClaimBulkRow(ctx context.Context, batchID string, row int) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	status := fmt.Sprintf("rows.%d.status", row-1)
+	filter := bson.D{
+		primitive.E{Key: "id", Value: batchID},
+		primitive.E{Key: status, Value: models.RowPending},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{primitive.E{Key: status, Value: models.RowProcessing}}}}
+
+	res, err := m.col(bulkColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// CompleteBulkBatch sets the summary of a batch that is still processing, so its failed rows are refunded
+// once. It returns mongo.ErrNoDocuments when the batch was completed first.
+func (m *mongoStore) CompleteBulkBatch(ctx context.Context, batch models.BulkBatch) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "id", Value: batch.ID},
+		primitive.E{Key: "status", Value: models.BulkProcessing},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		primitive.E{Key: "status", Value: batch.Status},
+		primitive.E{Key: "refunded", Value: batch.Refunded},
+		primitive.E{Key: "succeeded", Value: batch.Succeeded},
+		primitive.E{Key: "failed", Value: batch.Failed},
+		primitive.E{Key: "completed_at", Value: batch.CompletedAt},
+	}}}
+
+	res, err := m.col(bulkColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetProcessingBulkBatches returns the batches that still have rows to buy, oldest first.
+func (m *mongoStore) GetProcessingBulkBatches(ctx context.Context) ([]models.BulkBatch, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.BulkBatch{}
+
+	filter := bson.D{primitive.E{Key: "status", Value: models.BulkProcessing}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cur, err := m.col(bulkColl).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) GetBulkBatch(ctx context.Context, id string) (models.BulkBatch, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "id", Value: id}}
+
+	batch := models.BulkBatch{}
+	if err := m.col(bulkColl).FindOne(ctx, filter).Decode(&batch); err != nil {
+		return models.BulkBatch{}, err
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	disputeColl     = "disputes"
+	disputeFileColl = "dispute_attachments"
+)
+
+func (m *mongoStore) CreateDispute(ctx context.Context, dispute models.Dispute, files []models.DisputeFile) error {
+	return m.WithTransaction(ctx, func(ctx context.Context) error {
+		ctx, cancel := m.writeContext(ctx)
+		defer cancel()
+
+		if _, err := m.col(disputeColl).InsertOne(ctx, dispute); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return db.ErrDuplicate
+			}
+			return err
+		}
+
+		if len(files) == 0 {
+			return nil
+		}
+		docs := make([]interface{}, len(files))
+		for i := range files {
+			docs[i] = files[i]
+		}
+		_, err := m.col(disputeFileColl).InsertMany(ctx, docs)
+		return err
+	})
+}
+
+func (m *mongoStore) GetDispute(ctx context.Context, userID, id string) (models.Dispute, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	dispute := models.Dispute{}
+	filter := append(bson.D{primitive.E{Key: "id", Value: id}}, ownedBy(userID)...)
+	if err := m.col(disputeColl).FindOne(ctx, filter).Decode(&dispute); err != nil {
+		return models.Dispute{}, err
+	}
+
+	return dispute, nil
+}
+
+func (m *mongoStore) GetDisputes(ctx context.Context, opts db.ListOptions) ([]models.Dispute, string, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.Dispute{}
+
+	cur, err := m.listRecords(ctx, disputeColl, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
+		var dispute models.Dispute
+		if err := cur.Decode(&dispute); err != nil {
+			return err
+		}
+		res = append(res, dispute)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, next, nil
+}
+
+func (m *mongoStore) GetDisputeFile(ctx context.Context, disputeID, id string) (models.DisputeFile, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	file := models.DisputeFile{}
+	filter := bson.D{
+		primitive.E{Key: "dispute_id", Value: disputeID},
+		primitive.E{Key: "id", Value: id},
+	}
+	if err := m.col(disputeFileColl).FindOne(ctx, filter).Decode(&file); err != nil {
+		return models.DisputeFile{}, err
+	}
+
+	return file, nil
+}
+
+func (m *mongoStore) AddDisputeEvent(ctx context.Context, id, from string, event models.DisputeEvent) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "id", Value: id}}
+	if from != "" {
+		filter = append(filter, primitive.E{Key: "status", Value: from})
+	} else {
+		filter = append(filter, primitive.E{Key: "status", Value: bson.D{primitive.E{Key: "$ne", Value: models.DisputeClosed}}})
+	}
+
+	set := bson.D{primitive.E{Key: "updated_at", Value: event.At}}
+	update := bson.D{primitive.E{Key: "$push", Value: bson.D{primitive.E{Key: "events", Value: event}}}}
+	if event.RefundID != "" {
+		set = append(set, primitive.E{Key: "refund_id", Value: event.RefundID})
+	}
+	if event.Type == models.DisputeEventStatus {
+		set = append(set, primitive.E{Key: "status", Value: event.Status})
+		switch event.Status {
+		case models.DisputeResolved, models.DisputeClosed:
+			// $min keeps the time the ticket was first resolved when it is closed afterwards
+			update = append(update, primitive.E{Key: "$min", Value: bson.D{primitive.E{Key: "resolved_at", Value: event.At}}})
+		default:
+			update = append(update, primitive.E{Key: "$unset", Value: bson.D{primitive.E{Key: "resolved_at", Value: ""}}})
+		}
+		if event.Status == models.DisputeClosed {
+			update = append(update, primitive.E{Key: "$unset", Value: bson.D{primitive.E{Key: "active", Value: ""}}})
+		}
+	}
+	update = append(update, primitive.E{Key: "$set", Value: set})
+
+	res, err := m.col(disputeColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
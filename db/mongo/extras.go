@@ -9,9 +9,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func (m *mongoStore) UpdateReferralCount(referralCode string) error {
+func (m *mongoStore) UpdateReferralCount(ctx context.Context, referralCode string) error {
 	// TODO: using the referral code as the filter, update the count field on the user document
-	ctx := context.Background()
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
 	filter := bson.D{primitive.E{Key: "ref_code", Value: referralCode}}
 	updateFilter := bson.D{}
@@ -24,9 +25,10 @@ func (m *mongoStore) UpdateReferralCount(referralCode string) error {
 	return nil
 }
 
-func (m *mongoStore) CreateUserReferral(userID, refcode string) error {
+func (m *mongoStore) CreateUserReferral(ctx context.Context, userID, refcode string) error {
 	// TODO: create a referral document for user using userID and refCode
-	ctx := context.Background()
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
 	refDoc := models.Referral{
 		UserID:  userID,
@@ -42,9 +44,10 @@ func (m *mongoStore) CreateUserReferral(userID, refcode string) error {
 	return nil
 }
 
-func (m *mongoStore) GetReferral(userID string) (string, error) {
+func (m *mongoStore) GetReferral(ctx context.Context, userID string) (string, error) {
 	// TODO: get user referral code from the user's document
-	ctx := context.Background()
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 
 	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
 	refDoc := models.Referral{}
@@ -57,9 +60,10 @@ func (m *mongoStore) GetReferral(userID string) (string, error) {
 	return refDoc.RefCode, nil
 }
 
-func (m *mongoStore) UpdatePoint(userID string, points int) error {
+func (m *mongoStore) UpdatePoint(ctx context.Context, userID string, points int) error {
 	// TODO: update the point doucument using the userID as the filter and adding the points to the previous point balance
-	ctx := context.Background()
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
 	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
 	updateFilter := bson.D{}
@@ -72,9 +76,10 @@ func (m *mongoStore) UpdatePoint(userID string, points int) error {
 	return nil
 }
 
-func (m *mongoStore) GetPoint(userID string) (models.Points, error) {
+func (m *mongoStore) GetPoint(ctx context.Context, userID string) (models.Points, error) {
 
-	ctx := context.Background()
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 
 	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
 	points := models.Points{}
@@ -87,9 +92,10 @@ func (m *mongoStore) GetPoint(userID string) (models.Points, error) {
 	return points, nil
 }
 
-func (m *mongoStore) CreatePointDoc(userID string) error {
+func (m *mongoStore) CreatePointDoc(ctx context.Context, userID string) error {
 	// TODO: Create a document on the collection points for the user on signUp
-	ctx := context.Background()
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
 	point := models.Points{
 		UserID:  userID,
@@ -104,9 +110,10 @@ func (m *mongoStore) CreatePointDoc(userID string) error {
 	return nil
 }
 
-func (m *mongoStore) CanRedeemPoints(userID string, points int) bool {
+func (m *mongoStore) CanRedeemPoints(ctx context.Context, userID string, points int) bool {
 	// TODO: first get the user point from the database and then compare with the points to redeem
-	ctx := context.Background()
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 
 	pointDoc := models.Points{}
 
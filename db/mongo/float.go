@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var floatColl = "provider_balances"
+
+// floatRetention is how long the balances checked are kept, older ones are removed by a TTL index.
+const floatRetention = 90 * 24 * time.Hour
+
+func (m *mongoStore) SaveProviderBalance(ctx context.Context, balance models.ProviderBalance) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	_, err := m.col(floatColl).InsertOne(ctx, balance)
+	return err
+}
+
+func (m *mongoStore) GetProviderBalances(ctx context.Context, provider string, from, to time.Time) ([]models.ProviderBalance, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.ProviderBalance{}
+
+	filter := bson.D{
+		primitive.E{Key: "provider", Value: provider},
+		primitive.E{Key: "checked_at", Value: bson.D{
+			primitive.E{Key: "$gte", Value: from},
+			primitive.E{Key: "$lte", Value: to},
+		}},
+	}
+	sort := bson.D{primitive.E{Key: "checked_at", Value: 1}}
+
+	cur, err := m.col(floatColl).Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) GetLatestProviderBalances(ctx context.Context) ([]models.ProviderBalance, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.ProviderBalance{}
+
+	pipeline := mongo.Pipeline{
+		{primitive.E{Key: "$sort", Value: bson.D{primitive.E{Key: "provider", Value: 1}, primitive.E{Key: "checked_at", Value: -1}}}},
+		{primitive.E{Key: "$group", Value: bson.D{
+			primitive.E{Key: "_id", Value: "$provider"},
+			primitive.E{Key: "latest", Value: bson.D{primitive.E{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{primitive.E{Key: "$replaceWith", Value: "$latest"}},
+		{primitive.E{Key: "$sort", Value: bson.D{primitive.E{Key: "provider", Value: 1}}}},
+	}
+
+	cur, err := m.col(floatColl).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
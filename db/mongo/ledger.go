@@ -0,0 +1,168 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ledgerColl    = "ledger"
+	statementColl = "statements"
+)
+
+// ledgerOrder sorts entries in the order they were made, ids are increasing within an instant.
+var ledgerOrder = bson.D{primitive.E{Key: "created_at", Value: 1}, primitive.E{Key: "id", Value: 1}}
+
+func (m *mongoStore) SaveLedgerEntry(ctx context.Context, entry models.LedgerEntry) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	return m.saveToDB(ctx, ledgerColl, entry)
+}
+
+func (m *mongoStore) GetLedgerEntries(ctx context.Context, userID string, from, to time.Time) ([]models.LedgerEntry, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.LedgerEntry{}
+
+	filter := bson.D{
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "created_at", Value: bson.D{
+			primitive.E{Key: "$gte", Value: from},
+			primitive.E{Key: "$lte", Value: to},
+		}},
+	}
+
+	cur, err := m.col(ledgerColl).Find(ctx, filter, options.Find().SetSort(ledgerOrder))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) GetLedgerEntriesByType(ctx context.Context, entryType string, from, to time.Time) ([]models.LedgerEntry, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.LedgerEntry{}
+
+	filter := bson.D{
+		primitive.E{Key: "created_at", Value: bson.D{
+			primitive.E{Key: "$gte", Value: from},
+			primitive.E{Key: "$lt", Value: to},
+		}},
+		primitive.E{Key: "type", Value: entryType},
+	}
+
+	cur, err := m.col(ledgerColl).Find(ctx, filter, options.Find().SetSort(ledgerOrder))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) GetLedgerEntriesByReference(ctx context.Context, userID, reference string) ([]models.LedgerEntry, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.LedgerEntry{}
+
+	filter := bson.D{
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "reference", Value: reference},
+	}
+
+	cur, err := m.col(ledgerColl).Find(ctx, filter, options.Find().SetSort(ledgerOrder))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) GetBalanceAt(ctx context.Context, userID string, t time.Time) (float64, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "created_at", Value: bson.D{primitive.E{Key: "$lt", Value: t}}},
+	}
+	latest := bson.D{primitive.E{Key: "created_at", Value: -1}, primitive.E{Key: "id", Value: -1}}
+
+	entry := models.LedgerEntry{}
+	err := m.col(ledgerColl).FindOne(ctx, filter, options.FindOne().SetSort(latest)).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return entry.Balance, nil
+}
+
+func (m *mongoStore) GetLedgerUsers(ctx context.Context, from, to time.Time) ([]string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "created_at", Value: bson.D{
+		primitive.E{Key: "$gte", Value: from},
+		primitive.E{Key: "$lte", Value: to},
+	}}}
+
+	values, err := m.col(ledgerColl).Distinct(ctx, "user_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]string, 0, len(values))
+	for _, v := range values {
+		if userID, ok := v.(string); ok && userID != "" {
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
+
+// ClaimStatement relies on the unique index on the user and period of the statements.
+func (m *mongoStore) ClaimStatement(ctx context.Context, userID, period string) (bool, error) {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	claim := bson.D{
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "period", Value: period},
+		primitive.E{Key: "claimed_at", Value: time.Now().UTC()},
+	}
+	_, err := m.col(statementColl).InsertOne(ctx, claim)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pageKey is the position of a document in a list.
+type pageKey struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// listRecords finds the page of collectionName selected by opts, newest first. It fetches one document more
+// than the page size to tell whether there is a next page, the cursor must be read with readPage.
+func (m *mongoStore) listRecords(ctx context.Context, collectionName string, opts db.ListOptions, extra ...primitive.E) (*mongo.Cursor, error) {
+	filter, err := listFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+	filter = append(filter, extra...)
+
+	findOpts := options.Find().
+		SetSort(bson.D{primitive.E{Key: "created_at", Value: -1}, primitive.E{Key: "_id", Value: -1}}).
+		SetLimit(int64(opts.PageSize() + 1))
+
+	return m.col(collectionName).Find(ctx, filter, findOpts)
+}
+
+// readPage passes the documents of a page to decode and returns the cursor of the next page, which is empty
+// on the last page.
+func readPage(ctx context.Context, cur *mongo.Cursor, opts db.ListOptions, decode func(cur *mongo.Cursor) error) (string, error) {
+	defer cur.Close(ctx)
+
+	var last pageKey
+	for n := 0; cur.Next(ctx); n++ {
+		if n == opts.PageSize() {
+			return db.Cursor{CreatedAt: last.CreatedAt, ID: last.ID.Hex()}.Encode(), nil
+		}
+		if err := cur.Decode(&last); err != nil {
+			return "", err
+		}
+		if err := decode(cur); err != nil {
+			return "", err
+		}
+	}
+
+	return "", cur.Err()
+}
+
+func listFilter(opts db.ListOptions) (bson.D, error) {
+	filter := bson.D{}
+	filter = append(filter, ownedBy(opts.UserID)...)
+	if opts.Status != "" {
+		filter = append(filter, primitive.E{Key: "status", Value: opts.Status})
+	}
+
+	createdAt := bson.D{}
+	if !opts.From.IsZero() {
+		createdAt = append(createdAt, primitive.E{Key: "$gte", Value: opts.From})
+	}
+	if !opts.To.IsZero() {
+		createdAt = append(createdAt, primitive.E{Key: "$lte", Value: opts.To})
+	}
+	if len(createdAt) > 0 {
+		filter = append(filter, primitive.E{Key: "created_at", Value: createdAt})
+	}
+
+	if opts.MinAmount > 0 || opts.MaxAmount > 0 {
+		// amounts are stored as numbers by some products and as strings by others
+		amount := bson.D{primitive.E{Key: "$convert", Value: bson.D{
+			primitive.E{Key: "input", Value: "$amount"},
+			primitive.E{Key: "to", Value: "double"},
+			primitive.E{Key: "onError", Value: nil},
+			primitive.E{Key: "onNull", Value: nil},
+		}}}
+		conditions := bson.A{bson.D{primitive.E{Key: "$ne", Value: bson.A{amount, nil}}}}
+		if opts.MinAmount > 0 {
+			conditions = append(conditions, bson.D{primitive.E{Key: "$gte", Value: bson.A{amount, opts.MinAmount}}})
+		}
+		if opts.MaxAmount > 0 {
+			conditions = append(conditions, bson.D{primitive.E{Key: "$lte", Value: bson.A{amount, opts.MaxAmount}}})
+		}
+		filter = append(filter, primitive.E{Key: "$expr", Value: bson.D{primitive.E{Key: "$and", Value: conditions}}})
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := db.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(cursor.ID)
+		if err != nil {
+			return nil, db.ErrInvalidCursor
+		}
+
+		filter = append(filter, primitive.E{Key: "$or", Value: bson.A{
+			bson.D{primitive.E{Key: "created_at", Value: bson.D{primitive.E{Key: "$lt", Value: cursor.CreatedAt}}}},
+			bson.D{
+				primitive.E{Key: "created_at", Value: cursor.CreatedAt},
+				primitive.E{Key: "_id", Value: bson.D{primitive.E{Key: "$lt", Value: id}}},
+			},
+		}})
+	}
+
+	return filter, nil
+}
+
+// withCreatedAt returns details as a document with created_at set to now when it is missing or zero, the
+// lists are ordered by it.
+func withCreatedAt(details interface{}) (bson.D, error) {
+	raw, err := bson.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for i, e := range doc {
+		if e.Key != "created_at" {
+			continue
+		}
+		if t, ok := e.Value.(primitive.DateTime); !ok || t.Time().IsZero() {
+			doc[i].Value = now
+		}
+		return doc, nil
+	}
+
+	return append(doc, primitive.E{Key: "created_at", Value: now}), nil
+}
@@ -0,0 +1,97 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListFilter(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	id := primitive.NewObjectID()
+
+	var tests = []struct {
+		name    string
+		opts    db.ListOptions
+		keys    []string
+		wantErr error
+	}{
+		{name: "Test everything", opts: db.ListOptions{}, keys: []string{}},
+		{name: "Test one user", opts: db.ListOptions{UserID: "user-1", Status: "successful"}, keys: []string{"user_id", "status"}},
+		{name: "Test date and amount range", opts: db.ListOptions{From: from, To: to, MinAmount: 100}, keys: []string{"created_at", "$expr"}},
+		{name: "Test next page", opts: db.ListOptions{Cursor: db.Cursor{CreatedAt: from, ID: id.Hex()}.Encode()}, keys: []string{"$or"}},
+		{name: "Test invalid cursor", opts: db.ListOptions{Cursor: "invalid"}, wantErr: db.ErrInvalidCursor},
+		{name: "Test invalid cursor id", opts: db.ListOptions{Cursor: db.Cursor{CreatedAt: from, ID: "1"}.Encode()}, wantErr: db.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := listFilter(tt.opts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			keys := []string{}
+			for _, e := range filter {
+				keys = append(keys, e.Key)
+			}
+			assert.Equal(t, tt.keys, keys)
+		})
+	}
+}
+
+func TestWithCreatedAt(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name    string
+		details interface{}
+		keep    bool
+	}{
+		{name: "Test missing", details: bson.M{"order_id": "1"}},
+		{name: "Test zero", details: struct {
+			OrderID   string    `bson:"order_id"`
+			CreatedAt time.Time `bson:"created_at"`
+		}{OrderID: "1"}},
+		{name: "Test set", details: bson.M{"order_id": "1", "created_at": createdAt}, keep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := withCreatedAt(tt.details)
+			assert.NoError(t, err)
+
+			got, ok := doc.Map()["created_at"].(primitive.DateTime)
+			assert.True(t, ok)
+			if tt.keep {
+				assert.True(t, createdAt.Equal(got.Time()))
+				return
+			}
+			assert.WithinDuration(t, time.Now(), got.Time(), time.Minute)
+		})
+	}
+}
+
+func TestOwnedBy(t *testing.T) {
+	var tests = []struct {
+		name   string
+		userID string
+		want   []primitive.E
+	}{
+		{name: "Test every user", userID: ""},
+		{name: "Test one user", userID: "user-1", want: []primitive.E{{Key: "user_id", Value: "user-1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ownedBy(tt.userID))
+		})
+	}
+}
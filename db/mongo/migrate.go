@@ -0,0 +1,289 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// migrationsColl records the migrations applied to the database, one document per version.
+const migrationsColl = "schema_migrations"
+
+var (
+	ErrIrreversible      = errors.New("migration can not be rolled back")
+	ErrNothingToRollback = errors.New("no migration has been applied")
+)
+
+// Migration is a versioned change to the schema or the data of the database. Down undoes Up, it is nil
+// when the change can not be undone.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// MigrationStatus is a migration and when it was applied, AppliedAt is nil while it is pending.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time
+}
+
+type appliedMigration struct {
+	Version     int       `bson:"version"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrator applies and rolls back migrations in version order.
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewMigrator returns a Migrator for the migrations of the application.
+func NewMigrator(db *mongo.Database, logger *zap.Logger) *Migrator {
+	return &Migrator{db: db, migrations: migrations, logger: logger}
+}
+
+// Up applies the pending migrations in version order and returns how many were applied. It stops at the
+// first migration that fails, the ones before it stay applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if err := validateMigrations(m.migrations); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		m.logger.Info("applying migration", zap.Int("version", migration.Version), zap.String("description", migration.Description))
+		if err := migration.Up(ctx, m.db); err != nil {
+			return count, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+
+		record := appliedMigration{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now().UTC()}
+		if _, err := m.db.Collection(migrationsColl).InsertOne(ctx, record); err != nil {
+			return count, fmt.Errorf("recording migration %d: %w", migration.Version, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if err := validateMigrations(m.migrations); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(applied) == 0 {
+		return 0, ErrNothingToRollback
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return count, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, ErrIrreversible)
+		}
+
+		m.logger.Info("rolling back migration", zap.Int("version", migration.Version), zap.String("description", migration.Description))
+		if err := migration.Down(ctx, m.db); err != nil {
+			return count, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+
+		filter := bson.D{primitive.E{Key: "version", Value: migration.Version}}
+		if _, err := m.db.Collection(migrationsColl).DeleteOne(ctx, filter); err != nil {
+			return count, fmt.Errorf("removing migration %d: %w", migration.Version, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Status returns every migration with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// Pending returns the number of migrations that have not been applied.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	cur, err := m.db.Collection(migrationsColl).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []appliedMigration
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// validateMigrations checks that the migrations are declared in strictly increasing version order.
+func validateMigrations(migrations []Migration) error {
+	if !sort.SliceIsSorted(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version }) {
+		return errors.New("migrations are not in version order")
+	}
+	for i, migration := range migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migration %q has no version", migration.Description)
+		}
+		if i > 0 && migrations[i-1].Version == migration.Version {
+			return fmt.Errorf("migration version %d is declared twice", migration.Version)
+		}
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d has no up step", migration.Version)
+		}
+	}
+	return nil
+}
+
+// collectionIndexes are the indexes declared on one collection.
+type collectionIndexes struct {
+	collection string
+	indexes    []mongo.IndexModel
+}
+
+// createIndexes creates the indexes, creating an index that already exists with the same options is a no-op.
+func createIndexes(ctx context.Context, db *mongo.Database, declared []collectionIndexes) error {
+	for _, c := range declared {
+		if _, err := db.Collection(c.collection).Indexes().CreateMany(ctx, c.indexes); err != nil {
+			return fmt.Errorf("creating indexes on %s: %w", c.collection, err)
+		}
+	}
+	return nil
+}
+
+// dropIndexes drops the indexes, the ones that no longer exist are skipped.
+func dropIndexes(ctx context.Context, db *mongo.Database, declared []collectionIndexes) error {
+	for _, c := range declared {
+		for _, index := range c.indexes {
+			name := indexName(index)
+			if _, err := db.Collection(c.collection).Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+				return fmt.Errorf("dropping index %s on %s: %w", name, c.collection, err)
+			}
+		}
+	}
+	return nil
+}
+
+// index returns an index on keys, keys alternate field names and sort orders. The index is named the way
+// mongo names it by default so it matches the indexes the store used to create on the fly.
+func index(opts *options.IndexOptions, keys ...interface{}) mongo.IndexModel {
+	doc := bson.D{}
+	for i := 0; i+1 < len(keys); i += 2 {
+		doc = append(doc, primitive.E{Key: keys[i].(string), Value: keys[i+1]})
+	}
+	if opts == nil {
+		opts = options.Index()
+	}
+	model := mongo.IndexModel{Keys: doc, Options: opts}
+	opts.SetName(indexName(model))
+	return model
+}
+
+func indexName(model mongo.IndexModel) string {
+	if opts := model.Options; opts != nil && opts.Name != nil {
+		return *opts.Name
+	}
+
+	keys := model.Keys.(bson.D)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Name == "IndexNotFound")
+}
+
+// fieldRename renames fields in every document of a collection, documents without the field are left alone.
+type fieldRename struct {
+	collection string
+	from, to   string
+}
+
+func renameFields(ctx context.Context, db *mongo.Database, renames []fieldRename) error {
+	for _, r := range renames {
+		filter := bson.D{primitive.E{Key: r.from, Value: bson.D{primitive.E{Key: "$exists", Value: true}}}}
+		update := bson.D{primitive.E{Key: "$rename", Value: bson.D{primitive.E{Key: r.from, Value: r.to}}}}
+		if _, err := db.Collection(r.collection).UpdateMany(ctx, filter, update); err != nil {
+			return fmt.Errorf("renaming %s to %s in %s: %w", r.from, r.to, r.collection, err)
+		}
+	}
+	return nil
+}
+
+func reverseRenames(renames []fieldRename) []fieldRename {
+	reversed := make([]fieldRename, 0, len(renames))
+	for i := len(renames) - 1; i >= 0; i-- {
+		r := renames[i]
+		reversed = append(reversed, fieldRename{collection: r.collection, from: r.to, to: r.from})
+	}
+	return reversed
+}
@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	up := func(context.Context, *mongo.Database) error { return nil }
+
+	var tests = []struct {
+		name       string
+		migrations []Migration
+		wantErr    bool
+	}{
+		{name: "Test declared migrations", migrations: migrations},
+		{name: "Test out of order", migrations: []Migration{{Version: 2, Up: up}, {Version: 1, Up: up}}, wantErr: true},
+		{name: "Test duplicate version", migrations: []Migration{{Version: 1, Up: up}, {Version: 1, Up: up}}, wantErr: true},
+		{name: "Test missing version", migrations: []Migration{{Up: up}}, wantErr: true},
+		{name: "Test missing up", migrations: []Migration{{Version: 1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMigrations(tt.migrations)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	var tests = []struct {
+		name  string
+		index mongo.IndexModel
+		want  string
+	}{
+		{name: "Test single key", index: index(nil, "expireAt", 1), want: "expireAt_1"},
+		{name: "Test compound key", index: index(options.Index().SetUnique(true), "email", 1, "expireAt", -1), want: "email_1_expireAt_-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, indexName(tt.index))
+			assert.Equal(t, tt.want, *tt.index.Options.Name)
+		})
+	}
+}
+
+func TestDeclaredIndexesAreUnique(t *testing.T) {
+	for _, c := range declaredIndexes() {
+		names := map[string]bool{}
+		for _, index := range c.indexes {
+			name := indexName(index)
+			assert.False(t, names[name], "index %s is declared twice on %s", name, c.collection)
+			names[name] = true
+		}
+	}
+}
+
+func TestReverseRenames(t *testing.T) {
+	renames := []fieldRename{
+		{collection: balColl, from: "virtualnuban", to: "virtual_nuban"},
+		{collection: pinColl, from: "userid", to: "user_id"},
+	}
+
+	assert.Equal(t, []fieldRename{
+		{collection: pinColl, from: "user_id", to: "userid"},
+		{collection: balColl, from: "virtual_nuban", to: "virtualnuban"},
+	}, reverseRenames(renames))
+}
+
+func TestRecipientNetworkUpdate(t *testing.T) {
+	filter, update, opts := recipientNetworkUpdate("01", "mtn")
+
+	assert.Equal(t, bson.D{primitive.E{Key: "recipients.network", Value: "01"}}, filter)
+	assert.Equal(t, bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "recipients.$[r].network", Value: "mtn"}}}}, update)
+	// only the recipients with the old code are rewritten
+	assert.Equal(t, []interface{}{bson.D{primitive.E{Key: "r.network", Value: "01"}}}, opts.ArrayFilters.Filters)
+}
+
+func TestReverseNetworks(t *testing.T) {
+	assert.Equal(t, map[string]string{"mtn": "01", "glo": "02", "airtel": "03", "9mobile": "04"}, reverseNetworks(recipientNetworks))
+}
+
+func TestBackfilledOwners(t *testing.T) {
+	// data and airtime were backfilled by migration 6
+	backfilled := map[string]bool{dataColl: true, airColl: true}
+	for _, o := range emailOwners {
+		backfilled[o.collection] = true
+	}
+	for _, collection := range ledgerOwned {
+		backfilled[collection] = true
+	}
+
+	// every collection a user's history is read from, see ownedBy
+	for _, collection := range append(append([]string{}, listedCollections...), transactionColls...) {
+		assert.True(t, backfilled[collection], "user_id of %s is not backfilled", collection)
+	}
+}
@@ -0,0 +1,578 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations are applied in order, a migration must never be edited once it has been released. Add a new
+// one with the next version instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "use snake case for user, pin and balance fields",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return renameFields(ctx, db, accountRenames)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return renameFields(ctx, db, reverseRenames(accountRenames))
+		},
+	},
+	{
+		// the old names can not be restored as several of them were merged into one
+		Version:     2,
+		Description: "use order_id, transaction_id and request_id in transactions",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return renameFields(ctx, db, transactionRenames())
+		},
+	},
+	{
+		Version:     3,
+		Description: "declare indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, declaredIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, declaredIndexes())
+		},
+	},
+	{
+		// the documents saved before the lists were paged have no created_at, their id holds the time they
+		// were inserted
+		Version:     4,
+		Description: "backfill created_at of transactions from their id",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return backfillCreatedAt(ctx, db, listedCollections)
+		},
+	},
+	{
+		Version:     5,
+		Description: "index transactions by creation time for paging",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, listIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, listIndexes())
+		},
+	},
+	{
+		// data and airtime transactions recorded the username that bought them, the others are backfilled by 17
+		Version:     6,
+		Description: "backfill user_id of transactions from the username that bought them",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return backfillUserID(ctx, db, []ownerField{
+				{collection: dataColl, field: "username", userField: "username"},
+				{collection: airColl, field: "name", userField: "username"},
+			})
+		},
+	},
+	{
+		Version:     7,
+		Description: "index transactions by owner for paging",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, ownerIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, ownerIndexes())
+		},
+	},
+	{
+		Version:     8,
+		Description: "index the wallet ledger and the statements sent",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, ledgerIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, ledgerIndexes())
+		},
+	},
+	{
+		Version:     9,
+		Description: "index the provider balances and expire old ones",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, floatIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, floatIndexes())
+		},
+	},
+	{
+		Version:     10,
+		Description: "index the reconciliation reports and exceptions",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, reconIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, reconIndexes())
+		},
+	},
+	{
+		Version:     11,
+		Description: "index the refunds",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, refundIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, refundIndexes())
+		},
+	},
+	{
+		Version:     12,
+		Description: "index the dispute tickets and their attachments",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, disputeIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, disputeIndexes())
+		},
+	},
+	{
+		Version:     13,
+		Description: "index the notification inbox and preferences",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, notificationIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, notificationIndexes())
+		},
+	},
+	{
+		Version:     14,
+		Description: "index the bulk batches still processing",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, bulkIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, bulkIndexes())
+		},
+	},
+	{
+		// recipients saved before numbers were normalised hold the easyaccess network code
+		Version:     15,
+		Description: "store the network names of saved airtime recipients",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return renameRecipientNetworks(ctx, db, recipientNetworks)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return renameRecipientNetworks(ctx, db, reverseNetworks(recipientNetworks))
+		},
+	},
+	{
+		Version:     16,
+		Description: "index the ledger entries of an order",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, ledgerReferenceIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, ledgerReferenceIndexes())
+		},
+	},
+	{
+		Version:     17,
+		Description: "backfill user_id of edu, tv, electricity, transfer and deposit records",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := backfillUserID(ctx, db, emailOwners); err != nil {
+				return err
+			}
+			return backfillUserIDFromLedger(ctx, db, ledgerOwned)
+		},
+	},
+	{
+		Version:     18,
+		Description: "index the transfers still pending",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, pendingTransferIndexes())
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, pendingTransferIndexes())
+		},
+	},
+}
+
+// emailOwners are the collections whose records only kept the email of the user who bought them.
+var emailOwners = []ownerField{
+	{collection: eduColl, field: "email", userField: "email"},
+	{collection: tvColl, field: "email", userField: "email"},
+}
+
+// ledgerOwned are the collections whose records kept no owner, the wallet ledger entries made for their
+// order ids tell it. Records older than the ledger stay without an owner.
+var ledgerOwned = []string{bankTransColl}
+
+// listedCollections hold the transactions returned by the paged lists.
+var listedCollections = []string{dataColl, airColl, eduColl, tvColl, bankTransColl}
+
+var accountRenames = []fieldRename{
+	{collection: models.UserCollectionName, from: "has_Pin", to: "has_pin"},
+	{collection: models.UserCollectionName, from: "invitation_Code", to: "invitation_code"},
+	{collection: pinColl, from: "userid", to: "user_id"},
+	{collection: balColl, from: "virtualnuban", to: "virtual_nuban"},
+	{collection: balColl, from: "userid", to: "user_id"},
+}
+
+// transactionRenames renames the identifiers of the purchases, which were stored under the default bson
+// names of untagged fields or misspelled tags.
+func transactionRenames() []fieldRename {
+	fields := []struct{ from, to string }{
+		{"orderid", "order_id"},
+		{"transcation_id", "transaction_id"},
+		{"transcationid", "transaction_id"},
+		{"transactionid", "transaction_id"},
+		{"request_ID", "request_id"},
+		{"requestid", "request_id"},
+		{"DiscoType", "disco_type"},
+	}
+
+	var renames []fieldRename
+	for _, collection := range []string{dataColl, airColl, eduColl, tvColl} {
+		for _, field := range fields {
+			renames = append(renames, fieldRename{collection: collection, from: field.from, to: field.to})
+		}
+	}
+	return renames
+}
+
+func declaredIndexes() []collectionIndexes {
+	unique := func() *options.IndexOptions { return options.Index().SetUnique(true) }
+	// transaction ids are only set once the provider has answered
+	uniqueTransaction := func() *options.IndexOptions {
+		return options.Index().SetUnique(true).SetPartialFilterExpression(
+			bson.D{primitive.E{Key: "transaction_id", Value: bson.D{primitive.E{Key: "$gt", Value: ""}}}},
+		)
+	}
+
+	declared := []collectionIndexes{
+		{collection: migrationsColl, indexes: []mongo.IndexModel{
+			index(unique(), "version", 1),
+		}},
+		{collection: models.UserCollectionName, indexes: []mongo.IndexModel{
+			index(unique(), "id", 1),
+			index(unique(), "email", 1),
+			index(unique(), "username", 1),
+			// unverified users are removed once their sign up expires
+			index(options.Index().SetExpireAfterSeconds(0), "expireAt", 1),
+		}},
+		{collection: models.MessagesCollectionName, indexes: []mongo.IndexModel{
+			index(unique(), "id", 1),
+		}},
+		{collection: otpColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetExpireAfterSeconds(0), "expireAt", 1),
+			index(nil, "email", 1, "expireAt", -1),
+		}},
+		{collection: pinColl, indexes: []mongo.IndexModel{
+			index(unique(), "user_id", 1),
+		}},
+		{collection: balColl, indexes: []mongo.IndexModel{
+			index(unique(), "virtual_nuban", 1),
+			index(nil, "user_id", 1),
+		}},
+		{collection: virtualColl, indexes: []mongo.IndexModel{
+			index(unique(), "account_name", 1),
+			index(nil, "user_id", 1),
+		}},
+		{collection: counterColl, indexes: []mongo.IndexModel{
+			index(nil, "accountnumber", 1, "bankname", 1),
+		}},
+		{collection: depositIDColl, indexes: []mongo.IndexModel{
+			index(unique(), "ID", 1),
+		}},
+		{collection: bankTransColl, indexes: []mongo.IndexModel{
+			index(unique(), "order_id", 1),
+			index(nil, "username", 1),
+		}},
+		{collection: scheduleColl, indexes: []mongo.IndexModel{
+			index(unique(), "id", 1),
+			index(nil, "username", 1, "created_at", -1),
+			index(nil, "status", 1, "next_run", 1),
+		}},
+		{collection: runColl, indexes: []mongo.IndexModel{
+			index(nil, "order_id", 1, "ran_at", -1),
+		}},
+		{collection: bulkColl, indexes: []mongo.IndexModel{
+			index(unique(), "id", 1),
+		}},
+		{collection: planColl, indexes: []mongo.IndexModel{
+			index(unique(), "id", 1),
+			index(unique(), "provider", 1, "provider_code", 1),
+			index(nil, "network", 1, "price", 1),
+			index(nil, "provider", 1, "updated_at", 1),
+		}},
+	}
+
+	for _, collection := range []string{dataColl, airColl, eduColl, tvColl} {
+		declared = append(declared, collectionIndexes{collection: collection, indexes: []mongo.IndexModel{
+			index(unique(), "order_id", 1),
+			index(uniqueTransaction(), "transaction_id", 1),
+			index(nil, "username", 1),
+		}})
+	}
+
+	return declared
+}
+
+// listIndexes serve the paged lists, newest first for everyone and for one user.
+func listIndexes() []collectionIndexes {
+	var declared []collectionIndexes
+	for _, collection := range listedCollections {
+		declared = append(declared, collectionIndexes{collection: collection, indexes: []mongo.IndexModel{
+			index(nil, "created_at", -1, "_id", -1),
+			index(nil, "username", 1, "created_at", -1, "_id", -1),
+		}})
+	}
+	return declared
+}
+
+// backfillCreatedAt sets created_at to the time held in the id of the documents that have no date in it.
+func backfillCreatedAt(ctx context.Context, db *mongo.Database, collections []string) error {
+	filter := bson.D{primitive.E{Key: "created_at", Value: bson.D{primitive.E{Key: "$not", Value: bson.D{primitive.E{Key: "$type", Value: "date"}}}}}}
+	update := mongo.Pipeline{
+		bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "created_at", Value: bson.D{primitive.E{Key: "$toDate", Value: "$_id"}}}}}},
+	}
+
+	for _, collection := range collections {
+		if _, err := db.Collection(collection).UpdateMany(ctx, filter, update); err != nil {
+			return fmt.Errorf("backfilling created_at in %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// ownerIndexes serve the paged lists of one user and the lookups of a transaction by its owner.
+func ownerIndexes() []collectionIndexes {
+	var declared []collectionIndexes
+	for _, collection := range listedCollections {
+		declared = append(declared, collectionIndexes{collection: collection, indexes: []mongo.IndexModel{
+			index(nil, "user_id", 1, "created_at", -1, "_id", -1),
+		}})
+	}
+	return declared
+}
+
+// ownerField is the field of a collection holding the username or email of the owner of its documents,
+// userField is the field of the user it matches.
+type ownerField struct {
+	collection string
+	field      string
+	userField  string
+}
+
+// backfillUserID sets user_id on the documents that have none to the id of the user whose username or
+// email is in the owner field. Documents whose user no longer exists are left alone.
+func backfillUserID(ctx context.Context, db *mongo.Database, owners []ownerField) error {
+	for _, o := range owners {
+		cur, err := db.Collection(o.collection).Aggregate(ctx, ownerPipeline(o))
+		if err != nil {
+			return fmt.Errorf("backfilling user_id in %s: %w", o.collection, err)
+		}
+		cur.Close(ctx)
+	}
+	return nil
+}
+
+func ownerPipeline(o ownerField) mongo.Pipeline {
+	return mongo.Pipeline{
+		bson.D{primitive.E{Key: "$match", Value: bson.D{
+			primitive.E{Key: "user_id", Value: bson.D{primitive.E{Key: "$exists", Value: false}}},
+			primitive.E{Key: o.field, Value: bson.D{primitive.E{Key: "$type", Value: "string"}, primitive.E{Key: "$ne", Value: ""}}},
+		}}},
+		bson.D{primitive.E{Key: "$lookup", Value: bson.D{
+			primitive.E{Key: "from", Value: models.UserCollectionName},
+			primitive.E{Key: "localField", Value: o.field},
+			primitive.E{Key: "foreignField", Value: o.userField},
+			primitive.E{Key: "as", Value: "owner"},
+		}}},
+		bson.D{primitive.E{Key: "$unwind", Value: "$owner"}},
+		bson.D{primitive.E{Key: "$project", Value: bson.D{primitive.E{Key: "user_id", Value: "$owner.id"}}}},
+		mergeInto(o.collection),
+	}
+}
+
+// backfillUserIDFromLedger sets user_id on the documents of the collections that have none to the user of
+// the ledger entries made for their order id.
+func backfillUserIDFromLedger(ctx context.Context, db *mongo.Database, collections []string) error {
+	for _, collection := range collections {
+		cur, err := db.Collection(collection).Aggregate(ctx, ledgerOwnerPipeline(collection))
+		if err != nil {
+			return fmt.Errorf("backfilling user_id in %s: %w", collection, err)
+		}
+		cur.Close(ctx)
+	}
+	return nil
+}
+
+func ledgerOwnerPipeline(collection string) mongo.Pipeline {
+	return mongo.Pipeline{
+		bson.D{primitive.E{Key: "$match", Value: bson.D{
+			primitive.E{Key: "user_id", Value: bson.D{primitive.E{Key: "$exists", Value: false}}},
+			primitive.E{Key: "order_id", Value: bson.D{primitive.E{Key: "$type", Value: "number"}}},
+		}}},
+		// the ledger references an order by its id as a string
+		bson.D{primitive.E{Key: "$lookup", Value: bson.D{
+			primitive.E{Key: "from", Value: ledgerColl},
+			primitive.E{Key: "let", Value: bson.D{primitive.E{Key: "reference", Value: bson.D{primitive.E{Key: "$toString", Value: "$order_id"}}}}},
+			primitive.E{Key: "pipeline", Value: bson.A{
+				bson.D{primitive.E{Key: "$match", Value: bson.D{primitive.E{Key: "$expr", Value: bson.D{
+					primitive.E{Key: "$eq", Value: bson.A{"$reference", "$$reference"}},
+				}}}}},
+				bson.D{primitive.E{Key: "$limit", Value: 1}},
+			}},
+			primitive.E{Key: "as", Value: "entry"},
+		}}},
+		bson.D{primitive.E{Key: "$unwind", Value: "$entry"}},
+		bson.D{primitive.E{Key: "$project", Value: bson.D{primitive.E{Key: "user_id", Value: "$entry.user_id"}}}},
+		mergeInto(collection),
+	}
+}
+
+// mergeInto writes the documents of a pipeline over the documents of collection with the same _id.
+func mergeInto(collection string) bson.D {
+	return bson.D{primitive.E{Key: "$merge", Value: bson.D{
+		primitive.E{Key: "into", Value: collection},
+		primitive.E{Key: "on", Value: "_id"},
+		primitive.E{Key: "whenMatched", Value: "merge"},
+		primitive.E{Key: "whenNotMatched", Value: "discard"},
+	}}}
+}
+
+func ledgerIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: ledgerColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "id", 1),
+			index(nil, "user_id", 1, "created_at", 1, "id", 1),
+			index(nil, "created_at", 1),
+		}},
+		// a statement is sent once for each user and period
+		{collection: statementColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "user_id", 1, "period", 1),
+		}},
+	}
+}
+
+// ledgerReferenceIndexes serve the refunds, which look up what was debited for an order.
+func ledgerReferenceIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: ledgerColl, indexes: []mongo.IndexModel{
+			index(nil, "user_id", 1, "reference", 1),
+		}},
+	}
+}
+
+func floatIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: floatColl, indexes: []mongo.IndexModel{
+			index(nil, "provider", 1, "checked_at", -1),
+			index(options.Index().SetExpireAfterSeconds(int32(floatRetention.Seconds())), "checked_at", 1),
+		}},
+	}
+}
+
+func reconIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		// a provider has one report a day, running it again replaces it
+		{collection: reconColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "provider", 1, "date", 1),
+			index(nil, "created_at", -1, "_id", -1),
+		}},
+		{collection: reconExceptionColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "id", 1),
+			index(nil, "provider", 1, "date", 1, "status", 1),
+			index(nil, "status", 1, "created_at", -1, "_id", -1),
+		}},
+	}
+}
+
+func refundIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: refundColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "id", 1),
+			// only refunds still going through hold active, so a transaction has one at a time
+			index(options.Index().SetUnique(true).SetSparse(true), "active", 1),
+			index(nil, "created_at", -1, "_id", -1),
+			index(nil, "user_id", 1, "created_at", -1, "_id", -1),
+			index(nil, "status", 1, "created_at", -1, "_id", -1),
+		}},
+	}
+}
+
+func disputeIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: disputeColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "id", 1),
+			// tickets hold active until they are closed, a transaction has one open ticket at a time
+			index(options.Index().SetUnique(true).SetSparse(true), "active", 1),
+			index(nil, "created_at", -1, "_id", -1),
+			index(nil, "user_id", 1, "created_at", -1, "_id", -1),
+			index(nil, "status", 1, "created_at", -1, "_id", -1),
+		}},
+		{collection: disputeFileColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "dispute_id", 1, "id", 1),
+		}},
+	}
+}
+
+func notificationIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: models.MessagesCollectionName, indexes: []mongo.IndexModel{
+			index(nil, "customer_id", 1, "type", 1, "created_at", -1, "_id", -1),
+			// the unread count
+			index(nil, "customer_id", 1, "type", 1, "status", 1),
+		}},
+		{collection: notificationPrefColl, indexes: []mongo.IndexModel{
+			index(options.Index().SetUnique(true), "user_id", 1),
+		}},
+	}
+}
+
+func bulkIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: bulkColl, indexes: []mongo.IndexModel{
+			index(nil, "status", 1, "created_at", 1),
+		}},
+	}
+}
+
+// pendingTransferIndexes serve the transfers anchor has not been confirmed to have.
+func pendingTransferIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{collection: bankTransColl, indexes: []mongo.IndexModel{
+			index(nil, "status", 1, "created_at", 1),
+		}},
+	}
+}
+
+// recipientNetworks are the easyaccess network codes and the names that replaced them.
+var recipientNetworks = map[string]string{"01": "mtn", "02": "glo", "03": "airtel", "04": "9mobile"}
+
+// renameRecipientNetworks rewrites the network of every saved recipient from a key of networks to its value.
+func renameRecipientNetworks(ctx context.Context, db *mongo.Database, networks map[string]string) error {
+	for from, to := range networks {
+		filter, update, opts := recipientNetworkUpdate(from, to)
+		if _, err := db.Collection(recipientColl).UpdateMany(ctx, filter, update, opts); err != nil {
+			return fmt.Errorf("renaming recipient network %s to %s: %w", from, to, err)
+		}
+	}
+	return nil
+}
+
+func recipientNetworkUpdate(from, to string) (bson.D, bson.D, *options.UpdateOptions) {
+	filter := bson.D{primitive.E{Key: "recipients.network", Value: from}}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "recipients.$[r].network", Value: to}}}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.D{primitive.E{Key: "r.network", Value: from}}},
+	})
+	return filter, update, opts
+}
+
+func reverseNetworks(networks map[string]string) map[string]string {
+	reversed := make(map[string]string, len(networks))
+	for from, to := range networks {
+		reversed[to] = from
+	}
+	return reversed
+}
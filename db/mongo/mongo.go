@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"time"
 
+	"github.com/aremxyplug-be/config"
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
 	"github.com/aremxyplug-be/lib/errorvalues"
@@ -21,11 +21,11 @@ import (
 
 // New returns a new instance of DataStore and Client
 // response can contain error
-func New(connectURI, databaseName string, logger *zap.Logger) (db.DataStore, *mongo.Client, error) {
+func New(cfg config.Mongo, logger *zap.Logger) (db.DataStore, *mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectURI))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URL).SetMonitor(newCommandMonitor()))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -34,7 +34,15 @@ func New(connectURI, databaseName string, logger *zap.Logger) (db.DataStore, *mo
 		return nil, nil, err
 	}
 
-	return &mongoStore{mongoClient: client, databaseName: databaseName, logger: logger}, client, nil
+	store := &mongoStore{mongoClient: client, databaseName: cfg.Database, timeouts: cfg, logger: logger}
+	if err := store.detectTransactions(ctx); err != nil {
+		return nil, nil, err
+	}
+	if !store.transactions {
+		logger.Warn("mongodb is not a replica set, wallet writes will not run in transactions")
+	}
+
+	return store, client, nil
 }
 
 var _ db.DataStore = &mongoStore{}
@@ -44,61 +52,53 @@ var (
 	eduColl  = "edu"
 	airColl  = "airtime"
 	tvColl   = "tv-sub"
+	otpColl  = "OTP"
+
+	recipientColl = "telcom-recipient"
 )
 
 type mongoStore struct {
 	mongoClient  *mongo.Client
 	databaseName string
+	timeouts     config.Mongo
+	transactions bool
 	logger       *zap.Logger
 }
 
-func (m *mongoStore) col(collectionName string) *mongo.Collection {
-	return m.mongoClient.Database(m.databaseName).Collection(collectionName)
+// readContext, writeContext and scanContext bound a query by the timeout of its class. They derive from
+// the caller's context, so a client that disconnects cancels its queries.
+func (m *mongoStore) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, m.timeouts.ReadTimeout)
 }
 
-func (m *mongoStore) otpColl() (*mongo.Collection, error) {
-	col := m.mongoClient.Database(m.databaseName).Collection("OTP")
-	ctx := context.Background()
-	indexModel := mongo.IndexModel{
-		Keys:    bson.D{primitive.E{Key: "expireAt", Value: 1}},
-		Options: options.Index().SetExpireAfterSeconds(0),
-	}
-
-	_, err := col.Indexes().CreateOne(ctx, indexModel)
-	if err != nil {
-		return nil, err
-	}
-
-	return col, nil
+func (m *mongoStore) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, m.timeouts.WriteTimeout)
 }
 
-func (m *mongoStore) userColl() (*mongo.Collection, error) {
-	col := m.mongoClient.Database(m.databaseName).Collection(models.UserCollectionName)
-	ctx := context.Background()
-	indexModel := mongo.IndexModel{
-		Keys:    bson.D{primitive.E{Key: "expireAt", Value: 1}},
-		Options: options.Index().SetExpireAfterSeconds(0),
-	}
+func (m *mongoStore) scanContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, m.timeouts.ScanTimeout)
+}
 
-	_, err := col.Indexes().CreateOne(ctx, indexModel)
-	if err != nil {
-		return nil, err
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
+}
 
-	return col, nil
+func (m *mongoStore) col(collectionName string) *mongo.Collection {
+	return m.mongoClient.Database(m.databaseName).Collection(collectionName)
 }
 
-func (m *mongoStore) SaveUser(user models.User) error {
+func (m *mongoStore) SaveUser(ctx context.Context, user models.User) error {
 
-	ctx := context.Background()
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 	user.ExpireAt = time.Now().Add(time.Duration(15) * time.Minute)
 
-	col, err := m.userColl()
-	if err != nil {
-		return err
-	}
+	col := m.col(models.UserCollectionName)
 
-	_, err = col.InsertOne(ctx, user)
+	_, err := col.InsertOne(ctx, user)
 	if err != nil {
 		return errorvalues.Format(errorvalues.DatabaseError, err)
 	}
@@ -106,44 +106,55 @@ func (m *mongoStore) SaveUser(user models.User) error {
 	return nil
 }
 
-func (m *mongoStore) GetUserByEmail(email string) (*models.User, error) {
+func (m *mongoStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	filter := bson.M{
 		"email": email,
 	}
 	user := &models.User{}
-	err := m.col(models.UserCollectionName).FindOne(context.Background(), filter).Decode(user)
+	err := m.col(models.UserCollectionName).FindOne(ctx, filter).Decode(user)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (m *mongoStore) GetUserByUsername(username string) (*models.User, error) {
+func (m *mongoStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	filter := bson.M{
 		"username": username,
 	}
 	user := &models.User{}
-	err := m.col(models.UserCollectionName).FindOne(context.Background(), filter).Decode(user)
+	err := m.col(models.UserCollectionName).FindOne(ctx, filter).Decode(user)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (m *mongoStore) GetUserByID(id string) (*models.User, error) {
+func (m *mongoStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 
 	filter := bson.M{
 		"id": id,
 	}
 	user := &models.User{}
-	err := m.col(models.UserCollectionName).FindOne(context.Background(), filter).Decode(user)
+	err := m.col(models.UserCollectionName).FindOne(ctx, filter).Decode(user)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (m *mongoStore) GetUserByUsernameOrEmail(email string, username string) (*models.User, error) {
+func (m *mongoStore) GetUserByUsernameOrEmail(ctx context.Context, email string, username string) (*models.User, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	filter := bson.M{
 		"$or": []bson.M{
 			{"email": email},
@@ -154,7 +165,7 @@ func (m *mongoStore) GetUserByUsernameOrEmail(email string, username string) (*m
 	err := m.mongoClient.
 		Database(m.databaseName).
 		Collection(models.UserCollectionName).
-		FindOne(context.Background(), filter).
+		FindOne(ctx, filter).
 		Decode(user)
 	if err != nil {
 		return nil, err
@@ -163,8 +174,9 @@ func (m *mongoStore) GetUserByUsernameOrEmail(email string, username string) (*m
 
 }
 
-func (m *mongoStore) CreateMessage(message *models.Message) error {
-	ctx := context.Background()
+func (m *mongoStore) CreateMessage(ctx context.Context, message *models.Message) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 	var modelInDB models.Message
 	err := m.mongoClient.
 		Database(m.databaseName).
@@ -195,8 +207,9 @@ func (m *mongoStore) CreateMessage(message *models.Message) error {
 }
 
 // update user password
-func (m *mongoStore) UpdateUserPassword(email string, password string) error {
-	ctx := context.Background()
+func (m *mongoStore) UpdateUserPassword(ctx context.Context, email string, password string) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 	filter := bson.M{"email": email}
 	update := bson.M{"$set": bson.M{"password": password}}
 	_, err := m.mongoClient.
@@ -209,9 +222,10 @@ func (m *mongoStore) UpdateUserPassword(email string, password string) error {
 	return nil
 }
 
-func (m *mongoStore) UpdateBVNField(user models.User) error {
-	ctx := context.Background()
-	filter := bson.M{"userID": user.ID}
+func (m *mongoStore) UpdateBVNField(ctx context.Context, user models.User) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+	filter := bson.M{"id": user.ID}
 	update := bson.M{"$set": bson.M{"bvn": user.BVN}}
 	_, err := m.mongoClient.
 		Database(m.databaseName).
@@ -223,9 +237,10 @@ func (m *mongoStore) UpdateBVNField(user models.User) error {
 	return nil
 }
 
-func (m *mongoStore) VerifyUser(email string) (*models.User, error) {
+func (m *mongoStore) VerifyUser(ctx context.Context, email string) (*models.User, error) {
 	userColl := m.col(models.UserCollectionName)
-	ctx := context.Background()
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
 	filter := bson.M{"email": email}
 	user := &models.User{}
@@ -268,15 +283,14 @@ func (m *mongoStore) VerifyUser(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (m *mongoStore) getRecord(id, collectionName string) *mongo.SingleResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+// getRecord finds the transaction with the order id, extra narrows the lookup, see ownedBy.
+func (m *mongoStore) getRecord(ctx context.Context, id, collectionName string, extra ...primitive.E) *mongo.SingleResult {
 	oID, err := strconv.Atoi(id)
 	if err != nil {
 		return &mongo.SingleResult{}
 	}
 
-	filter := bson.D{primitive.E{Key: "order_id", Value: oID}}
+	filter := append(bson.D{primitive.E{Key: "order_id", Value: oID}}, extra...)
 
 	result := m.col(collectionName).FindOne(ctx, filter)
 
@@ -284,42 +298,32 @@ func (m *mongoStore) getRecord(id, collectionName string) *mongo.SingleResult {
 
 }
 
-func (m *mongoStore) saveToDB(collectionName string, details interface{}) error {
-	ctx := context.Background()
-
-	_, err := m.col(collectionName).InsertOne(ctx, details)
-	if err != nil {
-		log.Fatal(err)
-		return err
+// ownedBy restricts a query to the transactions of userID, an empty userID does not restrict it.
+func ownedBy(userID string) []primitive.E {
+	if userID == "" {
+		return nil
 	}
-
-	return nil
+	return []primitive.E{{Key: "user_id", Value: userID}}
 }
 
-func (m *mongoStore) getAllRecords(collectionName, username string) (*mongo.Cursor, error) {
-	ctx := context.Background()
-	var filter bson.D
-
-	if username == "" {
-		filter = bson.D{}
-	} else {
-		filter = bson.D{primitive.E{Key: "username", Value: username}}
+func (m *mongoStore) saveToDB(ctx context.Context, collectionName string, details interface{}) error {
+	doc, err := withCreatedAt(details)
+	if err != nil {
+		return err
 	}
 
-	cur, err := m.col(collectionName).Find(ctx, filter)
-	return cur, err
+	_, err = m.col(collectionName).InsertOne(ctx, doc)
+	return err
 }
 
-func (m *mongoStore) SaveOTP(data models.OTP) error {
-	ctx := context.Background()
+func (m *mongoStore) SaveOTP(ctx context.Context, data models.OTP) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 	data.ExpireAt = time.Now().Add(time.Duration(5) * time.Minute)
 
-	col, err := m.otpColl()
-	if err != nil {
-		return err
-	}
+	col := m.col(otpColl)
 
-	_, err = col.InsertOne(ctx, data)
+	_, err := col.InsertOne(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -327,14 +331,14 @@ func (m *mongoStore) SaveOTP(data models.OTP) error {
 	return nil
 }
 
-func (m *mongoStore) GetOTP(email string) (models.OTP, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (m *mongoStore) GetOTP(ctx context.Context, email string) (models.OTP, error) {
+	ctx, cancel := m.readContext(ctx)
 	defer cancel()
 	data := models.OTP{}
 	filter := bson.D{primitive.E{Key: "email", Value: email}}
 	opts := options.FindOne().SetSort(bson.D{{Key: "expireAt", Value: -1}})
 
-	result := m.col("OTP").FindOne(ctx, filter, opts)
+	result := m.col(otpColl).FindOne(ctx, filter, opts)
 	err := result.Decode(&data)
 	if err == mongo.ErrNoDocuments {
 		return models.OTP{}, errors.New("no record found")
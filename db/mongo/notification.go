@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var notificationPrefColl = "notification_preferences"
+
+// inbox selects the notifications of a user among the messages.
+func inbox(userID string) []primitive.E {
+	return []primitive.E{
+		{Key: "customer_id", Value: userID},
+		{Key: "type", Value: models.INBOX_MESSAGE_TYPE},
+	}
+}
+
+func (m *mongoStore) GetNotifications(ctx context.Context, userID string, opts db.ListOptions) ([]models.Message, string, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.Message{}
+
+	// the messages belong to customer_id, not the user_id the lists filter on
+	opts.UserID = ""
+	cur, err := m.listRecords(ctx, models.MessagesCollectionName, opts, inbox(userID)...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
+		var message models.Message
+		if err := cur.Decode(&message); err != nil {
+			return err
+		}
+		res = append(res, message)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, next, nil
+}
+
+func (m *mongoStore) CountUnreadNotifications(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	filter := append(bson.D(inbox(userID)), primitive.E{Key: "status", Value: models.MessageUnread})
+	return m.col(models.MessagesCollectionName).CountDocuments(ctx, filter)
+}
+
+func (m *mongoStore) MarkNotificationRead(ctx context.Context, userID, id string, at time.Time) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := append(bson.D(inbox(userID)), primitive.E{Key: "id", Value: id})
+	res, err := m.col(models.MessagesCollectionName).UpdateOne(ctx, filter, markRead(at))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (m *mongoStore) MarkAllNotificationsRead(ctx context.Context, userID string, at time.Time) (int64, error) {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := append(bson.D(inbox(userID)), primitive.E{Key: "status", Value: models.MessageUnread})
+	res, err := m.col(models.MessagesCollectionName).UpdateMany(ctx, filter, markRead(at))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// markRead marks notifications read, $min keeps the time one was first read.
+func markRead(at time.Time) bson.D {
+	return bson.D{
+		primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "status", Value: models.MessageRead}}},
+		primitive.E{Key: "$min", Value: bson.D{primitive.E{Key: "read_at", Value: at}}},
+	}
+}
+
+func (m *mongoStore) GetNotificationPreferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	prefs := models.NotificationPreferences{}
+	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
+	if err := m.col(notificationPrefColl).FindOne(ctx, filter).Decode(&prefs); err != nil {
+		return models.NotificationPreferences{}, err
+	}
+
+	return prefs, nil
+}
+
+func (m *mongoStore) SaveNotificationPreferences(ctx context.Context, prefs models.NotificationPreferences) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "user_id", Value: prefs.UserID}}
+	_, err := m.col(notificationPrefColl).ReplaceOne(ctx, filter, prefs, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db/models/telcom"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var planColl = "data-plans"
+
+// SaveDataPlans upserts the plans pulled from a provider and removes the plans that the provider no longer lists.
+func (m *mongoStore) SaveDataPlans(ctx context.Context, provider string, plans []telcom.DataPlan) error {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+
+	syncedAt := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(plans))
+	for _, plan := range plans {
+		plan.UpdatedAt = syncedAt
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{primitive.E{Key: "id", Value: plan.ID}}).
+			SetReplacement(plan).
+			SetUpsert(true))
+	}
+
+	if len(writes) > 0 {
+		if _, err := m.col(planColl).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	filter := bson.D{
+		primitive.E{Key: "provider", Value: provider},
+		primitive.E{Key: "updated_at", Value: bson.D{primitive.E{Key: "$lt", Value: syncedAt}}},
+	}
+	if _, err := m.col(planColl).DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetDataPlans returns the plans for a network, if an empty string is passed it returns every plan in the catalogue.
+func (m *mongoStore) GetDataPlans(ctx context.Context, network string) ([]telcom.DataPlan, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []telcom.DataPlan{}
+
+	filter := bson.D{}
+	if network != "" {
+		filter = bson.D{primitive.E{Key: "network", Value: network}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "network", Value: 1}, {Key: "price", Value: 1}})
+
+	cur, err := m.col(planColl).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		plan := telcom.DataPlan{}
+		if err := cur.Decode(&plan); err != nil {
+			return nil, err
+		}
+		res = append(res, plan)
+	}
+
+	return res, cur.Err()
+}
+
+// GetDataPlan returns a single plan using the code the provider knows it by.
+func (m *mongoStore) GetDataPlan(ctx context.Context, provider, providerCode string) (telcom.DataPlan, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "provider", Value: provider},
+		primitive.E{Key: "provider_code", Value: providerCode},
+	}
+
+	plan := telcom.DataPlan{}
+	if err := m.col(planColl).FindOne(ctx, filter).Decode(&plan); err != nil {
+		return telcom.DataPlan{}, err
+	}
+
+	return plan, nil
+}
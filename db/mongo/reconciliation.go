@@ -0,0 +1,235 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	reconColl          = "reconciliations"
+	reconExceptionColl = "reconciliation_exceptions"
+)
+
+// reconSource is where the transactions made with a provider are recorded: the documents of collection
+// matching filter, with the provider's reference in the reference field.
+type reconSource struct {
+	collection string
+	filter     bson.D
+	reference  string
+	amount     string
+	product    string
+}
+
+func hasKey(key string, exists bool) bson.D {
+	return bson.D{primitive.E{Key: key, Value: bson.D{primitive.E{Key: "$exists", Value: exists}}}}
+}
+
+// reconSources lists the transactions of every provider. Dontech sells the plans we send by id and
+// VTpass the smile and spectranet data, which carry the request id we send it.
+var reconSources = map[string][]reconSource{
+	"dontech": {
+		{collection: dataColl, filter: hasKey("plan_name", true), reference: "reference_number", amount: "plan_amount", product: "data"},
+	},
+	"vtpass": {
+		{collection: dataColl, filter: hasKey("plan_name", false), reference: "request_id", amount: "amount", product: "data"},
+		{collection: tvColl, filter: hasKey("meter_number", false), reference: "request_id", amount: "amount", product: "tv"},
+		{collection: tvColl, filter: hasKey("meter_number", true), reference: "request_id", amount: "amount", product: "electricity"},
+	},
+	"easyaccess": {
+		{collection: airColl, reference: "reference_number", amount: "amount", product: "airtime"},
+		{collection: eduColl, reference: "reference_no", amount: "amount", product: "edu"},
+	},
+	"anchor": {
+		{collection: bankTransColl, filter: bson.D{productFilter(transferProduct)}, reference: "transaction_id", amount: "amount", product: "transfer"},
+		{collection: bankTransColl, filter: bson.D{productFilter(depositProduct)}, reference: "session_id", amount: "amount", product: "deposit"},
+	},
+}
+
+func (m *mongoStore) GetReconRecords(ctx context.Context, provider string, from, to time.Time) ([]models.ReconRecord, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.ReconRecord{}
+
+	for _, src := range reconSources[provider] {
+		match := append(bson.D{primitive.E{Key: "created_at", Value: bson.D{
+			primitive.E{Key: "$gte", Value: from},
+			primitive.E{Key: "$lte", Value: to},
+		}}}, src.filter...)
+		pipeline := mongo.Pipeline{
+			{primitive.E{Key: "$match", Value: match}},
+			{primitive.E{Key: "$project", Value: bson.D{
+				primitive.E{Key: "_id", Value: 0},
+				primitive.E{Key: "reference", Value: bson.D{primitive.E{Key: "$toString", Value: "$" + src.reference}}},
+				// amounts are stored as numbers by some products and as strings by others
+				primitive.E{Key: "amount", Value: bson.D{primitive.E{Key: "$convert", Value: bson.D{
+					primitive.E{Key: "input", Value: "$" + src.amount},
+					primitive.E{Key: "to", Value: "double"},
+					primitive.E{Key: "onError", Value: 0},
+					primitive.E{Key: "onNull", Value: 0},
+				}}}},
+				primitive.E{Key: "status", Value: "$status"},
+				primitive.E{Key: "product", Value: bson.D{primitive.E{Key: "$literal", Value: src.product}}},
+				primitive.E{Key: "created_at", Value: 1},
+			}}},
+		}
+
+		cur, err := m.col(src.collection).Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		records := []models.ReconRecord{}
+		err = cur.All(ctx, &records)
+		cur.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, records...)
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) SaveReconciliation(ctx context.Context, rec models.Reconciliation, exceptions []models.ReconException) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	day := bson.D{
+		primitive.E{Key: "provider", Value: rec.Provider},
+		primitive.E{Key: "date", Value: rec.Date},
+	}
+
+	return m.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := m.col(reconColl).ReplaceOne(ctx, day, rec, options.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+
+		open := append(day, primitive.E{Key: "status", Value: models.ExceptionOpen})
+		if _, err := m.col(reconExceptionColl).DeleteMany(ctx, open); err != nil {
+			return err
+		}
+		if len(exceptions) == 0 {
+			return nil
+		}
+
+		docs := make([]interface{}, len(exceptions))
+		for i, exception := range exceptions {
+			docs[i] = exception
+		}
+		_, err := m.col(reconExceptionColl).InsertMany(ctx, docs)
+		return err
+	})
+}
+
+func (m *mongoStore) GetReconciliations(ctx context.Context, opts db.ListOptions, provider string) ([]models.Reconciliation, string, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.Reconciliation{}
+
+	cur, err := m.listRecords(ctx, reconColl, opts, providerFilter(provider)...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
+		var rec models.Reconciliation
+		if err := cur.Decode(&rec); err != nil {
+			return err
+		}
+		res = append(res, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, next, nil
+}
+
+func (m *mongoStore) GetReconExceptions(ctx context.Context, opts db.ListOptions, provider string) ([]models.ReconException, string, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.ReconException{}
+
+	cur, err := m.listRecords(ctx, reconExceptionColl, opts, providerFilter(provider)...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
+		var exception models.ReconException
+		if err := cur.Decode(&exception); err != nil {
+			return err
+		}
+		res = append(res, exception)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, next, nil
+}
+
+func (m *mongoStore) GetResolvedReconExceptions(ctx context.Context, provider, date string) ([]models.ReconException, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.ReconException{}
+
+	filter := bson.D{
+		primitive.E{Key: "provider", Value: provider},
+		primitive.E{Key: "date", Value: date},
+		primitive.E{Key: "status", Value: models.ExceptionResolved},
+	}
+
+	cur, err := m.col(reconExceptionColl).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) ResolveReconException(ctx context.Context, id, resolution, resolvedBy string, at time.Time) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "id", Value: id},
+		primitive.E{Key: "status", Value: models.ExceptionOpen},
+	}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{
+		primitive.E{Key: "status", Value: models.ExceptionResolved},
+		primitive.E{Key: "resolution", Value: resolution},
+		primitive.E{Key: "resolved_by", Value: resolvedBy},
+		primitive.E{Key: "resolved_at", Value: at},
+	}}}
+
+	res, err := m.col(reconExceptionColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func providerFilter(provider string) []primitive.E {
+	if provider == "" {
+		return nil
+	}
+	return []primitive.E{{Key: "provider", Value: provider}}
+}
@@ -0,0 +1,82 @@
+package mongo
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// transactionColls are the collections holding the users' transactions, in the order GetTransaction
+// searches them.
+var transactionColls = []string{dataColl, airColl, eduColl, tvColl, bankTransColl}
+
+func (m *mongoStore) GetTransaction(ctx context.Context, userID, id string) (interface{}, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	for _, collection := range transactionColls {
+		raw, err := m.getRecord(ctx, id, collection, ownedBy(userID)...).Raw()
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return decodeTransaction(collection, raw)
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+// decodeTransaction decodes a transaction into the model of its product. Some collections hold the
+// transactions of several products, those are told apart by the fields only one of them has.
+func decodeTransaction(collection string, raw bson.Raw) (interface{}, error) {
+	var record interface{}
+	switch collection {
+	case dataColl:
+		switch {
+		case hasField(raw, "plan_name"):
+			record = &telcom.DataResult{}
+		case hasField(raw, "no_of_pins"):
+			record = &telcom.SpectranetResult{}
+		default:
+			record = &telcom.SmileResult{}
+		}
+	case airColl:
+		record = &telcom.AirtimeResponse{}
+	case eduColl:
+		record = &models.EduResponse{}
+	case tvColl:
+		if hasField(raw, "meter_number") {
+			record = &models.ElectricResult{}
+		} else {
+			record = &models.BillResult{}
+		}
+	default:
+		if product, _ := raw.Lookup("product").StringValueOK(); product == transferProduct {
+			record = &models.TransferResponse{}
+		} else {
+			record = &models.DepositResponse{}
+		}
+	}
+
+	if err := bson.Unmarshal(raw, record); err != nil {
+		return nil, err
+	}
+	// the detail methods return the models by value
+	return reflect.ValueOf(record).Elem().Interface(), nil
+}
+
+func hasField(raw bson.Raw, key string) bool {
+	_, err := raw.LookupErr(key)
+	return err == nil
+}
@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDecodeTransaction(t *testing.T) {
+	var tests = []struct {
+		name       string
+		collection string
+		record     interface{}
+	}{
+		{name: "Test data", collection: dataColl, record: telcom.DataResult{OrderID: 1, PlanName: "1GB"}},
+		{name: "Test spectranet", collection: dataColl, record: telcom.SpectranetResult{OrderID: 2, No_of_Pins: 1}},
+		{name: "Test smile", collection: dataColl, record: telcom.SmileResult{OrderID: 3, AccountID: "smile-1"}},
+		{name: "Test airtime", collection: airColl, record: telcom.AirtimeResponse{OrderID: 4}},
+		{name: "Test edu", collection: eduColl, record: models.EduResponse{OrderID: 5, Pin_Generated: []string{"1"}}},
+		{name: "Test tv", collection: tvColl, record: models.BillResult{OrderID: 6, IucNumber: "123"}},
+		{name: "Test electricity", collection: tvColl, record: models.ElectricResult{OrderID: 7, MeterNumber: "456"}},
+		{name: "Test transfer", collection: bankTransColl, record: models.TransferResponse{Order_ID: 8, Product: transferProduct}},
+		{name: "Test deposit", collection: bankTransColl, record: models.DepositResponse{Order_ID: 9, Product: depositProduct}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.record)
+			assert.NoError(t, err)
+
+			got, err := decodeTransaction(tt.collection, raw)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.record, got)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var refundColl = "refunds"
+
+func (m *mongoStore) CreateRefund(ctx context.Context, refund models.Refund) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	_, err := m.col(refundColl).InsertOne(ctx, refund)
+	if mongo.IsDuplicateKeyError(err) {
+		return db.ErrDuplicate
+	}
+	return err
+}
+
+func (m *mongoStore) GetRefund(ctx context.Context, id string) (models.Refund, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	refund := models.Refund{}
+	filter := bson.D{primitive.E{Key: "id", Value: id}}
+	if err := m.col(refundColl).FindOne(ctx, filter).Decode(&refund); err != nil {
+		return models.Refund{}, err
+	}
+
+	return refund, nil
+}
+
+func (m *mongoStore) GetRefunds(ctx context.Context, opts db.ListOptions) ([]models.Refund, string, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+	res := []models.Refund{}
+
+	cur, err := m.listRecords(ctx, refundColl, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
+		var refund models.Refund
+		if err := cur.Decode(&refund); err != nil {
+			return err
+		}
+		res = append(res, refund)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, next, nil
+}
+
+func (m *mongoStore) UpdateRefundStatus(ctx context.Context, id, from string, event models.RefundEvent) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "id", Value: id},
+		primitive.E{Key: "status", Value: from},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "status", Value: event.Status},
+			primitive.E{Key: "updated_at", Value: event.At},
+		}},
+		primitive.E{Key: "$push", Value: bson.D{primitive.E{Key: "trail", Value: event}}},
+	}
+	// a refund that did not go through frees its transaction to be refunded again
+	if event.Status == models.RefundRejected || event.Status == models.RefundFailed {
+		update = append(update, primitive.E{Key: "$unset", Value: bson.D{primitive.E{Key: "active", Value: ""}}})
+	}
+
+	res, err := m.col(refundColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	scheduleColl = "scheduled-orders"
+	runColl      = "scheduled-runs"
+)
+
+func (m *mongoStore) SaveScheduledOrder(ctx context.Context, order models.ScheduledOrder) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	_, err := m.col(scheduleColl).InsertOne(ctx, order)
+	return err
+}
+
+// ClaimScheduledOrder moves an active order due at previousRun on to order.NextRun and order.Status, so only
+// one scheduler runs it. It returns mongo.ErrNoDocuments when the order was claimed, paused or cancelled first.
+func (m *mongoStore) ClaimScheduledOrder(ctx context.Context, order models.ScheduledOrder, previousRun time.Time) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "id", Value: order.ID},
+		primitive.E{Key: "status", Value: models.ScheduleActive},
+		primitive.E{Key: "next_run", Value: previousRun},
+	}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{
+		primitive.E{Key: "next_run", Value: order.NextRun},
+		primitive.E{Key: "status", Value: order.Status},
+		primitive.E{Key: "updated_at", Value: order.UpdatedAt},
+	}}}
+
+	res, err := m.col(scheduleColl).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// UpdateScheduledOrderStatus moves an order from status from to order.Status, a resumed order also gets its
+// new next run. It returns mongo.ErrNoDocuments when the order is no longer in status from.
+func (m *mongoStore) UpdateScheduledOrderStatus(ctx context.Context, order models.ScheduledOrder, from string) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		primitive.E{Key: "id", Value: order.ID},
+		primitive.E{Key: "status", Value: from},
+	}
+	set := bson.D{
+		primitive.E{Key: "status", Value: order.Status},
+		primitive.E{Key: "updated_at", Value: order.UpdatedAt},
+	}
+	if from == models.SchedulePaused {
+		set = append(set, primitive.E{Key: "next_run", Value: order.NextRun})
+	}
+
+	res, err := m.col(scheduleColl).UpdateOne(ctx, filter, bson.D{primitive.E{Key: "$set", Value: set}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RecordScheduledRun sets the outcome of a run on its order, leaving the status and next run as they are.
+func (m *mongoStore) RecordScheduledRun(ctx context.Context, run models.ScheduledRun) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "id", Value: run.OrderID}}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "last_run", Value: run.RanAt},
+			primitive.E{Key: "last_status", Value: run.Status},
+			primitive.E{Key: "updated_at", Value: run.RanAt},
+		}},
+		primitive.E{Key: "$inc", Value: bson.D{primitive.E{Key: "runs", Value: 1}}},
+	}
+
+	_, err := m.col(scheduleColl).UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (m *mongoStore) GetScheduledOrder(ctx context.Context, id string) (models.ScheduledOrder, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
+	filter := bson.D{primitive.E{Key: "id", Value: id}}
+
+	order := models.ScheduledOrder{}
+	if err := m.col(scheduleColl).FindOne(ctx, filter).Decode(&order); err != nil {
+		return models.ScheduledOrder{}, err
+	}
+
+	return order, nil
+}
+
+func (m *mongoStore) GetScheduledOrders(ctx context.Context, username string) ([]models.ScheduledOrder, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.ScheduledOrder{}
+
+	filter := bson.D{primitive.E{Key: "username", Value: username}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cur, err := m.col(scheduleColl).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetDueScheduledOrders returns the active orders whose next run is at or before now.
+func (m *mongoStore) GetDueScheduledOrders(ctx context.Context, now time.Time) ([]models.ScheduledOrder, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.ScheduledOrder{}
+
+	filter := bson.D{
+		primitive.E{Key: "status", Value: models.ScheduleActive},
+		primitive.E{Key: "next_run", Value: bson.D{primitive.E{Key: "$lte", Value: now}}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "next_run", Value: 1}})
+
+	cur, err := m.col(scheduleColl).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mongoStore) SaveScheduledRun(ctx context.Context, run models.ScheduledRun) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	_, err := m.col(runColl).InsertOne(ctx, run)
+	return err
+}
+
+func (m *mongoStore) GetScheduledRuns(ctx context.Context, orderID string) ([]models.ScheduledRun, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
+	res := []models.ScheduledRun{}
+
+	filter := bson.D{primitive.E{Key: "order_id", Value: orderID}}
+	opts := options.Find().SetSort(bson.D{{Key: "ran_at", Value: -1}})
+
+	cur, err := m.col(runColl).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
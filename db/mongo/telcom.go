@@ -5,6 +5,7 @@ import (
 	"log"
 	"sort"
 
+	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models/telcom"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,9 +14,11 @@ import (
 )
 
 // SaveTransaction saves a data transaction to the database.
-func (m *mongoStore) SaveDataTransaction(details interface{}) error {
+func (m *mongoStore) SaveDataTransaction(ctx context.Context, details interface{}) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
 
-	err := m.saveToDB(dataColl, details)
+	err := m.saveToDB(ctx, dataColl, details)
 	if err != nil {
 		return err
 	}
@@ -24,10 +27,13 @@ func (m *mongoStore) SaveDataTransaction(details interface{}) error {
 }
 
 // getRecordDetails returns a data transaction detail.
-func (m *mongoStore) GetDataTransactionDetails(id string) (telcom.DataResult, error) {
+func (m *mongoStore) GetDataTransactionDetails(ctx context.Context, userID, id string) (telcom.DataResult, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := telcom.DataResult{}
 
-	findResult := m.getRecord(id, dataColl)
+	findResult := m.getRecord(ctx, id, dataColl, ownedBy(userID)...)
 	err := findResult.Decode(&res)
 
 	if err != nil {
@@ -43,33 +49,39 @@ func (m *mongoStore) GetDataTransactionDetails(id string) (telcom.DataResult, er
 
 }
 
-// getAllRecords returns all the data transactions associated to a user, if an empty string is passed it returns all data transactions.
-func (m *mongoStore) GetAllDataTransactions(username string) ([]telcom.DataResult, error) {
-	ctx := context.Background()
+// GetAllDataTransactions returns a page of the data transactions, of every user when opts has no username.
+func (m *mongoStore) GetAllDataTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.DataResult, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []telcom.DataResult{}
 
-	cur, err := m.getAllRecords(dataColl, username)
+	cur, err := m.listRecords(ctx, dataColl, opts)
 	if err != nil {
-		return []telcom.DataResult{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := telcom.DataResult{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
-
-	return res, nil
 
+	return res, next, nil
 }
 
-func (m *mongoStore) GetSpecTransDetails(id string) (telcom.SpectranetResult, error) {
+func (m *mongoStore) GetSpecTransDetails(ctx context.Context, userID, id string) (telcom.SpectranetResult, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := telcom.SpectranetResult{}
 
-	findResult := m.getRecord(id, dataColl)
+	findResult := m.getRecord(ctx, id, dataColl, ownedBy(userID)...)
 	err := findResult.Decode(&res)
 
 	if err != nil {
@@ -84,31 +96,38 @@ func (m *mongoStore) GetSpecTransDetails(id string) (telcom.SpectranetResult, er
 	return res, nil
 }
 
-func (m *mongoStore) GetAllSpecDataTransactions(username string) ([]telcom.SpectranetResult, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetAllSpecDataTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.SpectranetResult, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []telcom.SpectranetResult{}
 
-	cur, err := m.getAllRecords(dataColl, username)
+	cur, err := m.listRecords(ctx, dataColl, opts)
 	if err != nil {
-		return []telcom.SpectranetResult{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := telcom.SpectranetResult{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	return res, nil
+	return res, next, nil
 }
 
-func (m *mongoStore) GetSmileTransDetails(id string) (telcom.SmileResult, error) {
+func (m *mongoStore) GetSmileTransDetails(ctx context.Context, userID, id string) (telcom.SmileResult, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := telcom.SmileResult{}
 
-	findResult := m.getRecord(id, dataColl)
+	findResult := m.getRecord(ctx, id, dataColl, ownedBy(userID)...)
 	err := findResult.Decode(&res)
 
 	if err != nil {
@@ -123,39 +142,49 @@ func (m *mongoStore) GetSmileTransDetails(id string) (telcom.SmileResult, error)
 	return res, nil
 }
 
-func (m *mongoStore) GetAllSmileDataTransactions(username string) ([]telcom.SmileResult, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetAllSmileDataTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.SmileResult, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []telcom.SmileResult{}
 
-	cur, err := m.getAllRecords(dataColl, username)
+	cur, err := m.listRecords(ctx, dataColl, opts)
 	if err != nil {
-		return []telcom.SmileResult{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := telcom.SmileResult{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	return res, nil
+	return res, next, nil
 }
 
-func (m *mongoStore) SaveAirtimeTransaction(details *telcom.AirtimeResponse) error {
-	err := m.saveToDB(airColl, details)
+func (m *mongoStore) SaveAirtimeTransaction(ctx context.Context, details *telcom.AirtimeResponse) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, airColl, details)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *mongoStore) GetAirtimeTransactionDetails(id string) (telcom.AirtimeResponse, error) {
+func (m *mongoStore) GetAirtimeTransactionDetails(ctx context.Context, userID, id string) (telcom.AirtimeResponse, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := telcom.AirtimeResponse{}
 
-	result := m.getRecord(id, eduColl)
+	result := m.getRecord(ctx, id, airColl, ownedBy(userID)...)
 
 	err := result.Decode(&res)
 
@@ -170,31 +199,36 @@ func (m *mongoStore) GetAirtimeTransactionDetails(id string) (telcom.AirtimeResp
 	return res, nil
 }
 
-func (m *mongoStore) GetAllAirtimeTransactions(username string) ([]telcom.AirtimeResponse, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetAllAirtimeTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.AirtimeResponse, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []telcom.AirtimeResponse{}
 
-	cur, err := m.getAllRecords(dataColl, username)
+	cur, err := m.listRecords(ctx, airColl, opts)
 	if err != nil {
-		return []telcom.AirtimeResponse{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := telcom.AirtimeResponse{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	return res, nil
+	return res, next, nil
 }
 
-func (m *mongoStore) SaveTelcomRecipient(userID string, data telcom.Recipient) error {
+func (m *mongoStore) SaveTelcomRecipient(ctx context.Context, userID string, data telcom.Recipient) error {
 
-	ctx := context.Background()
-	coll := m.col("telcom-recipient")
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+	coll := m.col(recipientColl)
 
 	filter := bson.D{primitive.E{Key: "userID", Value: userID}}
 	projection := bson.M{"recipients.id": 1}
@@ -236,11 +270,12 @@ func (m *mongoStore) SaveTelcomRecipient(userID string, data telcom.Recipient) e
 	return nil
 }
 
-func (m *mongoStore) GetTelcomRecipients(userID string) (telcom.TelcomRecipient, error) {
+func (m *mongoStore) GetTelcomRecipients(ctx context.Context, userID string) (telcom.TelcomRecipient, error) {
 
-	ctx := context.Background()
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
 	recipients := telcom.TelcomRecipient{}
-	coll := m.col("telcom-recipient")
+	coll := m.col(recipientColl)
 
 	filter := bson.D{primitive.E{Key: "userID", Value: userID}}
 	res := coll.FindOne(ctx, filter)
@@ -256,10 +291,11 @@ func (m *mongoStore) GetTelcomRecipients(userID string) (telcom.TelcomRecipient,
 
 }
 
-func (m *mongoStore) EditTelcomRecipient(userID string, data telcom.Recipient) error {
+func (m *mongoStore) EditTelcomRecipient(ctx context.Context, userID string, data telcom.Recipient) error {
 
-	ctx := context.Background()
-	coll := m.col("telcom-recipient")
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+	coll := m.col(recipientColl)
 	telcomRecipient := telcom.TelcomRecipient{}
 
 	filter := bson.D{primitive.E{Key: "userID", Value: userID}}
@@ -307,10 +343,11 @@ func (m *mongoStore) EditTelcomRecipient(userID string, data telcom.Recipient) e
 	return nil
 }
 
-func (m *mongoStore) DeleteTelcomRecipient(recipientID int, userID string) error {
+func (m *mongoStore) DeleteTelcomRecipient(ctx context.Context, recipientID int, userID string) error {
 
-	ctx := context.Background()
-	coll := m.col("telcom-recipient")
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+	coll := m.col(recipientColl)
 
 	filter := bson.M{
 		"userID": userID,
@@ -347,9 +384,7 @@ func (m *mongoStore) DeleteTelcomRecipient(recipientID int, userID string) error
 
 }
 
-func (m *mongoStore) getRecipientRecords() (*mongo.Cursor, error) {
-	ctx := context.Background()
-
+func (m *mongoStore) getRecipientRecords(ctx context.Context) (*mongo.Cursor, error) {
 	filter := bson.D{}
 	cur, err := m.col("").Find(ctx, filter)
 	if err != nil {
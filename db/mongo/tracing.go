@@ -0,0 +1,71 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aremxyplug-be/lib/tracing"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// commandTracer starts a client span for every command sent to mongo, as a child of the span in the
+// context the command was run with. Command documents are not recorded as they hold user data.
+type commandTracer struct {
+	mu    sync.Mutex
+	spans map[int64]trace.Span
+}
+
+func newCommandMonitor() *event.CommandMonitor {
+	t := &commandTracer{spans: map[int64]trace.Span{}}
+	return &event.CommandMonitor{
+		Started:   t.started,
+		Succeeded: t.succeeded,
+		Failed:    t.failed,
+	}
+}
+
+func (t *commandTracer) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBNamespace(evt.DatabaseName),
+		semconv.DBOperationName(evt.CommandName),
+	}
+	collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK()
+	name := evt.CommandName
+	if ok {
+		attrs = append(attrs, semconv.DBCollectionName(collection))
+		name = collection + "." + evt.CommandName
+	}
+
+	_, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans[evt.RequestID] = span
+}
+
+func (t *commandTracer) succeeded(_ context.Context, evt *event.CommandSucceededEvent) {
+	if span, ok := t.finish(evt.RequestID); ok {
+		span.End()
+	}
+}
+
+func (t *commandTracer) failed(_ context.Context, evt *event.CommandFailedEvent) {
+	if span, ok := t.finish(evt.RequestID); ok {
+		span.SetStatus(codes.Error, evt.Failure)
+		span.End()
+	}
+}
+
+func (t *commandTracer) finish(requestID int64) (trace.Span, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span, ok := t.spans[requestID]
+	delete(t.spans, requestID)
+	return span, ok
+}
@@ -0,0 +1,49 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction runs fn in a mongo transaction, the store methods fn calls with the ctx it is given join
+// it. A call made inside another transaction joins the outer one. Standalone servers do not support
+// transactions, there fn runs without one.
+func (m *mongoStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !m.transactions || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := m.mongoClient.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// supportsTransactions reports whether the deployment described by the hello response runs transactions,
+// only replica sets and sharded clusters do.
+func supportsTransactions(hello bson.M) bool {
+	if _, ok := hello["setName"]; ok {
+		return true
+	}
+	return hello["msg"] == "isdbgrid"
+}
+
+func (m *mongoStore) detectTransactions(ctx context.Context) error {
+	var hello bson.M
+	cmd := bson.D{primitive.E{Key: "hello", Value: 1}}
+	if err := m.mongoClient.Database("admin").RunCommand(ctx, cmd).Decode(&hello); err != nil {
+		return err
+	}
+
+	m.transactions = supportsTransactions(hello)
+	return nil
+}
@@ -0,0 +1,26 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSupportsTransactions(t *testing.T) {
+	var tests = []struct {
+		name  string
+		hello bson.M
+		want  bool
+	}{
+		{name: "Test replica set", hello: bson.M{"isWritablePrimary": true, "setName": "rs0"}, want: true},
+		{name: "Test sharded cluster", hello: bson.M{"isWritablePrimary": true, "msg": "isdbgrid"}, want: true},
+		{name: "Test standalone", hello: bson.M{"isWritablePrimary": true}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, supportsTransactions(tt.hello))
+		})
+	}
+}
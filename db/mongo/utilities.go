@@ -3,12 +3,16 @@ package mongo
 import (
 	"context"
 
+	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func (m *mongoStore) SaveTVSubcriptionTransaction(details *models.BillResult) error {
-	err := m.saveToDB(tvColl, details)
+func (m *mongoStore) SaveTVSubcriptionTransaction(ctx context.Context, details *models.BillResult) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, tvColl, details)
 	if err != nil {
 		return err
 	}
@@ -16,10 +20,13 @@ func (m *mongoStore) SaveTVSubcriptionTransaction(details *models.BillResult) er
 	return nil
 }
 
-func (m *mongoStore) GetTvSubscriptionDetails(id string) (models.BillResult, error) {
+func (m *mongoStore) GetTvSubscriptionDetails(ctx context.Context, userID, id string) (models.BillResult, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := models.BillResult{}
 
-	result := m.getRecord(id, tvColl)
+	result := m.getRecord(ctx, id, tvColl, ownedBy(userID)...)
 
 	err := result.Decode(&res)
 
@@ -34,29 +41,36 @@ func (m *mongoStore) GetTvSubscriptionDetails(id string) (models.BillResult, err
 	return res, nil
 }
 
-func (m *mongoStore) GetAllTvSubTransactions(user string) ([]models.BillResult, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetAllTvSubTransactions(ctx context.Context, opts db.ListOptions) ([]models.BillResult, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []models.BillResult{}
 
-	cur, err := m.getAllRecords(dataColl, user)
+	cur, err := m.listRecords(ctx, tvColl, opts)
 	if err != nil {
-		return []models.BillResult{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := models.BillResult{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	return res, nil
+	return res, next, nil
 }
 
-func (m *mongoStore) SaveElectricTransaction(details *models.ElectricResult) error {
-	err := m.saveToDB(tvColl, details)
+func (m *mongoStore) SaveElectricTransaction(ctx context.Context, details *models.ElectricResult) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, tvColl, details)
 	if err != nil {
 		return err
 	}
@@ -64,10 +78,13 @@ func (m *mongoStore) SaveElectricTransaction(details *models.ElectricResult) err
 	return nil
 }
 
-func (m *mongoStore) GetElectricSubDetails(id string) (models.ElectricResult, error) {
+func (m *mongoStore) GetElectricSubDetails(ctx context.Context, userID, id string) (models.ElectricResult, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := models.ElectricResult{}
 
-	result := m.getRecord(id, tvColl)
+	result := m.getRecord(ctx, id, tvColl, ownedBy(userID)...)
 
 	err := result.Decode(&res)
 
@@ -82,30 +99,37 @@ func (m *mongoStore) GetElectricSubDetails(id string) (models.ElectricResult, er
 	return res, nil
 }
 
-func (m *mongoStore) GetAllElectricSubTransactions(user string) ([]models.ElectricResult, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetAllElectricSubTransactions(ctx context.Context, opts db.ListOptions) ([]models.ElectricResult, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []models.ElectricResult{}
 
-	cur, err := m.getAllRecords(dataColl, user)
+	cur, err := m.listRecords(ctx, tvColl, opts)
 	if err != nil {
-		return []models.ElectricResult{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := models.ElectricResult{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	return res, nil
+	return res, next, nil
 }
 
 // SaveEduTransactions saves the result of the edu transaction to the database.
-func (m *mongoStore) SaveEduTransaction(details *models.EduResponse) error {
-	err := m.saveToDB(eduColl, details)
+func (m *mongoStore) SaveEduTransaction(ctx context.Context, details *models.EduResponse) error {
+	ctx, cancel := m.writeContext(ctx)
+	defer cancel()
+
+	err := m.saveToDB(ctx, eduColl, details)
 	if err != nil {
 		return err
 	}
@@ -113,10 +137,13 @@ func (m *mongoStore) SaveEduTransaction(details *models.EduResponse) error {
 	return nil
 }
 
-func (m *mongoStore) GetEduTransactionDetails(id string) (models.EduResponse, error) {
+func (m *mongoStore) GetEduTransactionDetails(ctx context.Context, userID, id string) (models.EduResponse, error) {
+	ctx, cancel := m.readContext(ctx)
+	defer cancel()
+
 	res := models.EduResponse{}
 
-	result := m.getRecord(id, eduColl)
+	result := m.getRecord(ctx, id, eduColl, ownedBy(userID)...)
 
 	err := result.Decode(&res)
 
@@ -132,23 +159,27 @@ func (m *mongoStore) GetEduTransactionDetails(id string) (models.EduResponse, er
 
 }
 
-func (m *mongoStore) GetAllEduTransactions(user string) ([]models.EduResponse, error) {
-	ctx := context.Background()
+func (m *mongoStore) GetAllEduTransactions(ctx context.Context, opts db.ListOptions) ([]models.EduResponse, string, error) {
+	ctx, cancel := m.scanContext(ctx)
+	defer cancel()
 	res := []models.EduResponse{}
 
-	cur, err := m.getAllRecords(dataColl, user)
+	cur, err := m.listRecords(ctx, eduColl, opts)
 	if err != nil {
-		return []models.EduResponse{}, err
+		return nil, "", err
 	}
 
-	for cur.Next(ctx) {
+	next, err := readPage(ctx, cur, opts, func(cur *mongo.Cursor) error {
 		resp := models.EduResponse{}
 		if err := cur.Decode(&resp); err != nil {
-			return nil, err
+			return err
 		}
 		res = append(res, resp)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	return res, nil
+	return res, next, nil
 }
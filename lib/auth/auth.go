@@ -5,21 +5,24 @@ import (
 	"net/http"
 
 	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/lib/errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
 	"github.com/aremxyplug-be/lib/key_generator"
 	tokengenerator "github.com/aremxyplug-be/lib/tokekngenerator"
+	"github.com/aremxyplug-be/lib/tracing"
 )
 
 type AuthConn struct {
 	jwt tokengenerator.TokenGenerator
 }
 
-func NewAuthConn(secret *config.Secrets) *AuthConn {
-	publicKey, err := key_generator.GeneratePublicKey(secret.JWTPublicKey)
+func NewAuthConn(jwt config.JWT) *AuthConn {
+	publicKey, err := key_generator.GeneratePublicKey(jwt.PublicKey)
 	if err != nil {
 		log.Println(err)
 	}
 
-	privateKey, err := key_generator.GeneratePrivateKey(secret.JWTPublicKey)
+	privateKey, err := key_generator.GeneratePrivateKey(jwt.PrivateKey)
 	if err != nil {
 		// do something with the error
 		log.Println(err)
@@ -40,8 +43,11 @@ func (a *AuthConn) Authorize(next http.Handler) http.Handler {
 		//validate the token
 		_, err := a.jwt.ValidateToken(token)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized: invalid or missing token: " + err.Error()))
+			terr := errorvalues.New(errorvalues.InvalidTokenErr, "invalid or missing token: "+err.Error(),
+				errors.WithTraceID(tracing.CorrelationID(r.Context())), errors.WithInstance(r.URL.Path))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(terr.Status())
+			w.Write([]byte(terr.Error()))
 			return
 		}
 		next.ServeHTTP(w, r)
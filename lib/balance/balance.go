@@ -1,6 +1,14 @@
 package balance
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient balance to carry out the transaction")
+
+// TransferFee is charged on every transfer to a bank account on top of the amount sent.
+const TransferFee = 50.00
 
 func isEnough(balance, payment_value float64) bool {
 	return payment_value <= balance
@@ -24,9 +32,7 @@ func NewBalanceDeposit(balance, deposit float64) (newBalance, depositAmount floa
 
 func NewBalanceTransfer(balance, transferAmmount float64) (newBalance float64) {
 
-	deduction := 50.00
-
-	return balance - transferAmmount - deduction
+	return balance - transferAmmount - TransferFee
 }
 
 func NewBalancePayment(balance, payment float64) (newBalance float64) {
@@ -37,7 +43,7 @@ func NewBalancePayment(balance, payment float64) (newBalance float64) {
 func CanPay(balance, amount float64) (bool, error) {
 
 	if !isEnough(balance, amount) {
-		return false, errors.New("insufficient balance to carry out the transaction")
+		return false, ErrInsufficientBalance
 	}
 
 	return true, nil
@@ -45,10 +51,10 @@ func CanPay(balance, amount float64) (bool, error) {
 
 func CanTransfer(balance, amountToTransfer float64) (bool, error) {
 
-	totalAmountCharged := amountToTransfer + 50.00
+	totalAmountCharged := amountToTransfer + TransferFee
 
 	if balance < totalAmountCharged {
-		return false, errors.New("insufficient balance to carry out the transfer")
+		return false, fmt.Errorf("%w, the transfer fee is %.2f", ErrInsufficientBalance, TransferFee)
 	}
 
 	return true, nil
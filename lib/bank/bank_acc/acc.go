@@ -2,59 +2,49 @@ package bankacc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/aremxyplug-be/config"
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
-	"github.com/joho/godotenv"
+	"github.com/aremxyplug-be/lib/httpclient"
 	"go.uber.org/zap"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-/*
-var (
-	api         = os.Getenv("ANCHOR_SANDBOX")
-	apikey      = os.Getenv("ANCHORAPI_KEY")
-	deposit_id  = os.Getenv("DEPOSIT_ID")
-	customer_id = os.Getenv("CUSTOMER_ID")
-)
-*/
-
-var (
-	api    = os.Getenv("ANCHOR_API")
-	apikey = os.Getenv("ANCHORAPI_PROD")
-	//deposit_id  = os.Getenv("DEPOSIT_ID_LIVE")
-	customer_id = os.Getenv("CUSTOMER_ID_LIVE")
-	deposit_id  = os.Getenv("DEPOSIT_ID_LIVE_2")
-)
-
 type BankConfig struct {
-	dbConn db.DataStore
-	logger *zap.Logger
+	dbConn              db.DataStore
+	logger              *zap.Logger
+	client              *httpclient.Client
+	customerID          string
+	settlementAccountID string
 }
 
 // initialize BankConfig.
 
-func NewBankConfig(store db.DataStore, logger *zap.Logger) *BankConfig {
+func NewBankConfig(store db.DataStore, logger *zap.Logger, client *httpclient.Client, anchor config.Anchor) *BankConfig {
 	return &BankConfig{
-		dbConn: store,
-		logger: logger,
+		dbConn:              store,
+		logger:              logger,
+		client:              client,
+		customerID:          anchor.CustomerID,
+		settlementAccountID: anchor.SettlementAccountID,
 	}
 }
 
-func (b *BankConfig) VirtualAccount(user models.User) (models.AccountDetails, error) {
+func (b *BankConfig) VirtualAccount(ctx context.Context, user models.User) (models.AccountDetails, error) {
 
 	// create a new virtual accout for new users as soon as their account is confirmed
 	// should be called at the moment that a user's account is verified
 
-	url := fmt.Sprintf("%s/%s", api, "virtual-nubans")
+	url := "/virtual-nubans"
 	to := cases.Title(language.English)
 	full_name := to.String(user.FullName)
 
@@ -68,24 +58,22 @@ func (b *BankConfig) VirtualAccount(user models.User) (models.AccountDetails, er
 	payload.Data.Attributes.VirtualAccount.Email = user.Email
 	payload.Data.Attributes.VirtualAccount.Permanent = true
 	payload.Data.Relationships.SettlementAccount.Data.Type = "DepositAccount"
-	payload.Data.Relationships.SettlementAccount.Data.ID = deposit_id
+	payload.Data.Relationships.SettlementAccount.Data.ID = b.settlementAccountID
 
 	requestBody, err := json.Marshal(payload)
 	if err != nil {
 		return models.AccountDetails{}, JSONError(err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return models.AccountDetails{}, ErrCreatingHTTPRequest
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("accept", "application/json")
-	req.Header.Set("x-anchor-key", apikey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := b.client.Do(req)
 	if err != nil {
 		return models.AccountDetails{}, ErrAPIConnectionFailed
 	}
@@ -97,7 +85,6 @@ func (b *BankConfig) VirtualAccount(user models.User) (models.AccountDetails, er
 	if err != nil {
 		return models.AccountDetails{}, JSONError(err)
 	}
-	b.logger.Log(b.logger.Level(), string(body))
 	/*
 		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
 			return models.AccountDetails{}, JSONError(err)
@@ -121,7 +108,7 @@ func (b *BankConfig) VirtualAccount(user models.User) (models.AccountDetails, er
 		VirtualAccountID: apiResponse.Data.ID,
 	}
 
-	if err := b.saveAccount(result); err != nil {
+	if err := b.saveAccount(ctx, result); err != nil {
 		return models.AccountDetails{}, DBConnectionError(err)
 	}
 
@@ -129,9 +116,10 @@ func (b *BankConfig) VirtualAccount(user models.User) (models.AccountDetails, er
 
 }
 
-func (b *BankConfig) CreateDepositAccount() error {
+// CreateDepositAccount creates a settlement deposit account for the business customer at anchor and saves it.
+func (b *BankConfig) CreateDepositAccount(ctx context.Context) (models.DepositAccount, error) {
 
-	url := fmt.Sprintf("%s/%s", api, "accounts")
+	url := "/accounts"
 	payload := createDeposit{
 		Data: createDepositData{
 			Attributes: depositAttributes{
@@ -140,7 +128,7 @@ func (b *BankConfig) CreateDepositAccount() error {
 			Relationships: depositRelationships{
 				Customer: depositCustomer{
 					Data: depositCustomerData{
-						ID:   customer_id,
+						ID:   b.customerID,
 						Type: "BusinessCustomer",
 					},
 				},
@@ -153,42 +141,31 @@ func (b *BankConfig) CreateDepositAccount() error {
 	if err != nil {
 		b.logger.Error(err.Error())
 		fmt.Println("Error marshalling json payload:", err)
-		return JSONError(err)
+		return models.DepositAccount{}, JSONError(err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		b.logger.Error(err.Error())
 		fmt.Println("Error creating a http request:", err)
-		return ErrCreatingHTTPRequest
+		return models.DepositAccount{}, ErrCreatingHTTPRequest
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("accept", "application/json")
-	req.Header.Set("x-anchor-key", apikey)
 
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
-	resp, err := client.Do(req)
+	resp, err := b.client.Do(req)
 	if err != nil {
 		b.logger.Error(err.Error())
 		fmt.Println("Error calling external api:", err)
-		return ErrAPIConnectionFailed
+		return models.DepositAccount{}, ErrAPIConnectionFailed
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		// return unsuccessful response
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			b.logger.Error(err.Error())
-			fmt.Println("Error writing to .env file:", err)
-			return JSONError(err)
-		}
-		b.logger.Log(b.logger.Level(), string(body))
-		fmt.Println("Error creating deposit account:", resp.Status)
-		return ErrCreatingDepositAccount
+		// return unsuccessful response, the body is logged redacted by the provider client.
+		b.logger.Error("Error creating deposit account", zap.String("status", resp.Status))
+		return models.DepositAccount{}, ErrCreatingDepositAccount
 	}
 
 	apiResponse := depositCustomerResponse{}
@@ -196,39 +173,30 @@ func (b *BankConfig) CreateDepositAccount() error {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		b.logger.Error(err.Error())
-		fmt.Println("Error writing to .env file:", err)
-		return JSONError(err)
+		return models.DepositAccount{}, JSONError(err)
 	}
-	b.logger.Log(b.logger.Level(), string(body))
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
 		b.logger.Error(err.Error())
-		return JSONError(err)
+		return models.DepositAccount{}, JSONError(err)
 	}
 
-	if err := os.Setenv("DEPOSIT_ID_LIVE", apiResponse.Data.ID); err != nil {
-		b.logger.Error(err.Error())
-		fmt.Println("Error writing to .env file:", err)
-		return ErrSettingENV
+	// the account is saved for DEPOSIT_ID_LIVE to be set from, the running config is not changed
+	account := models.DepositAccount{
+		ID:         apiResponse.Data.ID,
+		CustomerID: b.customerID,
+		CreatedAt:  time.Now().UTC(),
 	}
-	envVars := make(map[string]string)
-	for _, envVar := range os.Environ() {
-		pair := strings.SplitN(envVar, "=", 2)
-		envVars[pair[0]] = pair[1]
+	if err := b.dbConn.SaveDepositAccount(ctx, account); err != nil {
+		b.logger.Error("failed to save deposit account", zap.String("id", account.ID), zap.Error(err))
+		return models.DepositAccount{}, DBConnectionError(err)
 	}
 
-	err = godotenv.Write(envVars, ".env")
-	if err != nil {
-		b.logger.Error(err.Error())
-		fmt.Println("Error writing to .env file:", err)
-		return ErrWritingToENV
-	}
-
-	return nil
+	return account, nil
 }
 
-func (b *BankConfig) saveAccount(account models.AccountDetails) error {
-	err := b.dbConn.SaveVirtualAccount(account)
+func (b *BankConfig) saveAccount(ctx context.Context, account models.AccountDetails) error {
+	err := b.dbConn.SaveVirtualAccount(ctx, account)
 	if err != nil {
 		return err
 	}
@@ -8,8 +8,6 @@ var (
 	ErrAPIConnectionFailed        = errors.New("error connecting to API server")
 	ErrCreatingHTTPRequest        = errors.New("error creating HTTP request")
 	ErrCreatingDepositAccount     = errors.New("error creating deposit account")
-	ErrWritingToENV               = errors.New("error writing to .env file")
-	ErrSettingENV                 = errors.New("error setting environment variable")
 )
 
 func JSONError(err error) error {
@@ -1,36 +1,33 @@
 package deposit
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
+	"time"
 
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
 	"github.com/aremxyplug-be/db/mongo"
 	"github.com/aremxyplug-be/lib/balance"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/notification"
 	"github.com/aremxyplug-be/lib/randomgen"
 	"go.uber.org/zap"
 )
 
-/*
-var (
-	api    = os.Getenv("ANCHOR_SANDBOX")
-	apikey = os.Getenv("ANCHORAPI_KEY")
-)
-*/
-
-var (
-	api    = os.Getenv("ANCHOR_API")
-	apikey = os.Getenv("ANCHORAPI_PROD")
-)
-
 type Config struct {
-	db     db.DataStore
-	logger *zap.Logger
+	db          db.DataStore
+	logger      *zap.Logger
+	client      *httpclient.Client
+	notifier    *notification.Notifier
+	idGenerator idgenerator.IdGenerator
 }
 
 type depositID struct {
@@ -38,32 +35,34 @@ type depositID struct {
 	ID           string `json:"id" bson:"ID"`
 }
 
-func NewDepositConfig(db db.DataStore, logger *zap.Logger) *Config {
+func NewDepositConfig(db db.DataStore, logger *zap.Logger, client *httpclient.Client, notifier *notification.Notifier) *Config {
 	return &Config{
-		db:     db,
-		logger: logger,
+		db:          db,
+		logger:      logger,
+		client:      client,
+		notifier:    notifier,
+		idGenerator: idgenerator.New(),
 	}
 }
 
-func (c *Config) Deposit(virtualNuban string) error {
+// Deposit credits the payments received on the virtual account to its owner.
+func (c *Config) Deposit(ctx context.Context, account models.AccountDetails) error {
 	// using the list payment endpoint.
-	url := fmt.Sprintf("%s/%s?%s=%s", api, "payments", "virtualNubanId", virtualNuban)
+	url := fmt.Sprintf("/payments?virtualNubanId=%s", account.VirtualAccountID)
 
-	if virtualNuban == "" {
+	if account.VirtualAccountID == "" {
 		c.logger.Error("missing virtualNuban")
 		return ErrEmptyVirtualNuban
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		// log the error
 		return ErrNewRequestFailed
 	}
 	req.Header.Add("accept", "application/json")
-	req.Header.Add("x-anchor-key", apikey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		// log the error
 		c.logger.Error(err.Error())
@@ -76,7 +75,6 @@ func (c *Config) Deposit(virtualNuban string) error {
 	if err != nil {
 		return JSONError(err)
 	}
-	c.logger.Log(c.logger.Level(), string(body))
 
 	/*
 		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
@@ -90,7 +88,6 @@ func (c *Config) Deposit(virtualNuban string) error {
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
 		return JSONError(err)
 	}
-	log.Printf("%+v", apiResponse)
 
 	// create a separate collection for saving virtualNubans and their associated deposit ID
 
@@ -119,67 +116,101 @@ func (c *Config) Deposit(virtualNuban string) error {
 		log.Printf("%s", virtualNuban)
 		log.Printf("%s", deposit.ID)
 
-		if err := c.db.SaveDepositID(deposit); err != nil {
-			if err == mongo.ErrDepositIDExist {
-				continue
+		deposit_amount := data.Attributes.Amount
+		log.Println(deposit_amount)
+
+		// the deposit id, the new balance and the deposit record are written together, a deposit is never
+		// marked as seen without the wallet being credited.
+		var depositAmount float64
+		err = c.db.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := c.db.SaveDepositID(ctx, deposit); err != nil {
+				return err
 			}
 
-			c.logger.Error(err.Error())
-			return DBConnectionError(err)
-		}
+			bal, err := c.db.GetBalance(ctx, virtualNuban)
+			if err != nil {
+				return err
+			}
+			log.Println(bal)
+
+			var newBalance float64
+			newBalance, depositAmount = balance.NewBalanceDeposit(bal, deposit_amount)
+			log.Println(newBalance)
+			userBalance := models.Balance{
+				VirtualNuban: virtualNuban,
+				UserID:       account.User_ID,
+				Balance:      newBalance,
+			}
+			if err := c.db.SaveBalance(ctx, virtualNuban, userBalance); err != nil {
+				return err
+			}
 
-		bal, err := c.db.GetBalance(virtualNuban)
-		if err != nil {
-			c.logger.Error(err.Error())
-			return DBConnectionError(err)
-		}
-		log.Println(bal)
+			// the ledger shows the full deposit and the 1% kept as a fee
+			received := bal + deposit_amount/100
+			now := time.Now().UTC()
+			entries := []models.LedgerEntry{
+				{Type: models.EntryDeposit, Amount: received - bal, Balance: received, Description: "Deposit from " + attributes.CounterParty.AccountName},
+				{Type: models.EntryFee, Amount: newBalance - received, Balance: newBalance, Description: "Deposit fee"},
+			}
+			for _, entry := range entries {
+				entry.ID = c.idGenerator.Generate()
+				entry.UserID = account.User_ID
+				entry.Reference = strconv.Itoa(orderID)
+				entry.CreatedAt = now
+				if err := c.db.SaveLedgerEntry(ctx, entry); err != nil {
+					return err
+				}
+			}
 
-		deposit_amount := data.Attributes.Amount
-		log.Println(deposit_amount)
+			log.Printf("%+v", data)
+
+			result := models.DepositResponse{
+				Amount:         fmt.Sprintf("%v", depositAmount),
+				WalletType:     "Nigerian NGN Wallet",
+				Bank_Name:      attributes.CounterParty.Bank.Name,
+				Account_Name:   attributes.CounterParty.AccountName,
+				Account_No:     attributes.CounterParty.AccountNumber,
+				Product:        "Virtual Account",
+				Description:    "NGN Wallet Top Up",
+				Message:        data.Attributes.Narration,
+				Order_ID:       orderID,
+				Transaction_ID: transctionID,
+				Session_ID:     data.Attributes.PaymentReference,
+				UserID:         account.User_ID,
+			}
 
-		newBalance, depositAmount := balance.NewBalanceDeposit(bal, deposit_amount)
-		log.Println(newBalance)
-		userBalance := models.Balance{
-			VirtualNuban: virtualNuban,
-			Balance:      newBalance,
-		}
-		if err = c.db.SaveBalance(virtualNuban, userBalance); err != nil {
-			// log the error and return
-			return DBConnectionError(err)
-		}
+			log.Printf("%+v", result)
 
-		log.Printf("%+v", data)
-
-		result := models.DepositResponse{
-			Amount:         fmt.Sprintf("%v", depositAmount),
-			WalletType:     "Nigerian NGN Wallet",
-			Bank_Name:      attributes.CounterParty.Bank.Name,
-			Account_Name:   attributes.CounterParty.AccountName,
-			Account_No:     attributes.CounterParty.AccountNumber,
-			Product:        "Virtual Account",
-			Description:    "NGN Wallet Top Up",
-			Message:        data.Attributes.Narration,
-			Order_ID:       orderID,
-			Transaction_ID: transctionID,
-			Session_ID:     data.Attributes.PaymentReference,
+			return c.saveTransaction(ctx, result)
+		})
+		if err == mongo.ErrDepositIDExist {
+			continue
 		}
-
-		log.Printf("%+v", result)
-
-		if err := c.saveTransaction(result); err != nil {
+		if err != nil {
 			// log the error and return
+			c.logger.Error(err.Error())
 			return DBConnectionError(err)
 		}
-
+		metrics.DepositCredited(depositAmount)
+
+		_, err = c.notifier.Notify(ctx, notification.Notification{
+			UserID:   account.User_ID,
+			Category: notification.CategoryDeposit,
+			Title:    "Deposit received",
+			Body: fmt.Sprintf("NGN %.2f from %s has been added to your wallet.",
+				depositAmount, attributes.CounterParty.AccountName),
+		})
+		if err != nil {
+			c.logger.Error("failed to notify deposit", zap.String("order_id", strconv.Itoa(orderID)), zap.Error(err))
+		}
 	}
 
 	return nil
 }
 
 // write to save transaction to the database
-func (c *Config) saveTransaction(detail models.DepositResponse) error {
-	err := c.db.SaveDeposit(detail)
+func (c *Config) saveTransaction(ctx context.Context, detail models.DepositResponse) error {
+	err := c.db.SaveDeposit(ctx, detail)
 	if err != nil {
 		return err
 	}
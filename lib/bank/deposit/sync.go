@@ -0,0 +1,51 @@
+package deposit
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultSyncInterval = 5 * time.Minute
+
+// Run credits the payments made into every virtual account on each tick of interval until ctx is done.
+func (c *Config) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Sync(ctx)
+		}
+	}
+}
+
+// Sync pulls the payments of every virtual account, deposits already credited are skipped by Deposit.
+// An account failing does not stop the others from syncing.
+func (c *Config) Sync(ctx context.Context) {
+	accounts, err := c.db.GetVirtualAccounts(ctx)
+	if err != nil {
+		c.logger.Error("failed to get virtual accounts", zap.Error(err))
+		return
+	}
+
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if account.VirtualAccountID == "" {
+			continue
+		}
+		if err := c.Deposit(ctx, account); err != nil {
+			c.logger.Error("deposit sync failed", zap.String("virtual_account", account.VirtualAccountID), zap.Error(err))
+		}
+	}
+}
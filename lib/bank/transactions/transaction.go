@@ -1,6 +1,7 @@
 package transactions
 
 import (
+	"context"
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
 )
@@ -17,8 +18,8 @@ func NewTransaction(store db.DataStore) *Transaction {
 	}
 }
 
-func (t *Transaction) GetTransferDetails(id string) (models.TransferResponse, error) {
-	result, err := t.store.GetTransferDetails(id)
+func (t *Transaction) GetTransferDetails(ctx context.Context, userID, id string) (models.TransferResponse, error) {
+	result, err := t.store.GetTransferDetails(ctx, userID, id)
 	if err != nil {
 		// log error
 		return models.TransferResponse{}, err
@@ -27,39 +28,41 @@ func (t *Transaction) GetTransferDetails(id string) (models.TransferResponse, er
 	return result, nil
 }
 
-func (t *Transaction) GetTransferHistory(user string) ([]models.TransferResponse, error) {
-	result, err := t.store.GetAllTransferHistory(user)
+func (t *Transaction) GetTransferHistory(ctx context.Context, userID string, opts db.ListOptions) ([]models.TransferResponse, string, error) {
+	opts.UserID = userID
+	result, next, err := t.store.GetAllTransferHistory(ctx, opts)
 	if err != nil {
 		// log error
-		return nil, err
+		return nil, "", err
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (t *Transaction) GetAllTransactionHistory() ([]interface{}, error) {
-	result, err := t.store.GetAllBankTransactions("")
+func (t *Transaction) GetAllTransactionHistory(ctx context.Context, opts db.ListOptions) ([]interface{}, string, error) {
+	result, next, err := t.store.GetAllBankTransactions(ctx, opts)
 	if err != nil {
 		// log error
-		return nil, err
+		return nil, "", err
 	}
 
-	return result, nil
+	return result, next, nil
 
 }
 
-func (t *Transaction) GetDepositHistory(user string) ([]models.DepositResponse, error) {
-	result, err := t.store.GetAllDepositHistory(user)
+func (t *Transaction) GetDepositHistory(ctx context.Context, userID string, opts db.ListOptions) ([]models.DepositResponse, string, error) {
+	opts.UserID = userID
+	result, next, err := t.store.GetAllDepositHistory(ctx, opts)
 	if err != nil {
 		// log error
-		return nil, err
+		return nil, "", err
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (t *Transaction) GetDepositDetails(id string) (models.DepositResponse, error) {
-	result, err := t.store.GetDepositDetails(id)
+func (t *Transaction) GetDepositDetails(ctx context.Context, userID, id string) (models.DepositResponse, error) {
+	result, err := t.store.GetDepositDetails(ctx, userID, id)
 	if err != nil {
 		// log error
 		return models.DepositResponse{}, err
@@ -69,8 +72,8 @@ func (t *Transaction) GetDepositDetails(id string) (models.DepositResponse, erro
 }
 
 // should be called at any point where the user get their balance
-func (t *Transaction) GetBalance(virtualNuban string) (float64, error) {
-	bal, err := t.store.GetBalance(virtualNuban)
+func (t *Transaction) GetBalance(ctx context.Context, virtualNuban string) (float64, error) {
+	bal, err := t.store.GetBalance(ctx, virtualNuban)
 	if err != nil {
 		return 0, err
 	}
@@ -78,8 +81,8 @@ func (t *Transaction) GetBalance(virtualNuban string) (float64, error) {
 }
 
 // To be used after making payment
-func (t *Transaction) UpdateBalance(virtualNuban string, amount float64) error {
-	err := t.store.UpdateBalance(virtualNuban, amount)
+func (t *Transaction) UpdateBalance(ctx context.Context, virtualNuban string, amount float64) error {
+	err := t.store.UpdateBalance(ctx, virtualNuban, amount)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,76 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeStore keeps transfers in memory and scopes lookups to their owner the way the mongo store does.
+type fakeStore struct {
+	db.DataStore
+	transfers []models.TransferResponse
+}
+
+func (f *fakeStore) GetTransferDetails(_ context.Context, userID, id string) (models.TransferResponse, error) {
+	for _, transfer := range f.transfers {
+		if transfer.Transaction_ID == id && (userID == "" || transfer.UserID == userID) {
+			return transfer, nil
+		}
+	}
+	return models.TransferResponse{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeStore) GetAllTransferHistory(_ context.Context, opts db.ListOptions) ([]models.TransferResponse, string, error) {
+	var result []models.TransferResponse
+	for _, transfer := range f.transfers {
+		if opts.UserID == "" || transfer.UserID == opts.UserID {
+			result = append(result, transfer)
+		}
+	}
+	return result, "", nil
+}
+
+func TestTransferOwnership(t *testing.T) {
+	store := &fakeStore{transfers: []models.TransferResponse{
+		{UserID: "user-1", Transaction_ID: "order-1"},
+		{UserID: "user-2", Transaction_ID: "order-2"},
+		{UserID: "user-1", Transaction_ID: "order-3"},
+	}}
+	transaction := NewTransaction(store)
+
+	var tests = []struct {
+		name    string
+		userID  string
+		id      string
+		want    string
+		wantErr error
+	}{
+		{name: "Test own transfer", userID: "user-1", id: "order-1", want: "order-1"},
+		{name: "Test transfer of another user", userID: "user-1", id: "order-2", wantErr: mongo.ErrNoDocuments},
+		{name: "Test missing transfer", userID: "user-2", id: "order-4", wantErr: mongo.ErrNoDocuments},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := transaction.GetTransferDetails(context.Background(), tt.userID, tt.id)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, result.Transaction_ID)
+		})
+	}
+
+	t.Run("Test history of one user", func(t *testing.T) {
+		result, _, err := transaction.GetTransferHistory(context.Background(), "user-1", db.ListOptions{UserID: "user-2"})
+		assert.NoError(t, err)
+
+		ids := []string{}
+		for _, transfer := range result {
+			ids = append(ids, transfer.Transaction_ID)
+		}
+		assert.Equal(t, []string{"order-1", "order-3"}, ids)
+	})
+}
@@ -11,6 +11,11 @@ var (
 	ErrCreatingHTTPRequest        = errors.New("error creating HTTP request")
 	ErrGeneratingOrderID          = errors.New("error generating order_id")
 	ErrReadingRequestBody         = errors.New("error reading request body")
+	ErrKYCLimit                   = errors.New("transfer is above the limit for users without a BVN")
+	ErrTransferRejected           = errors.New("the bank rejected the transfer, the amount has been returned to your wallet")
+	// ErrTransferUnconfirmed is returned when the bank could not be reached after the transfer was sent,
+	// it stays debited until anchor reports what happened to it.
+	ErrTransferUnconfirmed = errors.New("the transfer could not be confirmed, it will be settled once the bank reports it")
 )
 
 func JSONError(err error) error {
@@ -0,0 +1,15 @@
+package transfer
+
+import (
+	"fmt"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+// CheckKYCLimit returns ErrKYCLimit when a user without a BVN on their profile transfers more than limit.
+func CheckKYCLimit(user *models.User, amount, limit float64) error {
+	if user.BVN == "" && amount > limit {
+		return fmt.Errorf("%w of %.2f", ErrKYCLimit, limit)
+	}
+	return nil
+}
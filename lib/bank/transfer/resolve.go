@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/requery"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultResolveInterval = 15 * time.Minute
+	// resolveSettle is how long anchor is given to make a transfer before it is asked about it.
+	resolveSettle = 10 * time.Minute
+)
+
+// Run resolves the transfers left pending every interval, until ctx is cancelled.
+func (c *Config) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultResolveInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.ResolvePending(ctx, time.Now())
+		}
+	}
+}
+
+// ResolvePending asks anchor about the transfers still pending resolveSettle before now, the ones that were
+// not confirmed when they were sent. A transfer anchor has not decided yet is asked about again by the
+// next run.
+func (c *Config) ResolvePending(ctx context.Context, now time.Time) {
+	transfers, err := c.db.GetPendingTransfers(ctx, now.Add(-resolveSettle))
+	if err != nil {
+		c.logger.Error("failed to get pending transfers", zap.Error(err))
+		return
+	}
+
+	for _, transfer := range transfers {
+		if ctx.Err() != nil {
+			return
+		}
+		c.resolve(ctx, transfer)
+	}
+}
+
+// resolve reverses a pending transfer anchor reports failed or does not have, and marks one it has paid
+// sent.
+func (c *Config) resolve(ctx context.Context, transfer models.TransferResponse) {
+	target, err := requery.TargetOf(transfer)
+	if err != nil {
+		return
+	}
+
+	result, err := requery.Query(ctx, &httpclient.Providers{Anchor: c.client}, target)
+	switch {
+	case errors.Is(err, requery.ErrNotFound):
+		// anchor failed the request before it made the transfer
+	case err != nil:
+		c.logger.Warn("failed to requery transfer", zap.String("transaction_id", transfer.Transaction_ID), zap.Error(err))
+		return
+	case result.Succeeded():
+		err := c.db.SettleTransfer(ctx, transfer.Transaction_ID, models.TransferSent)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			c.logger.Error("failed to mark transfer sent", zap.String("transaction_id", transfer.Transaction_ID), zap.Error(err))
+		}
+		return
+	case !result.Failed():
+		return
+	}
+
+	user, err := c.db.GetUserByID(ctx, transfer.UserID)
+	if err != nil {
+		c.logger.Error("failed to get owner of failed transfer", zap.String("transaction_id", transfer.Transaction_ID), zap.Error(err))
+		return
+	}
+	c.reverse(ctx, user.Username, transfer)
+}
@@ -2,52 +2,55 @@ package transfer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strconv"
 
+	"github.com/aremxyplug-be/config"
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/balance"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
 	"github.com/aremxyplug-be/lib/randomgen"
+	"github.com/aremxyplug-be/lib/wallet"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 )
 
-var (
-	/*
-		api        = os.Getenv("ANCHOR_SANDBOX")
-		apikey     = os.Getenv("ANCHORAPI_KEY")
-		deposit_id = os.Getenv("DEPOSIT_ID")
-	*/
-	api        = os.Getenv("ANCHOR_API")
-	apikey     = os.Getenv("ANCHORAPI_PROD")
-	deposit_id = os.Getenv("DEPOSIT_ID_LIVE")
-)
-
 type Config struct {
-	db     db.DataStore
-	logger *zap.Logger
+	db               db.DataStore
+	wallet           *wallet.Wallet
+	logger           *zap.Logger
+	client           *httpclient.Client
+	idGenerator      idgenerator.IdGenerator
+	depositAccountID string
 }
 
-func NewConfig(store db.DataStore, logger *zap.Logger) *Config {
+func NewConfig(store db.DataStore, userWallet *wallet.Wallet, logger *zap.Logger, client *httpclient.Client, anchor config.Anchor) *Config {
 	return &Config{
-		db:     store,
-		logger: logger,
+		db:               store,
+		wallet:           userWallet,
+		logger:           logger,
+		client:           client,
+		idGenerator:      idgenerator.New(),
+		depositAccountID: anchor.DepositAccountID,
 	}
 }
 
 // this endpoint should auto automatically initialize
-func (c *Config) ListBanks() error {
-	url := fmt.Sprintf("%s/%s", api, "banks")
+func (c *Config) ListBanks(ctx context.Context) error {
+	url := "/banks"
 
-	req, _ := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 
 	req.Header.Add("accept", "application/json")
-	req.Header.Add("x-anchor-key", apikey)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
 		c.logger.Error(err.Error())
 		return ErrCreatingHTTPRequest
@@ -60,7 +63,6 @@ func (c *Config) ListBanks() error {
 		c.logger.Error(err.Error())
 		return err
 	}
-	c.logger.Log(c.logger.Level(), string(body))
 	// bankLists := []models.BankDetails{}
 
 	/*
@@ -82,7 +84,7 @@ func (c *Config) ListBanks() error {
 			NIPCode: bank.Atrributes.NIPCode,
 		}
 
-		if err := c.db.SaveBankList(bankList); err != nil {
+		if err := c.db.SaveBankList(ctx, bankList); err != nil {
 			return DBConnectionError(err)
 		}
 
@@ -91,17 +93,24 @@ func (c *Config) ListBanks() error {
 	return nil
 }
 
-func (c *Config) TransferToBank(info models.TransferInfo) (models.TransferResponse, error) {
+// TransferToBank takes the transfer and its fee from the user's wallet and sends the transfer to the bank.
+// The debit is saved with a pending record of the transfer, so two transfers can not spend the same
+// balance, and the money is returned when the bank rejects the transfer.
+func (c *Config) TransferToBank(ctx context.Context, username string, info models.TransferInfo) (models.TransferResponse, error) {
+	// transfers are paid from our deposit account at anchor
+	if err := c.client.CheckFloat(info.Amount); err != nil {
+		return models.TransferResponse{}, err
+	}
 
 	// first check if the details is already in the database. if it is just procced to the point of transfer
-	counterparty, err := c.getCounterParty(info.Account_Number, info.Bank_name)
+	counterparty, err := c.getCounterParty(ctx, info.Account_Number, info.Bank_name)
 	if err == mongo.ErrNoDocuments {
-		bankDetail, _ := c.db.GetBankDetail(info.Bank_name)
-		details, err := c.verifyAccount(bankDetail.NIPCode, info.Account_Number)
+		bankDetail, _ := c.db.GetBankDetail(ctx, info.Bank_name)
+		details, err := c.verifyAccount(ctx, bankDetail.NIPCode, info.Account_Number)
 		if err != nil {
 			return models.TransferResponse{}, JSONError(err)
 		}
-		counterparty, err = c.createCounterParty(details)
+		counterparty, err = c.createCounterParty(ctx, details)
 		if err != nil {
 			return models.TransferResponse{}, JSONError(err)
 		}
@@ -114,14 +123,62 @@ func (c *Config) TransferToBank(info models.TransferInfo) (models.TransferRespon
 		return models.TransferResponse{}, ErrGeneratingOrderID
 	}
 	transactionID := randomgen.GenerateTransactionID("TRF")
-	url := fmt.Sprintf("%s/%s", api, "transfers")
-	amount := info.Amount * 100
+
+	result := models.TransferResponse{
+		Bank_Name:      counterparty.BankName,
+		Account_Name:   counterparty.AccountName,
+		Account_No:     counterparty.AccountNumber,
+		Amount:         info.Amount,
+		Fee:            balance.TransferFee,
+		Product:        "Money Transfer",
+		Description:    "",
+		Reason:         info.Reason,
+		UserID:         info.UserID,
+		Order_ID:       orderID,
+		Transaction_ID: transactionID,
+		Status:         models.TransferPending,
+		// sessionID is gotten from the webhook
+	}
+
+	lines := linesOf(result)
+	err = c.wallet.DebitWith(ctx, username, lines, func(ctx context.Context) error {
+		return c.saveTransaction(ctx, result)
+	})
+	if err != nil {
+		return models.TransferResponse{}, err
+	}
+
+	if err := c.send(ctx, counterparty, info.Amount, transactionID); err != nil {
+		if errors.Is(err, ErrTransferRejected) {
+			c.reverse(ctx, username, result)
+		} else {
+			// left pending for ResolvePending to settle once anchor reports it
+			c.logger.Error("transfer not confirmed", zap.String("transaction_id", transactionID), zap.Error(err))
+		}
+		return models.TransferResponse{}, err
+	}
+
+	result.Status = models.TransferSent
+	if err := c.db.SettleTransfer(ctx, transactionID, result.Status); err != nil {
+		c.logger.Error("failed to mark transfer sent", zap.String("transaction_id", transactionID), zap.Error(err))
+	}
+
+	return result, nil
+}
+
+// send posts the transfer to anchor. It returns ErrTransferRejected when anchor turned it down with a
+// client error, and ErrTransferUnconfirmed when anchor could not be reached or answered with a server
+// error, as it may have made the transfer before failing.
+func (c *Config) send(ctx context.Context, counterparty models.CounterParty, amount float64, transactionID string) error {
+	url := "/transfers"
 
 	payload := intiateTransfer{
 		Data: transferData{
 			Attributes: transferDataAttributes{
-				Amount:   amount,
+				Amount:   amount * 100,
 				Currency: "NGN",
+				// anchor reports the transfer under our transaction id, reconciliation matches on it
+				Reference: transactionID,
 			},
 			Relationships: relationships{
 				DestinationAcc: destination{
@@ -133,7 +190,7 @@ func (c *Config) TransferToBank(info models.TransferInfo) (models.TransferRespon
 				},
 				Account: account{
 					Data: data{
-						ID:   deposit_id, // the ID of the deposit account
+						ID:   c.depositAccountID, // the ID of the deposit account
 						Type: "DepositAccount",
 					},
 				},
@@ -150,80 +207,80 @@ func (c *Config) TransferToBank(info models.TransferInfo) (models.TransferRespon
 
 	requestBody, err := json.Marshal(payload)
 	if err != nil {
-		return models.TransferResponse{}, JSONError(err)
+		return fmt.Errorf("%w: %v", ErrTransferRejected, JSONError(err))
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return models.TransferResponse{}, ErrCreatingHTTPRequest
+		return fmt.Errorf("%w: %v", ErrTransferRejected, err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-anchor-key", apikey)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return models.TransferResponse{}, ErrAPIConnectionFailed
+		return fmt.Errorf("%w: %v", ErrTransferUnconfirmed, err)
 	}
 	defer resp.Body.Close()
 
-	apiResponse := transferResult{}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.logger.Error(err.Error())
-		return models.TransferResponse{}, JSONError(err)
-	}
-	c.logger.Log(c.logger.Level(), string(body))
 	if resp.StatusCode != http.StatusCreated {
 		c.logger.Error(resp.Status)
-		return models.TransferResponse{}, ErrAccountValidationFailed
-	}
-	/*
-		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-			c.logger.Error(err.Error())
-			return models.TransferResponse{}, JSONError(err)
+		// a server error or a gateway timeout can come after anchor has made the transfer
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%w: %s", ErrTransferUnconfirmed, resp.Status)
 		}
-	*/
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		c.logger.Error(err.Error())
-		return models.TransferResponse{}, JSONError(err)
+		return fmt.Errorf("%w: %s", ErrTransferRejected, resp.Status)
 	}
 
-	result := models.TransferResponse{
-		Bank_Name:      counterparty.BankName,
-		Account_Name:   counterparty.AccountName,
-		Account_No:     counterparty.AccountNumber,
-		Product:        "Money Transfer",
-		Description:    "",
-		Reason:         info.Reason,
-		Order_ID:       orderID,
-		Transaction_ID: transactionID,
-		// sessionID is gotten from the webhook
-	}
+	return nil
+}
 
-	if err := c.saveTransaction(result); err != nil {
-		c.logger.Error(err.Error())
-		return result, DBConnectionError(err)
+// linesOf returns the debit of a transfer, the transfer and its fee are separate lines of the user's
+// statement.
+func linesOf(result models.TransferResponse) []wallet.Line {
+	reference := strconv.Itoa(result.Order_ID)
+	return []wallet.Line{
+		{Amount: result.Amount, Movement: wallet.Movement{Type: models.EntryTransfer, Reference: reference, Description: "Transfer to " + result.Account_Name}},
+		{Amount: result.Fee, Movement: wallet.Movement{Type: models.EntryFee, Reference: reference, Description: "Transfer fee"}},
 	}
+}
 
-	return result, nil
+// reverse returns a rejected transfer and its fee to the user's wallet and marks the transfer rejected in
+// the same transaction. Only a pending transfer is reversed, so a transfer is never returned twice.
+func (c *Config) reverse(ctx context.Context, username string, result models.TransferResponse) {
+	lines := linesOf(result)
+	reversal := make([]wallet.Line, 0, len(lines))
+	for _, line := range lines {
+		line.Type = models.EntryReversal
+		line.Description = "Reversal: " + line.Description
+		reversal = append(reversal, line)
+	}
 
+	err := c.wallet.CreditWith(ctx, username, reversal, func(ctx context.Context) error {
+		return c.db.SettleTransfer(ctx, result.Transaction_ID, models.TransferRejected)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// settled first, by anchor's event or another run
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to reverse rejected transfer", zap.String("transaction_id", result.Transaction_ID),
+			zap.Float64("amount", result.Amount+result.Fee), zap.Error(err))
+	}
 }
 
-func (c *Config) verifyAccount(sortCode, accNumber string) (verifyAccountResponse, error) {
+func (c *Config) verifyAccount(ctx context.Context, sortCode, accNumber string) (verifyAccountResponse, error) {
 
-	url := fmt.Sprintf("%s/%s/%s/%s/%s", api, "payments", "verify-account", sortCode, accNumber)
+	url := fmt.Sprintf("/payments/verify-account/%s/%s", sortCode, accNumber)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		c.logger.Error(err.Error())
 		return verifyAccountResponse{}, ErrCreatingHTTPRequest
 	}
 
 	req.Header.Add("accept", "application/json")
-	req.Header.Add("x-anchor-key", apikey)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
 		c.logger.Error(err.Error())
 		return verifyAccountResponse{}, ErrAPIConnectionFailed
@@ -236,7 +293,6 @@ func (c *Config) verifyAccount(sortCode, accNumber string) (verifyAccountRespons
 		c.logger.Error(err.Error())
 		return verifyAccountResponse{}, err
 	}
-	c.logger.Log(c.logger.Level(), string(body))
 
 	if res.StatusCode != http.StatusOK {
 		// return that account wasn't found
@@ -261,9 +317,9 @@ func (c *Config) verifyAccount(sortCode, accNumber string) (verifyAccountRespons
 
 }
 
-func (c *Config) createCounterParty(info verifyAccountResponse) (models.CounterParty, error) {
+func (c *Config) createCounterParty(ctx context.Context, info verifyAccountResponse) (models.CounterParty, error) {
 
-	url := fmt.Sprintf("%s/%s", api, "counterparties")
+	url := "/counterparties"
 
 	payload := counterPartyPayload{}
 	payload.Data.Type = "CounterParty"
@@ -271,7 +327,7 @@ func (c *Config) createCounterParty(info verifyAccountResponse) (models.CounterP
 	payload.Data.Attributes.BankCode = info.Data.Attributes.Bank.NipCode
 	payload.Data.Attributes.VerifyName = true
 	payload.Data.Attributes.AccountNumber = info.Data.Attributes.AccountNumber
-	payload.Data.Relationships.Bank.Data.ID = deposit_id
+	payload.Data.Relationships.Bank.Data.ID = c.depositAccountID
 	payload.Data.Relationships.Bank.Data.Type = "DepositAccount"
 
 	requestBody, err := json.Marshal(payload)
@@ -279,16 +335,14 @@ func (c *Config) createCounterParty(info verifyAccountResponse) (models.CounterP
 		return models.CounterParty{}, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return models.CounterParty{}, ErrCreatingHTTPRequest
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-anchor-key", apikey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return models.CounterParty{}, ErrAPIConnectionFailed
 	}
@@ -299,7 +353,6 @@ func (c *Config) createCounterParty(info verifyAccountResponse) (models.CounterP
 		c.logger.Error(err.Error())
 		return models.CounterParty{}, JSONError(err)
 	}
-	c.logger.Log(c.logger.Level(), string(body))
 	apiResponse := counterPartyAPIResponse{}
 
 	if resp.StatusCode != http.StatusCreated {
@@ -325,7 +378,7 @@ func (c *Config) createCounterParty(info verifyAccountResponse) (models.CounterP
 		NIPCode:       apiResponse.Data.Attributes.Bank.NipCode,
 	}
 
-	if err := c.saveCounterParty(result); err != nil {
+	if err := c.saveCounterParty(ctx, result); err != nil {
 		c.logger.Error(err.Error())
 		return result, DBConnectionError(err)
 	}
@@ -334,19 +387,18 @@ func (c *Config) createCounterParty(info verifyAccountResponse) (models.CounterP
 }
 
 // endpoint to verify a transfer from the API, we will save all transactions regardless.
-func (c *Config) verifyTransfer(id string) (transferResult, error) {
+func (c *Config) verifyTransfer(ctx context.Context, id string) (transferResult, error) {
 
-	url := fmt.Sprintf("%s/%s/%s", api, "verify", id)
+	url := fmt.Sprintf("/verify/%s", id)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return transferResult{}, ErrCreatingHTTPRequest
 	}
 
 	req.Header.Add("accept", "application/json")
-	req.Header.Add("x-anchor-key", apikey)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
 		return transferResult{}, ErrAPIConnectionFailed
 	}
@@ -361,9 +413,8 @@ func (c *Config) verifyTransfer(id string) (transferResult, error) {
 		c.logger.Error(err.Error())
 		return transferResult{}, JSONError(err)
 	}
-	c.logger.Log(c.logger.Level(), string(body))
 
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(body, &result); err != nil {
 		c.logger.Error(err.Error())
 		return transferResult{}, JSONError(err)
 	}
@@ -371,16 +422,16 @@ func (c *Config) verifyTransfer(id string) (transferResult, error) {
 	return result, nil
 }
 
-func (c *Config) saveTransaction(details models.TransferResponse) error {
-	err := c.db.SaveTransfer(details)
+func (c *Config) saveTransaction(ctx context.Context, details models.TransferResponse) error {
+	err := c.db.SaveTransfer(ctx, details)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Config) saveCounterParty(conterparty models.CounterParty) error {
-	err := c.db.SaveCounterParty(conterparty)
+func (c *Config) saveCounterParty(ctx context.Context, conterparty models.CounterParty) error {
+	err := c.db.SaveCounterParty(ctx, conterparty)
 	if err != nil {
 		return err
 	}
@@ -388,8 +439,8 @@ func (c *Config) saveCounterParty(conterparty models.CounterParty) error {
 	return nil
 }
 
-func (c *Config) getCounterParty(accountname, bankname string) (models.CounterParty, error) {
-	counterparty, err := c.db.GetCounterParty(accountname, bankname)
+func (c *Config) getCounterParty(ctx context.Context, accountname, bankname string) (models.CounterParty, error) {
+	counterparty, err := c.db.GetCounterParty(ctx, accountname, bankname)
 	if err != nil {
 		return models.CounterParty{}, err
 	}
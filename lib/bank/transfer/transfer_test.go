@@ -0,0 +1,292 @@
+package transfer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the balance, ledger and transfers in memory and undoes a failed transaction.
+type fakeStore struct {
+	db.DataStore
+	balance   float64
+	entries   []models.LedgerEntry
+	transfers map[string]models.TransferResponse
+}
+
+func (f *fakeStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	balance, entries := f.balance, len(f.entries)
+	transfers := map[string]models.TransferResponse{}
+	for k, v := range f.transfers {
+		transfers[k] = v
+	}
+	if err := fn(ctx); err != nil {
+		f.balance, f.entries, f.transfers = balance, f.entries[:entries], transfers
+		return err
+	}
+	return nil
+}
+
+func (f *fakeStore) GetCounterParty(_ context.Context, accountNumber, bankName string) (models.CounterParty, error) {
+	return models.CounterParty{ID: "cp-1", AccountName: "Bola", AccountNumber: accountNumber, BankName: bankName}, nil
+}
+
+func (f *fakeStore) GetVirtualNuban(_ context.Context, name string) (models.AccountDetails, error) {
+	return models.AccountDetails{User_ID: "user-" + name, VirtualAccountID: "nuban-" + name}, nil
+}
+
+func (f *fakeStore) GetBalance(_ context.Context, _ string) (float64, error) {
+	return f.balance, nil
+}
+
+func (f *fakeStore) UpdateBalance(_ context.Context, _ string, balance float64) error {
+	f.balance = balance
+	return nil
+}
+
+func (f *fakeStore) SaveLedgerEntry(_ context.Context, entry models.LedgerEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fakeStore) SaveTransfer(_ context.Context, transfer models.TransferResponse) error {
+	// the store dates the records it saves
+	transfer.CreatedAt = time.Now()
+	f.transfers[transfer.Transaction_ID] = transfer
+	return nil
+}
+
+func (f *fakeStore) UpdateTransferStatus(_ context.Context, transactionID, status string) error {
+	transfer := f.transfers[transactionID]
+	transfer.Status = status
+	f.transfers[transactionID] = transfer
+	return nil
+}
+
+func (f *fakeStore) SettleTransfer(_ context.Context, transactionID, status string) error {
+	transfer, ok := f.transfers[transactionID]
+	if !ok || transfer.Status != models.TransferPending {
+		return mongo.ErrNoDocuments
+	}
+	transfer.Status = status
+	f.transfers[transactionID] = transfer
+	return nil
+}
+
+func (f *fakeStore) GetPendingTransfers(_ context.Context, before time.Time) ([]models.TransferResponse, error) {
+	var pending []models.TransferResponse
+	for _, transfer := range f.transfers {
+		if transfer.Status == models.TransferPending && transfer.CreatedAt.Before(before) {
+			pending = append(pending, transfer)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	return &models.User{ID: id, Username: strings.TrimPrefix(id, "user-")}, nil
+}
+
+func TestTransferToBank(t *testing.T) {
+	var tests = []struct {
+		name string
+		// status is what anchor answers, 0 closes the connection
+		status      int
+		balance     float64
+		wantErr     error
+		wantStatus  string
+		wantBalance float64
+		wantEntries []string
+	}{
+		{
+			name:        "Test transfer sent",
+			status:      http.StatusCreated,
+			balance:     2000,
+			wantStatus:  models.TransferSent,
+			wantBalance: 950,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee},
+		},
+		{
+			name:        "Test rejected transfer reversed",
+			status:      http.StatusBadRequest,
+			balance:     2000,
+			wantErr:     ErrTransferRejected,
+			wantStatus:  models.TransferRejected,
+			wantBalance: 2000,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee, models.EntryReversal, models.EntryReversal},
+		},
+		{
+			name:        "Test unconfirmed transfer stays debited",
+			status:      http.StatusBadGateway,
+			balance:     2000,
+			wantErr:     ErrTransferUnconfirmed,
+			wantStatus:  models.TransferPending,
+			wantBalance: 950,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee},
+		},
+		{
+			name:        "Test fee above the balance",
+			status:      http.StatusCreated,
+			balance:     1020,
+			wantErr:     wallet.ErrInsufficientFunds,
+			wantBalance: 1020,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent++
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(srv.Close)
+			client := httpclient.New(&httpclient.Options{Name: t.Name(), BaseURL: srv.URL, Retries: 0, Backoff: time.Millisecond})
+
+			store := &fakeStore{balance: tt.balance, transfers: map[string]models.TransferResponse{}}
+			c := NewConfig(store, wallet.NewWallet(store, zap.NewNop()), zap.NewNop(), client, config.Anchor{})
+
+			_, err := c.TransferToBank(context.Background(), "ada", models.TransferInfo{
+				Account_Number: "0123456789",
+				Bank_name:      "GTBank",
+				Amount:         1000,
+				UserID:         "user-ada",
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantBalance, store.balance)
+			var entries []string
+			for _, entry := range store.entries {
+				entries = append(entries, entry.Type)
+			}
+			assert.Equal(t, tt.wantEntries, entries)
+
+			if tt.wantStatus == "" {
+				// nothing is sent or recorded without the debit
+				assert.Zero(t, sent)
+				assert.Empty(t, store.transfers)
+				return
+			}
+			require.Len(t, store.transfers, 1)
+			for _, transfer := range store.transfers {
+				assert.Equal(t, tt.wantStatus, transfer.Status)
+			}
+		})
+	}
+}
+
+func TestResolvePending(t *testing.T) {
+	var tests = []struct {
+		name string
+		// status and body are what anchor answers the requery with
+		status      int
+		body        string
+		wantStatus  string
+		wantBalance float64
+		wantEntries []string
+	}{
+		{
+			name:        "Test completed transfer sent",
+			status:      http.StatusOK,
+			body:        `{"data":{"attributes":{"status":"COMPLETED"}}}`,
+			wantStatus:  models.TransferSent,
+			wantBalance: 950,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee},
+		},
+		{
+			name:        "Test failed transfer reversed",
+			status:      http.StatusOK,
+			body:        `{"data":{"attributes":{"status":"FAILED","failureReason":"account closed"}}}`,
+			wantStatus:  models.TransferRejected,
+			wantBalance: 2000,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee, models.EntryReversal, models.EntryReversal},
+		},
+		{
+			name:        "Test transfer anchor does not have reversed",
+			status:      http.StatusNotFound,
+			wantStatus:  models.TransferRejected,
+			wantBalance: 2000,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee, models.EntryReversal, models.EntryReversal},
+		},
+		{
+			name:        "Test processing transfer left pending",
+			status:      http.StatusOK,
+			body:        `{"data":{"attributes":{"status":"PROCESSING"}}}`,
+			wantStatus:  models.TransferPending,
+			wantBalance: 950,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee},
+		},
+		{
+			name:        "Test unanswered requery left pending",
+			status:      http.StatusInternalServerError,
+			wantStatus:  models.TransferPending,
+			wantBalance: 950,
+			wantEntries: []string{models.EntryTransfer, models.EntryFee},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/transfers" {
+					// the transfer is not confirmed when it is sent
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+			client := httpclient.New(&httpclient.Options{Name: t.Name(), BaseURL: srv.URL, Retries: 0, Backoff: time.Millisecond})
+
+			store := &fakeStore{balance: 2000, transfers: map[string]models.TransferResponse{}}
+			c := NewConfig(store, wallet.NewWallet(store, zap.NewNop()), zap.NewNop(), client, config.Anchor{})
+
+			_, err := c.TransferToBank(context.Background(), "ada", models.TransferInfo{
+				Account_Number: "0123456789",
+				Bank_name:      "GTBank",
+				Amount:         1000,
+				UserID:         "user-ada",
+			})
+			require.ErrorIs(t, err, ErrTransferUnconfirmed)
+
+			// a transfer just sent is left to anchor
+			c.ResolvePending(context.Background(), time.Now())
+			require.Len(t, store.transfers, 1)
+			for _, transfer := range store.transfers {
+				assert.Equal(t, models.TransferPending, transfer.Status)
+			}
+
+			c.ResolvePending(context.Background(), time.Now().Add(resolveSettle+time.Minute))
+			// a second run does not reverse it again
+			c.ResolvePending(context.Background(), time.Now().Add(resolveSettle+time.Minute))
+
+			assert.Equal(t, tt.wantBalance, store.balance)
+			var entries []string
+			for _, entry := range store.entries {
+				entries = append(entries, entry.Type)
+			}
+			assert.Equal(t, tt.wantEntries, entries)
+			for _, transfer := range store.transfers {
+				assert.Equal(t, tt.wantStatus, transfer.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package electricity
+
+import "errors"
+
+var (
+	ErrMeterRequired  = errors.New("meter number is required")
+	ErrInvalidMeter   = errors.New("meter number is not valid")
+	ErrProviderFailed = errors.New("electricity provider could not complete the payment")
+)
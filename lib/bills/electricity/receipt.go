@@ -0,0 +1,92 @@
+package electricity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/notification"
+	"github.com/aremxyplug-be/lib/receipt"
+	"go.uber.org/zap"
+)
+
+// ReceiptAlias is the postmark template used for the electricity token receipt.
+const ReceiptAlias = "electricity-receipt"
+
+// sendReceipt sends the token receipt to the customer as a transaction notification, on the channels they
+// chose. The payment has already gone through, so failures are logged and not returned.
+func (e *ElectricConn) sendReceipt(ctx context.Context, result *models.ElectricResult) {
+	receipt := formatReceipt(result)
+	n := notification.Notification{
+		UserID:     result.UserID,
+		Category:   notification.CategoryTransaction,
+		Title:      "Electricity Token Receipt",
+		Body:       receipt,
+		TemplateID: ReceiptAlias,
+		DataMap: map[string]string{
+			"Name":          result.Name,
+			"Disco":         strings.ToUpper(result.DiscoType),
+			"MeterNumber":   result.MeterNumber,
+			"MeterType":     result.MeterType,
+			"Token":         result.Token,
+			"Units":         result.Units,
+			"Tariff":        result.Tariff,
+			"Debt":          result.Debt,
+			"Amount":        result.Amount,
+			"TransactionID": result.TransactionID,
+			"Receipt":       receipt,
+		},
+	}
+	if attachment, err := receiptAttachment(*result, time.Now().Unix()); err != nil {
+		e.logger.Error("error rendering electricity receipt", zap.String("transaction_id", result.TransactionID), zap.Error(err))
+	} else {
+		n.Attachments = []models.Attachment{attachment}
+	}
+	if _, err := e.notifier.Notify(ctx, n); err != nil {
+		e.logger.Error("error sending electricity receipt", zap.String("transaction_id", result.TransactionID), zap.Error(err))
+	}
+}
+
+// receiptAttachment renders the receipt as a PDF. created_at is only set on the stored document, so the
+// receipt is dated now when the result has none.
+func receiptAttachment(result models.ElectricResult, now int64) (models.Attachment, error) {
+	if result.CreatedAt.IsZero() {
+		result.CreatedAt = time.Unix(now, 0)
+	}
+	rec, err := receipt.FromTransaction(result)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	return rec.Attachment(receipt.PDF)
+}
+
+// formatReceipt renders the receipt as plain text, it is short enough to be sent as an sms.
+func formatReceipt(result *models.ElectricResult) string {
+	lines := []string{
+		fmt.Sprintf("%s %s electricity", strings.ToUpper(result.DiscoType), result.MeterType),
+		"Meter: " + result.MeterNumber,
+	}
+	if result.Name != "" {
+		lines = append(lines, "Name: "+result.Name)
+	}
+	if result.Token != "" {
+		lines = append(lines, "Token: "+result.Token)
+	}
+	if result.Units != "" {
+		lines = append(lines, "Units: "+result.Units+"kWh")
+	}
+	if result.Tariff != "" {
+		lines = append(lines, "Tariff: "+result.Tariff)
+	}
+	if result.Debt != "" {
+		lines = append(lines, "Debt: N"+result.Debt)
+	}
+	lines = append(lines,
+		"Amount: N"+result.Amount,
+		"Ref: "+result.TransactionID,
+	)
+
+	return strings.Join(lines, "\n")
+}
@@ -2,105 +2,155 @@ package electricity
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/notification"
 	"github.com/aremxyplug-be/lib/randomgen"
 	"go.uber.org/zap"
 )
 
-var (
-	api = os.Getenv("VTPASS_SANDBOX")
-	pk  = os.Getenv("APIKey")
-	sk  = os.Getenv("SK")
-)
-
 type ElectricConn struct {
-	db     db.UtilitiesStore
-	logger *zap.Logger
+	db       db.UtilitiesStore
+	logger   *zap.Logger
+	notifier *notification.Notifier
+	client   *httpclient.Client
 }
 
-func NewElectricConn(db db.UtilitiesStore, logger *zap.Logger) *ElectricConn {
+func NewElectricConn(db db.UtilitiesStore, logger *zap.Logger, notifier *notification.Notifier, client *httpclient.Client) *ElectricConn {
 	return &ElectricConn{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+		client:   client,
 	}
 }
 
 // pay electricity bill
-func (e *ElectricConn) PayBill(data models.ElectricInfo) (*models.ElectricResult, error) {
-
-	log.Printf("%+v", data)
+func (e *ElectricConn) PayBill(ctx context.Context, data models.ElectricInfo) (*models.ElectricResult, error) {
 
 	data.RequestID = randomgen.GenerateRequestID()
-	orderID, err := randomgen.GenerateOrderID()
-	if err != nil {
-		return nil, e.logAndReturnError("error generating orderID", err)
+	orderID := data.OrderID
+	if orderID == 0 {
+		var err error
+		if orderID, err = randomgen.GenerateOrderID(); err != nil {
+			return nil, e.logAndReturnError("error generating orderID", err)
+		}
 	}
 	transactionID := randomgen.GenerateTransactionID("ele")
-	log.Printf("%+v", data)
-	validNo, err := e.verifyMeterNo(data.DiscoType, data.Meter_No, data.Meter_Type)
+	meter, err := e.VerifyMeter(ctx, data.DiscoType, data.Meter_No, data.Meter_Type)
 	if err != nil {
-		return nil, e.logAndReturnError("error verifying meter number", err)
+		return nil, err
 	}
-	if !validNo {
-		return nil, e.logAndReturnError("meter number is not valid", errors.New("invalid meter number"))
+	if err := e.client.CheckFloat(float64(data.Amount)); err != nil {
+		return nil, err
 	}
 
-	resp, err := e.payBill(data)
+	resp, err := e.payBill(ctx, data)
 	if err != nil {
-		return nil, e.logAndReturnError("error communicating with server", err)
+		e.logger.Error("error communicating with server", zap.Error(err))
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, httpclient.Unconfirmed(err))
 	}
 	defer resp.Body.Close()
 
 	apiResponse := models.ElectricAPI{}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, e.logAndReturnError("error decoding response body", err)
+		e.logger.Error("error decoding response body", zap.Error(err))
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, httpclient.Unconfirmed(err))
 	}
-	log.Println(apiResponse)
 	transDetails := apiResponse.Contents.Transactions
 	description := data.DiscoType + " " + data.Meter_Type
-
-	parts := strings.Split(apiResponse.Purchased_Token, ":")
-
-	token_generated := strings.TrimSpace(parts[1])
-	billGenerated := token_generated
+	token := parseToken(apiResponse)
+	if apiResponse.Code != "000" {
+		e.logger.Warn("electricity payment failed", zap.String("code", apiResponse.Code),
+			zap.String("request_id", data.RequestID), zap.String("status", transDetails.Status))
+		return nil, fmt.Errorf("%w: provider returned code %s", ErrProviderFailed, apiResponse.Code)
+	}
+	// postpaid meters are paid without a token, a prepaid vend without one may still be pending
+	if meter.Meter_Type == "prepaid" && token.Token == "" {
+		e.logger.Warn("electricity payment returned no token", zap.String("request_id", data.RequestID),
+			zap.String("status", transDetails.Status))
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, httpclient.Unconfirmed(errors.New("no token was returned")))
+	}
 
 	result := &models.ElectricResult{
 		Amount:        apiResponse.Amount,
 		DiscoType:     data.DiscoType,
 		MeterType:     data.Meter_Type,
+		Name:          meter.Customer_Name,
 		MeterNumber:   transDetails.Meter_No,
 		Phone:         data.Phone,
-		BillGenerated: billGenerated,
+		BillGenerated: token.Token,
+		Token:         token.Token,
+		Units:         token.Units,
+		Tariff:        token.Tariff,
+		Debt:          token.Debt,
 		Email:         data.Email,
 		Product:       transDetails.Type,
 		Description:   description,
+		UserID:        data.UserID,
 		OrderID:       orderID,
 		TransactionID: transactionID,
 		RequestID:     apiResponse.RequestID,
 	}
 
-	if err := e.saveTransaction(result); err != nil {
-		return nil, e.logAndReturnError("error saving transaction to database", err)
+	if err := e.saveTransaction(context.WithoutCancel(ctx), result); err != nil {
+		return nil, httpclient.Unconfirmed(e.logAndReturnError("error saving transaction to database", err))
 	}
 
+	e.sendReceipt(context.WithoutCancel(ctx), result)
+
 	return result, nil
 }
 
+// VerifyMeter looks up a meter number with the disco and returns the customer's name, address and meter type.
+func (e *ElectricConn) VerifyMeter(ctx context.Context, discoType, meterNo, meterType string) (models.MeterInfo, error) {
+	if meterNo == "" {
+		return models.MeterInfo{}, ErrMeterRequired
+	}
+
+	apiResponse, err := e.verifyMeterNo(ctx, discoType, meterNo, meterType)
+	if err != nil {
+		return models.MeterInfo{}, e.logAndReturnError("error verifying meter number", err)
+	}
+	content := apiResponse.Content
+	if apiResponse.Code != "000" || content.Err != "" || content.Name == "" {
+		return models.MeterInfo{}, ErrInvalidMeter
+	}
+
+	if content.Meter_Type == "" {
+		content.Meter_Type = meterType
+	}
+	if content.Meter_Number == "" {
+		content.Meter_Number = meterNo
+	}
+	arrears, _ := content.Arrears.Float64()
+	minAmount, _ := content.Min_Purchase_Amount.Float64()
+
+	return models.MeterInfo{
+		DiscoType:           discoType,
+		Meter_Number:        content.Meter_Number,
+		Meter_Type:          strings.ToLower(content.Meter_Type),
+		Customer_Name:       content.Name,
+		Address:             content.Address,
+		Arrears:             arrears,
+		Min_Purchase_Amount: minAmount,
+	}, nil
+}
+
 // query eletricity bill
-func (e *ElectricConn) QueryTransaction(id string) (models.ElectricResult, error) {
+func (e *ElectricConn) QueryTransaction(ctx context.Context, id string) (models.ElectricResult, error) {
 
-	resp, err := e.queryTransaction(id)
+	resp, err := e.queryTransaction(ctx, id)
 	if err != nil {
 		return models.ElectricResult{}, e.logAndReturnError("error communicating with server", err)
 	}
@@ -115,7 +165,7 @@ func (e *ElectricConn) QueryTransaction(id string) (models.ElectricResult, error
 		return models.ElectricResult{}, nil
 	}
 
-	result, err := e.getTransactionDetails(apiResponse.RequestID)
+	result, err := e.getTransactionDetails(ctx, "", apiResponse.RequestID)
 	if err != nil {
 		return models.ElectricResult{}, e.logAndReturnError("failed to get user's transactions", err)
 	}
@@ -124,17 +174,18 @@ func (e *ElectricConn) QueryTransaction(id string) (models.ElectricResult, error
 }
 
 // get transaction history
-func (e *ElectricConn) GetUserTransactions(user string) ([]models.ElectricResult, error) {
-	result, err := e.getAllTransaction("user")
+func (e *ElectricConn) GetUserTransactions(ctx context.Context, userID string, opts db.ListOptions) ([]models.ElectricResult, string, error) {
+	opts.UserID = userID
+	result, next, err := e.getAllTransaction(ctx, opts)
 	if err != nil {
-		return nil, e.logAndReturnError("failed to get user's transactions", err)
+		return nil, "", e.logAndReturnError("failed to get user's transactions", err)
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (e *ElectricConn) GetTransactionDetails(id string) (models.ElectricResult, error) {
-	result, err := e.getTransactionDetails(id)
+func (e *ElectricConn) GetTransactionDetails(ctx context.Context, userID, id string) (models.ElectricResult, error) {
+	result, err := e.getTransactionDetails(ctx, userID, id)
 	if err != nil {
 		return models.ElectricResult{}, e.logAndReturnError("failed to get transaction details", err)
 	}
@@ -143,18 +194,18 @@ func (e *ElectricConn) GetTransactionDetails(id string) (models.ElectricResult,
 }
 
 // GetAllTransaction returns all transactions, to be used by admin
-func (e *ElectricConn) GetAllTransactions() ([]models.ElectricResult, error) {
+func (e *ElectricConn) GetAllTransactions(ctx context.Context, opts db.ListOptions) ([]models.ElectricResult, string, error) {
 
-	result, err := e.getAllTransaction("")
+	result, next, err := e.getAllTransaction(ctx, opts)
 	if err != nil {
-		return nil, e.logAndReturnError("failed to get transactions from database", err)
+		return nil, "", e.logAndReturnError("failed to get transactions from database", err)
 	}
 
-	return result, nil
+	return result, next, nil
 
 }
 
-func (e *ElectricConn) payBill(data models.ElectricInfo) (*http.Response, error) {
+func (e *ElectricConn) payBill(ctx context.Context, data models.ElectricInfo) (*http.Response, error) {
 
 	amount := strconv.Itoa(data.Amount)
 	phone := data.Phone
@@ -169,18 +220,15 @@ func (e *ElectricConn) payBill(data models.ElectricInfo) (*http.Response, error)
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", api, "pay")
+	url := "/pay"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("api-key", pk)
-	req.Header.Set("secret-key", sk)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := e.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -188,47 +236,42 @@ func (e *ElectricConn) payBill(data models.ElectricInfo) (*http.Response, error)
 	return resp, nil
 }
 
-func (e *ElectricConn) saveTransaction(details *models.ElectricResult) error {
-	err := e.db.SaveElectricTransaction(details)
+func (e *ElectricConn) saveTransaction(ctx context.Context, details *models.ElectricResult) error {
+	err := e.db.SaveElectricTransaction(ctx, details)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (e *ElectricConn) getTransactionDetails(id string) (models.ElectricResult, error) {
-	result, err := e.db.GetElectricSubDetails(id)
+func (e *ElectricConn) getTransactionDetails(ctx context.Context, userID, id string) (models.ElectricResult, error) {
+	result, err := e.db.GetElectricSubDetails(ctx, userID, id)
 	if err != nil {
 		return models.ElectricResult{}, err
 	}
 	return result, nil
 }
 
-func (e *ElectricConn) getAllTransaction(user string) ([]models.ElectricResult, error) {
-	result, err := e.db.GetAllElectricSubTransactions(user)
-	if err != nil {
-		return nil, err
-	}
-
-	return result, nil
+func (e *ElectricConn) getAllTransaction(ctx context.Context, opts db.ListOptions) ([]models.ElectricResult, string, error) {
+	return e.db.GetAllElectricSubTransactions(ctx, opts)
 }
 
-func (e *ElectricConn) queryTransaction(requestID string) (*http.Response, error) {
+func (e *ElectricConn) queryTransaction(ctx context.Context, requestID string) (*http.Response, error) {
 
 	formdata := url.Values{
 		"request_id": {requestID},
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", api, "requery")
+	url := "/requery"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, e.logAndReturnError("failed to create request", err)
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := e.client.Do(req)
 	if err != nil {
 		return nil, e.logAndReturnError("error communicating with server", err)
 	}
@@ -238,15 +281,10 @@ func (e *ElectricConn) queryTransaction(requestID string) (*http.Response, error
 
 func (e *ElectricConn) logAndReturnError(errorMsg string, err error) error {
 	e.logger.Error(errorMsg, zap.Error(err))
-	return errors.New(errorMsg)
+	return fmt.Errorf("%s: %w", errorMsg, err)
 }
 
-// return an error message for when the meter number is not correct
-func (e *ElectricConn) verifyMeterNo(discoType, meterNo, meterType string) (bool, error) {
-
-	if meterNo == "" {
-		return false, errors.New("no meter number")
-	}
+func (e *ElectricConn) verifyMeterNo(ctx context.Context, discoType, meterNo, meterType string) (models.VerifyMeterResponse, error) {
 
 	formdata := url.Values{
 		"serviceID":   {discoType},
@@ -255,30 +293,24 @@ func (e *ElectricConn) verifyMeterNo(discoType, meterNo, meterType string) (bool
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", api, "merchant-verify")
+	url := "/merchant-verify"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
-		return false, err
+		return models.VerifyMeterResponse{}, err
 	}
-	req.Header.Set("api-key", pk)
-	req.Header.Set("secret-key", sk)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-
+	resp, err := e.client.Do(req)
 	if err != nil {
-		return false, err
+		return models.VerifyMeterResponse{}, err
 	}
+	defer resp.Body.Close()
 
 	apiResponse := models.VerifyMeterResponse{}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return false, err
+		return models.VerifyMeterResponse{}, err
 	}
 
-	if apiResponse.Content.Err != "" {
-		return false, errors.New(apiResponse.Content.Err)
-	}
-	return true, nil
+	return apiResponse, nil
 }
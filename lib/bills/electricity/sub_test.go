@@ -0,0 +1,135 @@
+package electricity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the saved payments and notifications in memory.
+type fakeStore struct {
+	db.DataStore
+	saved    []models.ElectricResult
+	messages []models.Message
+}
+
+func (f *fakeStore) SaveElectricTransaction(_ context.Context, details *models.ElectricResult) error {
+	f.saved = append(f.saved, *details)
+	return nil
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	return &models.User{ID: id, Email: "ada@example.com"}, nil
+}
+
+func (f *fakeStore) GetNotificationPreferences(_ context.Context, userID string) (models.NotificationPreferences, error) {
+	return models.NotificationPreferences{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeStore) CreateMessage(_ context.Context, message *models.Message) error {
+	f.messages = append(f.messages, *message)
+	return nil
+}
+
+// fakeSender records the messages it is given.
+type fakeSender struct {
+	sent []models.Message
+}
+
+func (s *fakeSender) Send(message *models.Message) error {
+	s.sent = append(s.sent, *message)
+	return nil
+}
+
+func TestPayBill(t *testing.T) {
+	var tests = []struct {
+		name            string
+		meterType       string
+		pay             string
+		wantErr         error
+		wantUnconfirmed bool
+	}{
+		{
+			name:      "Test prepaid token delivered",
+			meterType: "prepaid",
+			pay:       `{"code":"000","amount":"5000","purchased_code":"Token : 4226-4723-3834-4013-4322"}`,
+		},
+		{
+			name:      "Test postpaid without token",
+			meterType: "postpaid",
+			pay:       `{"code":"000","amount":"5000","purchased_code":"Payment of N5,000.00 received"}`,
+		},
+		{
+			name:      "Test provider rejected",
+			meterType: "prepaid",
+			pay:       `{"code":"016","amount":"5000","content":{"transactions":{"status":"failed"}}}`,
+			wantErr:   ErrProviderFailed,
+		},
+		{
+			name:            "Test prepaid without token",
+			meterType:       "prepaid",
+			pay:             `{"code":"000","amount":"5000","content":{"transactions":{"status":"pending"}}}`,
+			wantErr:         ErrProviderFailed,
+			wantUnconfirmed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/merchant-verify":
+					fmt.Fprintf(w, `{"code":"000","content":{"Customer_Name":"Ada Obi","Meter_Type":%q}}`, tt.meterType)
+				case "/pay":
+					fmt.Fprint(w, tt.pay)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			store := &fakeStore{}
+			email := &fakeSender{}
+			notifier := notification.NewNotifier(&notification.Options{
+				Store:   store,
+				Senders: map[models.MessageType]notification.Sender{models.EMAIL_MESSAGE_TYPE: email},
+				Logger:  zap.NewNop(),
+			})
+			client := httpclient.New(&httpclient.Options{Name: t.Name(), BaseURL: srv.URL, Backoff: time.Millisecond})
+			conn := NewElectricConn(store, zap.NewNop(), notifier, client)
+
+			result, err := conn.PayBill(context.Background(), models.ElectricInfo{
+				UserID: "user-ada", DiscoType: "ikeja-electric", Meter_No: "45012345678", Meter_Type: tt.meterType, Amount: 5000,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantUnconfirmed, errors.Is(err, httpclient.ErrUnconfirmed))
+				assert.Empty(t, store.saved)
+				assert.Empty(t, email.sent)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, store.saved, 1)
+			assert.Equal(t, result.TransactionID, store.saved[0].TransactionID)
+			require.Len(t, store.messages, 1)
+			assert.Equal(t, notification.CategoryTransaction, store.messages[0].Category)
+			require.Len(t, email.sent, 1)
+			assert.Equal(t, ReceiptAlias, email.sent[0].TemplateID)
+			assert.Len(t, email.sent[0].Attachments, 1)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package electricity
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+// token details come back from the discos in different shapes, e.g.
+// "Token : 4226-4723-3834-4013-4322", "Token: 42264723383440134322 Units: 33.4kWh Tariff: R2" or
+// "Token : 4226 4723 3834 4013 4322 : 33.4 Units, Debt: N1,200.00". Prepaid tokens are always 20
+// digits, so the digits of the units written after one are not read as part of it.
+var (
+	tokenPattern  = regexp.MustCompile(`(?i)token\s*(?:no|number)?\s*[:=-]?\s*(\d(?:[\s-]?\d){19})(?:\D|$)`)
+	digitsPattern = regexp.MustCompile(`(?:^|\D)(\d(?:[\s-]?\d){19})(?:\D|$)`)
+	// a labelled "Units: 33.4" is preferred to a number followed by "units" or "kWh"
+	unitsPattern  = regexp.MustCompile(`(?i)units?\s*[:=]?\s*(\d+(?:\.\d+)?)`)
+	amountPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(?:kwh|units?)`)
+	tariffPattern = regexp.MustCompile(`(?i)tariff\s*(?:class|rate)?\s*[:=]?\s*([^,;|]+)`)
+	debtPattern   = regexp.MustCompile(`(?i)debt\s*(?:amount)?\s*[:=]?\s*(?:NGN|N|₦)?\s*(\d[\d,]*(?:\.\d+)?)`)
+)
+
+type tokenDetails struct {
+	Token  string
+	Units  string
+	Tariff string
+	Debt   string
+}
+
+// parseToken reads the token, units, tariff and debt from the vend response, preferring the
+// structured fields and falling back to the purchased_code text. Postpaid meters have no token.
+func parseToken(resp models.ElectricAPI) tokenDetails {
+	details := tokenDetails{
+		Token:  firstNonEmpty(resp.Token, resp.MainToken),
+		Units:  firstNonEmpty(rawString(resp.Units), rawString(resp.TokenUnits)),
+		Tariff: rawString(resp.Tariff),
+		Debt:   rawString(resp.Debt),
+	}
+
+	code := resp.Purchased_Token
+	rawToken := ""
+	if match := digitsPattern.FindStringSubmatch(code); match != nil {
+		rawToken = match[1]
+	}
+	if match := tokenPattern.FindStringSubmatch(code); match != nil {
+		rawToken = match[1]
+	}
+	if details.Token == "" {
+		details.Token = rawToken
+	}
+	details.Token = formatToken(details.Token)
+
+	// strip the token so its digits are not mistaken for units
+	rest := code
+	if rawToken != "" {
+		rest = strings.Replace(code, rawToken, "", 1)
+	}
+	if details.Units == "" {
+		if match := unitsPattern.FindStringSubmatch(rest); match != nil {
+			details.Units = match[1]
+		} else if match := amountPattern.FindStringSubmatch(rest); match != nil {
+			details.Units = match[1]
+		}
+	}
+	if details.Tariff == "" {
+		if match := tariffPattern.FindStringSubmatch(rest); match != nil {
+			details.Tariff = strings.TrimSpace(match[1])
+		}
+	}
+	if details.Debt == "" {
+		if match := debtPattern.FindStringSubmatch(rest); match != nil {
+			details.Debt = strings.ReplaceAll(match[1], ",", "")
+		}
+	}
+
+	return details
+}
+
+// formatToken groups the token digits in fours, the way it is keyed into the meter.
+func formatToken(token string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, token)
+
+	groups := []string{}
+	for len(digits) > 4 {
+		groups = append(groups, digits[:4])
+		digits = digits[4:]
+	}
+	if digits != "" {
+		groups = append(groups, digits)
+	}
+
+	return strings.Join(groups, "-")
+}
+
+// rawString returns a json string or number as a string.
+func rawString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+
+	return strings.TrimSpace(string(raw))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package electricity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseToken(t *testing.T) {
+	var tests = []struct {
+		name string
+		resp models.ElectricAPI
+		want tokenDetails
+	}{
+		{
+			name: "Test empty token",
+			resp: models.ElectricAPI{},
+			want: tokenDetails{},
+		},
+		{
+			name: "Test postpaid meter without token",
+			resp: models.ElectricAPI{Purchased_Token: "Payment of N5,000.00 received"},
+			want: tokenDetails{},
+		},
+		{
+			name: "Test token prefix with dashes",
+			resp: models.ElectricAPI{Purchased_Token: "Token : 4226-4723-3834-4013-4322"},
+			want: tokenDetails{Token: "4226-4723-3834-4013-4322"},
+		},
+		{
+			name: "Test token with units and tariff",
+			resp: models.ElectricAPI{Purchased_Token: "Token: 42264723383440134322 Units: 33.4kWh Tariff: R2"},
+			want: tokenDetails{Token: "4226-4723-3834-4013-4322", Units: "33.4", Tariff: "R2"},
+		},
+		{
+			name: "Test units suffix and debt",
+			resp: models.ElectricAPI{Purchased_Token: "Token : 4226 4723 3834 4013 4322 : 33.4 Units, Debt: N1,200.00"},
+			want: tokenDetails{Token: "4226-4723-3834-4013-4322", Units: "33.4", Debt: "1200.00"},
+		},
+		{
+			name: "Test kwh suffix",
+			resp: models.ElectricAPI{Purchased_Token: "Token No 12345678901234567890 120.5 kWh"},
+			want: tokenDetails{Token: "1234-5678-9012-3456-7890", Units: "120.5"},
+		},
+		{
+			name: "Test bare digits without prefix",
+			resp: models.ElectricAPI{Purchased_Token: "12345678901234567890"},
+			want: tokenDetails{Token: "1234-5678-9012-3456-7890"},
+		},
+		{
+			name: "Test too short to be a token",
+			resp: models.ElectricAPI{Purchased_Token: "Token : 12345 Units: 10"},
+			want: tokenDetails{Units: "10"},
+		},
+		{
+			name: "Test structured fields preferred",
+			resp: models.ElectricAPI{
+				Purchased_Token: "Token : 1111-2222-3333-4444-5555 Units: 1",
+				Token:           "42264723383440134322",
+				Units:           json.RawMessage(`33.4`),
+				Tariff:          json.RawMessage(`"R3"`),
+				Debt:            json.RawMessage(`null`),
+			},
+			want: tokenDetails{Token: "4226-4723-3834-4013-4322", Units: "33.4", Tariff: "R3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseToken(tt.resp))
+		})
+	}
+}
+
+func TestFormatToken(t *testing.T) {
+	var tests = []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "Test empty", token: "", want: ""},
+		{name: "Test twenty digits", token: "42264723383440134322", want: "4226-4723-3834-4013-4322"},
+		{name: "Test regrouped", token: "42 2647 2338-3440 134322", want: "4226-4723-3834-4013-4322"},
+		{name: "Test length not a multiple of four", token: "1234567890123", want: "1234-5678-9012-3"},
+		{name: "Test exactly four", token: "1234", want: "1234"},
+		{name: "Test no digits", token: "N/A", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatToken(tt.token))
+		})
+	}
+}
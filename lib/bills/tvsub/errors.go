@@ -0,0 +1,12 @@
+package tvsub
+
+import "errors"
+
+var (
+	ErrUnknownProvider    = errors.New("unknown tv provider")
+	ErrUnknownPackage     = errors.New("package is not offered by the provider")
+	ErrInvalidSmartCard   = errors.New("smart card number is not valid")
+	ErrInvalidSubType     = errors.New("subscription type must be renew or change")
+	ErrRenewalUnavailable = errors.New("smart card has no bouquet to renew")
+	ErrProviderFailed     = errors.New("tv provider could not complete the subscription")
+)
@@ -0,0 +1,159 @@
+package tvsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+const defaultPackageTTL = time.Hour
+
+// providers are the VTpass serviceIDs of the tv providers we sell.
+var providers = map[string]bool{
+	"dstv":      true,
+	"gotv":      true,
+	"startimes": true,
+	"showmax":   true,
+}
+
+type packageCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cachedPackages
+}
+
+type cachedPackages struct {
+	packages  []models.TvPackage
+	fetchedAt time.Time
+}
+
+func newPackageCache(ttl time.Duration) *packageCache {
+	if ttl <= 0 {
+		ttl = defaultPackageTTL
+	}
+
+	return &packageCache{
+		ttl:     ttl,
+		entries: map[string]cachedPackages{},
+	}
+}
+
+func (c *packageCache) get(provider string) ([]models.TvPackage, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[provider]
+	if !ok || time.Since(entry.fetchedAt) > c.ttl {
+		return nil, false
+	}
+
+	return entry.packages, true
+}
+
+func (c *packageCache) set(provider string, packages []models.TvPackage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[provider] = cachedPackages{packages: packages, fetchedAt: time.Now()}
+}
+
+// Packages returns the bouquets offered by a provider, the list is cached for TV_PACKAGE_CACHE_TTL.
+func (t *TvConn) Packages(ctx context.Context, provider string) ([]models.TvPackage, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if !providers[provider] {
+		return nil, ErrUnknownProvider
+	}
+
+	if packages, ok := t.cache.get(provider); ok {
+		return packages, nil
+	}
+
+	packages, err := t.fetchPackages(ctx, provider)
+	if err != nil {
+		return nil, t.logAndReturnError("failed to get tv packages", err)
+	}
+	t.cache.set(provider, packages)
+
+	return packages, nil
+}
+
+// findPackage looks up a bouquet in the provider's catalogue by its variation code.
+func (t *TvConn) findPackage(ctx context.Context, provider, code string) (models.TvPackage, error) {
+	packages, err := t.Packages(ctx, provider)
+	if err != nil {
+		return models.TvPackage{}, err
+	}
+
+	for _, p := range packages {
+		if p.Code == code {
+			return p, nil
+		}
+	}
+
+	return models.TvPackage{}, ErrUnknownPackage
+}
+
+type variationsResponse struct {
+	ResponseDescription string `json:"response_description"`
+	Content             struct {
+		Varations  []variation `json:"varations"`
+		Variations []variation `json:"variations"`
+	} `json:"content"`
+}
+
+type variation struct {
+	VariationCode   string      `json:"variation_code"`
+	Name            string      `json:"name"`
+	VariationAmount json.Number `json:"variation_amount"`
+}
+
+func (t *TvConn) fetchPackages(ctx context.Context, provider string) ([]models.TvPackage, error) {
+	url := fmt.Sprintf("/%s?serviceID=%s", "service-variations", provider)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	apiResponse := variationsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, err
+	}
+	if apiResponse.ResponseDescription != "000" {
+		return nil, fmt.Errorf("unexpected response %q", apiResponse.ResponseDescription)
+	}
+
+	// VTpass spells the field "varations" on most services.
+	variations := apiResponse.Content.Varations
+	if len(variations) == 0 {
+		variations = apiResponse.Content.Variations
+	}
+
+	packages := []models.TvPackage{}
+	for _, v := range variations {
+		price, err := v.VariationAmount.Float64()
+		if err != nil {
+			continue
+		}
+		packages = append(packages, models.TvPackage{
+			Provider: provider,
+			Code:     v.VariationCode,
+			Name:     v.Name,
+			Price:    price,
+		})
+	}
+
+	return packages, nil
+}
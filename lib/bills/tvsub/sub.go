@@ -2,72 +2,89 @@ package tvsub
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
 	"github.com/aremxyplug-be/lib/randomgen"
 	"go.uber.org/zap"
 )
 
-var (
-	api = os.Getenv("VTPASS_SANDBOX")
-	pk  = os.Getenv("APIKey")
-	sk  = os.Getenv("SK")
+const (
+	SubTypeRenew  = "renew"
+	SubTypeChange = "change"
 )
 
 type TvConn struct {
 	db     db.UtilitiesStore
 	logger *zap.Logger
+	cache  *packageCache
+	client *httpclient.Client
 }
 
-type responses struct {
-	Code string `json:"code"`
+type verifyResponse struct {
+	Code    string `json:"code"`
+	Content struct {
+		Customer_Name        string      `json:"Customer_Name"`
+		Status               string      `json:"Status"`
+		Due_Date             string      `json:"Due_Date"`
+		Current_Bouquet      string      `json:"Current_Bouquet"`
+		Current_Bouquet_Code string      `json:"Current_Bouquet_Code"`
+		Renewal_Amount       json.Number `json:"Renewal_Amount"`
+		Error                string      `json:"error"`
+	} `json:"content"`
 }
 
-func NewTvConn(db db.UtilitiesStore, Logger *zap.Logger) *TvConn {
+func NewTvConn(db db.UtilitiesStore, Logger *zap.Logger, client *httpclient.Client, packageTTL time.Duration) *TvConn {
 	return &TvConn{
 		db:     db,
 		logger: Logger,
+		cache:  newPackageCache(packageTTL),
+		client: client,
 	}
 }
 
 // buy tvsubscription
-// first verifiy the smartcard number
-func (t *TvConn) BuySub(data models.TvInfo) (*models.BillResult, error) {
+// first verifiy the smartcard number, then price the subscription from the card's renewal amount
+// or from the package catalogue, the amount sent by the client is ignored.
+func (t *TvConn) BuySub(ctx context.Context, data models.TvInfo) (*models.BillResult, error) {
 
-	data.RequestID = randomgen.GenerateRequestID()
-	orderID, err := randomgen.GenerateOrderID()
+	card, err := t.price(ctx, &data)
 	if err != nil {
-		return nil, t.logAndReturnError("error generating orderID", err)
+		return nil, err
 	}
-	transactionID := randomgen.GenerateTransactionID("tv")
-	valid, err := verifyCard(data.SmartCard_Number, data.DecoderType)
-	if err != nil || !valid {
-		// return unable to verify the card number
-		t.logger.Error("Verification failed", zap.Error(err))
+	if err := t.client.CheckFloat(float64(data.Amount)); err != nil {
 		return nil, err
 	}
-	resp, err := t.buySub(data)
+
+	data.RequestID = randomgen.GenerateRequestID()
+	orderID := data.OrderID
+	if orderID == 0 {
+		if orderID, err = randomgen.GenerateOrderID(); err != nil {
+			return nil, t.logAndReturnError("error generating orderID", err)
+		}
+	}
+	transactionID := randomgen.GenerateTransactionID("tv")
+	resp, err := t.buySub(ctx, data)
 	if err != nil {
 		t.logger.Error("Buying failed", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, httpclient.Unconfirmed(err))
 	}
 	defer resp.Body.Close()
 
 	apiResponse := &models.TvAPI{}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		log.Print(err)
+		t.logger.Error("failed to decode tv subscription response", zap.Error(err))
 	}
-	log.Println(apiResponse)
-	fmt.Printf("%v\n", apiResponse)
 
 	result := &models.BillResult{
 		DecoderType:   data.DecoderType,
@@ -75,15 +92,17 @@ func (t *TvConn) BuySub(data models.TvInfo) (*models.BillResult, error) {
 		IucNumber:     data.SmartCard_Number,
 		Phone:         data.Phone,
 		Email:         data.Email,
+		Name:          card.Customer_Name,
 		Product:       apiResponse.Content.Transcations.Type,
 		Description:   apiResponse.Content.Transcations.Product_Desc,
+		UserID:        data.UserID,
 		OrderID:       orderID,
 		TranscationID: transactionID,
 		RequestID:     apiResponse.RequestID,
 		Amount:        apiResponse.Content.Transcations.Amount,
 	}
 
-	if err := t.saveTransaction(result); err != nil {
+	if err := t.saveTransaction(context.WithoutCancel(ctx), result); err != nil {
 		t.logAndReturnError("error saving transaction to database", err)
 	}
 
@@ -91,9 +110,9 @@ func (t *TvConn) BuySub(data models.TvInfo) (*models.BillResult, error) {
 }
 
 // query tvsubscription
-func (t *TvConn) QueryTransaction(requestID string) (models.BillResult, error) {
+func (t *TvConn) QueryTransaction(ctx context.Context, requestID string) (models.BillResult, error) {
 
-	resp, err := t.queryTransaction(requestID)
+	resp, err := t.queryTransaction(ctx, requestID)
 	if err != nil {
 		return models.BillResult{}, t.logAndReturnError("error communicating with server", err)
 	}
@@ -108,7 +127,7 @@ func (t *TvConn) QueryTransaction(requestID string) (models.BillResult, error) {
 		return models.BillResult{}, nil
 	}
 
-	result, err := t.getTransactionDetails(apiResponse.RequestID)
+	result, err := t.getTransactionDetails(ctx, "", apiResponse.RequestID)
 	if err != nil {
 		return models.BillResult{}, t.logAndReturnError("failed to get user's transactions", err)
 	}
@@ -118,20 +137,21 @@ func (t *TvConn) QueryTransaction(requestID string) (models.BillResult, error) {
 }
 
 // get tvsubscription transaction history
-func (t *TvConn) GetUserTransactions(user string) ([]models.BillResult, error) {
+func (t *TvConn) GetUserTransactions(ctx context.Context, userID string, opts db.ListOptions) ([]models.BillResult, string, error) {
 
-	result, err := t.getAllTransaction("user")
+	opts.UserID = userID
+	result, next, err := t.getAllTransaction(ctx, opts)
 	if err != nil {
-		return nil, t.logAndReturnError("failed to get user's transactions", err)
+		return nil, "", t.logAndReturnError("failed to get user's transactions", err)
 	}
 
-	return result, nil
+	return result, next, nil
 
 }
 
-func (t *TvConn) GetTransactionDetails(id string) (models.BillResult, error) {
+func (t *TvConn) GetTransactionDetails(ctx context.Context, userID, id string) (models.BillResult, error) {
 
-	result, err := t.getTransactionDetails(id)
+	result, err := t.getTransactionDetails(ctx, userID, id)
 	if err != nil {
 		return models.BillResult{}, t.logAndReturnError("failed to get transaction details", err)
 	}
@@ -140,58 +160,126 @@ func (t *TvConn) GetTransactionDetails(id string) (models.BillResult, error) {
 }
 
 // func to be used by admin to return all transaction in database
-func (t *TvConn) GetAllTransactions() ([]models.BillResult, error) {
+func (t *TvConn) GetAllTransactions(ctx context.Context, opts db.ListOptions) ([]models.BillResult, string, error) {
 
-	result, err := t.getAllTransaction("")
+	result, next, err := t.getAllTransaction(ctx, opts)
 	if err != nil {
 
-		return nil, t.logAndReturnError("failed to get transactions from database", err)
+		return nil, "", t.logAndReturnError("failed to get transactions from database", err)
 	}
 
-	return result, nil
+	return result, next, nil
 
 }
 
-func verifyCard(iucNumber, service string) (bool, error) {
+// Price returns what the subscription will cost without buying it.
+func (t *TvConn) Price(ctx context.Context, data models.TvInfo) (float64, error) {
+	if _, err := t.price(ctx, &data); err != nil {
+		return 0, err
+	}
+
+	return float64(data.Amount), nil
+}
+
+// price verifies the smartcard and sets the package and amount of the subscription.
+func (t *TvConn) price(ctx context.Context, data *models.TvInfo) (models.SmartCardInfo, error) {
+	data.DecoderType = strings.ToLower(strings.TrimSpace(data.DecoderType))
+	data.SubType = strings.ToLower(strings.TrimSpace(data.SubType))
+	if data.SubType == "" {
+		data.SubType = SubTypeChange
+		if data.Package == "" {
+			data.SubType = SubTypeRenew
+		}
+	}
+	if data.SubType != SubTypeRenew && data.SubType != SubTypeChange {
+		return models.SmartCardInfo{}, ErrInvalidSubType
+	}
+
+	card, err := t.VerifyCard(ctx, data.SmartCard_Number, data.DecoderType)
+	if err != nil {
+		t.logger.Error("Verification failed", zap.Error(err))
+		return models.SmartCardInfo{}, err
+	}
+
+	switch data.SubType {
+	case SubTypeRenew:
+		if card.Renewal_Amount <= 0 {
+			return models.SmartCardInfo{}, ErrRenewalUnavailable
+		}
+		data.Package = card.Current_Bouquet_ID
+		data.Amount = int(math.Ceil(card.Renewal_Amount))
+	case SubTypeChange:
+		pkg, err := t.findPackage(ctx, data.DecoderType, data.Package)
+		if err != nil {
+			return models.SmartCardInfo{}, err
+		}
+		data.Amount = int(math.Ceil(pkg.Price))
+	}
+
+	return card, nil
+}
+
+// VerifyCard looks up a smartcard/IUC number with the provider and returns the customer's details.
+func (t *TvConn) VerifyCard(ctx context.Context, iucNumber, provider string) (models.SmartCardInfo, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if !providers[provider] {
+		return models.SmartCardInfo{}, ErrUnknownProvider
+	}
+
+	apiResponse, err := t.verifyCard(ctx, iucNumber, provider)
+	if err != nil {
+		return models.SmartCardInfo{}, t.logAndReturnError("error verifying smart card", err)
+	}
+	content := apiResponse.Content
+	if apiResponse.Code != "000" || content.Error != "" || content.Customer_Name == "" {
+		return models.SmartCardInfo{}, ErrInvalidSmartCard
+	}
+
+	// startimes and showmax do not return a renewal amount.
+	renewal, _ := content.Renewal_Amount.Float64()
+
+	return models.SmartCardInfo{
+		Provider:           provider,
+		SmartCard_Number:   iucNumber,
+		Customer_Name:      content.Customer_Name,
+		Status:             content.Status,
+		Current_Bouquet:    content.Current_Bouquet,
+		Current_Bouquet_ID: content.Current_Bouquet_Code,
+		Due_Date:           content.Due_Date,
+		Renewal_Amount:     renewal,
+	}, nil
+}
+
+func (t *TvConn) verifyCard(ctx context.Context, iucNumber, service string) (verifyResponse, error) {
 	formdata := url.Values{
 		"billersCode": {iucNumber},
 		"serviceID":   {service},
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", api, "merchant-verify")
+	url := "/merchant-verify"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
-		return false, err
+		return verifyResponse{}, err
 	}
-	req.Header.Set("api-key", pk)
-	req.Header.Set("secret-key", sk)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	log.Println(url)
-	log.Printf("%s\n%s", pk, sk)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := t.client.Do(req)
 	if err != nil {
-		return false, err
+		return verifyResponse{}, err
 	}
 	defer resp.Body.Close()
 
-	apiResponse := responses{}
+	apiResponse := verifyResponse{}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return false, err
-	}
-	code := apiResponse.Code
-	log.Println(code)
-	if code != "000" {
-		return false, errors.New("smart card number is not valid")
+		return verifyResponse{}, err
 	}
 
-	return true, nil
+	return apiResponse, nil
 }
 
-func (t *TvConn) buySub(data models.TvInfo) (*http.Response, error) {
+func (t *TvConn) buySub(ctx context.Context, data models.TvInfo) (*http.Response, error) {
 
 	amount := strconv.Itoa(data.Amount)
 
@@ -199,25 +287,25 @@ func (t *TvConn) buySub(data models.TvInfo) (*http.Response, error) {
 		"request_id":        {data.RequestID},
 		"serviceID":         {data.DecoderType},
 		"billersCode":       {data.SmartCard_Number},
-		"variation_code":    {data.Package},
 		"amount":            {amount},
 		"phone":             {data.Phone},
 		"subscription_type": {data.SubType},
 	}
+	// a renewal keeps the current bouquet, vtpass only expects a variation code when changing bouquet.
+	if data.SubType == SubTypeChange {
+		formdata.Set("variation_code", data.Package)
+	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", api, "pay")
+	url := "/pay"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("api-key", pk)
-	req.Header.Set("secret-key", sk)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := t.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -225,46 +313,42 @@ func (t *TvConn) buySub(data models.TvInfo) (*http.Response, error) {
 	return resp, nil
 }
 
-func (t *TvConn) saveTransaction(details *models.BillResult) error {
-	err := t.db.SaveTVSubcriptionTransaction(details)
+func (t *TvConn) saveTransaction(ctx context.Context, details *models.BillResult) error {
+	err := t.db.SaveTVSubcriptionTransaction(ctx, details)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *TvConn) getTransactionDetails(id string) (models.BillResult, error) {
-	result, err := t.db.GetTvSubscriptionDetails(id)
+func (t *TvConn) getTransactionDetails(ctx context.Context, userID, id string) (models.BillResult, error) {
+	result, err := t.db.GetTvSubscriptionDetails(ctx, userID, id)
 	if err != nil {
 		return models.BillResult{}, err
 	}
 	return result, nil
 }
 
-func (t *TvConn) getAllTransaction(user string) ([]models.BillResult, error) {
-	result, err := t.db.GetAllTvSubTransactions(user)
-	if err != nil {
-		return nil, err
-	}
-	return result, nil
+func (t *TvConn) getAllTransaction(ctx context.Context, opts db.ListOptions) ([]models.BillResult, string, error) {
+	return t.db.GetAllTvSubTransactions(ctx, opts)
 }
 
-func (t *TvConn) queryTransaction(requestID string) (*http.Response, error) {
+func (t *TvConn) queryTransaction(ctx context.Context, requestID string) (*http.Response, error) {
 
 	formdata := url.Values{
 		"request_id": {requestID},
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", api, "requery")
+	url := "/requery"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := t.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -274,5 +358,5 @@ func (t *TvConn) queryTransaction(requestID string) (*http.Response, error) {
 
 func (d *TvConn) logAndReturnError(errorMsg string, err error) error {
 	d.logger.Error(errorMsg, zap.Error(err))
-	return errors.New(errorMsg)
+	return fmt.Errorf("%s: %w", errorMsg, err)
 }
@@ -0,0 +1,430 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/phone"
+	"github.com/aremxyplug-be/lib/randomgen"
+	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/telcom/data"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/wallet"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	ProductAirtime = "airtime"
+	ProductData    = "data"
+
+	MaxRows = 500
+
+	defaultWorkers   = 5
+	defaultInterval  = time.Minute
+	minAirtimeAmount = 50
+
+	// interruptedAfter is how old a batch is before the rows it was buying when the service stopped are
+	// given up on, a purchase never takes this long.
+	interruptedAfter = time.Hour
+)
+
+type Options struct {
+	Store   db.DataStore
+	Wallet  *wallet.Wallet
+	Airtime *vtu.AirtimeConn
+	Data    *data.DataConn
+	// Workers is the number of purchases made at the same time, 0 uses the default.
+	Workers int
+	Logger  *zap.Logger
+}
+
+// Bulk buys airtime or data for many phone numbers in one batch.
+type Bulk struct {
+	db          db.DataStore
+	wallet      *wallet.Wallet
+	airtime     *vtu.AirtimeConn
+	data        *data.DataConn
+	idGenerator idgenerator.IdGenerator
+	workers     int
+	// wake starts processing a batch as soon as it is created rather than on the next tick
+	wake   chan struct{}
+	logger *zap.Logger
+}
+
+func NewBulk(opt *Options) *Bulk {
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	return &Bulk{
+		db:          opt.Store,
+		wallet:      opt.Wallet,
+		airtime:     opt.Airtime,
+		data:        opt.Data,
+		idGenerator: idgenerator.New(),
+		workers:     workers,
+		wake:        make(chan struct{}, 1),
+		logger:      opt.Logger,
+	}
+}
+
+// Create validates every row, reserves the total from the user's wallet and saves the batch for Run to process.
+// When a row is invalid nothing is charged and the rows are returned with ErrInvalidRows so they can be fixed.
+func (b *Bulk) Create(ctx context.Context, user *models.User, product string, rows []models.BulkRow) (models.BulkBatch, error) {
+	if product != ProductAirtime && product != ProductData {
+		return models.BulkBatch{}, ErrInvalidProduct
+	}
+	if len(rows) == 0 {
+		return models.BulkBatch{}, ErrNoRows
+	}
+	if len(rows) > MaxRows {
+		return models.BulkBatch{}, fmt.Errorf("%w: the limit is %d", ErrTooManyRows, MaxRows)
+	}
+
+	batch := models.BulkBatch{
+		ID:        b.idGenerator.Generate(),
+		UserID:    user.ID,
+		Username:  user.Username,
+		Product:   product,
+		Status:    models.BulkProcessing,
+		Rows:      rows,
+		CreatedAt: time.Now(),
+	}
+
+	invalid := false
+	for i := range batch.Rows {
+		row := &batch.Rows[i]
+		row.Row = i + 1
+		row.Status = models.RowPending
+		row.Error = ""
+		row.Reference = ""
+		row.OrderID = 0
+
+		if err := b.validate(ctx, product, row); err != nil {
+			row.Status = models.RowInvalid
+			row.Error = err.Error()
+			invalid = true
+			continue
+		}
+		batch.Total += row.Price
+	}
+	if invalid {
+		return batch, ErrInvalidRows
+	}
+
+	// every row is its own purchase, debited under its own order id
+	purchase := make([]wallet.Line, 0, len(batch.Rows))
+	for i := range batch.Rows {
+		row := &batch.Rows[i]
+		orderID, err := randomgen.GenerateOrderID()
+		if err != nil {
+			return models.BulkBatch{}, b.logAndReturnError("failed to generate order id", err)
+		}
+		row.OrderID = orderID
+		purchase = append(purchase, wallet.Line{Amount: row.Price, Movement: wallet.Movement{
+			Type: models.EntryPurchase, Reference: strconv.Itoa(orderID), Description: "Bulk " + product + " " + row.Phone,
+		}})
+	}
+
+	// the batch is saved with the debit, so a batch is never charged without being saved
+	err := b.wallet.DebitWith(ctx, user.Username, purchase, func(ctx context.Context) error {
+		if err := b.db.SaveBulkBatch(ctx, batch); err != nil {
+			return b.logAndReturnError("failed to save batch", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.BulkBatch{}, err
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return batch, nil
+}
+
+// Get returns one of the user's batches.
+func (b *Bulk) Get(ctx context.Context, id, username string) (models.BulkBatch, error) {
+	batch, err := b.db.GetBulkBatch(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.BulkBatch{}, ErrBatchNotFound
+		}
+		return models.BulkBatch{}, b.logAndReturnError("failed to get batch", err)
+	}
+	if batch.Username != username {
+		return models.BulkBatch{}, ErrBatchNotFound
+	}
+
+	return batch, nil
+}
+
+// validate checks a row and sets its price.
+func (b *Bulk) validate(ctx context.Context, product string, row *models.BulkRow) error {
+	number, err := phone.Validate(row.Phone, row.Network)
+	if err != nil {
+		return err
+	}
+	row.Phone = number.Local
+	row.Network = number.Selected
+	row.Warning = number.Warning
+	if row.Network == "" {
+		return errors.New("network is required, it could not be detected from the phone number")
+	}
+
+	switch product {
+	case ProductAirtime:
+		if _, ok := vtu.NetworkCode(row.Network); !ok {
+			return errors.New("unknown network")
+		}
+		amount, err := strconv.ParseFloat(row.Amount, 64)
+		if err != nil || amount != math.Trunc(amount) {
+			return errors.New("amount must be a whole number")
+		}
+		if amount < minAirtimeAmount {
+			return fmt.Errorf("amount must be at least %d", minAirtimeAmount)
+		}
+		row.Price = amount
+	case ProductData:
+		network, ok := plans.DontechNetworkID(row.Network)
+		if !ok {
+			return errors.New("unknown network")
+		}
+		price, err := b.data.Price(ctx, telcom.DataInfo{Network: network, Plan: row.Plan})
+		if err != nil {
+			return err
+		}
+		row.Price = price
+	}
+
+	return nil
+}
+
+// Run processes the batches that have rows left to buy when one is created and on every tick of interval,
+// until ctx is done. A batch stopped part way by a shutdown is resumed from its pending rows.
+func (b *Bulk) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// pick up the batches left by the last shutdown
+	b.resume(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.wake:
+		}
+		b.resume(ctx)
+	}
+}
+
+func (b *Bulk) resume(ctx context.Context) {
+	batches, err := b.db.GetProcessingBulkBatches(ctx)
+	if err != nil {
+		b.logger.Error("failed to get processing bulk batches", zap.Error(err))
+		return
+	}
+
+	for _, batch := range batches {
+		if ctx.Err() != nil {
+			return
+		}
+		b.process(ctx, batch)
+	}
+}
+
+// process buys the pending rows of the batch with a bounded number of workers, then completes it. When ctx
+// is done no more rows are started, the rows being bought are finished and the rest wait for the next run.
+func (b *Bulk) process(ctx context.Context, batch models.BulkBatch) {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, b.workers)
+	)
+
+	for i := range batch.Rows {
+		if batch.Rows[i].Status != models.RowPending {
+			continue
+		}
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+
+		go func(row models.BulkRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// a row claimed by another worker is left to it
+			if err := b.db.ClaimBulkRow(ctx, batch.ID, row.Row); err != nil {
+				if !errors.Is(err, mongo.ErrNoDocuments) {
+					b.logger.Error("failed to claim bulk row", zap.String("batch_id", batch.ID), zap.Int("row", row.Row), zap.Error(err))
+				}
+				return
+			}
+			// a claimed row is bought and recorded even when the worker is stopped
+			rowCtx := context.WithoutCancel(ctx)
+
+			reference, err := b.buy(rowCtx, batch, row)
+			if errors.Is(err, httpclient.ErrUnconfirmed) {
+				b.logger.Warn("bulk row purchase was not confirmed", zap.String("batch_id", batch.ID), zap.Int("row", row.Row), zap.Error(err))
+				row.Status = models.RowUnconfirmed
+				row.Error = err.Error()
+			} else if err != nil {
+				row.Status = models.RowFailed
+				row.Error = err.Error()
+			} else {
+				row.Status = models.RowSuccess
+				row.Reference = reference
+			}
+
+			if err := b.db.UpdateBulkRow(rowCtx, batch.ID, row); err != nil {
+				b.logger.Error("failed to update bulk row", zap.String("batch_id", batch.ID), zap.Int("row", row.Row), zap.Error(err))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			batch.Rows[row.Row-1] = row
+		}(batch.Rows[i])
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+	b.complete(ctx, batch, time.Now())
+}
+
+// complete refunds the failed rows of a batch once every row is bought, unconfirmed rows are not refunded.
+// Rows left processing by a worker that stopped without recording them are marked unconfirmed once the
+// batch is old enough that they are not still being bought.
+func (b *Bulk) complete(ctx context.Context, batch models.BulkBatch, now time.Time) {
+	batch.Succeeded, batch.Failed, batch.Refunded = 0, 0, 0
+	for i := range batch.Rows {
+		row := &batch.Rows[i]
+		switch row.Status {
+		case models.RowPending:
+			return
+		case models.RowProcessing:
+			if now.Sub(batch.CreatedAt) < interruptedAfter {
+				return
+			}
+			row.Status = models.RowUnconfirmed
+			row.Error = "the purchase was interrupted, contact support to confirm it"
+			if err := b.db.UpdateBulkRow(ctx, batch.ID, *row); err != nil {
+				b.logger.Error("failed to update bulk row", zap.String("batch_id", batch.ID), zap.Int("row", row.Row), zap.Error(err))
+				return
+			}
+		}
+
+		switch row.Status {
+		case models.RowSuccess:
+			batch.Succeeded++
+		case models.RowFailed:
+			batch.Failed++
+			batch.Refunded += row.Price
+		case models.RowUnconfirmed:
+			batch.Failed++
+		}
+	}
+
+	batch.Status = models.BulkCompleted
+	batch.CompletedAt = &now
+	if err := b.db.CompleteBulkBatch(ctx, batch); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			b.logger.Error("failed to complete bulk batch", zap.String("batch_id", batch.ID), zap.Error(err))
+		}
+		return
+	}
+
+	if batch.Refunded > 0 {
+		var refund []wallet.Line
+		for _, row := range batch.Rows {
+			if row.Status == models.RowFailed {
+				refund = append(refund, wallet.Line{Amount: row.Price, Movement: wallet.Movement{
+					Type: models.EntryRefund, Reference: purchaseReference(batch, row), Description: "Bulk " + batch.Product + " " + row.Phone + " failed",
+				}})
+			}
+		}
+		if err := b.wallet.CreditWith(context.WithoutCancel(ctx), batch.Username, refund, nil); err != nil {
+			b.logger.Error("failed to refund bulk batch", zap.String("batch_id", batch.ID), zap.Float64("amount", batch.Refunded), zap.Error(err))
+			return
+		}
+		for _, row := range batch.Rows {
+			if row.Status == models.RowFailed {
+				metrics.Reversed(batch.Product, row.Price)
+			}
+		}
+	}
+}
+
+// purchaseReference is the ledger reference of the debit of a row.
+func purchaseReference(batch models.BulkBatch, row models.BulkRow) string {
+	if row.OrderID == 0 {
+		return batch.ID
+	}
+	return strconv.Itoa(row.OrderID)
+}
+
+func (b *Bulk) buy(ctx context.Context, batch models.BulkBatch, row models.BulkRow) (string, error) {
+	switch batch.Product {
+	case ProductAirtime:
+		network, _ := vtu.NetworkCode(row.Network)
+		res, err := b.airtime.BuyAirtime(ctx, telcom.AirtimeInfo{
+			Network:     network,
+			Amount:      row.Amount,
+			Phone_no:    row.Phone,
+			AirtimeType: "VTU",
+			Username:    batch.Username,
+			UserID:      batch.UserID,
+			OrderID:     row.OrderID,
+		})
+		if err != nil {
+			return "", err
+		}
+		return res.TransactionID, nil
+	case ProductData:
+		network, _ := plans.DontechNetworkID(row.Network)
+		res, err := b.data.BuyData(ctx, telcom.DataInfo{
+			Network:    network,
+			Plan:       row.Plan,
+			Mobile_Num: row.Phone,
+			Username:   batch.Username,
+			UserID:     batch.UserID,
+			OrderID:    row.OrderID,
+		})
+		if err != nil {
+			return "", err
+		}
+		return res.TransactionID, nil
+	}
+
+	return "", ErrInvalidProduct
+}
+
+func (b *Bulk) logAndReturnError(errorMsg string, err error) error {
+	b.logger.Error(errorMsg, zap.Error(err))
+	return fmt.Errorf("%s: %w", errorMsg, err)
+}
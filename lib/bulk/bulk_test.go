@@ -0,0 +1,420 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/telcom/data"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the batches, data plans and balances in memory.
+type fakeStore struct {
+	db.DataStore
+	mu       sync.Mutex
+	batches  map[string]models.BulkBatch
+	balances map[string]float64
+	entries  []models.LedgerEntry
+	saveErr  error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{batches: map[string]models.BulkBatch{}, balances: map[string]float64{"nuban-ada": 0}}
+}
+
+func (f *fakeStore) GetDataPlan(_ context.Context, provider, providerCode string) (telcom.DataPlan, error) {
+	if provider != plans.ProviderDontech || providerCode != "7" {
+		return telcom.DataPlan{}, mongo.ErrNoDocuments
+	}
+	return telcom.DataPlan{ID: "dontech:7", NetworkID: 1, Price: 271}, nil
+}
+
+func (f *fakeStore) SaveAirtimeTransaction(_ context.Context, _ *telcom.AirtimeResponse) error {
+	return nil
+}
+
+func (f *fakeStore) SaveBulkBatch(_ context.Context, batch models.BulkBatch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.saveErr != nil {
+		return f.saveErr
+	}
+	batch.Rows = append([]models.BulkRow(nil), batch.Rows...)
+	f.batches[batch.ID] = batch
+	return nil
+}
+
+func (f *fakeStore) ClaimBulkRow(_ context.Context, batchID string, row int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch := f.batches[batchID]
+	if batch.Rows[row-1].Status != models.RowPending {
+		return mongo.ErrNoDocuments
+	}
+	batch.Rows[row-1].Status = models.RowProcessing
+	return nil
+}
+
+func (f *fakeStore) UpdateBulkRow(_ context.Context, batchID string, row models.BulkRow) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches[batchID].Rows[row.Row-1] = row
+	return nil
+}
+
+func (f *fakeStore) CompleteBulkBatch(_ context.Context, batch models.BulkBatch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	saved := f.batches[batch.ID]
+	if saved.Status != models.BulkProcessing {
+		return mongo.ErrNoDocuments
+	}
+	saved.Status, saved.Refunded, saved.Succeeded, saved.Failed, saved.CompletedAt =
+		batch.Status, batch.Refunded, batch.Succeeded, batch.Failed, batch.CompletedAt
+	f.batches[batch.ID] = saved
+	return nil
+}
+
+func (f *fakeStore) GetProcessingBulkBatches(_ context.Context) ([]models.BulkBatch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var res []models.BulkBatch
+	for _, batch := range f.batches {
+		if batch.Status == models.BulkProcessing {
+			batch.Rows = append([]models.BulkRow(nil), batch.Rows...)
+			res = append(res, batch)
+		}
+	}
+	return res, nil
+}
+
+// WithTransaction rolls the balances and ledger back when fn fails.
+func (f *fakeStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.mu.Lock()
+	balances := map[string]float64{}
+	for k, v := range f.balances {
+		balances[k] = v
+	}
+	entries := len(f.entries)
+	f.mu.Unlock()
+
+	err := fn(ctx)
+	if err != nil {
+		f.mu.Lock()
+		f.balances, f.entries = balances, f.entries[:entries]
+		f.mu.Unlock()
+	}
+	return err
+}
+
+func (f *fakeStore) GetVirtualNuban(_ context.Context, name string) (models.AccountDetails, error) {
+	return models.AccountDetails{User_ID: "user-" + name, VirtualAccountID: "nuban-" + name}, nil
+}
+
+func (f *fakeStore) GetBalance(_ context.Context, virtualNuban string) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.balances[virtualNuban], nil
+}
+
+func (f *fakeStore) UpdateBalance(_ context.Context, virtualNuban string, balance float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[virtualNuban] = balance
+	return nil
+}
+
+func (f *fakeStore) SaveLedgerEntry(_ context.Context, entry models.LedgerEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+// newBulk buys airtime from a fake easyaccess that fails the numbers in failing and does not answer for
+// the numbers in unanswered.
+func newBulk(t *testing.T, store *fakeStore, failing, unanswered []string) *Bulk {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		for _, number := range unanswered {
+			if r.Form.Get("mobileno") == number {
+				w.WriteHeader(http.StatusGatewayTimeout)
+				w.Write([]byte(`<html>gateway timeout</html>`))
+				return
+			}
+		}
+		for _, number := range failing {
+			if r.Form.Get("mobileno") == number {
+				w.Write([]byte(`{"success":"false","message":"transaction failed"}`))
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"success":"true","network":"MTN","airtimeamount":%s,"mobileno":%q,"status":"successful"}`,
+			r.Form.Get("amount"), r.Form.Get("mobileno"))
+	}))
+	t.Cleanup(srv.Close)
+	client := httpclient.New(&httpclient.Options{Name: t.Name(), BaseURL: srv.URL, Retries: 0, Backoff: time.Millisecond})
+
+	return NewBulk(&Options{
+		Store:   store,
+		Wallet:  wallet.NewWallet(store, zap.NewNop()),
+		Airtime: vtu.NewAirtimeConn(store, zap.NewNop(), client),
+		Data:    data.NewData(store, zap.NewNop(), nil, nil),
+		Workers: 2,
+		Logger:  zap.NewNop(),
+	})
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name      string
+		product   string
+		row       models.BulkRow
+		wantPrice float64
+		wantErr   string
+	}{
+		{
+			name:      "Test airtime with detected network",
+			product:   ProductAirtime,
+			row:       models.BulkRow{Phone: "+234 803 123 4567", Amount: "100"},
+			wantPrice: 100,
+		},
+		{
+			name:      "Test data priced from the catalogue",
+			product:   ProductData,
+			row:       models.BulkRow{Phone: "08031234567", Network: "MTN", Plan: 7},
+			wantPrice: 271,
+		},
+		{name: "Test bad phone", product: ProductAirtime, row: models.BulkRow{Phone: "0803", Amount: "100"}, wantErr: "phone"},
+		{name: "Test unknown network", product: ProductAirtime, row: models.BulkRow{Phone: "08031234567", Network: "vodafone", Amount: "100"}, wantErr: "network"},
+		{name: "Test no network detected", product: ProductAirtime, row: models.BulkRow{Phone: "07991234567", Amount: "100"}, wantErr: "network is required"},
+		{name: "Test amount with kobo", product: ProductAirtime, row: models.BulkRow{Phone: "08031234567", Amount: "100.50"}, wantErr: "whole number"},
+		{name: "Test amount below minimum", product: ProductAirtime, row: models.BulkRow{Phone: "08031234567", Amount: "20"}, wantErr: "at least 50"},
+		{name: "Test unknown plan", product: ProductData, row: models.BulkRow{Phone: "08031234567", Plan: 99}, wantErr: "plan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBulk(t, newFakeStore(), nil, nil)
+			row := tt.row
+
+			err := b.validate(context.Background(), tt.product, &row)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPrice, row.Price)
+			assert.Equal(t, "08031234567", row.Phone)
+			assert.Equal(t, "mtn", row.Network)
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	rows := []models.BulkRow{{Phone: "08031234567", Amount: "100"}, {Phone: "08031234568", Amount: "200"}}
+
+	var tests = []struct {
+		name        string
+		balance     float64
+		saveErr     error
+		wantErr     error
+		wantBalance float64
+	}{
+		{name: "Test batch saved with the debit", balance: 500, wantBalance: 200},
+		{name: "Test insufficient funds", balance: 100, wantErr: wallet.ErrInsufficientFunds, wantBalance: 100},
+		{name: "Test failed save is not charged", balance: 500, saveErr: errors.New("connection reset"), wantBalance: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.balances["nuban-ada"] = tt.balance
+			store.saveErr = tt.saveErr
+			b := newBulk(t, store, nil, nil)
+
+			batch, err := b.Create(context.Background(), &models.User{ID: "user-ada", Username: "ada"}, ProductAirtime,
+				append([]models.BulkRow(nil), rows...))
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.saveErr != nil:
+				assert.ErrorIs(t, err, tt.saveErr)
+			default:
+				require.NoError(t, err)
+				assert.Contains(t, store.batches, batch.ID)
+				// every row is debited under its own order id
+				require.Len(t, store.entries, len(batch.Rows))
+				for i, row := range batch.Rows {
+					assert.NotZero(t, row.OrderID)
+					assert.Equal(t, models.EntryPurchase, store.entries[i].Type)
+					assert.Equal(t, strconv.Itoa(row.OrderID), store.entries[i].Reference)
+					assert.Equal(t, -row.Price, store.entries[i].Amount)
+				}
+			}
+
+			assert.Equal(t, tt.wantBalance, store.balances["nuban-ada"])
+			if err != nil {
+				assert.Empty(t, store.batches)
+				assert.Empty(t, store.entries)
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	rows := []models.BulkRow{
+		{Row: 1, Phone: "08031234567", Network: "mtn", Amount: "100", Price: 100, Status: models.RowPending, OrderID: 101},
+		{Row: 2, Phone: "08031234568", Network: "mtn", Amount: "200", Price: 200, Status: models.RowPending, OrderID: 102},
+		{Row: 3, Phone: "08031234569", Network: "mtn", Amount: "300", Price: 300, Status: models.RowPending, OrderID: 103},
+	}
+
+	var tests = []struct {
+		name string
+		// failing are the numbers the provider fails, unanswered the ones it does not answer for
+		failing    []string
+		unanswered []string
+		// done is set on rows that were finished before a restart
+		done      map[int]string
+		age       time.Duration
+		cancelled bool
+		// whole batches were debited in one line under the batch id, before the rows had order ids
+		whole         bool
+		wantStatus    string
+		wantSucceeded int
+		wantFailed    int
+		wantRefunded  float64
+	}{
+		{name: "Test every row bought", wantStatus: models.BulkCompleted, wantSucceeded: 3},
+		{
+			name:          "Test failed rows refunded",
+			failing:       []string{"08031234568", "08031234569"},
+			wantStatus:    models.BulkCompleted,
+			wantSucceeded: 1,
+			wantFailed:    2,
+			wantRefunded:  500,
+		},
+		{
+			name:          "Test unconfirmed row is not refunded",
+			failing:       []string{"08031234568"},
+			unanswered:    []string{"08031234569"},
+			wantStatus:    models.BulkCompleted,
+			wantSucceeded: 1,
+			wantFailed:    2,
+			wantRefunded:  200,
+		},
+		{
+			name:          "Test resumed batch keeps finished rows",
+			failing:       []string{"08031234569"},
+			done:          map[int]string{1: models.RowFailed, 2: models.RowSuccess},
+			wantStatus:    models.BulkCompleted,
+			wantSucceeded: 1,
+			wantFailed:    2,
+			wantRefunded:  400,
+		},
+		{
+			name:          "Test batch debited as a whole refunded under its id",
+			failing:       []string{"08031234568"},
+			whole:         true,
+			wantStatus:    models.BulkCompleted,
+			wantSucceeded: 2,
+			wantFailed:    1,
+			wantRefunded:  200,
+		},
+		{
+			name:          "Test interrupted row is not refunded",
+			done:          map[int]string{1: models.RowProcessing},
+			age:           2 * time.Hour,
+			wantStatus:    models.BulkCompleted,
+			wantSucceeded: 2,
+			wantFailed:    1,
+		},
+		{
+			name:       "Test recent row left processing waits",
+			done:       map[int]string{1: models.RowProcessing},
+			wantStatus: models.BulkProcessing,
+		},
+		{name: "Test stopped before starting", cancelled: true, wantStatus: models.BulkProcessing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			b := newBulk(t, store, tt.failing, tt.unanswered)
+
+			batch := models.BulkBatch{
+				ID:        "batch-1",
+				Username:  "ada",
+				Product:   ProductAirtime,
+				Status:    models.BulkProcessing,
+				Total:     600,
+				Rows:      append([]models.BulkRow(nil), rows...),
+				CreatedAt: time.Now().Add(-tt.age),
+			}
+			for row, status := range tt.done {
+				batch.Rows[row-1].Status = status
+			}
+			if tt.whole {
+				for i := range batch.Rows {
+					batch.Rows[i].OrderID = 0
+				}
+			}
+			require.NoError(t, store.SaveBulkBatch(context.Background(), batch))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			b.resume(ctx)
+			if !tt.cancelled {
+				// a second pass finds nothing left to do and refunds nothing again
+				b.resume(ctx)
+			}
+
+			saved := store.batches["batch-1"]
+			assert.Equal(t, tt.wantStatus, saved.Status)
+			assert.Equal(t, tt.wantSucceeded, saved.Succeeded)
+			assert.Equal(t, tt.wantFailed, saved.Failed)
+			assert.Equal(t, tt.wantRefunded, saved.Refunded)
+			assert.Equal(t, tt.wantRefunded, store.balances["nuban-ada"])
+			// a failed row is refunded under the reference it was debited under
+			var wantReferences, references []string
+			for _, row := range saved.Rows {
+				if row.Status == models.RowFailed {
+					wantReferences = append(wantReferences, purchaseReference(saved, row))
+				}
+			}
+			for _, entry := range store.entries {
+				assert.Equal(t, models.EntryRefund, entry.Type)
+				references = append(references, entry.Reference)
+			}
+			assert.Equal(t, wantReferences, references)
+			if tt.whole && tt.wantRefunded > 0 {
+				assert.Equal(t, []string{"batch-1"}, references)
+			}
+			if tt.cancelled {
+				for _, row := range saved.Rows {
+					assert.Equal(t, models.RowPending, row.Status)
+				}
+			}
+		})
+	}
+}
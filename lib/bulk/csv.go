@@ -0,0 +1,114 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+// ParseCSV reads the rows of a bulk purchase from a csv file with a header row.
+// Airtime files have phone, network and amount columns, data files have phone, network and plan columns.
+func ParseCSV(r io.Reader, product string) ([]models.BulkRow, error) {
+	valueColumn := "amount"
+	if product == ProductData {
+		valueColumn = "plan"
+	}
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoRows
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		// excel adds a byte order mark to the first header
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"phone", "network", valueColumn} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing %q column", ErrInvalidCSV, name)
+		}
+	}
+
+	rows := []models.BulkRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+
+		get := func(name string) string {
+			i := columns[name]
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if get("phone") == "" && get("network") == "" && get(valueColumn) == "" {
+			continue
+		}
+
+		row := models.BulkRow{
+			Row:     len(rows) + 1,
+			Phone:   get("phone"),
+			Network: get("network"),
+		}
+		if product == ProductData {
+			// an unreadable plan is left as 0 and reported by validation
+			row.Plan, _ = strconv.Atoi(get("plan"))
+		} else {
+			row.Amount = get("amount")
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// WriteReport writes the per-row status of a batch as csv.
+func WriteReport(w io.Writer, batch models.BulkBatch) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"row", "phone", "network", "amount", "plan", "price", "status", "reference", "error"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range batch.Rows {
+		plan := ""
+		if row.Plan != 0 {
+			plan = strconv.Itoa(row.Plan)
+		}
+		record := []string{
+			strconv.Itoa(row.Row),
+			row.Phone,
+			row.Network,
+			row.Amount,
+			plan,
+			strconv.FormatFloat(row.Price, 'f', 2, 64),
+			row.Status,
+			row.Reference,
+			row.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,59 @@
+package bulk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSV(t *testing.T) {
+	var tests = []struct {
+		name    string
+		product string
+		csv     string
+		want    []models.BulkRow
+		wantErr error
+	}{
+		{
+			name:    "Test airtime",
+			product: ProductAirtime,
+			csv:     "\ufeffPhone, Network, Amount\n08031234567, mtn, 100\n\n,,\n0805 123 4567,glo,200\n",
+			want: []models.BulkRow{
+				{Row: 1, Phone: "08031234567", Network: "mtn", Amount: "100"},
+				{Row: 2, Phone: "0805 123 4567", Network: "glo", Amount: "200"},
+			},
+		},
+		{
+			name:    "Test data with columns in any order",
+			product: ProductData,
+			csv:     "plan,phone,network\n7,08031234567,mtn\nbig,08021234567,airtel\n",
+			want: []models.BulkRow{
+				{Row: 1, Phone: "08031234567", Network: "mtn", Plan: 7},
+				{Row: 2, Phone: "08021234567", Network: "airtel"},
+			},
+		},
+		{
+			name:    "Test short record",
+			product: ProductAirtime,
+			csv:     "phone,network,amount\n08031234567\n",
+			want:    []models.BulkRow{{Row: 1, Phone: "08031234567"}},
+		},
+		{name: "Test empty file", product: ProductAirtime, csv: "", wantErr: ErrNoRows},
+		{name: "Test missing column", product: ProductData, csv: "phone,network,amount\n08031234567,mtn,100\n", wantErr: ErrInvalidCSV},
+		{name: "Test bad quoting", product: ProductAirtime, csv: "phone,network,amount\n\"0803,mtn,100\n", wantErr: ErrInvalidCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseCSV(strings.NewReader(tt.csv), tt.product)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rows)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package bulk
+
+import "errors"
+
+var (
+	ErrNoRows         = errors.New("batch has no rows")
+	ErrTooManyRows    = errors.New("batch has too many rows")
+	ErrInvalidRows    = errors.New("batch has invalid rows")
+	ErrInvalidCSV     = errors.New("csv file is not valid")
+	ErrInvalidProduct = errors.New("product must be airtime or data")
+	ErrBatchNotFound  = errors.New("batch not found")
+)
@@ -0,0 +1,444 @@
+// Package dispute keeps the tickets users open against their transactions. Support works a ticket through
+// its statuses, comments on it, requeries the provider and refunds the transaction from it, and the user is
+// emailed as it moves.
+package dispute
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/requery"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	// NotificationAlias is the postmark template of the updates emailed to the user.
+	NotificationAlias = "dispute-update"
+
+	MaxAttachments    = 3
+	MaxAttachmentSize = 2 << 20
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction to dispute not found")
+	ErrInvalidCategory     = errors.New("category must be not_received, wrong_number or double_debit")
+	ErrDescriptionRequired = errors.New("dispute description is required")
+	ErrTooManyAttachments  = errors.New("a dispute takes at most 3 attachments")
+	ErrInvalidAttachment   = errors.New("attachments must be png, jpeg or pdf files of at most 2MB")
+	ErrDisputeExists       = errors.New("transaction already has an open dispute")
+	ErrDisputeNotFound     = errors.New("dispute not found")
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrInvalidStatus       = errors.New("dispute can not move to that status")
+	ErrDisputeClosed       = errors.New("dispute is closed")
+	ErrCommentRequired     = errors.New("comment is required")
+	ErrNotStaff            = errors.New("only support staff can work on any ticket")
+)
+
+var categories = map[string]bool{
+	models.DisputeNotReceived: true,
+	models.DisputeWrongNumber: true,
+	models.DisputeDoubleDebit: true,
+}
+
+// transitions lists the statuses a ticket can move to from each status.
+var transitions = map[string][]string{
+	models.DisputeOpen:       {models.DisputeInProgress, models.DisputeResolved, models.DisputeClosed},
+	models.DisputeInProgress: {models.DisputeResolved, models.DisputeClosed},
+	models.DisputeResolved:   {models.DisputeInProgress, models.DisputeClosed},
+}
+
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"application/pdf": true,
+}
+
+type Options struct {
+	Store       db.DataStore
+	Refunds     *refund.Refunds
+	Providers   *httpclient.Providers
+	EmailClient emailclient.EmailClient
+	// SLA is how long support has to resolve a ticket.
+	SLA    time.Duration
+	Logger *zap.Logger
+}
+
+// Ticket is what a user tells about the transaction they dispute.
+type Ticket struct {
+	TransactionID string `json:"transaction_id"`
+	Category      string `json:"category"`
+	Description   string `json:"description"`
+}
+
+// Attachment is a file sent with a ticket, a screenshot or a bank statement.
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// Disputes opens and works the dispute tickets.
+type Disputes struct {
+	db          db.DataStore
+	refunds     *refund.Refunds
+	providers   *httpclient.Providers
+	emailClient emailclient.EmailClient
+	sla         time.Duration
+	idGenerator idgenerator.IdGenerator
+	logger      *zap.Logger
+}
+
+func NewDisputes(opt *Options) *Disputes {
+	return &Disputes{
+		db:          opt.Store,
+		refunds:     opt.Refunds,
+		providers:   opt.Providers,
+		emailClient: opt.EmailClient,
+		sla:         opt.SLA,
+		idGenerator: idgenerator.New(),
+		logger:      opt.Logger,
+	}
+}
+
+// Open opens a ticket against one of the user's transactions, the SLA starts running from now.
+func (d *Disputes) Open(ctx context.Context, userID string, ticket Ticket, attachments []Attachment) (models.Dispute, error) {
+	if !categories[ticket.Category] {
+		return models.Dispute{}, ErrInvalidCategory
+	}
+	if strings.TrimSpace(ticket.Description) == "" {
+		return models.Dispute{}, ErrDescriptionRequired
+	}
+	if len(attachments) > MaxAttachments {
+		return models.Dispute{}, ErrTooManyAttachments
+	}
+
+	txn, err := d.db.GetTransaction(ctx, userID, ticket.TransactionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Dispute{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return models.Dispute{}, d.logAndReturnError("failed to get disputed transaction", err)
+	}
+	t, err := requery.TargetOf(txn)
+	if err != nil {
+		// not a product the disputes know
+		return models.Dispute{}, ErrTransactionNotFound
+	}
+
+	now := time.Now().UTC()
+	dispute := models.Dispute{
+		ID:            d.idGenerator.Generate(),
+		UserID:        userID,
+		TransactionID: ticket.TransactionID,
+		Product:       t.Product,
+		Category:      ticket.Category,
+		Description:   ticket.Description,
+		Status:        models.DisputeOpen,
+		Attachments:   []models.DisputeAttachment{},
+		Events:        []models.DisputeEvent{},
+		Active:        ticket.TransactionID,
+		DueAt:         now.Add(d.sla),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	files := make([]models.DisputeFile, 0, len(attachments))
+	for _, attachment := range attachments {
+		contentType := http.DetectContentType(attachment.Data)
+		if len(attachment.Data) > MaxAttachmentSize || !attachmentTypes[contentType] {
+			return models.Dispute{}, ErrInvalidAttachment
+		}
+
+		file := models.DisputeFile{
+			ID:          d.idGenerator.Generate(),
+			DisputeID:   dispute.ID,
+			Name:        attachment.Name,
+			ContentType: contentType,
+			Data:        attachment.Data,
+			CreatedAt:   now,
+		}
+		files = append(files, file)
+		dispute.Attachments = append(dispute.Attachments, models.DisputeAttachment{
+			ID:          file.ID,
+			Name:        file.Name,
+			ContentType: file.ContentType,
+			Size:        len(file.Data),
+		})
+	}
+
+	err = d.db.CreateDispute(ctx, dispute, files)
+	if errors.Is(err, db.ErrDuplicate) {
+		return models.Dispute{}, ErrDisputeExists
+	}
+	if err != nil {
+		return models.Dispute{}, d.logAndReturnError("failed to save dispute", err)
+	}
+
+	d.notify(ctx, dispute, "We have received your complaint and will get back to you by "+dispute.DueAt.Format(time.RFC1123)+".")
+	return withSLA(dispute, now), nil
+}
+
+// Get returns a ticket, only the user's own when userID is given.
+func (d *Disputes) Get(ctx context.Context, userID, id string) (models.Dispute, error) {
+	dispute, err := d.db.GetDispute(ctx, userID, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Dispute{}, ErrDisputeNotFound
+	}
+	if err != nil {
+		return models.Dispute{}, d.logAndReturnError("failed to get dispute", err)
+	}
+	return withSLA(dispute, time.Now()), nil
+}
+
+// List returns a page of the tickets, the status and user of opts narrow it.
+func (d *Disputes) List(ctx context.Context, opts db.ListOptions) ([]models.Dispute, string, error) {
+	disputes, next, err := d.db.GetDisputes(ctx, opts)
+	if err != nil {
+		return nil, "", d.logAndReturnError("failed to get disputes", err)
+	}
+
+	now := time.Now()
+	for i := range disputes {
+		disputes[i] = withSLA(disputes[i], now)
+	}
+	return disputes, next, nil
+}
+
+// Attachment returns the content of an attachment of a ticket, only of the user's own when userID is given.
+func (d *Disputes) Attachment(ctx context.Context, userID, id, attachmentID string) (models.DisputeFile, error) {
+	if _, err := d.Get(ctx, userID, id); err != nil {
+		return models.DisputeFile{}, err
+	}
+
+	file, err := d.db.GetDisputeFile(ctx, id, attachmentID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.DisputeFile{}, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return models.DisputeFile{}, d.logAndReturnError("failed to get dispute attachment", err)
+	}
+	return file, nil
+}
+
+// Comment adds a comment of actor to a ticket that is not closed. A user comments on their own tickets,
+// staff comments as support on any and the user is emailed the comments of support.
+func (d *Disputes) Comment(ctx context.Context, actor *models.User, id string, staff bool, body string) (models.Dispute, error) {
+	owner := actor.ID
+	if staff {
+		if !actor.IsStaff() {
+			return models.Dispute{}, ErrNotStaff
+		}
+		owner = ""
+	}
+	if strings.TrimSpace(body) == "" {
+		return models.Dispute{}, ErrCommentRequired
+	}
+	dispute, err := d.Get(ctx, owner, id)
+	if err != nil {
+		return models.Dispute{}, err
+	}
+
+	event := models.DisputeEvent{Type: models.DisputeEventComment, Actor: actor.Username, Staff: staff, Note: body, At: time.Now().UTC()}
+	dispute, err = d.addEvent(ctx, dispute, "", event)
+	if err != nil {
+		return models.Dispute{}, err
+	}
+
+	if staff {
+		d.notify(ctx, dispute, body)
+	}
+	return dispute, nil
+}
+
+// SetStatus moves a ticket to status, following the transitions. Only staff moves a ticket.
+func (d *Disputes) SetStatus(ctx context.Context, actor *models.User, id, status, note string) (models.Dispute, error) {
+	if !actor.IsStaff() {
+		return models.Dispute{}, ErrNotStaff
+	}
+	dispute, err := d.Get(ctx, "", id)
+	if err != nil {
+		return models.Dispute{}, err
+	}
+	if dispute.Status == models.DisputeClosed {
+		return models.Dispute{}, ErrDisputeClosed
+	}
+	if !allowed(dispute.Status, status) {
+		return models.Dispute{}, ErrInvalidStatus
+	}
+
+	event := models.DisputeEvent{Type: models.DisputeEventStatus, Actor: actor.Username, Staff: true, Status: status, Note: note, At: time.Now().UTC()}
+	dispute, err = d.addEvent(ctx, dispute, dispute.Status, event)
+	if err != nil {
+		return models.Dispute{}, err
+	}
+
+	d.notify(ctx, dispute, note)
+	return dispute, nil
+}
+
+// Requery asks the provider for the status of the disputed transaction and records the answer on the ticket.
+// Only staff requeries a ticket.
+func (d *Disputes) Requery(ctx context.Context, actor *models.User, id string) (models.Dispute, requery.Result, error) {
+	if !actor.IsStaff() {
+		return models.Dispute{}, requery.Result{}, ErrNotStaff
+	}
+	dispute, err := d.Get(ctx, "", id)
+	if err != nil {
+		return models.Dispute{}, requery.Result{}, err
+	}
+
+	txn, err := d.db.GetTransaction(ctx, dispute.UserID, dispute.TransactionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Dispute{}, requery.Result{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return models.Dispute{}, requery.Result{}, d.logAndReturnError("failed to get disputed transaction", err)
+	}
+	t, err := requery.TargetOf(txn)
+	if err != nil {
+		return models.Dispute{}, requery.Result{}, ErrTransactionNotFound
+	}
+
+	result, err := requery.Query(ctx, d.providers, t)
+	if err != nil {
+		return models.Dispute{}, requery.Result{}, err
+	}
+
+	event := models.DisputeEvent{Type: models.DisputeEventRequery, Actor: actor.Username, Staff: true, Note: result.Note(), At: time.Now().UTC()}
+	dispute, err = d.addEvent(ctx, dispute, "", event)
+	if err != nil {
+		return models.Dispute{}, requery.Result{}, err
+	}
+	return dispute, result, nil
+}
+
+// Refund requests a refund of the disputed transaction, of all of it when amount is 0. The refund goes
+// through the approval of the refunds like the ones support requests directly. Only staff refunds a ticket.
+func (d *Disputes) Refund(ctx context.Context, actor *models.User, id string, amount float64, note string) (models.Dispute, models.Refund, error) {
+	if !actor.IsStaff() {
+		return models.Dispute{}, models.Refund{}, ErrNotStaff
+	}
+	dispute, err := d.Get(ctx, "", id)
+	if err != nil {
+		return models.Dispute{}, models.Refund{}, err
+	}
+	if dispute.Status == models.DisputeClosed {
+		return models.Dispute{}, models.Refund{}, ErrDisputeClosed
+	}
+
+	reason := "Dispute " + dispute.ID + ": " + strings.ReplaceAll(dispute.Category, "_", " ")
+	if note != "" {
+		reason += ", " + note
+	}
+	result, err := d.refunds.Request(ctx, refund.Request{
+		UserID:        dispute.UserID,
+		TransactionID: dispute.TransactionID,
+		Amount:        amount,
+		Reason:        reason,
+		Source:        models.RefundSourceSupport,
+		RequestedBy:   actor.Username,
+	})
+	// a refund that could not be credited is still recorded, support follows it up from the ticket
+	if err != nil && !errors.Is(err, refund.ErrCreditFailed) {
+		return models.Dispute{}, models.Refund{}, err
+	}
+
+	event := models.DisputeEvent{
+		Type:     models.DisputeEventRefund,
+		Actor:    actor.Username,
+		Staff:    true,
+		Note:     "refund of " + dispute.TransactionID + " " + strings.ReplaceAll(result.Status, "_", " "),
+		RefundID: result.ID,
+		At:       time.Now().UTC(),
+	}
+	dispute, eventErr := d.addEvent(ctx, dispute, "", event)
+	if eventErr != nil {
+		return models.Dispute{}, result, eventErr
+	}
+	return dispute, result, err
+}
+
+// addEvent records event on the ticket and returns the ticket as it is now.
+func (d *Disputes) addEvent(ctx context.Context, dispute models.Dispute, from string, event models.DisputeEvent) (models.Dispute, error) {
+	err := d.db.AddDisputeEvent(ctx, dispute.ID, from, event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// closed or moved by someone else since it was read
+		current, getErr := d.Get(ctx, "", dispute.ID)
+		if getErr != nil {
+			return models.Dispute{}, getErr
+		}
+		if current.Status == models.DisputeClosed {
+			return models.Dispute{}, ErrDisputeClosed
+		}
+		return models.Dispute{}, ErrInvalidStatus
+	}
+	if err != nil {
+		return models.Dispute{}, d.logAndReturnError("failed to update dispute", err)
+	}
+
+	return d.Get(ctx, "", dispute.ID)
+}
+
+// notify emails the user an update of their ticket. A failed email is logged, the update stands.
+func (d *Disputes) notify(ctx context.Context, dispute models.Dispute, message string) {
+	user, err := d.db.GetUserByID(ctx, dispute.UserID)
+	if err != nil || user.Email == "" {
+		return
+	}
+
+	name := user.FullName
+	if name == "" {
+		name = user.Username
+	}
+	email := models.Message{
+		ID:         d.idGenerator.Generate(),
+		Target:     user.Email,
+		Type:       models.EMAIL_MESSAGE_TYPE,
+		Title:      "Update on your complaint " + dispute.ID,
+		TemplateID: NotificationAlias,
+		DataMap: map[string]string{
+			"Name":          name,
+			"DisputeID":     dispute.ID,
+			"TransactionID": dispute.TransactionID,
+			"Status":        strings.ReplaceAll(dispute.Status, "_", " "),
+			"Message":       message,
+		},
+		Ts: time.Now().Unix(),
+	}
+	if err := d.emailClient.Send(&email); err != nil {
+		d.logger.Error("failed to send dispute update", zap.String("dispute_id", dispute.ID), zap.Error(err))
+	}
+}
+
+func (d *Disputes) logAndReturnError(msg string, err error) error {
+	d.logger.Error(msg, zap.Error(err))
+	return err
+}
+
+func allowed(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// withSLA sets whether the ticket missed its SLA, by when it was resolved or, while it is open, by now.
+func withSLA(dispute models.Dispute, now time.Time) models.Dispute {
+	end := now
+	if dispute.ResolvedAt != nil {
+		end = *dispute.ResolvedAt
+	}
+	dispute.SLABreached = end.After(dispute.DueAt)
+	return dispute
+}
@@ -0,0 +1,310 @@
+package dispute
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/requery"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+var (
+	ada     = &models.User{ID: "user-1", Username: "ada"}
+	eve     = &models.User{ID: "user-2", Username: "eve"}
+	support = &models.User{ID: "staff-1", Username: "support", Role: models.RoleSupport}
+)
+
+type fakeStore struct {
+	db.DataStore
+	disputes map[string]models.Dispute
+	files    map[string]models.DisputeFile
+	refunds  []models.Refund
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{disputes: map[string]models.Dispute{}, files: map[string]models.DisputeFile{}}
+}
+
+func (f *fakeStore) GetTransaction(_ context.Context, userID, id string) (interface{}, error) {
+	if userID != "user-1" || id != "10" {
+		return nil, mongo.ErrNoDocuments
+	}
+	return telcom.AirtimeResponse{Amount: "500", UserID: userID, OrderID: 10, ReferenceNumber: "EA-10"}, nil
+}
+
+func (f *fakeStore) CreateDispute(_ context.Context, dispute models.Dispute, files []models.DisputeFile) error {
+	for _, existing := range f.disputes {
+		if existing.Active != "" && existing.Active == dispute.Active {
+			return db.ErrDuplicate
+		}
+	}
+	f.disputes[dispute.ID] = dispute
+	for _, file := range files {
+		f.files[file.ID] = file
+	}
+	return nil
+}
+
+func (f *fakeStore) GetDispute(_ context.Context, userID, id string) (models.Dispute, error) {
+	dispute, ok := f.disputes[id]
+	if !ok || (userID != "" && dispute.UserID != userID) {
+		return models.Dispute{}, mongo.ErrNoDocuments
+	}
+	return dispute, nil
+}
+
+func (f *fakeStore) GetDisputeFile(_ context.Context, disputeID, id string) (models.DisputeFile, error) {
+	file, ok := f.files[id]
+	if !ok || file.DisputeID != disputeID {
+		return models.DisputeFile{}, mongo.ErrNoDocuments
+	}
+	return file, nil
+}
+
+func (f *fakeStore) AddDisputeEvent(_ context.Context, id, from string, event models.DisputeEvent) error {
+	dispute, ok := f.disputes[id]
+	if !ok || dispute.Status == models.DisputeClosed || (from != "" && dispute.Status != from) {
+		return mongo.ErrNoDocuments
+	}
+	dispute.Events = append(append([]models.DisputeEvent{}, dispute.Events...), event)
+	if event.RefundID != "" {
+		dispute.RefundID = event.RefundID
+	}
+	if event.Type == models.DisputeEventStatus {
+		dispute.Status = event.Status
+		switch event.Status {
+		case models.DisputeResolved, models.DisputeClosed:
+			if dispute.ResolvedAt == nil {
+				at := event.At
+				dispute.ResolvedAt = &at
+			}
+		default:
+			dispute.ResolvedAt = nil
+		}
+		if event.Status == models.DisputeClosed {
+			dispute.Active = ""
+		}
+	}
+	f.disputes[id] = dispute
+	return nil
+}
+
+func (f *fakeStore) GetLedgerEntriesByReference(_ context.Context, userID, reference string) ([]models.LedgerEntry, error) {
+	return []models.LedgerEntry{{UserID: userID, Type: models.EntryPurchase, Reference: reference, Amount: -500}}, nil
+}
+
+func (f *fakeStore) CreateRefund(_ context.Context, refund models.Refund) error {
+	f.refunds = append(f.refunds, refund)
+	return nil
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	return &models.User{ID: id, Username: "ada", Email: "ada@example.com"}, nil
+}
+
+type fakeEmail struct {
+	sent []models.Message
+}
+
+func (f *fakeEmail) Send(email *models.Message) error {
+	f.sent = append(f.sent, *email)
+	return nil
+}
+
+func newDisputes(store *fakeStore, email *fakeEmail, providers *httpclient.Providers) *Disputes {
+	return NewDisputes(&Options{
+		Store: store,
+		// every refund waits for approval, no wallet is touched
+		Refunds:     refund.NewRefunds(&refund.Options{Store: store, EmailClient: email, Logger: zap.NewNop()}),
+		Providers:   providers,
+		EmailClient: email,
+		SLA:         48 * time.Hour,
+		Logger:      zap.NewNop(),
+	})
+}
+
+func TestOpen(t *testing.T) {
+	valid := Ticket{TransactionID: "10", Category: models.DisputeNotReceived, Description: "airtime never came"}
+
+	var tests = []struct {
+		name        string
+		userID      string
+		ticket      Ticket
+		attachments []Attachment
+		wantErr     error
+	}{
+		{name: "Test open with screenshot", userID: "user-1", ticket: valid, attachments: []Attachment{{Name: "shot.png", Data: png}}},
+		{name: "Test invalid category", userID: "user-1", ticket: Ticket{TransactionID: "10", Category: "angry", Description: "x"}, wantErr: ErrInvalidCategory},
+		{name: "Test no description", userID: "user-1", ticket: Ticket{TransactionID: "10", Category: models.DisputeDoubleDebit}, wantErr: ErrDescriptionRequired},
+		{name: "Test transaction of another user", userID: "user-2", ticket: valid, wantErr: ErrTransactionNotFound},
+		{name: "Test attachment not an image", userID: "user-1", ticket: valid, attachments: []Attachment{{Name: "a.exe", Data: []byte("MZ\x90\x00")}}, wantErr: ErrInvalidAttachment},
+		{name: "Test too many attachments", userID: "user-1", ticket: valid, attachments: make([]Attachment, MaxAttachments+1), wantErr: ErrTooManyAttachments},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, email := newFakeStore(), &fakeEmail{}
+			d := newDisputes(store, email, nil)
+
+			got, err := d.Open(context.Background(), tt.userID, tt.ticket, tt.attachments)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Empty(t, store.disputes)
+				return
+			}
+
+			assert.Equal(t, models.DisputeOpen, got.Status)
+			assert.Equal(t, "airtime", got.Product)
+			assert.WithinDuration(t, got.CreatedAt.Add(48*time.Hour), got.DueAt, time.Second)
+			assert.False(t, got.SLABreached)
+			if assert.Len(t, got.Attachments, 1) {
+				assert.Equal(t, "image/png", got.Attachments[0].ContentType)
+				file, err := d.Attachment(context.Background(), tt.userID, got.ID, got.Attachments[0].ID)
+				assert.NoError(t, err)
+				assert.Equal(t, png, file.Data)
+				_, err = d.Attachment(context.Background(), "user-2", got.ID, got.Attachments[0].ID)
+				assert.ErrorIs(t, err, ErrDisputeNotFound)
+			}
+			assert.Len(t, email.sent, 1)
+
+			_, err = d.Open(context.Background(), tt.userID, tt.ticket, nil)
+			assert.ErrorIs(t, err, ErrDisputeExists)
+		})
+	}
+}
+
+func TestWorkTicket(t *testing.T) {
+	store, email := newFakeStore(), &fakeEmail{}
+	d := newDisputes(store, email, nil)
+	ctx := context.Background()
+
+	ticket, err := d.Open(ctx, "user-1", Ticket{TransactionID: "10", Category: models.DisputeWrongNumber, Description: "sent to 0803"}, nil)
+	assert.NoError(t, err)
+
+	_, err = d.Comment(ctx, eve, ticket.ID, false, "mine too")
+	assert.ErrorIs(t, err, ErrDisputeNotFound)
+	_, err = d.Comment(ctx, ada, ticket.ID, false, "it was 0806")
+	assert.NoError(t, err)
+	_, err = d.Comment(ctx, support, ticket.ID, true, "checking with the network")
+	assert.NoError(t, err)
+	// the open email and the comment of support, not the user's own comment
+	assert.Len(t, email.sent, 2)
+
+	_, err = d.SetStatus(ctx, support, ticket.ID, models.DisputeOpen, "")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	ticket, refunded, err := d.Refund(ctx, support, ticket.ID, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundPending, refunded.Status)
+	assert.Equal(t, 500.0, refunded.Amount)
+	assert.Equal(t, refunded.ID, ticket.RefundID)
+
+	ticket, err = d.SetStatus(ctx, support, ticket.ID, models.DisputeResolved, "refunded")
+	assert.NoError(t, err)
+	assert.NotNil(t, ticket.ResolvedAt)
+	assert.False(t, ticket.SLABreached)
+
+	ticket, err = d.SetStatus(ctx, support, ticket.ID, models.DisputeClosed, "")
+	assert.NoError(t, err)
+	var types []string
+	for _, event := range ticket.Events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		models.DisputeEventComment, models.DisputeEventComment, models.DisputeEventRefund,
+		models.DisputeEventStatus, models.DisputeEventStatus,
+	}, types)
+
+	_, err = d.Comment(ctx, ada, ticket.ID, false, "thanks")
+	assert.ErrorIs(t, err, ErrDisputeClosed)
+	_, err = d.SetStatus(ctx, support, ticket.ID, models.DisputeInProgress, "")
+	assert.ErrorIs(t, err, ErrDisputeClosed)
+
+	// a closed ticket frees the transaction for a new one
+	_, err = d.Open(ctx, "user-1", Ticket{TransactionID: "10", Category: models.DisputeDoubleDebit, Description: "charged twice"}, nil)
+	assert.NoError(t, err)
+}
+
+func TestNotStaff(t *testing.T) {
+	store, email := newFakeStore(), &fakeEmail{}
+	d := newDisputes(store, email, nil)
+	ctx := context.Background()
+
+	ticket, err := d.Open(ctx, "user-1", Ticket{TransactionID: "10", Category: models.DisputeNotReceived, Description: "nothing"}, nil)
+	assert.NoError(t, err)
+
+	// not even on their own ticket
+	_, err = d.Comment(ctx, ada, ticket.ID, true, "refund me")
+	assert.ErrorIs(t, err, ErrNotStaff)
+	_, err = d.SetStatus(ctx, ada, ticket.ID, models.DisputeResolved, "")
+	assert.ErrorIs(t, err, ErrNotStaff)
+	_, _, err = d.Requery(ctx, eve, ticket.ID)
+	assert.ErrorIs(t, err, ErrNotStaff)
+	_, _, err = d.Refund(ctx, eve, ticket.ID, 0, "")
+	assert.ErrorIs(t, err, ErrNotStaff)
+
+	assert.Empty(t, store.refunds)
+	assert.Empty(t, store.disputes[ticket.ID].Events)
+}
+
+func TestSLABreached(t *testing.T) {
+	due := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	late := due.Add(time.Hour)
+	early := due.Add(-time.Hour)
+
+	var tests = []struct {
+		name     string
+		resolved *time.Time
+		now      time.Time
+		want     bool
+	}{
+		{name: "Test open within sla", now: early},
+		{name: "Test open past sla", now: late, want: true},
+		{name: "Test resolved in time", resolved: &early, now: late},
+		{name: "Test resolved late", resolved: &late, now: late, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withSLA(models.Dispute{DueAt: due, ResolvedAt: tt.resolved}, tt.now)
+			assert.Equal(t, tt.want, got.SLABreached)
+		})
+	}
+}
+
+func TestRequery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/query_transaction.php", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "EA-10", r.PostForm.Get("reference"))
+		w.Write([]byte(`{"success":"true","status":"Successful","message":"delivered to 08030000000"}`))
+	}))
+	defer srv.Close()
+
+	store := newFakeStore()
+	providers := &httpclient.Providers{EasyAccess: httpclient.New(&httpclient.Options{Name: "easyaccess", BaseURL: srv.URL})}
+	d := newDisputes(store, &fakeEmail{}, providers)
+	ctx := context.Background()
+
+	ticket, err := d.Open(ctx, "user-1", Ticket{TransactionID: "10", Category: models.DisputeNotReceived, Description: "nothing"}, nil)
+	assert.NoError(t, err)
+
+	ticket, got, err := d.Requery(ctx, support, ticket.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, requery.Result{Provider: "easyaccess", Reference: "EA-10", Status: "Successful", Description: "delivered to 08030000000"}, got)
+	if assert.Len(t, ticket.Events, 1) {
+		assert.Equal(t, "easyaccess reports EA-10 as successful: delivered to 08030000000", ticket.Events[0].Note)
+	}
+}
@@ -2,11 +2,13 @@ package postmark
 
 import (
 	"errors"
+	"time"
 
 	"github.com/aremxyplug-be/config"
 	"github.com/aremxyplug-be/db/models"
 	"github.com/aremxyplug-be/lib/emailclient"
 	"github.com/aremxyplug-be/lib/logger"
+	"github.com/aremxyplug-be/lib/metrics"
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
@@ -21,7 +23,7 @@ var _ emailclient.EmailClient = (*emailClient)(nil)
 
 type emailClient struct {
 	RESTClient *resty.Client
-	secret     *config.Secrets
+	config     config.Postmark
 }
 
 // Send generate and send a new email message using postmark API
@@ -36,7 +38,7 @@ func (e *emailClient) Send(message *models.Message) error {
 		TemplateModel: map[string]interface{}{
 			"Data": message.DataMap,
 		},
-		From: e.secret.PlatformEmail,
+		From: e.config.PlatformEmail,
 		To:   message.Target,
 	}
 
@@ -55,11 +57,17 @@ func (e *emailClient) Send(message *models.Message) error {
 	// Execute call to postmark API
 	var result EmailWithTemplateResponse
 	var errorResponse ErrorResponse
+	start := time.Now()
 	response, err := e.RESTClient.R().
 		SetBody(request).
 		SetResult(&result).
 		SetError(&errorResponse).
 		Post(sendEmailWithTemplateEndpoint)
+	status := 0
+	if response != nil {
+		status = response.StatusCode()
+	}
+	metrics.ObserveProvider("postmark", metrics.ProviderOutcome(status, err), time.Since(start))
 	if err != nil {
 		return err
 	}
@@ -80,19 +88,19 @@ func (e *emailClient) Send(message *models.Message) error {
 }
 
 // New return a new instance of a Postmark definition for EmailClient interface
-func New(secrets *config.Secrets) emailclient.EmailClient {
+func New(cfg config.Postmark) emailclient.EmailClient {
 	// Build REST client
 	restClient := resty.New()
 	restClient.SetBaseURL(postmarkAPIURL)
 	restClient.SetHeader("Content-Type", "application/json")
 	restClient.SetHeader("Accept", "application/json")
-	restClient.SetHeader("X-Postmark-Server-Token", secrets.PostmarkKey)
+	restClient.SetHeader("X-Postmark-Server-Token", cfg.Key)
 	restClient.SetDebug(true)
 
 	// Define service attributes
 	emailClient := emailClient{
 		RESTClient: restClient,
-		secret:     secrets,
+		config:     cfg,
 	}
 
 	return &emailClient
@@ -15,6 +15,15 @@ type Terror struct {
 	traceID   string
 	instance  string
 	help      string
+	fields    []FieldError
+	cause     error
+}
+
+// FieldError defines a failed validation rule on a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 // Code getter
@@ -57,6 +66,16 @@ func (t *Terror) Help() string {
 	return t.help
 }
 
+// Fields getter
+func (t *Terror) Fields() []FieldError {
+	return t.fields
+}
+
+// Unwrap returns the error the Terror was created from, it is not part of the json representation
+func (t *Terror) Unwrap() error {
+	return t.cause
+}
+
 // terrorJSONModel represents the json sharable model of a Roava Terror
 // for internal use only
 type terrorJSONModel struct {
@@ -64,14 +83,15 @@ type terrorJSONModel struct {
 }
 
 type terrorError struct {
-	Code      int    `json:"code"`
-	ErrorType string `json:"type"`
-	Message   string `json:"message"`
-	Status    int    `json:"status,omitempty"`
-	Detail    string `json:"detail"`
-	TraceID   string `json:"trace_id,omitempty"`
-	Instance  string `json:"instance,omitempty"`
-	Help      string `json:"help,omitempty"`
+	Code      int          `json:"code"`
+	ErrorType string       `json:"type"`
+	Message   string       `json:"message"`
+	Status    int          `json:"status,omitempty"`
+	Detail    string       `json:"detail"`
+	TraceID   string       `json:"trace_id,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Help      string       `json:"help,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
 }
 
 // Error returns a json string representation of the Roava Terror
@@ -87,6 +107,7 @@ func (t *Terror) Error() string {
 			TraceID:   t.traceID,
 			Instance:  t.instance,
 			Help:      t.help,
+			Fields:    t.fields,
 		},
 	})
 
@@ -135,5 +156,6 @@ func NewTerrorFromJSONString(jsonString string) (*Terror, error) {
 		traceID:   terrorJSON.TraceID,
 		instance:  terrorJSON.Instance,
 		help:      terrorJSON.Help,
+		fields:    terrorJSON.Fields,
 	}, nil
 }
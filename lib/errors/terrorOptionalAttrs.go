@@ -27,3 +27,17 @@ func WithHelp(help string) TerrorOptionalAttrs {
 		t.help = help
 	}
 }
+
+// WithFields Terror optional attribute
+func WithFields(fields ...FieldError) TerrorOptionalAttrs {
+	return func(t *Terror) {
+		t.fields = fields
+	}
+}
+
+// WithCause Terror optional attribute
+func WithCause(err error) TerrorOptionalAttrs {
+	return func(t *Terror) {
+		t.cause = err
+	}
+}
@@ -1,6 +1,8 @@
 package errors
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -67,6 +69,17 @@ func TestTerror_Error(t *testing.T) {
 			),
 			want: "{\"error\":{\"code\":7001,\"type\":\"InvalidPhoneNumberException\",\"message\":\"Provided phone number is already attached to Roava account\",\"detail\":\"This phone number is already attached to a Roava account. Kindly recheck the phone number or logon to continue\",\"help\":\"http://somehelpfulwebsite.com\"}}",
 		},
+		{
+			name: "Test with fields",
+			terror: NewTerror(
+				7001,
+				"InvalidPhoneNumberException",
+				"Provided phone number is already attached to Roava account",
+				"This phone number is already attached to a Roava account. Kindly recheck the phone number or logon to continue",
+				WithFields(FieldError{Field: "phone_number", Rule: "required", Message: "phone_number is required"}),
+			),
+			want: "{\"error\":{\"code\":7001,\"type\":\"InvalidPhoneNumberException\",\"message\":\"Provided phone number is already attached to Roava account\",\"detail\":\"This phone number is already attached to a Roava account. Kindly recheck the phone number or logon to continue\",\"fields\":[{\"field\":\"phone_number\",\"rule\":\"required\",\"message\":\"phone_number is required\"}]}}",
+		},
 		{
 			name: "Test with all attrs",
 			terror: NewTerror(
@@ -141,3 +154,12 @@ func TestNewTerrorFromJSONString(t *testing.T) {
 		})
 	}
 }
+
+func TestTerror_Unwrap(t *testing.T) {
+	cause := fmt.Errorf("finding user: %w", context.DeadlineExceeded)
+	terror := NewTerror(7420, "TimeoutErr", "the request took too long to complete", cause.Error(), WithCause(cause))
+
+	assert.ErrorIs(t, terror, context.DeadlineExceeded)
+	assert.NotContains(t, terror.Error(), "cause")
+	assert.Nil(t, NewTerror(7000, "InternalServerError", "", "").Unwrap())
+}
@@ -2,6 +2,8 @@ package errorvalues
 
 import (
 	"fmt"
+	"net/http"
+
 	"github.com/aremxyplug-be/lib/errors"
 )
 
@@ -20,8 +22,19 @@ const (
 	DuplicatedCustomerEmailError      = 7411
 	AuthenthicationFailedErr          = 7412
 	InvalidTokenErr                   = 7413
+	InsufficientFundsErr              = 7414
+	ProviderErr                       = 7415
+	KYCLimitErr                       = 7416
+	ConflictErr                       = 7417
+	ValidationErr                     = 7418
+	RequestCanceledErr                = 7419
+	TimeoutErr                        = 7420
+	ForbiddenErr                      = 7421
 )
 
+// statusClientClosedRequest is the non standard status used when the client goes away before the response.
+const statusClientClosedRequest = 499
+
 var (
 	errorTypes = map[int]string{
 		DatabaseError:                     "DatabaseError",
@@ -35,6 +48,16 @@ var (
 		DuplicateCustomerPhoneNumberError: "DuplicateCustomerPhoneNumberError",
 		InvalidPhoneNumberError:           "InvalidPhoneNumberError",
 		DuplicatedCustomerEmailError:      "DuplicatedCustomerEmailError",
+		AuthenthicationFailedErr:          "AuthenthicationFailedErr",
+		InvalidTokenErr:                   "InvalidTokenErr",
+		InsufficientFundsErr:              "InsufficientFundsErr",
+		ProviderErr:                       "ProviderErr",
+		KYCLimitErr:                       "KYCLimitErr",
+		ConflictErr:                       "ConflictErr",
+		ValidationErr:                     "ValidationErr",
+		RequestCanceledErr:                "RequestCanceledErr",
+		TimeoutErr:                        "TimeoutErr",
+		ForbiddenErr:                      "ForbiddenErr",
 	}
 
 	errorMessages = map[int]string{
@@ -49,6 +72,40 @@ var (
 		DuplicateCustomerPhoneNumberError: "phone number is already registered on roava, please input a different phone number",
 		InvalidPhoneNumberError:           "please enter a valid phone number",
 		DuplicatedCustomerEmailError:      "email is registered with an existing rova customer",
+		AuthenthicationFailedErr:          "authentication failed",
+		InvalidTokenErr:                   "token is invalid or has expired",
+		InsufficientFundsErr:              "insufficient balance to carry out the transaction",
+		ProviderErr:                       "the service provider could not complete the request at this time. Please retry",
+		KYCLimitErr:                       "transaction is above the limit for your verification level, complete your KYC to increase it",
+		ConflictErr:                       "request conflicts with the current state of the resource",
+		ValidationErr:                     "one or more fields are invalid",
+		RequestCanceledErr:                "the request was cancelled before it completed",
+		TimeoutErr:                        "the request took too long to complete. Please retry",
+		ForbiddenErr:                      "you are not allowed to carry out this request",
+	}
+
+	errorStatuses = map[int]int{
+		DatabaseError:                     http.StatusInternalServerError,
+		DatabaseNotFoundError:             http.StatusNotFound,
+		InvalidAuthenticationError:        http.StatusUnauthorized,
+		PulsarError:                       http.StatusInternalServerError,
+		InvalidRequestErr:                 http.StatusBadRequest,
+		CustomerNotFound:                  http.StatusNotFound,
+		InternalServerError:               http.StatusInternalServerError,
+		SamePhoneNumberError:              http.StatusBadRequest,
+		DuplicateCustomerPhoneNumberError: http.StatusConflict,
+		InvalidPhoneNumberError:           http.StatusBadRequest,
+		DuplicatedCustomerEmailError:      http.StatusConflict,
+		AuthenthicationFailedErr:          http.StatusUnauthorized,
+		InvalidTokenErr:                   http.StatusUnauthorized,
+		InsufficientFundsErr:              http.StatusPaymentRequired,
+		ProviderErr:                       http.StatusBadGateway,
+		KYCLimitErr:                       http.StatusForbidden,
+		ConflictErr:                       http.StatusConflict,
+		ValidationErr:                     http.StatusBadRequest,
+		RequestCanceledErr:                statusClientClosedRequest,
+		TimeoutErr:                        http.StatusGatewayTimeout,
+		ForbiddenErr:                      http.StatusForbidden,
 	}
 )
 
@@ -66,6 +123,20 @@ func Message(code int) string {
 	return "unknown"
 }
 
+// Status returns the http status of an error code.
+func Status(code int) int {
+	if value, ok := errorStatuses[code]; ok {
+		return value
+	}
+	return http.StatusInternalServerError
+}
+
+// New returns a Terror for the code with its type, message and status.
+func New(code int, detail string, optionalAttrs ...errors.TerrorOptionalAttrs) *errors.Terror {
+	optionalAttrs = append([]errors.TerrorOptionalAttrs{errors.WithStatus(Status(code))}, optionalAttrs...)
+	return errors.NewTerror(code, Type(code), Message(code), detail, optionalAttrs...)
+}
+
 func Format(code int, err error) error {
-	return errors.NewTerror(code, Type(code), Message(code), fmt.Sprintf("%s: %v", Message(code), err))
+	return errors.NewTerror(code, Type(code), Message(code), fmt.Sprintf("%s: %v", Message(code), err), errors.WithCause(err))
 }
@@ -0,0 +1,111 @@
+package float
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aremxyplug-be/lib/httpclient"
+)
+
+var errNoBalance = errors.New("provider did not return a balance")
+
+// amount reads a balance the provider sends as a number or as a string, which may have thousands separators.
+type amount float64
+
+func (a *amount) UnmarshalJSON(b []byte) error {
+	s := strings.ReplaceAll(strings.Trim(string(b), `"`), ",", "")
+	if s == "" || s == "null" {
+		return errNoBalance
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid balance %s: %w", b, err)
+	}
+	*a = amount(f)
+	return nil
+}
+
+// fetcher returns our balance in naira at the provider of client.
+type fetcher func(ctx context.Context, client *httpclient.Client) (float64, error)
+
+func vtpassBalance(ctx context.Context, client *httpclient.Client) (float64, error) {
+	var res struct {
+		Contents struct {
+			Balance *amount `json:"balance"`
+		} `json:"contents"`
+	}
+	if err := get(ctx, client, "/balance", &res); err != nil {
+		return 0, err
+	}
+	return value(res.Contents.Balance)
+}
+
+func easyAccessBalance(ctx context.Context, client *httpclient.Client) (float64, error) {
+	var res struct {
+		Balance *amount `json:"balance"`
+	}
+	if err := get(ctx, client, "/wallet_balance.php", &res); err != nil {
+		return 0, err
+	}
+	return value(res.Balance)
+}
+
+func dontechBalance(ctx context.Context, client *httpclient.Client) (float64, error) {
+	var res struct {
+		User struct {
+			WalletBalance *amount `json:"wallet_balance"`
+		} `json:"user"`
+	}
+	if err := get(ctx, client, "/user/", &res); err != nil {
+		return 0, err
+	}
+	return value(res.User.WalletBalance)
+}
+
+// anchorBalance returns the balance of the deposit account transfers are paid from, anchor reports it in kobo.
+func anchorBalance(accountID string) fetcher {
+	return func(ctx context.Context, client *httpclient.Client) (float64, error) {
+		var res struct {
+			Data struct {
+				AvailableBalance *amount `json:"availableBalance"`
+			} `json:"data"`
+		}
+		if err := get(ctx, client, "/accounts/balance/"+accountID, &res); err != nil {
+			return 0, err
+		}
+		balance, err := value(res.Data.AvailableBalance)
+		return balance / 100, err
+	}
+}
+
+func get(ctx context.Context, client *httpclient.Client, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s balance request failed with status %d", client.Name(), res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func value(a *amount) (float64, error) {
+	if a == nil {
+		return 0, errNoBalance
+	}
+	return float64(*a), nil
+}
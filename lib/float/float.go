@@ -0,0 +1,223 @@
+package float
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/receipt"
+	"go.uber.org/zap"
+)
+
+const (
+	// AlertAlias is the postmark template used to tell the operators a provider float is low.
+	AlertAlias = "provider-float-alert"
+
+	defaultInterval = 5 * time.Minute
+)
+
+var ErrUnknownProvider = errors.New("unknown provider")
+
+type Options struct {
+	Store     db.DataStore
+	Providers *httpclient.Providers
+	// DepositAccountID is the anchor account transfers are paid from, its balance is the anchor float.
+	DepositAccountID string
+	Config           config.Float
+	EmailClient      emailclient.EmailClient
+	Logger           *zap.Logger
+}
+
+// source is a provider whose float is monitored.
+type source struct {
+	client    *httpclient.Client
+	threshold float64
+	fetch     fetcher
+}
+
+// Monitor checks our balances at the providers, keeps them as time series and emails the operators when
+// one falls below its threshold. The balances are set on the provider clients, which turn down purchases
+// the float can not pay.
+type Monitor struct {
+	db          db.DataStore
+	sources     []source
+	alertEmails []string
+	emailClient emailclient.EmailClient
+	idGenerator idgenerator.IdGenerator
+	logger      *zap.Logger
+}
+
+func NewMonitor(opt *Options) *Monitor {
+	return &Monitor{
+		db: opt.Store,
+		sources: []source{
+			{client: opt.Providers.VTpass, threshold: opt.Config.VTpass, fetch: vtpassBalance},
+			{client: opt.Providers.EasyAccess, threshold: opt.Config.EasyAccess, fetch: easyAccessBalance},
+			{client: opt.Providers.Dontech, threshold: opt.Config.Dontech, fetch: dontechBalance},
+			{client: opt.Providers.Anchor, threshold: opt.Config.Anchor, fetch: anchorBalance(opt.DepositAccountID)},
+		},
+		alertEmails: opt.Config.AlertEmails,
+		emailClient: opt.EmailClient,
+		idGenerator: idgenerator.New(),
+		logger:      opt.Logger,
+	}
+}
+
+// Latest returns the last balance checked at every provider.
+func (m *Monitor) Latest(ctx context.Context) ([]models.ProviderBalance, error) {
+	balances, err := m.db.GetLatestProviderBalances(ctx)
+	if err != nil {
+		return nil, m.logAndReturnError("failed to get provider balances", err)
+	}
+	return balances, nil
+}
+
+// History returns the balances checked at provider from from to to, oldest first.
+func (m *Monitor) History(ctx context.Context, provider string, from, to time.Time) ([]models.ProviderBalance, error) {
+	if _, ok := m.source(provider); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+
+	balances, err := m.db.GetProviderBalances(ctx, provider, from, to)
+	if err != nil {
+		return nil, m.logAndReturnError("failed to get provider balance history", err)
+	}
+	return balances, nil
+}
+
+// Run checks the balances at the providers every interval until ctx is cancelled. The clients start from
+// the last balances saved, so a restart neither forgets a low float nor alerts about it again.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	m.restore(ctx)
+	m.Check(ctx, time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx, time.Now())
+		}
+	}
+}
+
+// Check fetches the balance at every provider at once, saves it and alerts when a provider has just gone
+// below its threshold. A provider that can not be reached is logged and keeps its last balance.
+func (m *Monitor) Check(ctx context.Context, now time.Time) {
+	var wg sync.WaitGroup
+	for _, src := range m.sources {
+		wg.Add(1)
+		go func(src source) {
+			defer wg.Done()
+			m.check(ctx, src, now)
+		}(src)
+	}
+	wg.Wait()
+}
+
+func (m *Monitor) check(ctx context.Context, src source, now time.Time) {
+	provider := src.client.Name()
+	balance, err := src.fetch(ctx, src.client)
+	if err != nil {
+		m.logger.Error("failed to fetch provider balance", zap.String("provider", provider), zap.Error(err))
+		return
+	}
+
+	previous, checkedAt := src.client.Float()
+	wasLow := !checkedAt.IsZero() && low(previous, src.threshold)
+	src.client.SetFloat(balance, now)
+	metrics.ProviderFloat(provider, balance)
+
+	record := models.ProviderBalance{
+		Provider:  provider,
+		Balance:   balance,
+		Threshold: src.threshold,
+		Low:       low(balance, src.threshold),
+		CheckedAt: now,
+	}
+	if err := m.db.SaveProviderBalance(ctx, record); err != nil {
+		m.logger.Error("failed to save provider balance", zap.String("provider", provider), zap.Error(err))
+	}
+
+	if record.Low && !wasLow {
+		m.alert(record)
+	}
+}
+
+// restore sets the clients' floats to the last balances saved.
+func (m *Monitor) restore(ctx context.Context) {
+	balances, err := m.db.GetLatestProviderBalances(ctx)
+	if err != nil {
+		m.logger.Error("failed to restore provider balances", zap.Error(err))
+		return
+	}
+
+	for _, balance := range balances {
+		if src, ok := m.source(balance.Provider); ok {
+			src.client.SetFloat(balance.Balance, balance.CheckedAt)
+			metrics.ProviderFloat(balance.Provider, balance.Balance)
+		}
+	}
+}
+
+func (m *Monitor) alert(balance models.ProviderBalance) {
+	m.logger.Warn("provider float is low",
+		zap.String("provider", balance.Provider),
+		zap.Float64("balance", balance.Balance),
+		zap.Float64("threshold", balance.Threshold))
+
+	for _, email := range m.alertEmails {
+		message := models.Message{
+			ID:         m.idGenerator.Generate(),
+			Target:     email,
+			Type:       models.EMAIL_MESSAGE_TYPE,
+			Title:      "Low float at " + balance.Provider,
+			TemplateID: AlertAlias,
+			DataMap: map[string]string{
+				"Provider":  balance.Provider,
+				"Balance":   receipt.FormatAmount(balance.Balance),
+				"Threshold": receipt.FormatAmount(balance.Threshold),
+				"CheckedAt": balance.CheckedAt.Format(time.RFC1123),
+			},
+			Ts: time.Now().Unix(),
+		}
+		if err := m.emailClient.Send(&message); err != nil {
+			m.logger.Error("failed to send float alert", zap.String("provider", balance.Provider), zap.String("email", email), zap.Error(err))
+		}
+	}
+}
+
+func (m *Monitor) source(provider string) (source, bool) {
+	for _, src := range m.sources {
+		if src.client.Name() == provider {
+			return src, true
+		}
+	}
+	return source{}, false
+}
+
+func (m *Monitor) logAndReturnError(msg string, err error) error {
+	m.logger.Error(msg, zap.Error(err))
+	return err
+}
+
+// low reports whether balance is below threshold, a threshold of 0 never alerts.
+func low(balance, threshold float64) bool {
+	return threshold > 0 && balance < threshold
+}
@@ -0,0 +1,160 @@
+package float
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	db.DataStore
+	saved []models.ProviderBalance
+}
+
+func (f *fakeStore) SaveProviderBalance(_ context.Context, balance models.ProviderBalance) error {
+	f.saved = append(f.saved, balance)
+	return nil
+}
+
+func (f *fakeStore) GetLatestProviderBalances(_ context.Context) ([]models.ProviderBalance, error) {
+	latest := map[string]models.ProviderBalance{}
+	for _, balance := range f.saved {
+		latest[balance.Provider] = balance
+	}
+	res := []models.ProviderBalance{}
+	for _, balance := range latest {
+		res = append(res, balance)
+	}
+	return res, nil
+}
+
+type fakeEmailClient struct {
+	sent []*models.Message
+}
+
+func (f *fakeEmailClient) Send(email *models.Message) error {
+	f.sent = append(f.sent, email)
+	return nil
+}
+
+func newClient(name string, handler http.HandlerFunc) *httpclient.Client {
+	srv := httptest.NewServer(handler)
+	return httpclient.New(&httpclient.Options{Name: name, BaseURL: srv.URL, Retries: 0, Backoff: time.Millisecond})
+}
+
+func respond(path, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}
+}
+
+func TestFetchers(t *testing.T) {
+	var tests = []struct {
+		name    string
+		path    string
+		body    string
+		fetch   fetcher
+		want    float64
+		wantErr bool
+	}{
+		{name: "Test vtpass", path: "/balance", body: `{"code":1,"contents":{"balance":152300.5}}`, fetch: vtpassBalance, want: 152300.5},
+		{name: "Test easyaccess string balance", path: "/wallet_balance.php", body: `{"success":"true","balance":"48,250.00"}`, fetch: easyAccessBalance, want: 48250},
+		{name: "Test dontech", path: "/user/", body: `{"user":{"username":"aremxyplug","wallet_balance":"9000.75"}}`, fetch: dontechBalance, want: 9000.75},
+		{name: "Test anchor balance in kobo", path: "/accounts/balance/acc-1", body: `{"data":{"availableBalance":1250000}}`, fetch: anchorBalance("acc-1"), want: 12500},
+		{name: "Test missing balance", path: "/balance", body: `{"code":1,"contents":{}}`, fetch: vtpassBalance, wantErr: true},
+		{name: "Test failed request", path: "/other", body: `{}`, fetch: vtpassBalance, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient("test", respond(tt.path, tt.body))
+
+			got, err := tt.fetch(context.Background(), client)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	balance := "60000"
+	providers := &httpclient.Providers{
+		VTpass: newClient("vtpass", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"contents":{"balance":` + balance + `}}`))
+		}),
+		EasyAccess: newClient("easyaccess", respond("/wallet_balance.php", `{"balance":"5000"}`)),
+		Dontech:    newClient("dontech", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }),
+		Anchor:     newClient("anchor", respond("/accounts/balance/acc-1", `{"data":{"availableBalance":50000000}}`)),
+	}
+	store := &fakeStore{}
+	emails := &fakeEmailClient{}
+	m := NewMonitor(&Options{
+		Store:            store,
+		Providers:        providers,
+		DepositAccountID: "acc-1",
+		Config:           config.Float{AlertEmails: []string{"ops@example.com"}, VTpass: 50000, EasyAccess: 20000, Dontech: 50000},
+		EmailClient:      emails,
+		Logger:           zap.NewNop(),
+	})
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	m.Check(context.Background(), now)
+
+	// dontech failed and is not saved, anchor has no threshold
+	assert.Len(t, store.saved, 3)
+	if assert.Len(t, emails.sent, 1) {
+		assert.Equal(t, AlertAlias, emails.sent[0].TemplateID)
+		assert.Equal(t, "easyaccess", emails.sent[0].DataMap["Provider"])
+		assert.Equal(t, "ops@example.com", emails.sent[0].Target)
+	}
+	assert.ErrorIs(t, providers.EasyAccess.CheckFloat(6000), httpclient.ErrInsufficientFloat)
+	assert.NoError(t, providers.Anchor.CheckFloat(500000))
+	assert.NoError(t, providers.Dontech.CheckFloat(1e9), "an unchecked provider is not blocked")
+
+	// vtpass goes low, easyaccess stays low and is not alerted again
+	balance = "40000"
+	m.Check(context.Background(), now.Add(5*time.Minute))
+	if assert.Len(t, emails.sent, 2) {
+		assert.Equal(t, "vtpass", emails.sent[1].DataMap["Provider"])
+	}
+
+	// a restarted monitor starts from the saved balances and does not alert again
+	restarted := &httpclient.Providers{
+		VTpass:     newClient("vtpass", respond("/balance", `{"contents":{"balance":40000}}`)),
+		EasyAccess: newClient("easyaccess", respond("/wallet_balance.php", `{"balance":"5000"}`)),
+		Dontech:    providers.Dontech,
+		Anchor:     providers.Anchor,
+	}
+	m = NewMonitor(&Options{Store: store, Providers: restarted, DepositAccountID: "acc-1", Config: config.Float{AlertEmails: []string{"ops@example.com"}, VTpass: 50000, EasyAccess: 20000}, EmailClient: emails, Logger: zap.NewNop()})
+	m.restore(context.Background())
+	m.Check(context.Background(), now.Add(10*time.Minute))
+	assert.Len(t, emails.sent, 2)
+}
+
+func TestHistory(t *testing.T) {
+	m := NewMonitor(&Options{
+		Store:     &fakeStore{},
+		Providers: &httpclient.Providers{VTpass: newClient("vtpass", nil), EasyAccess: newClient("easyaccess", nil), Dontech: newClient("dontech", nil), Anchor: newClient("anchor", nil)},
+		Logger:    zap.NewNop(),
+	})
+
+	_, err := m.History(context.Background(), "paystack", time.Time{}, time.Now())
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aremxyplug-be/lib/supervisor"
+)
+
+const (
+	statusUp   = "up"
+	statusDown = "down"
+
+	pingTimeout = 2 * time.Second
+)
+
+// Checker reports the health of the service from the database connection and the background workers.
+type Checker struct {
+	ping     func(ctx context.Context) error
+	workers  *supervisor.Supervisor
+	draining atomic.Bool
+}
+
+type report struct {
+	Status   string                      `json:"status"`
+	Database string                      `json:"database"`
+	Draining bool                        `json:"draining,omitempty"`
+	Workers  map[string]supervisor.State `json:"workers"`
+}
+
+func New(ping func(ctx context.Context) error, workers *supervisor.Supervisor) *Checker {
+	return &Checker{ping: ping, workers: workers}
+}
+
+// Drain marks the service as shutting down, readiness fails from then on so no new traffic is routed to it.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Health responds 503 when the database is unreachable or a worker has stopped.
+func (c *Checker) Health(w http.ResponseWriter, r *http.Request) {
+	rep, healthy := c.check(r.Context())
+	write(w, rep, healthy)
+}
+
+// Ready responds 503 when the service is unhealthy or draining.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	rep, healthy := c.check(r.Context())
+	rep.Draining = c.draining.Load()
+	write(w, rep, healthy && !rep.Draining)
+}
+
+func (c *Checker) check(ctx context.Context) (report, bool) {
+	rep := report{Status: statusUp, Database: statusUp, Workers: c.workers.Status()}
+	healthy := true
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := c.ping(ctx); err != nil {
+		rep.Database = statusDown
+		healthy = false
+	}
+
+	// a restarting worker is still supervised, only a stopped one means the service is degraded.
+	for _, state := range rep.Workers {
+		if state == supervisor.StateStopped {
+			healthy = false
+		}
+	}
+
+	if !healthy {
+		rep.Status = statusDown
+	}
+	return rep, healthy
+}
+
+func write(w http.ResponseWriter, rep report, ok bool) {
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+		rep.Status = statusDown
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker opens after threshold consecutive failures. Once the cooldown has passed a single trial request
+// is let through, its result closes the circuit or opens it for another cooldown. A trial that ends
+// without a result lets the next request try.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent and whether it is the trial, a trial must be released
+// when it completes.
+func (b *breaker) allow() (ok, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false, false
+	}
+	b.trial = true
+	return true, true
+}
+
+// release ends the trial whether or not it recorded a result.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,51 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrInsufficientFloat = errors.New("provider float is too low for the purchase")
+
+// floatBalance is our balance at the provider as last reported by the float monitor.
+type floatBalance struct {
+	mu        sync.RWMutex
+	balance   float64
+	checkedAt time.Time
+}
+
+// Name is the provider the client sends requests to.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// SetFloat records our balance at the provider, it is called by the float monitor after every check.
+func (c *Client) SetFloat(balance float64, checkedAt time.Time) {
+	c.float.mu.Lock()
+	defer c.float.mu.Unlock()
+
+	c.float.balance = balance
+	c.float.checkedAt = checkedAt
+}
+
+// Float returns the last balance recorded at the provider and when it was checked, the time is zero when
+// it never was.
+func (c *Client) Float() (float64, time.Time) {
+	c.float.mu.RLock()
+	defer c.float.mu.RUnlock()
+
+	return c.float.balance, c.float.checkedAt
+}
+
+// CheckFloat returns ErrInsufficientFloat when the last balance recorded at the provider can not pay
+// amount, so a purchase is turned down before the provider is called. A provider whose balance was never
+// checked is assumed to have enough, a monitor that is off or failing must not stop sales.
+func (c *Client) CheckFloat(amount float64) error {
+	balance, checkedAt := c.Float()
+	if checkedAt.IsZero() || balance >= amount {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInsufficientFloat, c.name)
+}
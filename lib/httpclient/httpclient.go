@@ -0,0 +1,292 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrCircuitOpen = errors.New("provider circuit is open")
+	// ErrUnconfirmed marks a purchase that may have reached the provider without an answer telling how it
+	// went, it is left for a requery rather than refunded.
+	ErrUnconfirmed = errors.New("provider did not confirm the purchase")
+)
+
+// Unconfirmed wraps an error of a purchase sent to the provider with ErrUnconfirmed, unless the circuit
+// was open and it was never sent.
+func Unconfirmed(err error) error {
+	if errors.Is(err, ErrCircuitOpen) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnconfirmed, err)
+}
+
+const (
+	defaultTimeout          = 30 * time.Second
+	defaultRetries          = 2
+	defaultBackoff          = 200 * time.Millisecond
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+)
+
+// Options configures a Client for a single provider.
+type Options struct {
+	// Name identifies the provider in logs and errors.
+	Name string
+	// BaseURL is prepended to requests made with a relative url.
+	BaseURL string
+	// Headers holds the provider credentials, they are only set when the request does not already have them.
+	Headers map[string]string
+	Timeout time.Duration
+	// Retries is the number of extra attempts made for idempotent requests.
+	Retries int
+	Backoff time.Duration
+	// FailureThreshold is the number of consecutive failures that opens the circuit for Cooldown.
+	FailureThreshold int
+	Cooldown         time.Duration
+	Logger           *zap.Logger
+	// Transport is used in tests, http.DefaultTransport is used when it is nil.
+	Transport http.RoundTripper
+}
+
+// Client sends requests to one provider. Idempotent requests are retried with jitter on network errors,
+// 429 and 5xx responses, and every request goes through a circuit breaker shared by all its callers.
+type Client struct {
+	name    string
+	baseURL string
+	headers map[string]string
+	retries int
+	backoff time.Duration
+	http    *http.Client
+	breaker *breaker
+	float   floatBalance
+	logger  *zap.Logger
+}
+
+func New(opt *Options) *Client {
+	if opt.Timeout <= 0 {
+		opt.Timeout = defaultTimeout
+	}
+	if opt.Retries < 0 {
+		opt.Retries = 0
+	}
+	if opt.Backoff <= 0 {
+		opt.Backoff = defaultBackoff
+	}
+	if opt.FailureThreshold <= 0 {
+		opt.FailureThreshold = defaultFailureThreshold
+	}
+	if opt.Cooldown <= 0 {
+		opt.Cooldown = defaultCooldown
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+
+	return &Client{
+		name:    opt.Name,
+		baseURL: strings.TrimRight(opt.BaseURL, "/"),
+		headers: opt.Headers,
+		retries: opt.Retries,
+		backoff: opt.Backoff,
+		http:    &http.Client{Timeout: opt.Timeout, Transport: opt.Transport},
+		breaker: newBreaker(opt.FailureThreshold, opt.Cooldown),
+		logger:  opt.Logger.With(zap.String("provider", opt.Name)),
+	}
+}
+
+// Do sends req to the provider. A request with a relative url is sent to the provider base url.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if !req.URL.IsAbs() {
+		u, err := url.Parse(c.baseURL + "/" + strings.TrimLeft(req.URL.String(), "/"))
+		if err != nil {
+			return nil, err
+		}
+		req.URL = u
+		req.Host = u.Host
+	}
+	for k, v := range c.headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+
+	attempts := 1
+	if retryable(req) {
+		attempts += c.retries
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(req, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = c.send(req)
+		if errors.Is(err, ErrCircuitOpen) || !shouldRetry(resp, err) || attempt == attempts-1 {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+
+	return resp, err
+}
+
+func (c *Client) send(req *http.Request) (resp *http.Response, err error) {
+	ctx, span := tracing.Tracer().Start(req.Context(), c.name+" "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("provider", c.name),
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(redactURL(req.URL)),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	logger := tracing.Logger(ctx, c.logger)
+
+	ok, trial := c.breaker.allow()
+	if !ok {
+		logger.Warn("provider circuit is open", zap.String("method", req.Method), zap.String("url", redactURL(req.URL)))
+		metrics.ObserveProvider(c.name, metrics.OutcomeCircuitOpen, 0)
+		return nil, fmt.Errorf("%s: %w", c.name, ErrCircuitOpen)
+	}
+	if trial {
+		defer c.breaker.release()
+	}
+
+	attempt := req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(attempt.Header))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attempt.Body = body
+	}
+
+	debug := logger.Core().Enabled(zap.DebugLevel)
+	if debug {
+		logger.Debug("provider request", zap.String("method", req.Method), zap.String("url", redactURL(req.URL)),
+			zap.Any("headers", redactHeaders(req.Header)), zap.String("body", requestBody(req)))
+	}
+
+	start := time.Now()
+	resp, err = c.http.Do(attempt)
+	duration := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	metrics.ObserveProvider(c.name, metrics.ProviderOutcome(status, err), duration)
+
+	if err != nil {
+		// a request its caller gave up on says nothing of the provider
+		if ctx.Err() == nil {
+			c.breaker.record(false)
+		}
+		logger.Error("provider request failed", zap.String("method", req.Method), zap.String("url", redactURL(req.URL)),
+			zap.Duration("duration", duration), zap.Error(err))
+		return nil, err
+	}
+	c.breaker.record(resp.StatusCode < http.StatusInternalServerError)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	logger.Info("provider request", zap.String("method", req.Method), zap.String("url", redactURL(req.URL)),
+		zap.Int("status", resp.StatusCode), zap.Duration("duration", duration))
+	if debug {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			return nil, readErr
+		}
+		logger.Debug("provider response", zap.Int("status", resp.StatusCode), zap.String("body", redactBody(resp.Header.Get("Content-Type"), body)))
+	}
+
+	return resp, nil
+}
+
+// wait sleeps for an exponential backoff with full jitter, or until the request is cancelled.
+func (c *Client) wait(req *http.Request, attempt int) error {
+	backoff := c.backoff << (attempt - 1)
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(backoff) + 1)))
+	defer timer.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryable reports whether req can safely be sent again. Purchases and transfers are POSTs, so they are
+// only retried when the caller marks them with an Idempotency-Key the provider honours.
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return redactBody(req.Header.Get("Content-Type"), b)
+}
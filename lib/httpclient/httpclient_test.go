@@ -0,0 +1,233 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Retries(t *testing.T) {
+	var tests = []struct {
+		name     string
+		method   string
+		key      string
+		status   int
+		want     int32
+		wantCode int
+	}{
+		{name: "Test get is retried", method: http.MethodGet, status: http.StatusBadGateway, want: 3, wantCode: http.StatusBadGateway},
+		{name: "Test post is not retried", method: http.MethodPost, status: http.StatusBadGateway, want: 1, wantCode: http.StatusBadGateway},
+		{name: "Test post with idempotency key is retried", method: http.MethodPost, key: "req-1", status: http.StatusTooManyRequests, want: 3, wantCode: http.StatusTooManyRequests},
+		{name: "Test client error is not retried", method: http.MethodGet, status: http.StatusBadRequest, want: 1, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := New(&Options{Name: "test", BaseURL: server.URL, Retries: 2, Backoff: time.Millisecond})
+			req, err := http.NewRequest(tt.method, "/pay", strings.NewReader("amount=100"))
+			require.NoError(t, err)
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode)
+			assert.Equal(t, tt.want, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestClient_Credentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/requery", r.URL.Path)
+		assert.Equal(t, "pk", r.Header.Get("api-key"))
+		assert.Equal(t, "override", r.Header.Get("secret-key"))
+	}))
+	defer server.Close()
+
+	client := New(&Options{Name: "test", BaseURL: server.URL + "/api/", Headers: map[string]string{"api-key": "pk", "secret-key": "sk"}})
+	req, err := http.NewRequest(http.MethodPost, "/requery", nil)
+	require.NoError(t, err)
+	req.Header.Set("secret-key", "override")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	var calls int32
+	failing := int32(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := New(&Options{Name: "test", BaseURL: server.URL, FailureThreshold: 2, Cooldown: time.Hour})
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodPost, "/pay", nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/pay", nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// after the cooldown a single trial request closes the circuit again.
+	now = now.Add(2 * time.Hour)
+	atomic.StoreInt32(&failing, 0)
+	req, _ = http.NewRequest(http.MethodPost, "/pay", nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_CancelledTrial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := New(&Options{Name: "test", BaseURL: server.URL, FailureThreshold: 1, Cooldown: time.Hour})
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	req, _ := http.NewRequest(http.MethodPost, "/pay", nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// the trial is cancelled before it has a result
+	now = now.Add(2 * time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPost, "/pay", nil)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the next request is the trial instead of finding the circuit open for good
+	req, _ = http.NewRequest(http.MethodPost, "/pay", nil)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestOptions_Retries(t *testing.T) {
+	var tests = []struct {
+		name    string
+		retries int
+		want    int
+	}{
+		{name: "Test unset uses the default", want: defaultRetries},
+		{name: "Test configured retries", retries: 4, want: 4},
+		{name: "Test no retries", retries: -1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := options("test", "", config.Client{Retries: tt.retries}, nil, nil)
+			assert.Equal(t, tt.want, got.Retries)
+		})
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	var tests = []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name: "Test json",
+			body: `{"amount":100,"bvn":"22222222222","data":{"pins":["1234"],"token":"abc"}}`,
+			want: `{"amount":100,"bvn":"[REDACTED]","data":{"pins":"[REDACTED]","token":"[REDACTED]"}}`,
+		},
+		{
+			name:        "Test form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "amount=100&pin=1234",
+			want:        "amount=100&pin=%5BREDACTED%5D",
+		},
+		{
+			name:        "Test other content",
+			contentType: "text/html",
+			body:        "<html>token</html>",
+			want:        "[text/html body omitted]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactBody(tt.contentType, []byte(tt.body)))
+		})
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("x-anchor-key", "secret")
+	header.Set("Authorization", "Token abc")
+	header.Set("Content-Type", "application/json")
+
+	assert.Equal(t, map[string]string{
+		"X-Anchor-Key":  redacted,
+		"Authorization": redacted,
+		"Content-Type":  "application/json",
+	}, redactHeaders(header))
+}
+
+func TestClient_CheckFloat(t *testing.T) {
+	var tests = []struct {
+		name    string
+		balance float64
+		checked bool
+		amount  float64
+		wantErr error
+	}{
+		{name: "Test never checked", amount: 5000},
+		{name: "Test enough float", balance: 5000, checked: true, amount: 5000},
+		{name: "Test float too low", balance: 4999, checked: true, amount: 5000, wantErr: ErrInsufficientFloat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New(&Options{Name: "test"})
+			if tt.checked {
+				client.SetFloat(tt.balance, time.Now())
+			}
+
+			assert.ErrorIs(t, client.CheckFloat(tt.amount), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"github.com/aremxyplug-be/config"
+	"go.uber.org/zap"
+)
+
+// Providers holds one client per provider the lib packages integrate with. Every package calling a
+// provider is given the same client, so one circuit breaker covers all calls to it.
+type Providers struct {
+	VTpass     *Client
+	EasyAccess *Client
+	Dontech    *Client
+	Anchor     *Client
+}
+
+func NewProviders(cfg config.Providers, logger *zap.Logger) *Providers {
+	vtpassHeaders := map[string]string{
+		"api-key":    cfg.VTpass.APIKey,
+		"secret-key": cfg.VTpass.SecretKey,
+	}
+	if cfg.VTpass.PublicKey != "" {
+		vtpassHeaders["public-key"] = cfg.VTpass.PublicKey
+	}
+
+	return &Providers{
+		VTpass: New(options("vtpass", cfg.VTpass.BaseURL, cfg.VTpass.Client, logger, vtpassHeaders)),
+		// query_transaction.php reads the token from Authorization, the other endpoints from AuthorizationToken.
+		EasyAccess: New(options("easyaccess", cfg.EasyAccess.BaseURL, cfg.EasyAccess.Client, logger, map[string]string{
+			"AuthorizationToken": cfg.EasyAccess.Token,
+			"Authorization":      cfg.EasyAccess.Token,
+		})),
+		Dontech: New(options("dontech", cfg.Dontech.BaseURL, cfg.Dontech.Client, logger, map[string]string{
+			"Authorization": "Token " + cfg.Dontech.Token,
+		})),
+		Anchor: New(options("anchor", cfg.Anchor.BaseURL, cfg.Anchor.Client, logger, map[string]string{
+			"x-anchor-key": cfg.Anchor.APIKey,
+		})),
+	}
+}
+
+func options(name, baseURL string, client config.Client, logger *zap.Logger, headers map[string]string) *Options {
+	retries := client.Retries
+	switch {
+	case retries == 0:
+		retries = defaultRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	return &Options{
+		Name:             name,
+		BaseURL:          baseURL,
+		Headers:          headers,
+		Timeout:          client.Timeout,
+		Retries:          retries,
+		FailureThreshold: client.FailureThreshold,
+		Cooldown:         client.Cooldown,
+		Logger:           logger,
+	}
+}
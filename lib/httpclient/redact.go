@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched against lower cased header, form and json keys. Anything holding a token, pin or
+// bvn is left out of the logs.
+var sensitiveKeys = []string{"token", "pin", "bvn", "secret", "password", "key", "authorization"}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for k := range header {
+		if sensitive(k) {
+			headers[k] = redacted
+			continue
+		}
+		headers[k] = header.Get(k)
+	}
+	return headers
+}
+
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = redactValues(u.Query()).Encode()
+	return c.String()
+}
+
+func redactValues(values url.Values) url.Values {
+	for k := range values {
+		if sensitive(k) {
+			values[k] = []string{redacted}
+		}
+	}
+	return values
+}
+
+// redactBody returns the body with sensitive json or form fields masked. Bodies in any other format are not
+// logged since there is no telling what they hold.
+func redactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		b, _ := json.Marshal(redactJSON(v))
+		return string(b)
+	}
+
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err == nil {
+			return redactValues(values).Encode()
+		}
+	}
+
+	return "[" + contentType + " body omitted]"
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if sensitive(k) {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactJSON(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redactJSON(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "aremxyplug"
+
+// Outcomes of a provider call.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeClientError = "client_error"
+	OutcomeServerError = "server_error"
+	OutcomeNetwork     = "network_error"
+	OutcomeCircuitOpen = "circuit_open"
+)
+
+// Statuses of a purchase or transfer.
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// unmatchedRoute labels requests that did not match a route, so unknown paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	providerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Calls to third party providers by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	providerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of calls to third party providers.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider", "outcome"})
+
+	purchases = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "purchases_total",
+		Help:      "Purchases by product, network and status.",
+	}, []string{"product", "network", "status"})
+
+	transfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Bank transfers by status.",
+	}, []string{"status"})
+
+	transferVolume = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_volume_naira_total",
+		Help:      "Naira sent in successful bank transfers.",
+	})
+
+	depositCredits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposit_credits_total",
+		Help:      "Deposits credited to virtual accounts.",
+	})
+
+	depositVolume = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposit_volume_naira_total",
+		Help:      "Naira credited to virtual accounts.",
+	})
+
+	reversals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reversed_transactions_total",
+		Help:      "Failed transactions refunded to the wallet by product.",
+	}, []string{"product"})
+
+	reversalVolume = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reversed_volume_naira_total",
+		Help:      "Naira refunded to the wallet for failed transactions by product.",
+	}, []string{"product"})
+
+	providerFloat = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "provider_float_naira",
+		Help:      "Our last checked balance at each provider.",
+	}, []string{"provider"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		providerRequests, providerDuration,
+		purchases, transfers, transferVolume,
+		depositCredits, depositVolume,
+		reversals, reversalVolume,
+		providerFloat,
+	)
+}
+
+// Handler serves the collected metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records the count and latency of every request by its chi route pattern, the pattern is
+// used instead of the path so ids in the url do not create a series per request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		// the pattern is only complete once the request has been routed.
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveProvider records a call to provider that took d and ended with outcome.
+func ObserveProvider(provider, outcome string, d time.Duration) {
+	providerRequests.WithLabelValues(provider, outcome).Inc()
+	providerDuration.WithLabelValues(provider, outcome).Observe(d.Seconds())
+}
+
+// ProviderOutcome returns the outcome of a provider call from its response status code and error.
+func ProviderOutcome(status int, err error) string {
+	switch {
+	case err != nil:
+		return OutcomeNetwork
+	case status >= http.StatusInternalServerError:
+		return OutcomeServerError
+	case status >= http.StatusBadRequest:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// Purchase records a purchase of product on network, err is the error the purchase failed with.
+func Purchase(product, network string, err error) {
+	purchases.WithLabelValues(product, network, status(err)).Inc()
+}
+
+// Transfer records a bank transfer of amount, only successful transfers add to the volume.
+func Transfer(amount float64, err error) {
+	transfers.WithLabelValues(status(err)).Inc()
+	if err == nil {
+		transferVolume.Add(amount)
+	}
+}
+
+// DepositCredited records a deposit of amount credited to a virtual account.
+func DepositCredited(amount float64) {
+	depositCredits.Inc()
+	if amount > 0 {
+		depositVolume.Add(amount)
+	}
+}
+
+// Reversed records a failed transaction of product whose amount was refunded to the wallet.
+func Reversed(product string, amount float64) {
+	reversals.WithLabelValues(product).Inc()
+	if amount > 0 {
+		reversalVolume.WithLabelValues(product).Add(amount)
+	}
+}
+
+// ProviderFloat records our balance at provider.
+func ProviderFloat(provider string, balance float64) {
+	providerFloat.WithLabelValues(provider).Set(balance)
+}
+
+func status(err error) string {
+	if err != nil {
+		return StatusFailed
+	}
+	return StatusSuccess
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/data/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"1", "2", "3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/data/"+id, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/data/{id}", "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+}
+
+func TestProviderOutcome(t *testing.T) {
+	var tests = []struct {
+		name   string
+		status int
+		err    error
+		want   string
+	}{
+		{name: "Test success", status: http.StatusOK, want: OutcomeSuccess},
+		{name: "Test client error", status: http.StatusUnprocessableEntity, want: OutcomeClientError},
+		{name: "Test server error", status: http.StatusBadGateway, want: OutcomeServerError},
+		{name: "Test network error", err: errors.New("connection reset"), want: OutcomeNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ProviderOutcome(tt.status, tt.err))
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	Transfer(5000, nil)
+	Transfer(2000, errors.New("declined"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(transfers.WithLabelValues(StatusSuccess)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(transfers.WithLabelValues(StatusFailed)))
+	assert.Equal(t, float64(5000), testutil.ToFloat64(transferVolume))
+}
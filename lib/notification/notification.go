@@ -0,0 +1,252 @@
+// Package notification keeps every notification sent to the users in their in-app inbox and sends it
+// on the email, SMS and push channels they chose.
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// NotificationAlias is the postmark template of the emails of notifications that do not name one, it
+// is given the title and body in its data.
+const NotificationAlias = "notification"
+
+// categories of the notifications.
+const (
+	CategoryTransaction = "transaction"
+	CategoryDeposit     = "deposit"
+	CategoryLowBalance  = "low_balance"
+	CategorySecurity    = "security"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidStatus        = errors.New("notification status must be unread or read")
+)
+
+// Sender sends a message on one channel, the email and SMS clients are senders.
+type Sender interface {
+	Send(message *models.Message) error
+}
+
+type Options struct {
+	Store db.DataStore
+	// Senders are the channels messages can go out on, a channel without a sender is skipped.
+	Senders map[models.MessageType]Sender
+	// LowBalanceThreshold is the wallet balance below which the user is notified, 0 never notifies.
+	LowBalanceThreshold float64
+	Logger              *zap.Logger
+}
+
+// Notification is what to tell a user, TemplateID, DataMap and Attachments are used for the email.
+// Attachments are not kept in the inbox.
+type Notification struct {
+	UserID      string
+	Category    string
+	Title       string
+	Body        string
+	TemplateID  string
+	DataMap     map[string]string
+	Attachments []models.Attachment
+}
+
+// Notifier sends the notifications and serves the users' inboxes.
+type Notifier struct {
+	db          db.DataStore
+	senders     map[models.MessageType]Sender
+	lowBalance  float64
+	idGenerator idgenerator.IdGenerator
+	logger      *zap.Logger
+}
+
+func NewNotifier(opt *Options) *Notifier {
+	return &Notifier{
+		db:          opt.Store,
+		senders:     opt.Senders,
+		lowBalance:  opt.LowBalanceThreshold,
+		idGenerator: idgenerator.New(),
+		logger:      opt.Logger,
+	}
+}
+
+// DefaultPreferences are the channels of a user who has not chosen any.
+func DefaultPreferences(userID string) models.NotificationPreferences {
+	return models.NotificationPreferences{UserID: userID, Email: true, Push: true}
+}
+
+// Notify sends n on the channels the user enabled and keeps it in their inbox. A channel that fails is
+// recorded on the notification rather than failing it, the inbox still has it.
+func (n *Notifier) Notify(ctx context.Context, notification Notification) (models.Message, error) {
+	user, err := n.db.GetUserByID(ctx, notification.UserID)
+	if err != nil {
+		return models.Message{}, n.logAndReturnError("failed to get user", err)
+	}
+	prefs, err := n.Preferences(ctx, notification.UserID)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	now := time.Now().UTC()
+	message := models.Message{
+		ID:         n.idGenerator.Generate(),
+		CustomerID: notification.UserID,
+		Type:       models.INBOX_MESSAGE_TYPE,
+		Title:      notification.Title,
+		Body:       notification.Body,
+		TemplateID: notification.TemplateID,
+		DataMap:    notification.DataMap,
+		Ts:         now.Unix(),
+		Category:   notification.Category,
+		Status:     models.MessageUnread,
+		CreatedAt:  now,
+	}
+
+	channels := []struct {
+		channel models.MessageType
+		enabled bool
+		target  string
+	}{
+		// security alerts are always emailed, whatever the user chose
+		{models.EMAIL_MESSAGE_TYPE, prefs.Email || notification.Category == CategorySecurity, user.Email},
+		{models.SMS_MESSAGE_TYPE, prefs.SMS, user.PhoneNumber},
+		{models.PUSH_MESSAGE_TYPE, prefs.Push, user.ID},
+	}
+	for _, c := range channels {
+		sender, ok := n.senders[c.channel]
+		if !ok || !c.enabled || c.target == "" {
+			continue
+		}
+		out := message
+		if c.channel == models.EMAIL_MESSAGE_TYPE {
+			out.Attachments = notification.Attachments
+		}
+		message.Deliveries = append(message.Deliveries, n.send(sender, c.channel, c.target, out))
+	}
+
+	if err := n.db.CreateMessage(ctx, &message); err != nil {
+		return models.Message{}, n.logAndReturnError("failed to save notification", err)
+	}
+
+	return message, nil
+}
+
+// send sends a copy of message to target on channel.
+func (n *Notifier) send(sender Sender, channel models.MessageType, target string, message models.Message) models.Delivery {
+	out := message
+	out.Type = channel
+	out.Target = target
+	if channel == models.EMAIL_MESSAGE_TYPE && out.TemplateID == "" {
+		out.TemplateID = NotificationAlias
+		out.DataMap = map[string]string{"title": message.Title, "body": message.Body}
+	}
+
+	delivery := models.Delivery{Channel: channel, Target: target, Status: models.DeliverySent, At: time.Now().UTC()}
+	if err := sender.Send(&out); err != nil {
+		n.logger.Error("failed to send notification", zap.String("channel", string(channel)),
+			zap.String("id", message.ID), zap.Error(err))
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+	}
+	return delivery
+}
+
+// BalanceChanged tells a user their balance went below the low balance threshold, it only alerts when a
+// debit crosses the threshold so a user is not told again on every purchase.
+func (n *Notifier) BalanceChanged(ctx context.Context, userID string, before, after float64) {
+	if n.lowBalance <= 0 || before < n.lowBalance || after >= n.lowBalance {
+		return
+	}
+
+	balance := strconv.FormatFloat(after, 'f', 2, 64)
+	_, err := n.Notify(ctx, Notification{
+		UserID:   userID,
+		Category: CategoryLowBalance,
+		Title:    "Low wallet balance",
+		Body:     fmt.Sprintf("Your wallet balance is NGN %s, fund your wallet to keep making payments.", balance),
+	})
+	if err != nil {
+		n.logger.Error("failed to notify low balance", zap.String("user", userID), zap.Error(err))
+	}
+}
+
+// List returns a page of the user's notifications, newest first. opts.Status selects unread or read ones.
+func (n *Notifier) List(ctx context.Context, userID string, opts db.ListOptions) ([]models.Message, string, error) {
+	if opts.Status != "" && opts.Status != models.MessageUnread && opts.Status != models.MessageRead {
+		return nil, "", ErrInvalidStatus
+	}
+
+	messages, next, err := n.db.GetNotifications(ctx, userID, opts)
+	if err != nil {
+		return nil, "", n.logAndReturnError("failed to get notifications", err)
+	}
+
+	return messages, next, nil
+}
+
+func (n *Notifier) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	count, err := n.db.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return 0, n.logAndReturnError("failed to count unread notifications", err)
+	}
+
+	return count, nil
+}
+
+// MarkRead marks one of the user's notifications read, reading it again keeps the first read time.
+func (n *Notifier) MarkRead(ctx context.Context, userID, id string) error {
+	err := n.db.MarkNotificationRead(ctx, userID, id, time.Now().UTC())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotificationNotFound
+	}
+	if err != nil {
+		return n.logAndReturnError("failed to mark notification read", err)
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read and returns how many there were.
+func (n *Notifier) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	count, err := n.db.MarkAllNotificationsRead(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return 0, n.logAndReturnError("failed to mark notifications read", err)
+	}
+
+	return count, nil
+}
+
+// Preferences returns the channels the user chose, or the defaults when they have not.
+func (n *Notifier) Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	prefs, err := n.db.GetNotificationPreferences(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return DefaultPreferences(userID), nil
+	}
+	if err != nil {
+		return models.NotificationPreferences{}, n.logAndReturnError("failed to get notification preferences", err)
+	}
+
+	return prefs, nil
+}
+
+func (n *Notifier) SetPreferences(ctx context.Context, prefs models.NotificationPreferences) (models.NotificationPreferences, error) {
+	prefs.UpdatedAt = time.Now().UTC()
+	if err := n.db.SaveNotificationPreferences(ctx, prefs); err != nil {
+		return models.NotificationPreferences{}, n.logAndReturnError("failed to save notification preferences", err)
+	}
+
+	return prefs, nil
+}
+
+func (n *Notifier) logAndReturnError(errorMsg string, err error) error {
+	n.logger.Error(errorMsg, zap.Error(err))
+	return fmt.Errorf("%s: %w", errorMsg, err)
+}
@@ -0,0 +1,197 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the inbox and preferences in memory.
+type fakeStore struct {
+	db.DataStore
+	messages []models.Message
+	prefs    map[string]models.NotificationPreferences
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	if id != "user-ada" {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &models.User{ID: id, Email: "ada@example.com", PhoneNumber: "+2348012345678"}, nil
+}
+
+func (f *fakeStore) CreateMessage(_ context.Context, message *models.Message) error {
+	f.messages = append(f.messages, *message)
+	return nil
+}
+
+func (f *fakeStore) MarkNotificationRead(_ context.Context, userID, id string, at time.Time) error {
+	for i, message := range f.messages {
+		if message.ID == id && message.CustomerID == userID {
+			f.messages[i].Status = models.MessageRead
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (f *fakeStore) GetNotificationPreferences(_ context.Context, userID string) (models.NotificationPreferences, error) {
+	prefs, ok := f.prefs[userID]
+	if !ok {
+		return models.NotificationPreferences{}, mongo.ErrNoDocuments
+	}
+	return prefs, nil
+}
+
+// fakeSender records the messages it is given and fails with err.
+type fakeSender struct {
+	sent []models.Message
+	err  error
+}
+
+func (s *fakeSender) Send(message *models.Message) error {
+	s.sent = append(s.sent, *message)
+	return s.err
+}
+
+func TestNotify(t *testing.T) {
+	var tests = []struct {
+		name         string
+		prefs        *models.NotificationPreferences
+		category     string
+		emailErr     error
+		wantChannels []models.MessageType
+		wantFailed   bool
+	}{
+		{
+			name:         "Test default preferences",
+			category:     CategoryTransaction,
+			wantChannels: []models.MessageType{models.EMAIL_MESSAGE_TYPE, models.PUSH_MESSAGE_TYPE},
+		},
+		{
+			name:         "Test sms only",
+			prefs:        &models.NotificationPreferences{UserID: "user-ada", SMS: true},
+			category:     CategoryDeposit,
+			wantChannels: []models.MessageType{models.SMS_MESSAGE_TYPE},
+		},
+		{
+			name:         "Test security alert always emailed",
+			prefs:        &models.NotificationPreferences{UserID: "user-ada"},
+			category:     CategorySecurity,
+			wantChannels: []models.MessageType{models.EMAIL_MESSAGE_TYPE},
+		},
+		{
+			name:         "Test failed delivery kept in inbox",
+			category:     CategoryTransaction,
+			emailErr:     errors.New("postmark is down"),
+			wantChannels: []models.MessageType{models.EMAIL_MESSAGE_TYPE, models.PUSH_MESSAGE_TYPE},
+			wantFailed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{prefs: map[string]models.NotificationPreferences{}}
+			if tt.prefs != nil {
+				store.prefs["user-ada"] = *tt.prefs
+			}
+			email, sms, push := &fakeSender{err: tt.emailErr}, &fakeSender{}, &fakeSender{}
+			n := NewNotifier(&Options{
+				Store: store,
+				Senders: map[models.MessageType]Sender{
+					models.EMAIL_MESSAGE_TYPE: email,
+					models.SMS_MESSAGE_TYPE:   sms,
+					models.PUSH_MESSAGE_TYPE:  push,
+				},
+				Logger: zap.NewNop(),
+			})
+
+			message, err := n.Notify(context.Background(), Notification{
+				UserID: "user-ada", Category: tt.category, Title: "Airtime purchase", Body: "NGN 500 airtime sent",
+				Attachments: []models.Attachment{{Name: "receipt.pdf"}},
+			})
+			require.NoError(t, err)
+
+			require.Len(t, store.messages, 1)
+			saved := store.messages[0]
+			assert.Equal(t, message.ID, saved.ID)
+			assert.Equal(t, models.INBOX_MESSAGE_TYPE, saved.Type)
+			assert.Equal(t, models.MessageUnread, saved.Status)
+			assert.Equal(t, tt.category, saved.Category)
+			assert.Empty(t, saved.Attachments)
+
+			var channels []models.MessageType
+			for _, d := range saved.Deliveries {
+				channels = append(channels, d.Channel)
+				if d.Channel == models.EMAIL_MESSAGE_TYPE {
+					assert.Equal(t, tt.wantFailed, d.Status == models.DeliveryFailed)
+				}
+			}
+			assert.Equal(t, tt.wantChannels, channels)
+
+			if len(email.sent) > 0 {
+				assert.Equal(t, "ada@example.com", email.sent[0].Target)
+				assert.Equal(t, NotificationAlias, email.sent[0].TemplateID)
+				assert.Equal(t, "Airtime purchase", email.sent[0].DataMap["title"])
+				assert.Len(t, email.sent[0].Attachments, 1)
+			}
+			if len(sms.sent) > 0 {
+				assert.Equal(t, "+2348012345678", sms.sent[0].Target)
+				assert.Empty(t, sms.sent[0].Attachments)
+			}
+		})
+	}
+}
+
+func TestBalanceChanged(t *testing.T) {
+	var tests = []struct {
+		name      string
+		before    float64
+		after     float64
+		wantAlert bool
+	}{
+		{name: "Test crossing the threshold", before: 1500, after: 800, wantAlert: true},
+		{name: "Test already below the threshold", before: 800, after: 500},
+		{name: "Test above the threshold", before: 5000, after: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			n := NewNotifier(&Options{Store: store, LowBalanceThreshold: 1000, Logger: zap.NewNop()})
+
+			n.BalanceChanged(context.Background(), "user-ada", tt.before, tt.after)
+
+			if !tt.wantAlert {
+				assert.Empty(t, store.messages)
+				return
+			}
+			if assert.Len(t, store.messages, 1) {
+				assert.Equal(t, CategoryLowBalance, store.messages[0].Category)
+				assert.Contains(t, store.messages[0].Body, "NGN 800.00")
+			}
+		})
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	store := &fakeStore{messages: []models.Message{{ID: "n-1", CustomerID: "user-ada", Status: models.MessageUnread}}}
+	n := NewNotifier(&Options{Store: store, Logger: zap.NewNop()})
+
+	t.Run("Test own notification", func(t *testing.T) {
+		assert.NoError(t, n.MarkRead(context.Background(), "user-ada", "n-1"))
+		assert.Equal(t, models.MessageRead, store.messages[0].Status)
+	})
+
+	t.Run("Test notification of another user", func(t *testing.T) {
+		assert.ErrorIs(t, n.MarkRead(context.Background(), "user-bola", "n-1"), ErrNotificationNotFound)
+	})
+}
@@ -1,6 +1,7 @@
 package otpgen
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -19,7 +20,7 @@ func NewOTP(DbConn db.DataStore) *OTPConn {
 	}
 }
 
-func (o *OTPConn) GenerateOTP(email string) (string, error) {
+func (o *OTPConn) GenerateOTP(ctx context.Context, email string) (string, error) {
 	key, err := totp.Generate(
 		totp.GenerateOpts{
 			Issuer:      "AremxyPlug",
@@ -41,7 +42,7 @@ func (o *OTPConn) GenerateOTP(email string) (string, error) {
 		Email:  email,
 	}
 
-	if err := o.Dbconn.SaveOTP(data); err != nil {
+	if err := o.Dbconn.SaveOTP(ctx, data); err != nil {
 		return "", err
 	}
 
@@ -57,10 +58,10 @@ func (o *OTPConn) GenerateOTP(email string) (string, error) {
 
 }
 
-func (o *OTPConn) ValidateOTP(otp, email string) (bool, error) {
+func (o *OTPConn) ValidateOTP(ctx context.Context, otp, email string) (bool, error) {
 
 	// how do i get the email to search for the otp key associated with it?
-	data, err := o.Dbconn.GetOTP(email)
+	data, err := o.Dbconn.GetOTP(ctx, email)
 	if err != nil {
 		log.Print(err)
 		return false, err
@@ -0,0 +1,137 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MTN     = "mtn"
+	Airtel  = "airtel"
+	Glo     = "glo"
+	NineMob = "9mobile"
+
+	countryCode = "234"
+)
+
+var (
+	ErrInvalidNumber  = errors.New("phone number is not a valid nigerian mobile number")
+	ErrUnknownNetwork = errors.New("unknown network")
+)
+
+// prefixes maps the NCC number prefixes to their networks. Some 0702 ranges are allocated to
+// mtn at 5 digits, so longer prefixes are checked first.
+var prefixes = map[string]string{
+	"07025": MTN, "07026": MTN, "0703": MTN, "0704": MTN, "0706": MTN, "0803": MTN, "0806": MTN,
+	"0810": MTN, "0813": MTN, "0814": MTN, "0816": MTN, "0903": MTN, "0906": MTN, "0913": MTN, "0916": MTN,
+
+	"0701": Airtel, "0708": Airtel, "0802": Airtel, "0808": Airtel, "0812": Airtel, "0901": Airtel,
+	"0902": Airtel, "0904": Airtel, "0907": Airtel, "0911": Airtel, "0912": Airtel,
+
+	"0705": Glo, "0805": Glo, "0807": Glo, "0811": Glo, "0815": Glo, "0905": Glo, "0915": Glo,
+
+	"0809": NineMob, "0817": NineMob, "0818": NineMob, "0908": NineMob, "0909": NineMob,
+}
+
+// aliases are the other names users and providers give the networks.
+var aliases = map[string]string{
+	"mtn": MTN, "airtel": Airtel, "glo": Glo, "globacom": Glo,
+	"9mobile": NineMob, "9-mobile": NineMob, "etisalat": NineMob, "t2": NineMob,
+}
+
+// Number is a parsed nigerian mobile number.
+type Number struct {
+	E164    string `json:"e164"`    // +2348031234567
+	Local   string `json:"local"`   // 08031234567, the format the providers expect
+	Network string `json:"network"` // network of the prefix, empty when the prefix is unknown
+}
+
+// Result is a number checked against the network the user selected.
+type Result struct {
+	Number
+	Selected string `json:"selected_network,omitempty"`
+	// Ported is set when the selected network is not the network of the prefix,
+	// the purchase goes to the selected network as the number may have been ported.
+	Ported  bool   `json:"ported"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// Parse reads local (08031234567) and international (+2348031234567, 2348031234567, 002348031234567)
+// formats. Spaces, dashes, dots and brackets are ignored.
+func Parse(raw string) (Number, error) {
+	digits := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(digits, "+"+countryCode):
+		digits = digits[len(countryCode)+1:]
+	case strings.HasPrefix(digits, "00"+countryCode):
+		digits = digits[len(countryCode)+2:]
+	case strings.HasPrefix(digits, countryCode) && len(digits) >= 13:
+		digits = digits[len(countryCode):]
+	}
+	// +234 0803... is a common way of writing the number
+	if len(digits) == 11 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+
+	if len(digits) != 10 || strings.Trim(digits, "0123456789") != "" {
+		return Number{}, ErrInvalidNumber
+	}
+	// mobile numbers start with 7, 8 or 9 after the leading zero
+	if digits[0] < '7' || digits[0] > '9' {
+		return Number{}, ErrInvalidNumber
+	}
+
+	local := "0" + digits
+	return Number{
+		E164:    "+" + countryCode + digits,
+		Local:   local,
+		Network: detect(local),
+	}, nil
+}
+
+// Validate parses the number and compares its network with the selected network.
+// An empty selected network takes the network of the prefix.
+func Validate(raw, selected string) (Result, error) {
+	number, err := Parse(raw)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Number: number, Selected: NormaliseNetwork(selected)}
+	if selected != "" && result.Selected == "" {
+		return Result{}, fmt.Errorf("%w %q", ErrUnknownNetwork, selected)
+	}
+	if result.Selected == "" {
+		result.Selected = number.Network
+	}
+
+	if number.Network != "" && result.Selected != number.Network {
+		result.Ported = true
+		result.Warning = fmt.Sprintf("%s looks like a %s number but %s was selected, it will only work if the number was ported to %s",
+			number.Local, display(number.Network), display(result.Selected), display(result.Selected))
+	}
+
+	return result, nil
+}
+
+// NormaliseNetwork returns the network name used by this package for a user supplied name,
+// or an empty string if the name is not known.
+func NormaliseNetwork(name string) string {
+	return aliases[strings.ToLower(strings.TrimSpace(name))]
+}
+
+func detect(local string) string {
+	if network, ok := prefixes[local[:5]]; ok {
+		return network
+	}
+	return prefixes[local[:4]]
+}
+
+func display(network string) string {
+	if network == NineMob {
+		return network
+	}
+	return strings.ToUpper(network)
+}
@@ -0,0 +1,142 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name    string
+		raw     string
+		want    Number
+		wantErr error
+	}{
+		{
+			name: "Test local format",
+			raw:  "08031234567",
+			want: Number{E164: "+2348031234567", Local: "08031234567", Network: MTN},
+		},
+		{
+			name: "Test international format with plus",
+			raw:  "+2348021234567",
+			want: Number{E164: "+2348021234567", Local: "08021234567", Network: Airtel},
+		},
+		{
+			name: "Test international format without plus",
+			raw:  "2348051234567",
+			want: Number{E164: "+2348051234567", Local: "08051234567", Network: Glo},
+		},
+		{
+			name: "Test international format with leading zero",
+			raw:  "+234 0809 123 4567",
+			want: Number{E164: "+2348091234567", Local: "08091234567", Network: NineMob},
+		},
+		{
+			name: "Test double zero prefix and separators",
+			raw:  "00234-(703)-123.4567",
+			want: Number{E164: "+2347031234567", Local: "07031234567", Network: MTN},
+		},
+		{
+			name: "Test five digit prefix",
+			raw:  "07025123456",
+			want: Number{E164: "+2347025123456", Local: "07025123456", Network: MTN},
+		},
+		{
+			name: "Test unknown prefix",
+			raw:  "07021234567",
+			want: Number{E164: "+2347021234567", Local: "07021234567"},
+		},
+		{
+			name:    "Test too short",
+			raw:     "0803123456",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "Test letters",
+			raw:     "0803123456a",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "Test landline",
+			raw:     "01234567890",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "Test empty",
+			raw:     "",
+			wantErr: ErrInvalidNumber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name       string
+		raw        string
+		selected   string
+		wantNet    string
+		wantPorted bool
+		wantErr    error
+	}{
+		{
+			name:     "Test matching network",
+			raw:      "08031234567",
+			selected: "MTN",
+			wantNet:  MTN,
+		},
+		{
+			name:    "Test no network selected",
+			raw:     "08021234567",
+			wantNet: Airtel,
+		},
+		{
+			name:     "Test alias",
+			raw:      "08091234567",
+			selected: "etisalat",
+			wantNet:  NineMob,
+		},
+		{
+			name:       "Test mismatch is flagged as ported",
+			raw:        "08031234567",
+			selected:   "glo",
+			wantNet:    Glo,
+			wantPorted: true,
+		},
+		{
+			name:     "Test unknown prefix is not flagged",
+			raw:      "07021234567",
+			selected: "airtel",
+			wantNet:  Airtel,
+		},
+		{
+			name:     "Test unknown network",
+			raw:      "08031234567",
+			selected: "visafone",
+			wantErr:  ErrUnknownNetwork,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(tt.raw, tt.selected)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNet, got.Selected)
+			assert.Equal(t, tt.wantPorted, got.Ported)
+			assert.Equal(t, tt.wantPorted, got.Warning != "")
+		})
+	}
+}
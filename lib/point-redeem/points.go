@@ -1,6 +1,7 @@
 package pointredeem
 
 import (
+	"context"
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
 )
@@ -15,9 +16,9 @@ func NewPointConfig(store db.Extras) *PointConfig {
 	}
 }
 
-func (p *PointConfig) RedeemPoints(userID string, points int) bool {
+func (p *PointConfig) RedeemPoints(ctx context.Context, userID string, points int) bool {
 
-	yes := p.db.CanRedeemPoints(userID, points)
+	yes := p.db.CanRedeemPoints(ctx, userID, points)
 	if !yes {
 		return false
 	}
@@ -25,9 +26,9 @@ func (p *PointConfig) RedeemPoints(userID string, points int) bool {
 	return yes
 }
 
-func (p *PointConfig) UpdatePoints(userID string, points int) error {
+func (p *PointConfig) UpdatePoints(ctx context.Context, userID string, points int) error {
 
-	err := p.db.UpdatePoint(userID, points)
+	err := p.db.UpdatePoint(ctx, userID, points)
 	if err != nil {
 		return err
 	}
@@ -35,9 +36,9 @@ func (p *PointConfig) UpdatePoints(userID string, points int) error {
 	return nil
 }
 
-func (p *PointConfig) GetPoints(userID string) (models.Points, error) {
+func (p *PointConfig) GetPoints(ctx context.Context, userID string) (models.Points, error) {
 
-	point, err := p.db.GetPoint(userID)
+	point, err := p.db.GetPoint(ctx, userID)
 	if err != nil {
 		return models.Points{}, err
 	}
@@ -46,8 +47,8 @@ func (p *PointConfig) GetPoints(userID string) (models.Points, error) {
 
 }
 
-func (p *PointConfig) UserPoints(userID string) error {
-	err := p.db.CreatePointDoc(userID)
+func (p *PointConfig) UserPoints(ctx context.Context, userID string) error {
+	err := p.db.CreatePointDoc(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,86 @@
+package receipt
+
+import (
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfWidth  = 148.0 // A5, in mm
+	pdfMargin = 12.0
+)
+
+func renderPDF(w io.Writer, r Receipt) error {
+	pdf := gofpdf.New("P", "mm", "A5", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(r.Title+" receipt", true)
+	pdf.SetCreator(Brand, true)
+	if !r.Date.IsZero() {
+		// a receipt rendered twice is the same file
+		pdf.SetCreationDate(r.Date)
+	}
+	// the core fonts are cp1252, names and narrations may not be
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	content := pdfWidth - 2*pdfMargin
+
+	pdf.AddPage()
+	setFill(pdf, BrandColor)
+	pdf.Rect(0, 0, pdfWidth, 30, "F")
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetXY(pdfMargin, 8)
+	pdf.CellFormat(content, 9, Brand, "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(content, 6, "Transaction receipt", "", 1, "L", false, 0, "")
+
+	pdf.SetY(40)
+	setText(pdf, mutedColor)
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.CellFormat(content, 6, tr(r.Title), "", 1, "C", false, 0, "")
+	setText(pdf, textColor)
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.CellFormat(content, 12, FormatAmount(r.Total()), "", 1, "C", false, 0, "")
+	setText(pdf, mutedColor)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(content, 6, strings.ToUpper(r.Status), "", 1, "C", false, 0, "")
+	pdf.Ln(6)
+
+	setDraw(pdf, ruleColor)
+	labelWidth := content * 0.4
+	for _, line := range r.lines() {
+		y := pdf.GetY()
+		pdf.Line(pdfMargin, y, pdfWidth-pdfMargin, y)
+		pdf.Ln(2)
+
+		setText(pdf, mutedColor)
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(labelWidth, 6, tr(line.Label), "", 0, "L", false, 0, "")
+		setText(pdf, textColor)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(content-labelWidth, 6, tr(line.Value), "", "R", false)
+		pdf.Ln(2)
+	}
+
+	pdf.Ln(8)
+	setText(pdf, mutedColor)
+	pdf.SetFont("Helvetica", "I", 9)
+	pdf.CellFormat(content, 5, "Thank you for using "+Brand+".", "", 1, "C", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func setFill(pdf *gofpdf.Fpdf, c color.RGBA) {
+	pdf.SetFillColor(int(c.R), int(c.G), int(c.B))
+}
+
+func setText(pdf *gofpdf.Fpdf, c color.RGBA) {
+	pdf.SetTextColor(int(c.R), int(c.G), int(c.B))
+}
+
+func setDraw(pdf *gofpdf.Fpdf, c color.RGBA) {
+	pdf.SetDrawColor(int(c.R), int(c.G), int(c.B))
+}
@@ -0,0 +1,133 @@
+package receipt
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// the receipt is laid out in pixels of the 7x13 font and scaled up by pngScale, which keeps the text sharp.
+const (
+	pngWidth  = 320
+	pngMargin = 16
+	pngHeader = 56
+	pngLine   = 16
+	pngScale  = 2
+)
+
+var face = basicfont.Face7x13
+
+// pngRow is a line of the receipt with its value wrapped to the width left by the label.
+type pngRow struct {
+	label  string
+	values []string
+}
+
+func renderPNG(w io.Writer, r Receipt) error {
+	charWidth := face.Advance
+	valueChars := (pngWidth - 2*pngMargin) * 3 / 5 / charWidth
+
+	var rows []pngRow
+	height := pngHeader + 84
+	for _, line := range r.lines() {
+		row := pngRow{label: line.Label, values: wrap(line.Value, valueChars)}
+		rows = append(rows, row)
+		height += len(row.values)*pngLine + 8
+	}
+	height += 40
+
+	img := image.NewRGBA(image.Rect(0, 0, pngWidth, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, pngWidth, pngHeader), image.NewUniform(BrandColor), image.Point{}, draw.Src)
+
+	drawBold(img, Brand, pngMargin, 26, color.White)
+	drawText(img, "Transaction receipt", pngMargin, 44, color.White)
+
+	y := pngHeader + 28
+	drawCentered(img, r.Title, y, mutedColor, false)
+	y += 24
+	drawCentered(img, FormatAmount(r.Total()), y, textColor, true)
+	y += 20
+	drawCentered(img, strings.ToUpper(r.Status), y, mutedColor, false)
+	y += 12
+
+	for _, row := range rows {
+		draw.Draw(img, image.Rect(pngMargin, y, pngWidth-pngMargin, y+1), image.NewUniform(ruleColor), image.Point{}, draw.Src)
+		y += 4
+		drawText(img, row.label, pngMargin, y+12, mutedColor)
+		for _, value := range row.values {
+			drawBold(img, value, pngWidth-pngMargin-textWidth(value)-1, y+12, textColor)
+			y += pngLine
+		}
+		y += 4
+	}
+
+	drawCentered(img, "Thank you for using "+Brand+".", height-20, mutedColor, false)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, pngWidth*pngScale, height*pngScale))
+	xdraw.NearestNeighbor.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return png.Encode(w, scaled)
+}
+
+func drawText(img draw.Image, s string, x, y int, c color.Color) {
+	d := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+// drawBold draws s twice a pixel apart, the font has no bold face.
+func drawBold(img draw.Image, s string, x, y int, c color.Color) {
+	drawText(img, s, x, y, c)
+	drawText(img, s, x+1, y, c)
+}
+
+func drawCentered(img draw.Image, s string, y int, c color.Color, bold bool) {
+	x := (pngWidth - textWidth(s)) / 2
+	if bold {
+		drawBold(img, s, x, y, c)
+		return
+	}
+	drawText(img, s, x, y, c)
+}
+
+func textWidth(s string) int {
+	return font.MeasureString(face, s).Ceil()
+}
+
+// wrap splits s into lines of at most n characters, breaking at spaces where it can.
+func wrap(s string, n int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for len([]rune(word)) > n {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:n]))
+			word = string(runes[n:])
+		}
+
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= n:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,245 @@
+// Package receipt renders shareable receipts of the users' transactions as PDF and PNG.
+package receipt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+)
+
+// Brand is the name printed at the top of every receipt.
+const Brand = "AremxyPlug"
+
+var ErrUnknownTransaction = errors.New("transaction has no receipt")
+
+// location is the time zone receipts are dated in.
+var location = time.FixedZone("WAT", 60*60)
+
+// Field is a labelled line of a receipt.
+type Field struct {
+	Label string
+	Value string
+}
+
+// Receipt is a transaction normalised for rendering, whatever product it belongs to.
+type Receipt struct {
+	Title     string
+	Reference string
+	OrderID   int
+	Status    string
+	Amount    float64
+	Fee       float64
+	Date      time.Time
+	// Details describe what was bought, the recipient, plan, meter and so on.
+	Details []Field
+	Token   string
+	Pins    []string
+}
+
+// Total is what the user paid for the transaction.
+func (r Receipt) Total() float64 {
+	return r.Amount + r.Fee
+}
+
+// Receipts finds the transactions of the users and normalises them into receipts.
+type Receipts struct {
+	store db.TransactionStore
+}
+
+func NewReceipts(store db.TransactionStore) *Receipts {
+	return &Receipts{store: store}
+}
+
+// Get returns the receipt of the transaction of userID with the order id.
+func (r *Receipts) Get(ctx context.Context, userID, id string) (Receipt, error) {
+	transaction, err := r.store.GetTransaction(ctx, userID, id)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return FromTransaction(transaction)
+}
+
+// FromTransaction normalises a transaction as the store returns it.
+func FromTransaction(transaction interface{}) (Receipt, error) {
+	switch t := transaction.(type) {
+	case telcom.DataResult:
+		return Receipt{
+			Title:     "Data",
+			Reference: t.TransactionID,
+			OrderID:   t.OrderID,
+			Status:    status(t.Status),
+			Amount:    parseAmount(t.Plan_Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Network", t.Network,
+				"Plan", t.PlanName,
+				"Phone number", t.Phone_Number,
+			),
+		}, nil
+	case telcom.SmileResult:
+		return Receipt{
+			Title:     "Smile Data",
+			Reference: t.TranscationID,
+			OrderID:   t.OrderID,
+			Status:    status(""),
+			Amount:    float64(t.Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Plan", t.ProductPlan,
+				"Account ID", t.AccountID,
+				"Phone number", t.Phone_Number,
+			),
+		}, nil
+	case telcom.SpectranetResult:
+		return Receipt{
+			Title:     "Spectranet",
+			Reference: t.TranscationID,
+			OrderID:   t.OrderID,
+			Status:    status(""),
+			Amount:    float64(t.Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Plan", t.Plan,
+				"Pins", strconv.Itoa(t.No_of_Pins),
+				"Phone number", t.Phone_Number,
+			),
+		}, nil
+	case telcom.AirtimeResponse:
+		recipient := t.Phone_no
+		if t.Recipient != "" {
+			recipient = t.Recipient
+		}
+		return Receipt{
+			Title:     "Airtime",
+			Reference: t.TransactionID,
+			OrderID:   t.OrderID,
+			Status:    status(t.Status),
+			Amount:    parseAmount(t.Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Network", t.Network,
+				"Phone number", recipient,
+			),
+		}, nil
+	case models.EduResponse:
+		return Receipt{
+			Title:     "Education Pins",
+			Reference: t.TransactionID,
+			OrderID:   t.OrderID,
+			Status:    status(t.Status),
+			Amount:    t.Amount,
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Exam", strings.ToUpper(t.Exam_Type),
+				"Phone number", t.Phone,
+			),
+			Pins: t.Pin_Generated,
+		}, nil
+	case models.BillResult:
+		return Receipt{
+			Title:     "TV Subscription",
+			Reference: t.TranscationID,
+			OrderID:   t.OrderID,
+			Status:    status(""),
+			Amount:    float64(t.Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Decoder", strings.ToUpper(t.DecoderType),
+				"Package", t.Package,
+				"IUC number", t.IucNumber,
+				"Name", t.Name,
+			),
+		}, nil
+	case models.ElectricResult:
+		units := t.Units
+		if units != "" {
+			units += " kWh"
+		}
+		return Receipt{
+			Title:     "Electricity",
+			Reference: t.TransactionID,
+			OrderID:   t.OrderID,
+			Status:    status(""),
+			Amount:    parseAmount(t.Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Disco", strings.ToUpper(t.DiscoType),
+				"Meter number", t.MeterNumber,
+				"Meter type", t.MeterType,
+				"Name", t.Name,
+				"Units", units,
+			),
+			Token: t.Token,
+		}, nil
+	case models.TransferResponse:
+		return Receipt{
+			Title:     "Bank Transfer",
+			Reference: t.Transaction_ID,
+			OrderID:   t.Order_ID,
+			Status:    status(""),
+			Amount:    t.Amount,
+			Fee:       t.Fee,
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Beneficiary", t.Account_Name,
+				"Bank", t.Bank_Name,
+				"Account number", t.Account_No,
+				"Narration", t.Reason,
+				"Session ID", t.Session_ID,
+			),
+		}, nil
+	case models.DepositResponse:
+		return Receipt{
+			Title:     "Wallet Top Up",
+			Reference: t.Transaction_ID,
+			OrderID:   t.Order_ID,
+			Status:    status(""),
+			Amount:    parseAmount(t.Amount),
+			Date:      t.CreatedAt,
+			Details: fields(
+				"Sender", t.Account_Name,
+				"Bank", t.Bank_Name,
+				"Account number", t.Account_No,
+				"Narration", t.Message,
+				"Session ID", t.Session_ID,
+			),
+		}, nil
+	}
+
+	return Receipt{}, ErrUnknownTransaction
+}
+
+// status is the status of a transaction for its receipt. The products that store no status only record a
+// transaction once it went through.
+func status(s string) string {
+	if s == "" {
+		return "successful"
+	}
+	return strings.ToLower(s)
+}
+
+// fields pairs labels and values, the ones without a value are left out.
+func fields(labelsAndValues ...string) []Field {
+	var result []Field
+	for i := 0; i+1 < len(labelsAndValues); i += 2 {
+		if value := strings.TrimSpace(labelsAndValues[i+1]); value != "" {
+			result = append(result, Field{Label: labelsAndValues[i], Value: value})
+		}
+	}
+	return result
+}
+
+// parseAmount reads the amounts the products store as strings, an amount that can not be read is 0.
+func parseAmount(v string) float64 {
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return amount
+}
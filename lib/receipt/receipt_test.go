@@ -0,0 +1,184 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeStore struct {
+	db.TransactionStore
+	transactions map[string]interface{}
+}
+
+func (f *fakeStore) GetTransaction(_ context.Context, userID, id string) (interface{}, error) {
+	transaction, ok := f.transactions[userID+"/"+id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return transaction, nil
+}
+
+func TestFromTransaction(t *testing.T) {
+	var tests = []struct {
+		name        string
+		transaction interface{}
+		want        Receipt
+		wantErr     error
+	}{
+		{
+			name:        "Test airtime to a recipient",
+			transaction: telcom.AirtimeResponse{Status: "Successful", Network: "MTN", Amount: "1,500", Phone_no: "08031234567", Recipient: "08039876543", OrderID: 12, TransactionID: "AIR-1"},
+			want: Receipt{Title: "Airtime", Reference: "AIR-1", OrderID: 12, Status: "successful", Amount: 1500, Details: []Field{
+				{Label: "Network", Value: "MTN"},
+				{Label: "Phone number", Value: "08039876543"},
+			}},
+		},
+		{
+			name:        "Test edu pins",
+			transaction: models.EduResponse{Exam_Type: "waec", Phone: "08031234567", Amount: 3400, Status: "successful", Pin_Generated: []string{"1111", "2222"}, OrderID: 7, TransactionID: "EDU-1"},
+			want: Receipt{Title: "Education Pins", Reference: "EDU-1", OrderID: 7, Status: "successful", Amount: 3400, Details: []Field{
+				{Label: "Exam", Value: "WAEC"},
+				{Label: "Phone number", Value: "08031234567"},
+			}, Pins: []string{"1111", "2222"}},
+		},
+		{
+			name:        "Test electricity token",
+			transaction: models.ElectricResult{Amount: "5000", DiscoType: "ikedc", MeterNumber: "4501", MeterType: "prepaid", Token: "1234-5678", Units: "20.5", OrderID: 3, TransactionID: "ELE-1"},
+			want: Receipt{Title: "Electricity", Reference: "ELE-1", OrderID: 3, Status: "successful", Amount: 5000, Details: []Field{
+				{Label: "Disco", Value: "IKEDC"},
+				{Label: "Meter number", Value: "4501"},
+				{Label: "Meter type", Value: "prepaid"},
+				{Label: "Units", Value: "20.5 kWh"},
+			}, Token: "1234-5678"},
+		},
+		{
+			name:        "Test bank transfer with its fee",
+			transaction: models.TransferResponse{Bank_Name: "GTBANK", Account_Name: "Ada Obi", Account_No: "0123456789", Amount: 10000, Fee: 50, Order_ID: 9, Transaction_ID: "TRF-1"},
+			want: Receipt{Title: "Bank Transfer", Reference: "TRF-1", OrderID: 9, Status: "successful", Amount: 10000, Fee: 50, Details: []Field{
+				{Label: "Beneficiary", Value: "Ada Obi"},
+				{Label: "Bank", Value: "GTBANK"},
+				{Label: "Account number", Value: "0123456789"},
+			}},
+		},
+		{name: "Test unknown transaction", transaction: models.User{}, wantErr: ErrUnknownTransaction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromTransaction(tt.transaction)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReceiptsGet(t *testing.T) {
+	store := &fakeStore{transactions: map[string]interface{}{
+		"user-1/42": telcom.DataResult{OrderID: 42, Plan_Amount: "300", TransactionID: "DAT-1"},
+	}}
+	receipts := NewReceipts(store)
+
+	got, err := receipts.Get(context.Background(), "user-1", "42")
+	assert.NoError(t, err)
+	assert.Equal(t, "DAT-1", got.Reference)
+	assert.Equal(t, 300.0, got.Total())
+
+	_, err = receipts.Get(context.Background(), "user-2", "42")
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+}
+
+func TestRender(t *testing.T) {
+	r := Receipt{
+		Title:     "Bank Transfer",
+		Reference: "TRF-1",
+		OrderID:   9,
+		Status:    "successful",
+		Amount:    10000,
+		Fee:       50,
+		Date:      time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		Details:   []Field{{Label: "Narration", Value: "a narration long enough to be wrapped over more than one line of the image"}},
+	}
+
+	var tests = []struct {
+		name   string
+		format Format
+		check  func(t *testing.T, content []byte)
+	}{
+		{name: "Test pdf", format: PDF, check: func(t *testing.T, content []byte) {
+			assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+		}},
+		{name: "Test png", format: PNG, check: func(t *testing.T, content []byte) {
+			img, err := png.Decode(bytes.NewReader(content))
+			assert.NoError(t, err)
+			assert.Equal(t, pngWidth*pngScale, img.Bounds().Dx())
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachment, err := r.Attachment(tt.format)
+			assert.NoError(t, err)
+			assert.Equal(t, "receipt-9."+string(tt.format), attachment.Name)
+			assert.Equal(t, tt.format.ContentType(), attachment.ContentType)
+
+			content, err := base64.StdEncoding.DecodeString(attachment.Content)
+			assert.NoError(t, err)
+			tt.check(t, content)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	var tests = []struct {
+		name    string
+		value   string
+		want    Format
+		wantErr error
+	}{
+		{name: "Test default", value: "", want: PDF},
+		{name: "Test png", value: "PNG", want: PNG},
+		{name: "Test unknown", value: "jpg", wantErr: ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormat(tt.value)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	var tests = []struct {
+		name   string
+		amount float64
+		want   string
+	}{
+		{name: "Test small", amount: 50, want: "NGN 50.00"},
+		{name: "Test thousands", amount: 1250000.5, want: "NGN 1,250,000.50"},
+		{name: "Test negative", amount: -1500, want: "NGN -1,500.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatAmount(tt.amount))
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	assert.Equal(t, []string{"Rent for", "October"}, wrap("Rent for October", 10))
+	assert.Equal(t, []string{"1234567890", "12"}, wrap("123456789012", 10))
+	assert.Equal(t, []string{""}, wrap("", 10))
+}
@@ -0,0 +1,129 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+// Format is the file format a receipt is rendered in.
+type Format string
+
+const (
+	PDF Format = "pdf"
+	PNG Format = "png"
+)
+
+var ErrUnknownFormat = errors.New("receipt format must be pdf or png")
+
+// BrandColor is the colour of the header band of the receipts and statements.
+var BrandColor = color.RGBA{R: 0x4b, G: 0x1d, B: 0x8f, A: 0xff}
+
+// colours of the text and rules of the receipts.
+var (
+	textColor  = color.RGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff}
+	mutedColor = color.RGBA{R: 0x6b, G: 0x6b, B: 0x6b, A: 0xff}
+	ruleColor  = color.RGBA{R: 0xdd, G: 0xdd, B: 0xdd, A: 0xff}
+)
+
+// ParseFormat reads the format a receipt was requested in, PDF when none was.
+func ParseFormat(v string) (Format, error) {
+	switch Format(strings.ToLower(v)) {
+	case "", PDF:
+		return PDF, nil
+	case PNG:
+		return PNG, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f Format) ContentType() string {
+	if f == PNG {
+		return "image/png"
+	}
+	return "application/pdf"
+}
+
+// Filename is the name the receipt is downloaded or attached as.
+func (r Receipt) Filename(f Format) string {
+	return fmt.Sprintf("receipt-%d.%s", r.OrderID, f)
+}
+
+// Render writes the receipt to w in the format f.
+func (r Receipt) Render(w io.Writer, f Format) error {
+	switch f {
+	case PDF:
+		return renderPDF(w, r)
+	case PNG:
+		return renderPNG(w, r)
+	}
+	return ErrUnknownFormat
+}
+
+// Attachment renders the receipt as an email attachment.
+func (r Receipt) Attachment(f Format) (models.Attachment, error) {
+	var buf bytes.Buffer
+	if err := r.Render(&buf, f); err != nil {
+		return models.Attachment{}, err
+	}
+
+	return models.Attachment{
+		Name:        r.Filename(f),
+		Content:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+		ContentType: f.ContentType(),
+	}, nil
+}
+
+// lines are the rows printed under the amount, in both formats.
+func (r Receipt) lines() []Field {
+	lines := append([]Field{}, r.Details...)
+	if r.Token != "" {
+		lines = append(lines, Field{Label: "Token", Value: r.Token})
+	}
+	for i, pin := range r.Pins {
+		lines = append(lines, Field{Label: fmt.Sprintf("Pin %d", i+1), Value: pin})
+	}
+
+	lines = append(lines, Field{Label: "Amount", Value: FormatAmount(r.Amount)})
+	if r.Fee > 0 {
+		lines = append(lines,
+			Field{Label: "Fee", Value: FormatAmount(r.Fee)},
+			Field{Label: "Total", Value: FormatAmount(r.Total())},
+		)
+	}
+	lines = append(lines, Field{Label: "Reference", Value: r.Reference})
+	if r.OrderID != 0 {
+		lines = append(lines, Field{Label: "Order ID", Value: strconv.Itoa(r.OrderID)})
+	}
+	if !r.Date.IsZero() {
+		lines = append(lines, Field{Label: "Date", Value: r.Date.In(location).Format("02 Jan 2006, 15:04")})
+	}
+	return append(lines, Field{Label: "Status", Value: strings.ToUpper(r.Status)})
+}
+
+// FormatAmount formats an amount in naira with thousands separators. The PDF core fonts have no naira
+// sign, so the currency code is used in every format.
+func FormatAmount(amount float64) string {
+	s := strconv.FormatFloat(amount, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	whole, cents := s[:len(s)-3], s[len(s)-3:]
+	var grouped []string
+	for len(whole) > 3 {
+		grouped = append([]string{whole[len(whole)-3:]}, grouped...)
+		whole = whole[:len(whole)-3]
+	}
+	grouped = append([]string{whole}, grouped...)
+
+	return "NGN " + sign + strings.Join(grouped, ",") + cents
+}
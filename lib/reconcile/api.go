@@ -0,0 +1,152 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+)
+
+// maxPages bounds the pages read from a provider's transaction list for one day.
+const maxPages = 200
+
+// puller reads a provider's transactions from its api. The list may cover only some of the products we
+// buy from the provider, our transactions of the others are not reconciled against it.
+type puller struct {
+	client   *httpclient.Client
+	fetch    func(ctx context.Context, client *httpclient.Client, from, to time.Time) ([]models.ReconRecord, error)
+	products []string
+}
+
+// dontechTransactions reads the data purchases from dontech's history, which is paged newest first.
+func dontechTransactions(ctx context.Context, client *httpclient.Client, from, to time.Time) ([]models.ReconRecord, error) {
+	records := []models.ReconRecord{}
+	next := "/data/"
+	for page := 0; next != "" && page < maxPages; page++ {
+		var res struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Ident      string `json:"ident"`
+				PlanAmount string `json:"plan_amount"`
+				Status     string `json:"Status"`
+				CreateDate string `json:"create_date"`
+			} `json:"results"`
+		}
+		if err := get(ctx, client, next, &res); err != nil {
+			return nil, err
+		}
+
+		next = res.Next
+		for _, item := range res.Results {
+			// dontech dates carry no zone, they are Lagos time
+			createdAt, err := time.ParseInLocation("2006-01-02T15:04:05.999999", item.CreateDate, location)
+			if err != nil {
+				return nil, fmt.Errorf("dontech transaction %s has an invalid date %q", item.Ident, item.CreateDate)
+			}
+			if createdAt.Before(from) {
+				next = ""
+				break
+			}
+			if createdAt.After(to) {
+				continue
+			}
+
+			amount, err := strconv.ParseFloat(strings.ReplaceAll(item.PlanAmount, ",", ""), 64)
+			if err != nil {
+				return nil, fmt.Errorf("dontech transaction %s has an invalid amount %q", item.Ident, item.PlanAmount)
+			}
+			records = append(records, models.ReconRecord{
+				Reference: item.Ident,
+				Amount:    amount,
+				Status:    item.Status,
+				Product:   "data",
+				CreatedAt: createdAt,
+			})
+		}
+	}
+
+	return records, nil
+}
+
+// anchorTransfers reads the transfers paid from our account at anchor, amounts are in kobo.
+func anchorTransfers(ctx context.Context, client *httpclient.Client, from, to time.Time) ([]models.ReconRecord, error) {
+	records := []models.ReconRecord{}
+	for page := 0; page < maxPages; page++ {
+		query := url.Values{
+			"from": {from.Format(dateLayout)},
+			"to":   {to.Format(dateLayout)},
+			"page": {strconv.Itoa(page)},
+			"size": {"100"},
+		}
+		var res struct {
+			Data []struct {
+				ID         string `json:"id"`
+				Attributes struct {
+					Reference string    `json:"reference"`
+					Amount    float64   `json:"amount"`
+					Status    string    `json:"status"`
+					CreatedAt time.Time `json:"createdAt"`
+				} `json:"attributes"`
+			} `json:"data"`
+			Meta struct {
+				Pagination struct {
+					TotalPages int `json:"totalPages"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		if err := get(ctx, client, "/transfers?"+query.Encode(), &res); err != nil {
+			return nil, err
+		}
+
+		for _, item := range res.Data {
+			attributes := item.Attributes
+			if attributes.CreatedAt.Before(from) || attributes.CreatedAt.After(to) {
+				continue
+			}
+			// transfers made before we sent our reference are reported under anchor's id
+			reference := attributes.Reference
+			if reference == "" {
+				reference = item.ID
+			}
+			records = append(records, models.ReconRecord{
+				Reference: reference,
+				Amount:    attributes.Amount / 100,
+				Status:    attributes.Status,
+				Product:   "transfer",
+				CreatedAt: attributes.CreatedAt,
+			})
+		}
+
+		if page+1 >= res.Meta.Pagination.TotalPages {
+			break
+		}
+	}
+
+	return records, nil
+}
+
+func get(ctx context.Context, client *httpclient.Client, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s transaction list request failed with status %d", client.Name(), res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
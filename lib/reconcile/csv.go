@@ -0,0 +1,108 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+var ErrInvalidCSV = errors.New("csv report is not valid")
+
+// referenceColumns are the names the providers' exports give the reference we match on, the first one
+// found is used.
+var referenceColumns = []string{"reference", "request_id", "requestid", "transaction_id", "reference_number", "ident"}
+
+// csvTimeLayouts are the layouts accepted in the date column, times without a zone are Lagos time.
+var csvTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "02/01/2006 15:04", dateLayout}
+
+// ParseCSV reads a provider's transaction report from a csv file with a header row. It needs a reference
+// and an amount column, status, product and date columns are read when they are present.
+func ParseCSV(r io.Reader) ([]models.ReconRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		// excel adds a byte order mark to the first header
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	reference := ""
+	for _, name := range referenceColumns {
+		if _, ok := columns[name]; ok {
+			reference = name
+			break
+		}
+	}
+	if reference == "" {
+		return nil, fmt.Errorf("%w: missing %q column", ErrInvalidCSV, "reference")
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, fmt.Errorf("%w: missing %q column", ErrInvalidCSV, "amount")
+	}
+	dateColumn := "date"
+	if _, ok := columns["created_at"]; ok {
+		dateColumn = "created_at"
+	}
+
+	records := []models.ReconRecord{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+
+		get := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		if get(reference) == "" && get("amount") == "" {
+			continue
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(get("amount"), ",", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d has an invalid amount %q", ErrInvalidCSV, line, get("amount"))
+		}
+		record := models.ReconRecord{
+			Reference: get(reference),
+			Amount:    amount,
+			Status:    get("status"),
+			Product:   get("product"),
+		}
+		if v := get(dateColumn); v != "" {
+			if record.CreatedAt, err = parseCSVTime(v); err != nil {
+				return nil, fmt.Errorf("%w: line %d has an invalid date %q", ErrInvalidCSV, line, v)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseCSVTime(v string) (time.Time, error) {
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, v, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unknown time layout")
+}
@@ -0,0 +1,123 @@
+package reconcile
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+// tolerance is the difference between two amounts still taken as equal, it absorbs rounding of kobo.
+const tolerance = 0.01
+
+// result is the outcome of matching one reference.
+type result struct {
+	outcome   string
+	reference string
+	ours      *models.ReconRecord
+	theirs    *models.ReconRecord
+}
+
+// match pairs our records with theirs by reference and classifies every reference. Records without a
+// reference can not be paired and are reported missing on the other side.
+func match(ours, theirs []models.ReconRecord) []result {
+	byReference := map[string]*models.ReconRecord{}
+	for i := range theirs {
+		if theirs[i].Reference != "" {
+			byReference[theirs[i].Reference] = &theirs[i]
+		}
+	}
+
+	results := []result{}
+	paired := map[string]bool{}
+	for i := range ours {
+		record := &ours[i]
+		other, ok := byReference[record.Reference]
+		switch {
+		case !ok || paired[record.Reference]:
+			results = append(results, result{outcome: models.ReconMissingAtProvider, reference: record.Reference, ours: record})
+		case math.Abs(record.Amount-other.Amount) > tolerance:
+			results = append(results, result{outcome: models.ReconAmountMismatch, reference: record.Reference, ours: record, theirs: other})
+		default:
+			results = append(results, result{outcome: models.ReconMatched, reference: record.Reference, ours: record, theirs: other})
+		}
+		if ok {
+			paired[record.Reference] = true
+		}
+	}
+
+	for i := range theirs {
+		record := &theirs[i]
+		if record.Reference == "" || !paired[record.Reference] {
+			results = append(results, result{outcome: models.ReconMissingOnOurSide, reference: record.Reference, theirs: record})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return createdAt(results[i]).Before(createdAt(results[j]))
+	})
+	return results
+}
+
+func createdAt(r result) time.Time {
+	if r.ours != nil {
+		return r.ours.CreatedAt
+	}
+	return r.theirs.CreatedAt
+}
+
+// tally adds result to the counts and totals of rec.
+func tally(rec *models.Reconciliation, r result) {
+	switch r.outcome {
+	case models.ReconMatched:
+		rec.Matched++
+	case models.ReconMissingOnOurSide:
+		rec.MissingOnOurSide++
+	case models.ReconMissingAtProvider:
+		rec.MissingAtProvider++
+	case models.ReconAmountMismatch:
+		rec.AmountMismatch++
+	}
+	if r.ours != nil {
+		rec.OurTotal += r.ours.Amount
+	}
+	if r.theirs != nil {
+		rec.ProviderTotal += r.theirs.Amount
+	}
+}
+
+// only returns the records of products.
+func only(records []models.ReconRecord, products []string) []models.ReconRecord {
+	res := []models.ReconRecord{}
+	for _, record := range records {
+		for _, product := range products {
+			if record.Product == product {
+				res = append(res, record)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func validProvider(provider string) error {
+	switch provider {
+	case ProviderVTpass, ProviderEasyAccess, ProviderDontech, ProviderAnchor:
+		return nil
+	}
+	return ErrUnknownProvider
+}
+
+// day returns the start and end of date in Lagos, it must be a day before now.
+func day(date string, now time.Time) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation(dateLayout, date, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidDate
+	}
+	to := from.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	if !to.Before(now) {
+		return time.Time{}, time.Time{}, ErrInvalidDate
+	}
+	return from, to, nil
+}
@@ -0,0 +1,246 @@
+// Package reconcile matches the transactions we recorded on a day with the providers' reports of the same
+// day, and keeps the ones that do not match as exceptions for finance to resolve.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// providers reconciled, the names of their clients.
+const (
+	ProviderVTpass     = "vtpass"
+	ProviderEasyAccess = "easyaccess"
+	ProviderDontech    = "dontech"
+	ProviderAnchor     = "anchor"
+)
+
+// where the provider's transactions of a report came from.
+const (
+	SourceAPI = "api"
+	SourceCSV = "csv"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	defaultInterval = time.Hour
+)
+
+var (
+	ErrUnknownProvider   = errors.New("provider must be vtpass, easyaccess, dontech or anchor")
+	ErrNoAPI             = errors.New("provider has no transaction report api, upload its csv report")
+	ErrInvalidDate       = errors.New("date must be a past day (2006-01-02)")
+	ErrExceptionNotFound = errors.New("no open reconciliation exception with this id")
+	ErrNoResolution      = errors.New("resolution is required")
+)
+
+// location is the time zone the days are reconciled in.
+var location = time.FixedZone("WAT", 60*60)
+
+type Options struct {
+	Store     db.DataStore
+	Providers *httpclient.Providers
+	Logger    *zap.Logger
+}
+
+// Reconciler reconciles the transactions of a day with each provider.
+type Reconciler struct {
+	db          db.DataStore
+	pullers     map[string]puller
+	idGenerator idgenerator.IdGenerator
+	logger      *zap.Logger
+
+	// reconciled is the last day this instance pulled every provider's report for, so the worker does not
+	// pull them again until the next day.
+	reconciled string
+}
+
+func NewReconciler(opt *Options) *Reconciler {
+	return &Reconciler{
+		db: opt.Store,
+		pullers: map[string]puller{
+			ProviderDontech: {client: opt.Providers.Dontech, fetch: dontechTransactions, products: []string{"data"}},
+			ProviderAnchor:  {client: opt.Providers.Anchor, fetch: anchorTransfers, products: []string{"transfer"}},
+		},
+		idGenerator: idgenerator.New(),
+		logger:      opt.Logger,
+	}
+}
+
+// Pull fetches the provider's transactions of date from its api and reconciles them.
+func (r *Reconciler) Pull(ctx context.Context, provider, date string) (models.Reconciliation, error) {
+	if err := validProvider(provider); err != nil {
+		return models.Reconciliation{}, err
+	}
+	p, ok := r.pullers[provider]
+	if !ok {
+		return models.Reconciliation{}, fmt.Errorf("%w: %s", ErrNoAPI, provider)
+	}
+	from, to, err := day(date, time.Now())
+	if err != nil {
+		return models.Reconciliation{}, err
+	}
+
+	theirs, err := p.fetch(ctx, p.client, from, to)
+	if err != nil {
+		return models.Reconciliation{}, r.logAndReturnError("failed to fetch provider transactions", err)
+	}
+
+	return r.reconcile(ctx, provider, date, SourceAPI, theirs, p.products)
+}
+
+// Reconcile matches the provider's transactions of date, read from its report, with ours.
+func (r *Reconciler) Reconcile(ctx context.Context, provider, date string, theirs []models.ReconRecord) (models.Reconciliation, error) {
+	if err := validProvider(provider); err != nil {
+		return models.Reconciliation{}, err
+	}
+	if _, _, err := day(date, time.Now()); err != nil {
+		return models.Reconciliation{}, err
+	}
+
+	return r.reconcile(ctx, provider, date, SourceCSV, theirs, nil)
+}
+
+// reconcile matches theirs with our transactions of the day and saves the report. When products is not
+// empty the provider's report only covers them and our other transactions are left out.
+func (r *Reconciler) reconcile(ctx context.Context, provider, date, source string, theirs []models.ReconRecord, products []string) (models.Reconciliation, error) {
+	from, to, _ := day(date, time.Now())
+	ours, err := r.db.GetReconRecords(ctx, provider, from, to)
+	if err != nil {
+		return models.Reconciliation{}, r.logAndReturnError("failed to get transactions to reconcile", err)
+	}
+	if len(products) > 0 {
+		ours = only(ours, products)
+	}
+
+	resolved, err := r.db.GetResolvedReconExceptions(ctx, provider, date)
+	if err != nil {
+		return models.Reconciliation{}, r.logAndReturnError("failed to get resolved exceptions", err)
+	}
+	done := map[string]bool{}
+	for _, exception := range resolved {
+		done[exception.Type+"/"+exception.Reference] = true
+	}
+
+	now := time.Now()
+	rec := models.Reconciliation{
+		ID:        r.idGenerator.Generate(),
+		Provider:  provider,
+		Date:      date,
+		Source:    source,
+		CreatedAt: now,
+	}
+	exceptions := []models.ReconException{}
+	for _, result := range match(ours, theirs) {
+		tally(&rec, result)
+		if result.outcome == models.ReconMatched || done[result.outcome+"/"+result.reference] {
+			continue
+		}
+		exceptions = append(exceptions, models.ReconException{
+			ID:               r.idGenerator.Generate(),
+			ReconciliationID: rec.ID,
+			Provider:         provider,
+			Date:             date,
+			Type:             result.outcome,
+			Reference:        result.reference,
+			Ours:             result.ours,
+			Theirs:           result.theirs,
+			Status:           models.ExceptionOpen,
+			CreatedAt:        now,
+		})
+	}
+
+	if err := r.db.SaveReconciliation(ctx, rec, exceptions); err != nil {
+		return models.Reconciliation{}, r.logAndReturnError("failed to save reconciliation", err)
+	}
+
+	return rec, nil
+}
+
+// Reports returns a page of the reconciliation reports of provider, of every provider when it is empty.
+func (r *Reconciler) Reports(ctx context.Context, opts db.ListOptions, provider string) ([]models.Reconciliation, string, error) {
+	reports, next, err := r.db.GetReconciliations(ctx, opts, provider)
+	if err != nil {
+		return nil, "", r.logAndReturnError("failed to get reconciliations", err)
+	}
+	return reports, next, nil
+}
+
+// Exceptions returns a page of the exceptions of provider, of every provider when it is empty.
+func (r *Reconciler) Exceptions(ctx context.Context, opts db.ListOptions, provider string) ([]models.ReconException, string, error) {
+	exceptions, next, err := r.db.GetReconExceptions(ctx, opts, provider)
+	if err != nil {
+		return nil, "", r.logAndReturnError("failed to get reconciliation exceptions", err)
+	}
+	return exceptions, next, nil
+}
+
+// Resolve closes an open exception with a note of how it was settled.
+func (r *Reconciler) Resolve(ctx context.Context, id, resolution, resolvedBy string) error {
+	if resolution == "" {
+		return ErrNoResolution
+	}
+
+	err := r.db.ResolveReconException(ctx, id, resolution, resolvedBy, time.Now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrExceptionNotFound
+	}
+	if err != nil {
+		return r.logAndReturnError("failed to resolve reconciliation exception", err)
+	}
+	return nil
+}
+
+// Run reconciles the previous day with every provider that has a transaction api, checking every interval
+// until ctx is cancelled. Several instances may reconcile the same day, the last report replaces the others.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.ReconcileDaily(ctx, time.Now())
+		}
+	}
+}
+
+// ReconcileDaily pulls the reports of the day before now. A provider that fails is logged and not pulled
+// again by the worker, finance can pull it from the admin api.
+func (r *Reconciler) ReconcileDaily(ctx context.Context, now time.Time) {
+	date := now.In(location).AddDate(0, 0, -1).Format(dateLayout)
+	if r.reconciled == date {
+		return
+	}
+
+	for provider := range r.pullers {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := r.Pull(ctx, provider, date); err != nil {
+			r.logger.Error("failed to reconcile provider", zap.String("provider", provider), zap.String("date", date), zap.Error(err))
+		}
+	}
+
+	r.reconciled = date
+}
+
+func (r *Reconciler) logAndReturnError(msg string, err error) error {
+	r.logger.Error(msg, zap.Error(err))
+	return err
+}
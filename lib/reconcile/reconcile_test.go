@@ -0,0 +1,215 @@
+package reconcile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	db.DataStore
+	records    []models.ReconRecord
+	reports    []models.Reconciliation
+	exceptions []models.ReconException
+}
+
+func (f *fakeStore) GetReconRecords(_ context.Context, provider string, from, to time.Time) ([]models.ReconRecord, error) {
+	var res []models.ReconRecord
+	for _, record := range f.records {
+		if !record.CreatedAt.Before(from) && !record.CreatedAt.After(to) {
+			res = append(res, record)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) SaveReconciliation(_ context.Context, rec models.Reconciliation, exceptions []models.ReconException) error {
+	f.reports = append(f.reports, rec)
+	kept := []models.ReconException{}
+	for _, exception := range f.exceptions {
+		if exception.Status != models.ExceptionOpen || exception.Provider != rec.Provider || exception.Date != rec.Date {
+			kept = append(kept, exception)
+		}
+	}
+	f.exceptions = append(kept, exceptions...)
+	return nil
+}
+
+func (f *fakeStore) GetResolvedReconExceptions(_ context.Context, provider, date string) ([]models.ReconException, error) {
+	var res []models.ReconException
+	for _, exception := range f.exceptions {
+		if exception.Provider == provider && exception.Date == date && exception.Status == models.ExceptionResolved {
+			res = append(res, exception)
+		}
+	}
+	return res, nil
+}
+
+func at(hour int) time.Time {
+	return time.Date(2024, 5, 1, hour, 0, 0, 0, location)
+}
+
+func TestMatch(t *testing.T) {
+	ours := []models.ReconRecord{
+		{Reference: "A1", Amount: 100, CreatedAt: at(9)},
+		{Reference: "A2", Amount: 200, CreatedAt: at(10)},
+		{Reference: "A3", Amount: 300, CreatedAt: at(11)},
+		{Reference: "A1", Amount: 100, CreatedAt: at(12)},
+	}
+	theirs := []models.ReconRecord{
+		{Reference: "A1", Amount: 100.004, CreatedAt: at(9)},
+		{Reference: "A2", Amount: 250, CreatedAt: at(10)},
+		{Reference: "B1", Amount: 50, CreatedAt: at(13)},
+		{Reference: "", Amount: 70, CreatedAt: at(14)},
+	}
+
+	var tests = []struct {
+		name      string
+		reference string
+		want      string
+	}{
+		{name: "Test matched within tolerance", reference: "A1", want: models.ReconMatched},
+		{name: "Test amount mismatch", reference: "A2", want: models.ReconAmountMismatch},
+		{name: "Test missing at provider", reference: "A3", want: models.ReconMissingAtProvider},
+		{name: "Test missing on our side", reference: "B1", want: models.ReconMissingOnOurSide},
+	}
+
+	results := match(ours, theirs)
+	assert.Len(t, results, 6)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, result := range results {
+				if result.reference == tt.reference {
+					assert.Equal(t, tt.want, result.outcome)
+					return
+				}
+			}
+			t.Errorf("no result for %s", tt.reference)
+		})
+	}
+
+	t.Run("Test duplicate reference paired once", func(t *testing.T) {
+		if assert.Equal(t, models.ReconMissingAtProvider, results[3].outcome) {
+			assert.Equal(t, at(12), results[3].ours.CreatedAt)
+		}
+	})
+	t.Run("Test provider record without reference", func(t *testing.T) {
+		assert.Equal(t, models.ReconMissingOnOurSide, results[5].outcome)
+	})
+}
+
+func TestParseCSV(t *testing.T) {
+	var tests = []struct {
+		name    string
+		csv     string
+		want    []models.ReconRecord
+		wantErr error
+	}{
+		{
+			name: "Test vtpass export",
+			csv:  "\ufeffRequest_ID,Amount,Status,Date\nREQ1,\"1,500.00\",delivered,2024-05-01 10:15:00\n,,,\n",
+			want: []models.ReconRecord{{Reference: "REQ1", Amount: 1500, Status: "delivered", CreatedAt: time.Date(2024, 5, 1, 10, 15, 0, 0, location)}},
+		},
+		{
+			name: "Test reference column",
+			csv:  "reference,amount\nR1,100\n",
+			want: []models.ReconRecord{{Reference: "R1", Amount: 100}},
+		},
+		{name: "Test missing reference column", csv: "id,amount\n1,100\n", wantErr: ErrInvalidCSV},
+		{name: "Test missing amount column", csv: "reference,value\nR1,100\n", wantErr: ErrInvalidCSV},
+		{name: "Test invalid amount", csv: "reference,amount\nR1,ten\n", wantErr: ErrInvalidCSV},
+		{name: "Test empty file", csv: "", wantErr: ErrInvalidCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.csv))
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	store := &fakeStore{records: []models.ReconRecord{
+		{Reference: "R1", Amount: 100, Product: "airtime", CreatedAt: at(9)},
+		{Reference: "R2", Amount: 200, Product: "airtime", CreatedAt: at(10)},
+		{Reference: "R3", Amount: 300, Product: "edu", CreatedAt: at(11)},
+	}}
+	r := NewReconciler(&Options{Store: store, Providers: &httpclient.Providers{}, Logger: zap.NewNop()})
+	theirs := []models.ReconRecord{{Reference: "R1", Amount: 100}, {Reference: "R2", Amount: 150}}
+
+	rec, err := r.Reconcile(context.Background(), ProviderEasyAccess, "2024-05-01", theirs)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceCSV, rec.Source)
+	assert.Equal(t, 1, rec.Matched)
+	assert.Equal(t, 1, rec.AmountMismatch)
+	assert.Equal(t, 1, rec.MissingAtProvider)
+	assert.Equal(t, 600.0, rec.OurTotal)
+	assert.Equal(t, 250.0, rec.ProviderTotal)
+	assert.Len(t, store.exceptions, 2)
+
+	// a resolved exception is not raised again when the day is reconciled again
+	store.exceptions[0].Status = models.ExceptionResolved
+	_, err = r.Reconcile(context.Background(), ProviderEasyAccess, "2024-05-01", theirs)
+	assert.NoError(t, err)
+	assert.Len(t, store.exceptions, 2)
+
+	var tests = []struct {
+		name     string
+		provider string
+		date     string
+		wantErr  error
+	}{
+		{name: "Test unknown provider", provider: "paystack", date: "2024-05-01", wantErr: ErrUnknownProvider},
+		{name: "Test invalid date", provider: ProviderVTpass, date: "01-05-2024", wantErr: ErrInvalidDate},
+		{name: "Test day not over", provider: ProviderVTpass, date: time.Now().In(location).Format(dateLayout), wantErr: ErrInvalidDate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Reconcile(context.Background(), tt.provider, tt.date, nil)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPull(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"next":null,"results":[
+			{"ident":"D2","plan_amount":"500.0","Status":"successful","create_date":"2024-05-02T08:00:00.000000"},
+			{"ident":"D1","plan_amount":"260.0","Status":"successful","create_date":"2024-05-01T12:30:00.123456"},
+			{"ident":"D0","plan_amount":"100.0","Status":"successful","create_date":"2024-04-30T23:00:00.000000"}]}`))
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{records: []models.ReconRecord{
+		{Reference: "D1", Amount: 260, Product: "data", CreatedAt: at(12)},
+		{Reference: "T1", Amount: 900, Product: "transfer", CreatedAt: at(13)},
+	}}
+	providers := &httpclient.Providers{
+		Dontech: httpclient.New(&httpclient.Options{Name: "dontech", BaseURL: srv.URL}),
+	}
+	r := NewReconciler(&Options{Store: store, Providers: providers, Logger: zap.NewNop()})
+
+	rec, err := r.Pull(context.Background(), ProviderDontech, "2024-05-01")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceAPI, rec.Source)
+	// the transfer is not in the data report, D0 and D2 are other days
+	assert.Equal(t, 1, rec.Matched)
+	assert.Equal(t, 0, rec.MissingAtProvider)
+	assert.Equal(t, 0, rec.MissingOnOurSide)
+
+	_, err = r.Pull(context.Background(), ProviderVTpass, "2024-05-01")
+	assert.ErrorIs(t, err, ErrNoAPI)
+}
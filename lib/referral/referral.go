@@ -1,6 +1,7 @@
 package referral
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
@@ -19,10 +20,10 @@ func NewRefConfig(store db.Extras) *RefConfig {
 	}
 }
 
-func (r *RefConfig) CreateReferral(userID string) (string, error) {
+func (r *RefConfig) CreateReferral(ctx context.Context, userID string) (string, error) {
 	code := generateReferralCode(6)
 
-	err := r.db.CreateUserReferral(userID, code)
+	err := r.db.CreateUserReferral(ctx, userID, code)
 	if err != nil {
 		return "", err
 	}
@@ -30,9 +31,9 @@ func (r *RefConfig) CreateReferral(userID string) (string, error) {
 	return code, nil
 }
 
-func (r *RefConfig) GetReferral(userID string) (string, error) {
+func (r *RefConfig) GetReferral(ctx context.Context, userID string) (string, error) {
 
-	code, err := r.db.GetReferral(userID)
+	code, err := r.db.GetReferral(ctx, userID)
 	if err != nil {
 		return "", err
 	}
@@ -40,9 +41,9 @@ func (r *RefConfig) GetReferral(userID string) (string, error) {
 	return code, nil
 }
 
-func (r *RefConfig) UpdateReferralCount(userID, referralCode string) error {
+func (r *RefConfig) UpdateReferralCount(ctx context.Context, userID, referralCode string) error {
 
-	err := r.db.UpdateReferralCount(referralCode)
+	err := r.db.UpdateReferralCount(ctx, referralCode)
 	if err != nil {
 		return err
 	}
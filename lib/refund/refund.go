@@ -0,0 +1,435 @@
+// Package refund returns the money of failed or bounced transactions to the users' wallets. Refunds are
+// requested by support or automatically, wait for approval above a threshold and keep a trail of every
+// status they go through.
+package refund
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/aremxyplug-be/lib/wallet"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	// NotificationAlias is the postmark template used to tell a user a refund completed or was rejected.
+	NotificationAlias = "refund-update"
+
+	// actors of the changes made by the service itself.
+	actorApproval = "auto-approval"
+	actorWallet   = "wallet"
+	actorWebhook  = "anchor-webhook"
+	actorRequery  = "requery-worker"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction to refund not found")
+	ErrNotRefundable       = errors.New("deposits can not be refunded")
+	ErrInvalidAmount       = errors.New("refund amount must be positive and at most the amount paid")
+	ErrReasonRequired      = errors.New("refund reason is required")
+	ErrRefundExists        = errors.New("transaction already has a refund")
+	ErrAlreadyReturned     = errors.New("transaction was already returned to the wallet")
+	ErrNotFailed           = errors.New("only failed transactions can be refunded")
+	ErrNotDebited          = errors.New("transaction was not paid from the wallet")
+	ErrRefundNotFound      = errors.New("refund not found")
+	ErrInvalidStatus       = errors.New("refund is not pending approval")
+	ErrCreditFailed        = errors.New("refund could not be credited to the wallet")
+)
+
+type Options struct {
+	Store       db.DataStore
+	Wallet      *wallet.Wallet
+	EmailClient emailclient.EmailClient
+	// ApprovalThreshold is the amount above which a refund waits for support to approve it.
+	ApprovalThreshold float64
+	// Providers are requeried by Run for the purchases paid from the wallets.
+	Providers *httpclient.Providers
+	Logger    *zap.Logger
+}
+
+// Request asks for the refund of a transaction, identified by its order id. An Amount of 0 refunds
+// everything the user paid for it. The refund always goes to the owner of the transaction, UserID only
+// narrows the lookup to the transactions of that user.
+type Request struct {
+	UserID        string  `json:"-"`
+	TransactionID string  `json:"transaction_id"`
+	Amount        float64 `json:"amount"`
+	Reason        string  `json:"reason"`
+	Source        string  `json:"-"`
+	RequestedBy   string  `json:"-"`
+}
+
+// Refunds requests, approves and pays the refunds.
+type Refunds struct {
+	db          db.DataStore
+	wallet      *wallet.Wallet
+	emailClient emailclient.EmailClient
+	threshold   float64
+	providers   *httpclient.Providers
+	idGenerator idgenerator.IdGenerator
+	logger      *zap.Logger
+	// requeriedTo is where the next requery of the purchases starts, it is only used by Run.
+	requeriedTo time.Time
+}
+
+func NewRefunds(opt *Options) *Refunds {
+	return &Refunds{
+		db:          opt.Store,
+		wallet:      opt.Wallet,
+		emailClient: opt.EmailClient,
+		threshold:   opt.ApprovalThreshold,
+		providers:   opt.Providers,
+		idGenerator: idgenerator.New(),
+		logger:      opt.Logger,
+	}
+}
+
+// Request records a refund of the transaction. Only a transaction that did not succeed and was paid from
+// the wallet can be refunded, and at most what the wallet was debited for it. A refund up to the approval
+// threshold is approved and paid at once, a larger one waits for Approve.
+func (r *Refunds) Request(ctx context.Context, req Request) (models.Refund, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return models.Refund{}, ErrReasonRequired
+	}
+
+	txn, err := r.db.GetTransaction(ctx, req.UserID, req.TransactionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Refund{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return models.Refund{}, r.logAndReturnError("failed to get transaction to refund", err)
+	}
+
+	p, err := purchaseOf(txn)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	if p.owner == "" {
+		return models.Refund{}, ErrTransactionNotFound
+	}
+	if p.succeeded {
+		return models.Refund{}, ErrNotFailed
+	}
+
+	debited, err := r.debited(ctx, p.owner, req.TransactionID)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	paid := math.Min(p.paid, debited)
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = paid
+	}
+	if amount <= 0 || amount > paid+0.005 {
+		return models.Refund{}, ErrInvalidAmount
+	}
+
+	now := time.Now().UTC()
+	refund := models.Refund{
+		ID:            r.idGenerator.Generate(),
+		UserID:        p.owner,
+		Product:       p.product,
+		TransactionID: req.TransactionID,
+		Amount:        math.Round(amount*100) / 100,
+		Reason:        req.Reason,
+		Source:        req.Source,
+		RequestedBy:   req.RequestedBy,
+		Status:        models.RefundPending,
+		Active:        req.TransactionID,
+		Trail:         []models.RefundEvent{{Status: models.RefundPending, Actor: req.RequestedBy, Note: req.Reason, At: now}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	err = r.db.CreateRefund(ctx, refund)
+	if errors.Is(err, db.ErrDuplicate) {
+		return models.Refund{}, ErrRefundExists
+	}
+	if err != nil {
+		return models.Refund{}, r.logAndReturnError("failed to save refund", err)
+	}
+
+	if refund.Amount > r.threshold {
+		return refund, nil
+	}
+	return r.approve(ctx, refund, actorApproval, "below the approval threshold")
+}
+
+// TransferFailed marks a transfer the bank sent back failed and requests its refund, the transfer is found
+// by the transaction id anchor reports it under. The fee is refunded with the amount.
+func (r *Refunds) TransferFailed(ctx context.Context, transactionID, reason string) (models.Refund, error) {
+	transfer, err := r.db.GetTransferByTransactionID(ctx, transactionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Refund{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return models.Refund{}, r.logAndReturnError("failed to get failed transfer", err)
+	}
+
+	switch transfer.Status {
+	case models.TransferRejected:
+		// reversed when the bank rejected it
+		return models.Refund{}, ErrAlreadyReturned
+	case models.TransferFailed:
+	default:
+		err := r.db.UpdateTransferStatus(ctx, transactionID, models.TransferFailed)
+		if err != nil {
+			return models.Refund{}, r.logAndReturnError("failed to mark transfer failed", err)
+		}
+	}
+
+	if reason == "" {
+		reason = "transfer failed"
+	}
+	return r.Request(ctx, Request{
+		UserID:        transfer.UserID,
+		TransactionID: strconv.Itoa(transfer.Order_ID),
+		Reason:        "Transfer to " + transfer.Account_Name + " " + reason,
+		Source:        models.RefundSourceAutomatic,
+		RequestedBy:   actorWebhook,
+	})
+}
+
+// Approve approves a refund pending approval and pays it.
+func (r *Refunds) Approve(ctx context.Context, id, actor, note string) (models.Refund, error) {
+	refund, err := r.Get(ctx, id)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	if refund.Status != models.RefundPending {
+		return models.Refund{}, ErrInvalidStatus
+	}
+
+	return r.approve(ctx, refund, actor, note)
+}
+
+// Reject turns down a refund pending approval, its transaction can be refunded again later.
+func (r *Refunds) Reject(ctx context.Context, id, actor, note string) (models.Refund, error) {
+	if strings.TrimSpace(note) == "" {
+		return models.Refund{}, ErrReasonRequired
+	}
+
+	event := models.RefundEvent{Status: models.RefundRejected, Actor: actor, Note: note, At: time.Now().UTC()}
+	if err := r.move(ctx, id, models.RefundPending, event); err != nil {
+		return models.Refund{}, err
+	}
+
+	refund, err := r.Get(ctx, id)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	r.notify(ctx, refund, note)
+	return refund, nil
+}
+
+// Get returns the refund with id.
+func (r *Refunds) Get(ctx context.Context, id string) (models.Refund, error) {
+	refund, err := r.db.GetRefund(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Refund{}, ErrRefundNotFound
+	}
+	if err != nil {
+		return models.Refund{}, r.logAndReturnError("failed to get refund", err)
+	}
+	return refund, nil
+}
+
+// List returns a page of the refunds, the status and user of opts narrow it.
+func (r *Refunds) List(ctx context.Context, opts db.ListOptions) ([]models.Refund, string, error) {
+	refunds, next, err := r.db.GetRefunds(ctx, opts)
+	if err != nil {
+		return nil, "", r.logAndReturnError("failed to get refunds", err)
+	}
+	return refunds, next, nil
+}
+
+// approve moves the refund to approved and credits the wallet with a reversal entry of the transaction.
+// The credit and the completed status are written in one transaction, a refund that could not be
+// credited is marked failed so it can be requested again.
+func (r *Refunds) approve(ctx context.Context, refund models.Refund, actor, note string) (models.Refund, error) {
+	event := models.RefundEvent{Status: models.RefundApproved, Actor: actor, Note: note, At: time.Now().UTC()}
+	if err := r.move(ctx, refund.ID, models.RefundPending, event); err != nil {
+		return models.Refund{}, err
+	}
+
+	err := r.credit(ctx, refund)
+	if err != nil {
+		r.logger.Error("failed to credit refund", zap.String("refund_id", refund.ID), zap.Error(err))
+		failed := models.RefundEvent{Status: models.RefundFailed, Actor: actorWallet, Note: err.Error(), At: time.Now().UTC()}
+		if err := r.move(context.WithoutCancel(ctx), refund.ID, models.RefundApproved, failed); err != nil {
+			r.logger.Error("failed to mark refund failed", zap.String("refund_id", refund.ID), zap.Error(err))
+		}
+	}
+
+	updated, getErr := r.Get(ctx, refund.ID)
+	if getErr != nil {
+		return models.Refund{}, getErr
+	}
+	if err != nil {
+		return updated, fmt.Errorf("%w: %v", ErrCreditFailed, err)
+	}
+
+	metrics.Reversed(refund.Product, refund.Amount)
+	r.notify(ctx, updated, "")
+	return updated, nil
+}
+
+func (r *Refunds) credit(ctx context.Context, refund models.Refund) error {
+	user, err := r.db.GetUserByID(ctx, refund.UserID)
+	if err != nil {
+		return err
+	}
+
+	reversal := wallet.Movement{
+		Type:        models.EntryReversal,
+		Reference:   refund.TransactionID,
+		Description: "Refund of " + refund.Product + " " + refund.TransactionID,
+	}
+	completed := models.RefundEvent{Status: models.RefundCompleted, Actor: actorWallet, At: time.Now().UTC()}
+	return r.db.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := r.db.UpdateRefundStatus(ctx, refund.ID, models.RefundApproved, completed); err != nil {
+			return err
+		}
+		return r.wallet.Credit(ctx, user.Username, refund.Amount, reversal)
+	})
+}
+
+// move changes the status of a refund from from, it fails with ErrInvalidStatus when the refund is no
+// longer in it because another request moved it first.
+func (r *Refunds) move(ctx context.Context, id, from string, event models.RefundEvent) error {
+	err := r.db.UpdateRefundStatus(ctx, id, from, event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, getErr := r.Get(ctx, id); getErr != nil {
+			return getErr
+		}
+		return ErrInvalidStatus
+	}
+	if err != nil {
+		return r.logAndReturnError("failed to update refund", err)
+	}
+	return nil
+}
+
+// notify emails the user the outcome of the refund. A failed email is logged, the refund stands.
+func (r *Refunds) notify(ctx context.Context, refund models.Refund, note string) {
+	user, err := r.db.GetUserByID(ctx, refund.UserID)
+	if err != nil || user.Email == "" {
+		return
+	}
+
+	name := user.FullName
+	if name == "" {
+		name = user.Username
+	}
+	message := models.Message{
+		ID:         r.idGenerator.Generate(),
+		Target:     user.Email,
+		Type:       models.EMAIL_MESSAGE_TYPE,
+		Title:      "Refund " + strings.ReplaceAll(refund.Status, "_", " "),
+		TemplateID: NotificationAlias,
+		DataMap: map[string]string{
+			"Name":          name,
+			"Status":        refund.Status,
+			"Amount":        receipt.FormatAmount(refund.Amount),
+			"Product":       refund.Product,
+			"TransactionID": refund.TransactionID,
+			"Note":          note,
+		},
+		Ts: time.Now().Unix(),
+	}
+	if err := r.emailClient.Send(&message); err != nil {
+		r.logger.Error("failed to send refund notification", zap.String("refund_id", refund.ID), zap.Error(err))
+	}
+}
+
+func (r *Refunds) logAndReturnError(msg string, err error) error {
+	r.logger.Error(msg, zap.Error(err))
+	return err
+}
+
+// debited returns what the wallet of userID was debited for the order reference. It fails with
+// ErrNotDebited when nothing was and with ErrAlreadyReturned when the money already went back.
+func (r *Refunds) debited(ctx context.Context, userID, reference string) (float64, error) {
+	entries, err := r.db.GetLedgerEntriesByReference(ctx, userID, reference)
+	if err != nil {
+		return 0, r.logAndReturnError("failed to get ledger entries to refund", err)
+	}
+
+	var debited float64
+	for _, entry := range entries {
+		switch entry.Type {
+		case models.EntryPurchase, models.EntryTransfer, models.EntryFee:
+			debited -= entry.Amount
+		case models.EntryReversal, models.EntryRefund:
+			return 0, ErrAlreadyReturned
+		}
+	}
+	if debited <= 0 {
+		return 0, ErrNotDebited
+	}
+	return debited, nil
+}
+
+// purchase is what a refund needs to know of a transaction.
+type purchase struct {
+	product   string
+	owner     string
+	paid      float64
+	succeeded bool
+}
+
+// purchaseOf returns the product, owner and price of a transaction and whether it succeeded. Tv,
+// electricity, smile and spectranet purchases store no status, only the ledger tells if they can be
+// refunded. A transfer can only be refunded once anchor reported it failed.
+func purchaseOf(txn interface{}) (purchase, error) {
+	switch t := txn.(type) {
+	case telcom.DataResult:
+		return purchase{product: "data", owner: t.UserID, paid: parseAmount(t.Plan_Amount), succeeded: succeeded(t.Status)}, nil
+	case telcom.SmileResult:
+		return purchase{product: "data", owner: t.UserID, paid: float64(t.Amount)}, nil
+	case telcom.SpectranetResult:
+		return purchase{product: "data", owner: t.UserID, paid: float64(t.Amount)}, nil
+	case telcom.AirtimeResponse:
+		return purchase{product: "airtime", owner: t.UserID, paid: parseAmount(t.Amount), succeeded: succeeded(t.Status)}, nil
+	case models.EduResponse:
+		return purchase{product: "edu", owner: t.UserID, paid: t.Amount, succeeded: succeeded(t.Status)}, nil
+	case models.BillResult:
+		return purchase{product: "tv", owner: t.UserID, paid: float64(t.Amount)}, nil
+	case models.ElectricResult:
+		return purchase{product: "electricity", owner: t.UserID, paid: parseAmount(t.Amount)}, nil
+	case models.TransferResponse:
+		return purchase{product: "transfer", owner: t.UserID, paid: t.Amount + t.Fee,
+			succeeded: t.Status != models.TransferFailed && t.Status != models.TransferRejected}, nil
+	default:
+		return purchase{}, ErrNotRefundable
+	}
+}
+
+// succeeded reports whether a provider status is a success, the providers word it differently.
+func succeeded(status string) bool {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "successful", "delivered", "completed":
+		return true
+	}
+	return false
+}
+
+// parseAmount reads an amount stored as a string, an unreadable one is 0 and can not be refunded.
+func parseAmount(v string) float64 {
+	amount, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+	return amount
+}
@@ -0,0 +1,385 @@
+package refund
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/wallet"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the refunds and balances in memory and rolls them back when a transaction fails.
+type fakeStore struct {
+	db.DataStore
+	transactions map[string]interface{}
+	refunds      map[string]models.Refund
+	balances     map[string]float64
+	// debits are the ledger entries made before the test, entries the ones made by it
+	debits  []models.LedgerEntry
+	entries []models.LedgerEntry
+	depth   int
+}
+
+func debit(userID, entryType, reference string, amount float64) models.LedgerEntry {
+	return models.LedgerEntry{UserID: userID, Type: entryType, Reference: reference, Amount: -amount}
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		transactions: map[string]interface{}{
+			"1": telcom.AirtimeResponse{Amount: "500", UserID: "user-ada", Status: "failed"},
+			"2": models.TransferResponse{Amount: 20000, Fee: 50, UserID: "user-ada", Order_ID: 2, Transaction_ID: "TX-2", Account_Name: "Bola", Status: models.TransferFailed},
+			"3": models.DepositResponse{},
+			"4": telcom.AirtimeResponse{Amount: "300", UserID: "user-ghost"},
+			"5": models.TransferResponse{Amount: 1000, Fee: 50, UserID: "user-ada", Order_ID: 5, Transaction_ID: "TX-5", Status: models.TransferRejected},
+			"6": models.TransferResponse{Amount: 3000, Fee: 50, UserID: "user-ada", Order_ID: 6, Transaction_ID: "TX-6", Account_Name: "Bola", Status: models.TransferSent},
+			"7": telcom.AirtimeResponse{Amount: "500", UserID: "user-ada", Status: "Successful"},
+			"8": telcom.AirtimeResponse{Amount: "500", UserID: "user-ada"},
+			"9": telcom.AirtimeResponse{Amount: "500", UserID: "user-ada"},
+		},
+		debits: []models.LedgerEntry{
+			debit("user-ada", models.EntryPurchase, "1", 500),
+			debit("user-ada", models.EntryTransfer, "2", 20000),
+			debit("user-ada", models.EntryFee, "2", 50),
+			debit("user-ghost", models.EntryPurchase, "4", 300),
+			debit("user-ada", models.EntryTransfer, "5", 1000),
+			debit("user-ada", models.EntryFee, "5", 50),
+			{UserID: "user-ada", Type: models.EntryReversal, Reference: "5", Amount: 1050},
+			debit("user-ada", models.EntryTransfer, "6", 3000),
+			debit("user-ada", models.EntryFee, "6", 50),
+			debit("user-ada", models.EntryPurchase, "7", 500),
+			// a discount took less than the price from the wallet
+			debit("user-ada", models.EntryPurchase, "9", 400),
+		},
+		refunds:  map[string]models.Refund{},
+		balances: map[string]float64{"nuban-ada": 1000},
+	}
+}
+
+func (f *fakeStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if f.depth > 0 {
+		return fn(ctx)
+	}
+	f.depth++
+	defer func() { f.depth-- }()
+
+	refunds, balances, entries := map[string]models.Refund{}, map[string]float64{}, len(f.entries)
+	for k, v := range f.refunds {
+		refunds[k] = v
+	}
+	for k, v := range f.balances {
+		balances[k] = v
+	}
+	if err := fn(ctx); err != nil {
+		f.refunds, f.balances, f.entries = refunds, balances, f.entries[:entries]
+		return err
+	}
+	return nil
+}
+
+func (f *fakeStore) GetTransaction(_ context.Context, userID, id string) (interface{}, error) {
+	txn, ok := f.transactions[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	if p, err := purchaseOf(txn); userID != "" && err == nil && p.owner != userID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return txn, nil
+}
+
+func (f *fakeStore) GetTransferByTransactionID(_ context.Context, transactionID string) (models.TransferResponse, error) {
+	for _, txn := range f.transactions {
+		if transfer, ok := txn.(models.TransferResponse); ok && transfer.Transaction_ID == transactionID {
+			return transfer, nil
+		}
+	}
+	return models.TransferResponse{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeStore) UpdateTransferStatus(_ context.Context, transactionID, status string) error {
+	for id, txn := range f.transactions {
+		if transfer, ok := txn.(models.TransferResponse); ok && transfer.Transaction_ID == transactionID {
+			transfer.Status = status
+			f.transactions[id] = transfer
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (f *fakeStore) GetLedgerEntriesByReference(_ context.Context, userID, reference string) ([]models.LedgerEntry, error) {
+	var res []models.LedgerEntry
+	for _, entry := range append(append([]models.LedgerEntry(nil), f.debits...), f.entries...) {
+		if entry.UserID == userID && entry.Reference == reference {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) CreateRefund(_ context.Context, refund models.Refund) error {
+	for _, existing := range f.refunds {
+		if existing.Active != "" && existing.Active == refund.Active {
+			return db.ErrDuplicate
+		}
+	}
+	f.refunds[refund.ID] = refund
+	return nil
+}
+
+func (f *fakeStore) GetRefund(_ context.Context, id string) (models.Refund, error) {
+	refund, ok := f.refunds[id]
+	if !ok {
+		return models.Refund{}, mongo.ErrNoDocuments
+	}
+	return refund, nil
+}
+
+func (f *fakeStore) UpdateRefundStatus(_ context.Context, id, from string, event models.RefundEvent) error {
+	refund, ok := f.refunds[id]
+	if !ok || refund.Status != from {
+		return mongo.ErrNoDocuments
+	}
+	refund.Status = event.Status
+	refund.Trail = append(append([]models.RefundEvent{}, refund.Trail...), event)
+	if event.Status == models.RefundRejected || event.Status == models.RefundFailed {
+		refund.Active = ""
+	}
+	f.refunds[id] = refund
+	return nil
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	name := id[len("user-"):]
+	return &models.User{ID: id, Username: name, Email: name + "@example.com"}, nil
+}
+
+func (f *fakeStore) GetVirtualNuban(_ context.Context, name string) (models.AccountDetails, error) {
+	if _, ok := f.balances["nuban-"+name]; !ok {
+		return models.AccountDetails{}, nil
+	}
+	return models.AccountDetails{User_ID: "user-" + name, VirtualAccountID: "nuban-" + name}, nil
+}
+
+func (f *fakeStore) GetBalance(_ context.Context, virtualNuban string) (float64, error) {
+	return f.balances[virtualNuban], nil
+}
+
+func (f *fakeStore) UpdateBalance(_ context.Context, virtualNuban string, balance float64) error {
+	f.balances[virtualNuban] = balance
+	return nil
+}
+
+func (f *fakeStore) SaveLedgerEntry(_ context.Context, entry models.LedgerEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+type fakeEmail struct {
+	sent []models.Message
+}
+
+func (f *fakeEmail) Send(email *models.Message) error {
+	f.sent = append(f.sent, *email)
+	return nil
+}
+
+func newRefunds(store *fakeStore, email *fakeEmail) *Refunds {
+	return NewRefunds(&Options{
+		Store:             store,
+		Wallet:            wallet.NewWallet(store, zap.NewNop()),
+		EmailClient:       email,
+		ApprovalThreshold: 5000,
+		Logger:            zap.NewNop(),
+	})
+}
+
+func statuses(refund models.Refund) []string {
+	var res []string
+	for _, event := range refund.Trail {
+		res = append(res, event.Status)
+	}
+	return res
+}
+
+func TestRequest(t *testing.T) {
+	var tests = []struct {
+		name        string
+		req         Request
+		want        string
+		wantTrail   []string
+		wantBalance float64
+		wantErr     error
+	}{
+		{
+			name:        "Test refund below threshold is paid at once",
+			req:         Request{UserID: "user-ada", TransactionID: "1", Reason: "airtime not delivered"},
+			want:        models.RefundCompleted,
+			wantTrail:   []string{models.RefundPending, models.RefundApproved, models.RefundCompleted},
+			wantBalance: 1500,
+		},
+		{
+			name:        "Test refund above threshold waits for approval",
+			req:         Request{UserID: "user-ada", TransactionID: "2", Reason: "transfer bounced"},
+			want:        models.RefundPending,
+			wantTrail:   []string{models.RefundPending},
+			wantBalance: 1000,
+		},
+		{
+			name:        "Test partial refund",
+			req:         Request{UserID: "user-ada", TransactionID: "1", Amount: 200, Reason: "part delivered"},
+			want:        models.RefundCompleted,
+			wantTrail:   []string{models.RefundPending, models.RefundApproved, models.RefundCompleted},
+			wantBalance: 1200,
+		},
+		{
+			name:        "Test owner taken from the transaction",
+			req:         Request{TransactionID: "1", Reason: "airtime not delivered"},
+			want:        models.RefundCompleted,
+			wantTrail:   []string{models.RefundPending, models.RefundApproved, models.RefundCompleted},
+			wantBalance: 1500,
+		},
+		{
+			name:        "Test refund capped at the debit",
+			req:         Request{TransactionID: "9", Reason: "airtime not delivered"},
+			want:        models.RefundCompleted,
+			wantTrail:   []string{models.RefundPending, models.RefundApproved, models.RefundCompleted},
+			wantBalance: 1400,
+		},
+		{name: "Test amount above paid", req: Request{UserID: "user-ada", TransactionID: "1", Amount: 600, Reason: "x"}, wantErr: ErrInvalidAmount},
+		{name: "Test amount above debited", req: Request{UserID: "user-ada", TransactionID: "9", Amount: 450, Reason: "x"}, wantErr: ErrInvalidAmount},
+		{name: "Test deposit", req: Request{UserID: "user-ada", TransactionID: "3", Reason: "x"}, wantErr: ErrNotRefundable},
+		{name: "Test succeeded purchase", req: Request{UserID: "user-ada", TransactionID: "7", Reason: "x"}, wantErr: ErrNotFailed},
+		{name: "Test purchase not paid from the wallet", req: Request{UserID: "user-ada", TransactionID: "8", Reason: "x"}, wantErr: ErrNotDebited},
+		{name: "Test transfer not reported failed", req: Request{UserID: "user-ada", TransactionID: "6", Reason: "x"}, wantErr: ErrNotFailed},
+		{name: "Test transfer reversed when rejected", req: Request{UserID: "user-ada", TransactionID: "5", Reason: "x"}, wantErr: ErrAlreadyReturned},
+		{name: "Test unknown transaction", req: Request{UserID: "user-ada", TransactionID: "99", Reason: "x"}, wantErr: ErrTransactionNotFound},
+		{name: "Test transaction of another user", req: Request{UserID: "user-bola", TransactionID: "1", Reason: "x"}, wantErr: ErrTransactionNotFound},
+		{name: "Test no reason", req: Request{UserID: "user-ada", TransactionID: "1"}, wantErr: ErrReasonRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, email := newFakeStore(), &fakeEmail{}
+			r := newRefunds(store, email)
+
+			got, err := r.Request(context.Background(), tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Empty(t, store.refunds)
+				return
+			}
+
+			assert.Equal(t, tt.want, got.Status)
+			assert.Equal(t, tt.wantTrail, statuses(got))
+			assert.Equal(t, tt.req.TransactionID, got.TransactionID)
+			assert.Equal(t, tt.wantBalance, store.balances["nuban-ada"])
+			if tt.want == models.RefundCompleted {
+				if assert.Len(t, store.entries, 1) {
+					assert.Equal(t, models.EntryReversal, store.entries[0].Type)
+					assert.Equal(t, tt.req.TransactionID, store.entries[0].Reference)
+				}
+				if assert.Len(t, email.sent, 1) {
+					assert.Equal(t, NotificationAlias, email.sent[0].TemplateID)
+				}
+			} else {
+				assert.Empty(t, store.entries)
+				assert.Empty(t, email.sent)
+			}
+		})
+	}
+}
+
+func TestRequestTwice(t *testing.T) {
+	store := newFakeStore()
+	r := newRefunds(store, &fakeEmail{})
+	req := Request{UserID: "user-ada", TransactionID: "1", Reason: "airtime not delivered"}
+
+	_, err := r.Request(context.Background(), req)
+	assert.NoError(t, err)
+	// the reversal of the completed refund is in the ledger
+	_, err = r.Request(context.Background(), req)
+	assert.ErrorIs(t, err, ErrAlreadyReturned)
+	assert.Equal(t, 1500.0, store.balances["nuban-ada"])
+
+	req = Request{UserID: "user-ada", TransactionID: "2", Reason: "transfer bounced"}
+	_, err = r.Request(context.Background(), req)
+	assert.NoError(t, err)
+	_, err = r.Request(context.Background(), req)
+	assert.ErrorIs(t, err, ErrRefundExists)
+}
+
+func TestApproveAndReject(t *testing.T) {
+	store, email := newFakeStore(), &fakeEmail{}
+	r := newRefunds(store, email)
+	ctx := context.Background()
+	req := Request{UserID: "user-ada", TransactionID: "2", Reason: "transfer bounced"}
+
+	pending, err := r.Request(ctx, req)
+	assert.NoError(t, err)
+
+	_, err = r.Reject(ctx, pending.ID, "support", "")
+	assert.ErrorIs(t, err, ErrReasonRequired)
+
+	rejected, err := r.Reject(ctx, pending.ID, "support", "the bank paid it")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundRejected, rejected.Status)
+	assert.Len(t, email.sent, 1)
+
+	_, err = r.Approve(ctx, pending.ID, "support", "")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	// a rejected refund frees the transaction to be refunded again
+	pending, err = r.Request(ctx, req)
+	assert.NoError(t, err)
+
+	approved, err := r.Approve(ctx, pending.ID, "finance", "confirmed with the bank")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundCompleted, approved.Status)
+	assert.Equal(t, "finance", approved.Trail[1].Actor)
+	assert.Equal(t, 21050.0, store.balances["nuban-ada"])
+
+	_, err = r.Approve(ctx, "missing", "finance", "")
+	assert.ErrorIs(t, err, ErrRefundNotFound)
+}
+
+func TestCreditFailed(t *testing.T) {
+	store := newFakeStore()
+	r := newRefunds(store, &fakeEmail{})
+
+	got, err := r.Request(context.Background(), Request{UserID: "user-ghost", TransactionID: "4", Reason: "airtime not delivered"})
+	assert.ErrorIs(t, err, ErrCreditFailed)
+	assert.Equal(t, models.RefundFailed, got.Status)
+	assert.Equal(t, []string{models.RefundPending, models.RefundApproved, models.RefundFailed}, statuses(got))
+	assert.Empty(t, store.entries)
+}
+
+func TestTransferFailed(t *testing.T) {
+	store := newFakeStore()
+	r := newRefunds(store, &fakeEmail{})
+
+	got, err := r.TransferFailed(context.Background(), "TX-6", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 3050.0, got.Amount)
+	assert.Equal(t, "6", got.TransactionID)
+	assert.Equal(t, "user-ada", got.UserID)
+	assert.Equal(t, models.RefundSourceAutomatic, got.Source)
+	assert.Equal(t, models.TransferFailed, store.transactions["6"].(models.TransferResponse).Status)
+
+	// a refunded transfer is not refunded again when anchor sends the event again
+	_, err = r.TransferFailed(context.Background(), "TX-6", "")
+	assert.ErrorIs(t, err, ErrAlreadyReturned)
+
+	_, err = r.TransferFailed(context.Background(), "TX-5", "")
+	assert.ErrorIs(t, err, ErrAlreadyReturned)
+	assert.Equal(t, models.TransferRejected, store.transactions["5"].(models.TransferResponse).Status)
+
+	_, err = r.TransferFailed(context.Background(), "TX-9", "")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
@@ -0,0 +1,149 @@
+package refund
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/requery"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRequeryInterval = 15 * time.Minute
+	// requerySettle is how long a provider is given to deliver a purchase before it is requeried.
+	requerySettle = 10 * time.Minute
+	// requeryWindow is how far back the purchases are requeried, one still undecided after it is left to
+	// support.
+	requeryWindow = 24 * time.Hour
+)
+
+// Run requeries the purchases paid from the wallets every interval and refunds the ones their provider
+// reports failed, until ctx is cancelled.
+func (r *Refunds) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultRequeryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RequeryPurchases(ctx, time.Now())
+		}
+	}
+}
+
+// RequeryPurchases requeries the purchases debited since the last run, up to requerySettle before now. A
+// purchase the provider has not decided yet, or that could not be requeried, is requeried again by the
+// next run.
+func (r *Refunds) RequeryPurchases(ctx context.Context, now time.Time) {
+	if r.providers == nil {
+		return
+	}
+
+	from, to := now.Add(-requeryWindow), now.Add(-requerySettle)
+	if r.requeriedTo.After(from) {
+		from = r.requeriedTo
+	}
+	if !to.After(from) {
+		return
+	}
+
+	entries, err := r.db.GetLedgerEntriesByType(ctx, models.EntryPurchase, from, to)
+	if err != nil {
+		r.logger.Error("failed to get purchases to requery", zap.Error(err))
+		return
+	}
+
+	next := to
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		key := entry.UserID + "/" + entry.Reference
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if !r.requeryPurchase(ctx, entry) && entry.CreatedAt.Before(next) {
+			next = entry.CreatedAt
+		}
+	}
+	r.requeriedTo = next
+}
+
+// requeryPurchase requeries the purchase debited by entry and refunds it when it failed. It returns false
+// when the purchase has to be requeried again.
+func (r *Refunds) requeryPurchase(ctx context.Context, entry models.LedgerEntry) bool {
+	txn, err := r.db.GetTransaction(ctx, entry.UserID, entry.Reference)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// a purchase the provider failed is refunded when it fails and saves no record, one still debited
+		// without a record was not confirmed and has no provider reference to requery
+		_, err := r.debited(ctx, entry.UserID, entry.Reference)
+		switch {
+		case err == nil:
+			r.logger.Warn("debited purchase has no record, it is left to support", zap.String("user_id", entry.UserID),
+				zap.String("reference", entry.Reference))
+		case !errors.Is(err, ErrAlreadyReturned) && !errors.Is(err, ErrNotDebited):
+			return false
+		}
+		return true
+	}
+	if err != nil {
+		r.logger.Error("failed to get purchase to requery", zap.String("reference", entry.Reference), zap.Error(err))
+		return false
+	}
+
+	p, err := purchaseOf(txn)
+	if err != nil || p.succeeded {
+		return true
+	}
+	if _, err := r.debited(ctx, p.owner, entry.Reference); errors.Is(err, ErrAlreadyReturned) || errors.Is(err, ErrNotDebited) {
+		return true
+	} else if err != nil {
+		return false
+	}
+	t, err := requery.TargetOf(txn)
+	if err != nil {
+		return true
+	}
+
+	result, err := requery.Query(ctx, r.providers, t)
+	if errors.Is(err, requery.ErrNoRequery) {
+		return true
+	}
+	if err != nil {
+		r.logger.Warn("failed to requery purchase", zap.String("reference", entry.Reference), zap.Error(err))
+		return false
+	}
+	if !result.Failed() {
+		return succeeded(result.Status)
+	}
+
+	_, err = r.Request(ctx, Request{
+		UserID:        entry.UserID,
+		TransactionID: entry.Reference,
+		Reason:        "Requery: " + result.Note(),
+		Source:        models.RefundSourceAutomatic,
+		RequestedBy:   actorRequery,
+	})
+	switch {
+	case err == nil, errors.Is(err, ErrRefundExists), errors.Is(err, ErrAlreadyReturned):
+		return true
+	case errors.Is(err, ErrCreditFailed):
+		// recorded as failed for support to follow up
+		return true
+	default:
+		r.logger.Error("failed to refund failed purchase", zap.String("reference", entry.Reference), zap.Error(err))
+		return false
+	}
+}
@@ -0,0 +1,143 @@
+package refund
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func (f *fakeStore) GetLedgerEntriesByType(_ context.Context, entryType string, from, to time.Time) ([]models.LedgerEntry, error) {
+	var res []models.LedgerEntry
+	for _, entry := range append(append([]models.LedgerEntry(nil), f.debits...), f.entries...) {
+		if entry.Type == entryType && !entry.CreatedAt.Before(from) && entry.CreatedAt.Before(to) {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) SaveAirtimeTransaction(_ context.Context, details *telcom.AirtimeResponse) error {
+	f.transactions[strconv.Itoa(details.OrderID)] = *details
+	return nil
+}
+
+func TestRequeryPurchases(t *testing.T) {
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	// easyaccess answers with the status after the reference
+	answers := map[string]string{"EA-11": "Failed", "EA-12": "Successful", "EA-13": "Pending"}
+	queried := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		reference := r.PostForm.Get("reference")
+		queried[reference]++
+		w.Write([]byte(`{"success":"true","status":"` + answers[reference] + `","message":"network unavailable"}`))
+	}))
+	defer srv.Close()
+
+	store := newFakeStore()
+	purchase := func(id string, at time.Duration) {
+		store.transactions[id] = telcom.AirtimeResponse{Amount: "500", UserID: "user-ada", ReferenceNumber: "EA-" + id}
+		entry := debit("user-ada", models.EntryPurchase, id, 500)
+		entry.CreatedAt = now.Add(-at)
+		store.debits = append(store.debits, entry)
+	}
+	purchase("13", 2*time.Hour)
+	purchase("11", time.Hour)
+	purchase("12", 30*time.Minute)
+	// still left to the provider
+	purchase("14", 5*time.Minute)
+	for i, entry := range store.debits {
+		if entry.Reference == "7" {
+			// succeeded, not requeried
+			store.debits[i].CreatedAt = now.Add(-time.Hour)
+		}
+	}
+
+	r := newRefunds(store, &fakeEmail{})
+	r.providers = &httpclient.Providers{EasyAccess: httpclient.New(&httpclient.Options{Name: "easyaccess", BaseURL: srv.URL})}
+	ctx := context.Background()
+
+	r.RequeryPurchases(ctx, now)
+	assert.Equal(t, map[string]int{"EA-11": 1, "EA-12": 1, "EA-13": 1}, queried)
+	if assert.Len(t, store.refunds, 1) {
+		for _, refund := range store.refunds {
+			assert.Equal(t, "11", refund.TransactionID)
+			assert.Equal(t, models.RefundSourceAutomatic, refund.Source)
+			assert.Equal(t, models.RefundCompleted, refund.Status)
+		}
+	}
+	assert.Equal(t, 1500.0, store.balances["nuban-ada"])
+
+	// the pending purchase and the ones after it are requeried, the refunded one is not
+	r.RequeryPurchases(ctx, now.Add(time.Minute))
+	assert.Equal(t, map[string]int{"EA-11": 1, "EA-12": 2, "EA-13": 2}, queried)
+	assert.Len(t, store.refunds, 1)
+
+	// once the provider decides, the next run starts after the last purchase
+	answers["EA-13"] = "Successful"
+	r.RequeryPurchases(ctx, now.Add(2*time.Minute))
+	r.RequeryPurchases(ctx, now.Add(3*time.Minute))
+	assert.Equal(t, map[string]int{"EA-11": 1, "EA-12": 3, "EA-13": 3}, queried)
+	assert.Len(t, store.refunds, 1)
+}
+
+func TestRequeryDebitedPurchases(t *testing.T) {
+	// easyaccess takes the airtime and later reports it failed
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		switch r.URL.Path {
+		case "/airtime.php":
+			w.Write([]byte(`{"success":"true","network":"MTN","airtimeamount":500,"mobileno":"08031234567","status":"pending","reference_no":"EA-` +
+				r.PostForm.Get("mobileno") + `"}`))
+		case "/query_transaction.php":
+			w.Write([]byte(`{"success":"true","status":"Failed","message":"network unavailable"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := httpclient.New(&httpclient.Options{Name: "easyaccess", BaseURL: srv.URL})
+
+	store := newFakeStore()
+	r := newRefunds(store, &fakeEmail{})
+	r.providers = &httpclient.Providers{EasyAccess: client}
+	airtime := vtu.NewAirtimeConn(store, zap.NewNop(), client)
+	ctx := context.Background()
+
+	// two runs of the same scheduled order, each debited under the order id its purchase is saved under
+	for i, number := range []string{"08031234567", "08031234568"} {
+		orderID := 9001 + i
+		purchase := wallet.Movement{Type: models.EntryPurchase, Reference: strconv.Itoa(orderID), Description: "Scheduled airtime"}
+		require.NoError(t, r.wallet.Debit(ctx, "ada", 500, purchase))
+		_, err := airtime.BuyAirtime(ctx, telcom.AirtimeInfo{Network: "01", Amount: "500", Phone_no: number, AirtimeType: "VTU",
+			Username: "ada", UserID: "user-ada", OrderID: orderID})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0.0, store.balances["nuban-ada"])
+
+	r.RequeryPurchases(ctx, time.Now().Add(requerySettle+time.Minute))
+
+	var refunded []string
+	for _, refund := range store.refunds {
+		assert.Equal(t, models.RefundCompleted, refund.Status)
+		refunded = append(refunded, refund.TransactionID)
+	}
+	assert.ElementsMatch(t, []string{"9001", "9002"}, refunded)
+	assert.Equal(t, 1000.0, store.balances["nuban-ada"])
+
+	// the money went back once
+	_, err := r.Request(ctx, Request{TransactionID: "9001", Reason: "failed", Source: models.RefundSourceSupport})
+	assert.ErrorIs(t, err, ErrAlreadyReturned)
+}
@@ -0,0 +1,210 @@
+// Package requery asks the providers for the status of our transactions, with the references the
+// reconciliation matches on.
+package requery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+)
+
+var (
+	ErrNoRequery = errors.New("transaction can not be requeried")
+	ErrFailed    = errors.New("provider requery failed")
+	// ErrNotFound is returned when the provider has no transaction with the reference.
+	ErrNotFound = errors.New("provider has no such transaction")
+)
+
+// Result is what a provider reports about one of our transactions.
+type Result struct {
+	Provider    string `json:"provider"`
+	Reference   string `json:"reference"`
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+}
+
+// Target is the provider a transaction was bought from and the reference it knows it by.
+type Target struct {
+	Product   string
+	Provider  string
+	Reference string
+}
+
+// TargetOf returns the provider of a transaction, the same references the reconciliation matches on.
+func TargetOf(txn interface{}) (Target, error) {
+	switch t := txn.(type) {
+	case telcom.DataResult:
+		return Target{Product: "data", Provider: "dontech", Reference: t.ReferenceNumber}, nil
+	case telcom.SmileResult:
+		return Target{Product: "data", Provider: "vtpass", Reference: t.RequestID}, nil
+	case telcom.SpectranetResult:
+		return Target{Product: "data", Provider: "vtpass", Reference: t.RequestID}, nil
+	case telcom.AirtimeResponse:
+		return Target{Product: "airtime", Provider: "easyaccess", Reference: t.ReferenceNumber}, nil
+	case models.EduResponse:
+		return Target{Product: "edu", Provider: "easyaccess", Reference: t.ReferenceNumber}, nil
+	case models.BillResult:
+		return Target{Product: "tv", Provider: "vtpass", Reference: t.RequestID}, nil
+	case models.ElectricResult:
+		return Target{Product: "electricity", Provider: "vtpass", Reference: t.RequestID}, nil
+	case models.TransferResponse:
+		return Target{Product: "transfer", Provider: "anchor", Reference: t.Transaction_ID}, nil
+	case models.DepositResponse:
+		// deposits are credited from anchor's events, there is nothing to requery
+		return Target{Product: "deposit"}, nil
+	default:
+		return Target{}, ErrNoRequery
+	}
+}
+
+// Query asks the provider of t for the status of the transaction.
+func Query(ctx context.Context, providers *httpclient.Providers, t Target) (Result, error) {
+	if t.Reference == "" {
+		return Result{}, ErrNoRequery
+	}
+
+	res := Result{Provider: t.Provider, Reference: t.Reference}
+	var err error
+	switch t.Provider {
+	case "vtpass":
+		res.Status, res.Description, err = vtpassRequery(ctx, providers.VTpass, t.Reference)
+	case "easyaccess":
+		res.Status, res.Description, err = easyAccessRequery(ctx, providers.EasyAccess, t.Reference)
+	case "dontech":
+		res.Status, res.Description, err = dontechRequery(ctx, providers.Dontech, t.Reference)
+	case "anchor":
+		res.Status, res.Description, err = anchorRequery(ctx, providers.Anchor, t.Reference)
+	default:
+		return Result{}, ErrNoRequery
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %w", ErrFailed, err)
+	}
+	return res, nil
+}
+
+func vtpassRequery(ctx context.Context, client *httpclient.Client, requestID string) (string, string, error) {
+	var res struct {
+		Code    string `json:"code"`
+		Content struct {
+			Transactions struct {
+				Status string `json:"status"`
+			} `json:"transactions"`
+		} `json:"content"`
+		Description string `json:"response_description"`
+	}
+	form := url.Values{"request_id": {requestID}}
+	if err := do(ctx, client, http.MethodPost, "/requery", form, &res); err != nil {
+		return "", "", err
+	}
+	return res.Content.Transactions.Status, res.Description, nil
+}
+
+func easyAccessRequery(ctx context.Context, client *httpclient.Client, reference string) (string, string, error) {
+	var res struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	form := url.Values{"reference": {reference}}
+	if err := do(ctx, client, http.MethodPost, "/query_transaction.php", form, &res); err != nil {
+		return "", "", err
+	}
+	return res.Status, res.Message, nil
+}
+
+func dontechRequery(ctx context.Context, client *httpclient.Client, ident string) (string, string, error) {
+	var res struct {
+		Status      string `json:"Status"`
+		APIResponse string `json:"api_response"`
+	}
+	if err := do(ctx, client, http.MethodGet, "/data/"+url.PathEscape(ident)+"/", nil, &res); err != nil {
+		return "", "", err
+	}
+	return res.Status, res.APIResponse, nil
+}
+
+func anchorRequery(ctx context.Context, client *httpclient.Client, reference string) (string, string, error) {
+	var res struct {
+		Data struct {
+			Attributes struct {
+				Status        string `json:"status"`
+				FailureReason string `json:"failureReason"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := do(ctx, client, http.MethodGet, "/transfers/verify/"+url.PathEscape(reference), nil, &res); err != nil {
+		return "", "", err
+	}
+	return res.Data.Attributes.Status, res.Data.Attributes.FailureReason, nil
+}
+
+// do sends a request to the provider of client, form is sent url encoded when given.
+func do(ctx context.Context, client *httpclient.Client, method, path string, form url.Values, v interface{}) error {
+	var body bytes.Buffer
+	if form != nil {
+		body.WriteString(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, &body)
+	if err != nil {
+		return err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s requery failed with status %d", client.Name(), res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// Failed reports whether the provider says the transaction failed or was reversed, an unknown or pending
+// status is not a failure.
+func (q Result) Failed() bool {
+	switch strings.ToLower(strings.TrimSpace(q.Status)) {
+	case "failed", "fail", "reversed":
+		return true
+	}
+	return false
+}
+
+// Succeeded reports whether the provider says the transaction was delivered.
+func (q Result) Succeeded() bool {
+	switch strings.ToLower(strings.TrimSpace(q.Status)) {
+	case "success", "successful", "delivered", "completed":
+		return true
+	}
+	return false
+}
+
+// Note formats a requery for a ticket's timeline or a refund's reason.
+func (q Result) Note() string {
+	status := q.Status
+	if status == "" {
+		status = "unknown"
+	}
+	note := q.Provider + " reports " + q.Reference + " as " + strings.ToLower(status)
+	if q.Description != "" {
+		note += ": " + q.Description
+	}
+	return note
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// location is the time zone cadences are read in, users think of "8am" as 8am in Lagos.
+var location = time.FixedZone("WAT", 60*60)
+
+var descriptors = map[string]string{
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type field struct {
+	min, max int
+}
+
+var (
+	minuteField = field{0, 59}
+	hourField   = field{0, 23}
+	domField    = field{1, 31}
+	monthField  = field{1, 12}
+	dowField    = field{0, 7} // 0 and 7 are both sunday
+)
+
+// Schedule is a parsed cron expression with the standard five fields:
+// minute, hour, day of month, month and day of week.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// when both day fields are restricted a day matches if either field matches, as in cron.
+	domAny, dowAny bool
+}
+
+// Parse reads a cron expression or one of @daily, @weekly and @monthly.
+// Each field supports *, single values, ranges (1-5), lists (1,15) and steps (*/2, 1-10/3).
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, found %d", ErrInvalidCadence, len(fields))
+	}
+
+	s := &Schedule{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(expr, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step in %q", ErrInvalidCadence, expr)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			lo, err1 := strconv.Atoi(bounds[0])
+			hi, err2 := strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("%w: bad range in %q", ErrInvalidCadence, expr)
+			}
+			start, end = lo, hi
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("%w: bad value in %q", ErrInvalidCadence, expr)
+			}
+			start, end = n, n
+			// "5/15" means from 5 to the end of the field every 15
+			if step > 1 {
+				end = f.max
+			}
+		}
+
+		if start < f.min || end > f.max {
+			return 0, fmt.Errorf("%w: %q is out of range %d-%d", ErrInvalidCadence, expr, f.min, f.max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+// Next returns the first time after t that matches the schedule, or the zero time if there is none
+// within five years (e.g. "0 0 31 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, location)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, location)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func wat(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, location)
+}
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "Test every field", spec: "30 8 1 1 0"},
+		{name: "Test descriptor", spec: "@daily"},
+		{name: "Test descriptor any case", spec: " @WEEKLY "},
+		{name: "Test ranges lists and steps", spec: "*/15 9-17 1,15 1-12/3 1-5"},
+		{name: "Test sunday as seven", spec: "0 0 * * 7"},
+		{name: "Test too few fields", spec: "0 8 * *", wantErr: true},
+		{name: "Test unknown descriptor", spec: "@hourly", wantErr: true},
+		{name: "Test minute out of range", spec: "60 * * * *", wantErr: true},
+		{name: "Test day of month zero", spec: "0 0 0 * *", wantErr: true},
+		{name: "Test month out of range", spec: "0 0 1 13 *", wantErr: true},
+		{name: "Test backwards range", spec: "0 17-9 * * *", wantErr: true},
+		{name: "Test zero step", spec: "*/0 * * * *", wantErr: true},
+		{name: "Test bad value", spec: "0 eight * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCadence)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	var tests = []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{name: "Test step", spec: "*/15 * * * *", after: wat(2026, 10, 19, 10, 7), want: wat(2026, 10, 19, 10, 15)},
+		{name: "Test step from a value", spec: "5/20 * * * *", after: wat(2026, 10, 19, 10, 30), want: wat(2026, 10, 19, 10, 45)},
+		{name: "Test matching time is excluded", spec: "0 8 * * *", after: wat(2026, 10, 19, 8, 0), want: wat(2026, 10, 20, 8, 0)},
+		{name: "Test range into the next day", spec: "0 9-17 * * *", after: wat(2026, 10, 19, 17, 30), want: wat(2026, 10, 20, 9, 0)},
+		{name: "Test list", spec: "0 8 1,15 * *", after: wat(2026, 1, 2, 0, 0), want: wat(2026, 1, 15, 8, 0)},
+		{name: "Test range with step", spec: "0 0 1-10/3 * *", after: wat(2026, 1, 4, 0, 0), want: wat(2026, 1, 7, 0, 0)},
+		// 2 October 2026 is a friday, either day field matches when both are restricted
+		{name: "Test day of month or day of week", spec: "0 0 13 * 5", after: wat(2026, 10, 1, 0, 0), want: wat(2026, 10, 2, 0, 0)},
+		{name: "Test day of month with any day of week", spec: "0 0 13 * *", after: wat(2026, 10, 1, 0, 0), want: wat(2026, 10, 13, 0, 0)},
+		{name: "Test sunday as seven", spec: "0 0 * * 7", after: wat(2026, 10, 19, 0, 0), want: wat(2026, 10, 25, 0, 0)},
+		{name: "Test weekly", spec: "@weekly", after: wat(2026, 10, 19, 0, 0), want: wat(2026, 10, 25, 0, 0)},
+		{name: "Test month rollover", spec: "@monthly", after: wat(2026, 10, 19, 12, 0), want: wat(2026, 11, 1, 0, 0)},
+		{name: "Test month without the day skipped", spec: "30 23 31 * *", after: wat(2026, 11, 5, 0, 0), want: wat(2026, 12, 31, 23, 30)},
+		{name: "Test year rollover", spec: "0 0 1 1 *", after: wat(2026, 12, 31, 23, 59), want: wat(2027, 1, 1, 0, 0)},
+		{name: "Test leap day", spec: "0 0 29 2 *", after: wat(2026, 3, 1, 0, 0), want: wat(2028, 2, 29, 0, 0)},
+		{name: "Test read in lagos time", spec: "0 9 * * *", after: time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC), want: wat(2026, 10, 19, 9, 0)},
+		{name: "Test never runs", spec: "0 0 31 2 *", after: wat(2026, 1, 1, 0, 0), want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(tt.after))
+		})
+	}
+}
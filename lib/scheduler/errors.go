@@ -0,0 +1,15 @@
+package scheduler
+
+import "errors"
+
+var (
+	ErrInvalidCadence   = errors.New("cadence is not a valid cron expression")
+	ErrInvalidProduct   = errors.New("product must be airtime, data, tv or electricity")
+	ErrMissingDetails   = errors.New("order details for the product are required")
+	ErrInvalidDetails   = errors.New("order details are not valid")
+	ErrInvalidDates     = errors.New("end date must be after the start date")
+	ErrInvalidMaxAmount = errors.New("max amount must be greater than zero")
+	ErrOrderNotFound    = errors.New("scheduled order not found")
+	ErrInvalidStatus    = errors.New("scheduled order cannot be changed from its current status")
+	ErrAboveMaxAmount   = errors.New("price is above the order's max amount")
+)
@@ -0,0 +1,573 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	elect "github.com/aremxyplug-be/lib/bills/electricity"
+	"github.com/aremxyplug-be/lib/bills/tvsub"
+	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/phone"
+	"github.com/aremxyplug-be/lib/randomgen"
+	"github.com/aremxyplug-be/lib/smsclient"
+	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/telcom/data"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/validation"
+	"github.com/aremxyplug-be/lib/wallet"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	ProductAirtime     = "airtime"
+	ProductData        = "data"
+	ProductTv          = "tv"
+	ProductElectricity = "electricity"
+
+	// NotificationAlias is the postmark template used to tell a user about a scheduled order run.
+	NotificationAlias = "scheduled-order"
+
+	defaultInterval = time.Minute
+)
+
+type Options struct {
+	Store       db.DataStore
+	Wallet      *wallet.Wallet
+	Airtime     *vtu.AirtimeConn
+	Data        *data.DataConn
+	TvSub       *tvsub.TvConn
+	Electricity *elect.ElectricConn
+	EmailClient emailclient.EmailClient
+	SMSClient   smsclient.SMSClient
+	Logger      *zap.Logger
+}
+
+// Scheduler stores users' recurring orders and executes them when they are due.
+type Scheduler struct {
+	db          db.DataStore
+	wallet      *wallet.Wallet
+	airtime     *vtu.AirtimeConn
+	data        *data.DataConn
+	tv          *tvsub.TvConn
+	electricity *elect.ElectricConn
+	emailClient emailclient.EmailClient
+	smsClient   smsclient.SMSClient
+	idGenerator idgenerator.IdGenerator
+	validate    *validator.Validate
+	logger      *zap.Logger
+}
+
+func NewScheduler(opt *Options) *Scheduler {
+	return &Scheduler{
+		db:          opt.Store,
+		wallet:      opt.Wallet,
+		airtime:     opt.Airtime,
+		data:        opt.Data,
+		tv:          opt.TvSub,
+		electricity: opt.Electricity,
+		emailClient: opt.EmailClient,
+		smsClient:   opt.SMSClient,
+		idGenerator: idgenerator.New(),
+		validate:    validation.New(),
+		logger:      opt.Logger,
+	}
+}
+
+// Create validates a new recurring order for the user and saves it.
+func (s *Scheduler) Create(ctx context.Context, user *models.User, order models.ScheduledOrder) (models.ScheduledOrder, error) {
+	order.Product = strings.ToLower(strings.TrimSpace(order.Product))
+	if err := validateDetails(order); err != nil {
+		return models.ScheduledOrder{}, err
+	}
+	// the details are checked as they are for a purchase made by the user
+	if err := s.validate.Struct(order); err != nil {
+		return models.ScheduledOrder{}, fmt.Errorf("%w: %v", ErrInvalidDetails, err)
+	}
+	if err := checkPhone(&order); err != nil {
+		return models.ScheduledOrder{}, err
+	}
+	if order.MaxAmount <= 0 {
+		return models.ScheduledOrder{}, ErrInvalidMaxAmount
+	}
+
+	schedule, err := Parse(order.Cadence)
+	if err != nil {
+		return models.ScheduledOrder{}, err
+	}
+
+	now := time.Now()
+	if order.StartDate.Before(now) {
+		order.StartDate = now
+	}
+	if order.EndDate != nil && !order.EndDate.After(order.StartDate) {
+		return models.ScheduledOrder{}, ErrInvalidDates
+	}
+
+	// the start date itself runs if it matches the cadence
+	order.NextRun = schedule.Next(order.StartDate.Add(-time.Minute))
+	if order.NextRun.IsZero() || (order.EndDate != nil && order.NextRun.After(*order.EndDate)) {
+		return models.ScheduledOrder{}, fmt.Errorf("%w: it never runs between the start and end date", ErrInvalidCadence)
+	}
+
+	order.ID = s.idGenerator.Generate()
+	order.UserID = user.ID
+	order.Username = user.Username
+	order.Email = user.Email
+	order.Phone = user.PhoneNumber
+	order.Status = models.ScheduleActive
+	order.LastRun = nil
+	order.LastStatus = ""
+	order.Runs = 0
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	if err := s.db.SaveScheduledOrder(ctx, order); err != nil {
+		return models.ScheduledOrder{}, s.logAndReturnError("failed to save scheduled order", err)
+	}
+
+	return order, nil
+}
+
+// List returns the user's scheduled orders.
+func (s *Scheduler) List(ctx context.Context, username string) ([]models.ScheduledOrder, error) {
+	orders, err := s.db.GetScheduledOrders(ctx, username)
+	if err != nil {
+		return nil, s.logAndReturnError("failed to get scheduled orders", err)
+	}
+
+	return orders, nil
+}
+
+// Get returns one of the user's scheduled orders.
+func (s *Scheduler) Get(ctx context.Context, id, username string) (models.ScheduledOrder, error) {
+	order, err := s.db.GetScheduledOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.ScheduledOrder{}, ErrOrderNotFound
+		}
+		return models.ScheduledOrder{}, s.logAndReturnError("failed to get scheduled order", err)
+	}
+	if order.Username != username {
+		return models.ScheduledOrder{}, ErrOrderNotFound
+	}
+
+	return order, nil
+}
+
+// Runs returns the executions of one of the user's scheduled orders, most recent first.
+func (s *Scheduler) Runs(ctx context.Context, id, username string) ([]models.ScheduledRun, error) {
+	if _, err := s.Get(ctx, id, username); err != nil {
+		return nil, err
+	}
+
+	runs, err := s.db.GetScheduledRuns(ctx, id)
+	if err != nil {
+		return nil, s.logAndReturnError("failed to get scheduled order runs", err)
+	}
+
+	return runs, nil
+}
+
+// Pause stops an active order from running until it is resumed.
+func (s *Scheduler) Pause(ctx context.Context, id, username string) (models.ScheduledOrder, error) {
+	return s.setStatus(ctx, id, username, models.SchedulePaused, models.ScheduleActive)
+}
+
+// Resume restarts a paused order from its next run after now, missed runs are not made up.
+func (s *Scheduler) Resume(ctx context.Context, id, username string) (models.ScheduledOrder, error) {
+	return s.setStatus(ctx, id, username, models.ScheduleActive, models.SchedulePaused)
+}
+
+// Cancel stops an order for good.
+func (s *Scheduler) Cancel(ctx context.Context, id, username string) (models.ScheduledOrder, error) {
+	return s.setStatus(ctx, id, username, models.ScheduleCancelled, models.ScheduleActive, models.SchedulePaused)
+}
+
+func (s *Scheduler) setStatus(ctx context.Context, id, username, status string, from ...string) (models.ScheduledOrder, error) {
+	order, err := s.Get(ctx, id, username)
+	if err != nil {
+		return models.ScheduledOrder{}, err
+	}
+
+	allowed := false
+	for _, f := range from {
+		if order.Status == f {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return models.ScheduledOrder{}, ErrInvalidStatus
+	}
+
+	now := time.Now()
+	if status == models.ScheduleActive {
+		schedule, err := Parse(order.Cadence)
+		if err != nil {
+			return models.ScheduledOrder{}, err
+		}
+		after := now
+		if order.StartDate.After(now) {
+			after = order.StartDate.Add(-time.Minute)
+		}
+		order.NextRun = schedule.Next(after)
+		if order.NextRun.IsZero() || (order.EndDate != nil && order.NextRun.After(*order.EndDate)) {
+			status = models.ScheduleCompleted
+		}
+	}
+
+	previous := order.Status
+	order.Status = status
+	order.UpdatedAt = now
+	if err := s.db.UpdateScheduledOrderStatus(ctx, order, previous); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// the order was changed since it was read
+			return models.ScheduledOrder{}, ErrInvalidStatus
+		}
+		return models.ScheduledOrder{}, s.logAndReturnError("failed to update scheduled order", err)
+	}
+
+	return order, nil
+}
+
+// Run executes the due orders on every tick of interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.RunDue(ctx, time.Now())
+		}
+	}
+}
+
+// RunDue executes every active order whose next run is at or before now.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) {
+	orders, err := s.db.GetDueScheduledOrders(ctx, now)
+	if err != nil {
+		s.logger.Error("failed to get due scheduled orders", zap.Error(err))
+		return
+	}
+
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return
+		}
+		s.execute(ctx, order, now)
+	}
+}
+
+// claim moves the order on to its next run before it is executed, so a scheduler that reads the same due
+// order skips it. It reports false when the order was claimed, paused or cancelled first.
+func (s *Scheduler) claim(ctx context.Context, order *models.ScheduledOrder, now time.Time) bool {
+	previousRun := order.NextRun
+	schedule, err := Parse(order.Cadence)
+	if err != nil {
+		// the cadence was validated when the order was created
+		s.logger.Error("scheduled order has an invalid cadence", zap.String("order_id", order.ID), zap.Error(err))
+		order.NextRun = time.Time{}
+	} else {
+		order.NextRun = schedule.Next(now)
+	}
+	order.UpdatedAt = now
+	if order.NextRun.IsZero() || (order.EndDate != nil && order.NextRun.After(*order.EndDate)) {
+		order.Status = models.ScheduleCompleted
+	}
+
+	err = s.db.ClaimScheduledOrder(ctx, *order, previousRun)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false
+	}
+	if err != nil {
+		s.logger.Error("failed to claim scheduled order", zap.String("order_id", order.ID), zap.Error(err))
+		return false
+	}
+
+	return true
+}
+
+// execute claims the order, prices it, takes the money from the wallet and buys the product.
+// The money is returned to the wallet if the provider turned the purchase down, a purchase it did not
+// confirm is kept until a requery tells how it went.
+func (s *Scheduler) execute(ctx context.Context, order models.ScheduledOrder, now time.Time) {
+	// a run that has started is finished even when the scheduler is stopped, so a debit is never left
+	// without its purchase or refund.
+	ctx = context.WithoutCancel(ctx)
+	if !s.claim(ctx, &order, now) {
+		return
+	}
+
+	run := models.ScheduledRun{
+		OrderID:  order.ID,
+		Username: order.Username,
+		Product:  order.Product,
+		RanAt:    now,
+	}
+
+	amount, err := s.price(ctx, order)
+	run.Amount = amount
+	switch {
+	case err != nil:
+		run.Status = models.RunFailed
+		run.Error = err.Error()
+	case amount > order.MaxAmount:
+		run.Status = models.RunSkipped
+		run.Error = ErrAboveMaxAmount.Error()
+	default:
+		// every run is its own purchase, debited and refunded under its own order id
+		purchaseID, err := randomgen.GenerateOrderID()
+		if err != nil {
+			run.Status = models.RunFailed
+			run.Error = err.Error()
+			break
+		}
+		run.PurchaseID = strconv.Itoa(purchaseID)
+
+		purchase := wallet.Movement{Type: models.EntryPurchase, Reference: run.PurchaseID, Description: "Scheduled " + order.Product}
+		if err := s.wallet.Debit(ctx, order.Username, amount, purchase); err != nil {
+			run.Status = models.RunFailed
+			run.Error = err.Error()
+			break
+		}
+
+		reference, err := s.buy(ctx, order, purchaseID)
+		if errors.Is(err, httpclient.ErrUnconfirmed) {
+			s.logger.Warn("scheduled order purchase was not confirmed", zap.String("order_id", order.ID),
+				zap.String("purchase_id", run.PurchaseID), zap.Error(err))
+			run.Status = models.RunUnconfirmed
+			run.Error = err.Error()
+			break
+		}
+		if err != nil {
+			run.Status = models.RunFailed
+			run.Error = err.Error()
+			refund := wallet.Movement{Type: models.EntryRefund, Reference: run.PurchaseID, Description: "Scheduled " + order.Product + " failed"}
+			if err := s.wallet.Credit(ctx, order.Username, amount, refund); err != nil {
+				s.logger.Error("failed to refund scheduled order", zap.String("order_id", order.ID), zap.Float64("amount", amount), zap.Error(err))
+			} else {
+				metrics.Reversed(order.Product, amount)
+			}
+			break
+		}
+		run.Status = models.RunSuccess
+		run.Reference = reference
+	}
+
+	if err := s.db.SaveScheduledRun(ctx, run); err != nil {
+		s.logger.Error("failed to save scheduled order run", zap.String("order_id", order.ID), zap.Error(err))
+	}
+
+	// only the run fields are written, a pause or cancel made during the run is kept
+	order.LastRun = &now
+	order.LastStatus = run.Status
+	order.Runs++
+	if err := s.db.RecordScheduledRun(ctx, run); err != nil {
+		s.logger.Error("failed to update scheduled order", zap.String("order_id", order.ID), zap.Error(err))
+	}
+
+	s.notify(order, run)
+}
+
+func (s *Scheduler) price(ctx context.Context, order models.ScheduledOrder) (float64, error) {
+	switch order.Product {
+	case ProductAirtime:
+		amount, err := strconv.ParseFloat(order.Airtime.Amount, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid airtime amount %q", order.Airtime.Amount)
+		}
+		return amount, nil
+	case ProductData:
+		return s.data.Price(ctx, *order.Data)
+	case ProductTv:
+		return s.tv.Price(ctx, *order.Tv)
+	case ProductElectricity:
+		return float64(order.Electricity.Amount), nil
+	}
+
+	return 0, ErrInvalidProduct
+}
+
+// buy places the order through the same path as a purchase made by the user, saved under orderID, and
+// returns its transaction id.
+func (s *Scheduler) buy(ctx context.Context, order models.ScheduledOrder, orderID int) (string, error) {
+	switch order.Product {
+	case ProductAirtime:
+		info := *order.Airtime
+		info.OrderID = orderID
+		info.Username = order.Username
+		info.UserID = order.UserID
+		res, err := s.airtime.BuyAirtime(ctx, info)
+		if err != nil {
+			return "", err
+		}
+		return res.TransactionID, nil
+	case ProductData:
+		info := *order.Data
+		info.OrderID = orderID
+		info.Username = order.Username
+		info.UserID = order.UserID
+		res, err := s.data.BuyData(ctx, info)
+		if err != nil {
+			return "", err
+		}
+		return res.TransactionID, nil
+	case ProductTv:
+		info := *order.Tv
+		info.OrderID = orderID
+		info.UserID = order.UserID
+		if info.Email == "" {
+			info.Email = order.Email
+		}
+		res, err := s.tv.BuySub(ctx, info)
+		if err != nil {
+			return "", err
+		}
+		return res.TranscationID, nil
+	case ProductElectricity:
+		info := *order.Electricity
+		info.OrderID = orderID
+		info.UserID = order.UserID
+		if info.Email == "" {
+			info.Email = order.Email
+		}
+		if info.Phone == "" {
+			info.Phone = order.Phone
+		}
+		res, err := s.electricity.PayBill(ctx, info)
+		if err != nil {
+			return "", err
+		}
+		return res.TransactionID, nil
+	}
+
+	return "", ErrInvalidProduct
+}
+
+func (s *Scheduler) notify(order models.ScheduledOrder, run models.ScheduledRun) {
+	var body string
+	switch run.Status {
+	case models.RunSuccess:
+		body = fmt.Sprintf("Your scheduled %s purchase of N%.2f was successful. Ref: %s.", order.Product, run.Amount, run.Reference)
+	case models.RunSkipped:
+		body = fmt.Sprintf("Your scheduled %s purchase was skipped, the price N%.2f is above your limit of N%.2f.", order.Product, run.Amount, order.MaxAmount)
+	case models.RunUnconfirmed:
+		body = fmt.Sprintf("Your scheduled %s purchase of N%.2f could not be confirmed, it will be refunded if it failed.", order.Product, run.Amount)
+	default:
+		body = fmt.Sprintf("Your scheduled %s purchase failed: %s.", order.Product, run.Error)
+	}
+	if order.Status == models.ScheduleActive {
+		body += " Next run: " + order.NextRun.In(location).Format("02 Jan 2006 15:04") + "."
+	}
+
+	now := time.Now().Unix()
+	if s.emailClient != nil && order.Email != "" {
+		message := models.Message{
+			ID:         s.idGenerator.Generate(),
+			CustomerID: order.UserID,
+			Target:     order.Email,
+			Type:       models.EMAIL_MESSAGE_TYPE,
+			Title:      "Scheduled Order",
+			Body:       body,
+			TemplateID: NotificationAlias,
+			DataMap: map[string]string{
+				"Username": order.Username,
+				"Product":  order.Product,
+				"Status":   run.Status,
+				"Amount":   strconv.FormatFloat(run.Amount, 'f', 2, 64),
+				"Message":  body,
+			},
+			Ts: now,
+		}
+		if err := s.emailClient.Send(&message); err != nil {
+			s.logger.Error("error sending scheduled order email", zap.String("order_id", order.ID), zap.Error(err))
+		}
+	}
+
+	if s.smsClient != nil && order.Phone != "" {
+		message := models.Message{
+			ID:         s.idGenerator.Generate(),
+			CustomerID: order.UserID,
+			Target:     order.Phone,
+			Type:       models.SMS_MESSAGE_TYPE,
+			Title:      "Scheduled Order",
+			Body:       body,
+			Ts:         now,
+		}
+		if err := s.smsClient.Send(&message); err != nil {
+			s.logger.Error("error sending scheduled order sms", zap.String("order_id", order.ID), zap.Error(err))
+		}
+	}
+}
+
+// validateDetails checks that the order carries the details of its product and only those.
+func validateDetails(order models.ScheduledOrder) error {
+	set := 0
+	for _, ok := range []bool{order.Airtime != nil, order.Data != nil, order.Tv != nil, order.Electricity != nil} {
+		if ok {
+			set++
+		}
+	}
+
+	var ok bool
+	switch order.Product {
+	case ProductAirtime:
+		ok = order.Airtime != nil
+	case ProductData:
+		ok = order.Data != nil
+	case ProductTv:
+		ok = order.Tv != nil
+	case ProductElectricity:
+		ok = order.Electricity != nil
+	default:
+		return ErrInvalidProduct
+	}
+	if !ok || set != 1 {
+		return ErrMissingDetails
+	}
+
+	return nil
+}
+
+// checkPhone parses the phone number of an airtime or data order and keeps it in the format the
+// providers expect.
+func checkPhone(order *models.ScheduledOrder) error {
+	switch order.Product {
+	case ProductAirtime:
+		number, err := phone.Validate(order.Airtime.Phone_no, vtu.NetworkName(order.Airtime.Network))
+		if err != nil {
+			return err
+		}
+		order.Airtime.Phone_no = number.Local
+	case ProductData:
+		number, err := phone.Validate(order.Data.Mobile_Num, plans.DontechNetwork(order.Data.Network))
+		if err != nil {
+			return err
+		}
+		order.Data.Mobile_Num = number.Local
+	}
+
+	return nil
+}
+
+func (s *Scheduler) logAndReturnError(errorMsg string, err error) error {
+	s.logger.Error(errorMsg, zap.Error(err))
+	return fmt.Errorf("%s: %w", errorMsg, err)
+}
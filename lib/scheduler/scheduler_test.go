@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/phone"
+	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the scheduled orders, runs and balances in memory.
+type fakeStore struct {
+	db.DataStore
+	orders   []models.ScheduledOrder
+	runs     []models.ScheduledRun
+	balances map[string]float64
+	entries  []models.LedgerEntry
+}
+
+func (f *fakeStore) SaveScheduledOrder(_ context.Context, order models.ScheduledOrder) error {
+	f.orders = append(f.orders, order)
+	return nil
+}
+
+func (f *fakeStore) ClaimScheduledOrder(_ context.Context, _ models.ScheduledOrder, _ time.Time) error {
+	return nil
+}
+
+func (f *fakeStore) SaveScheduledRun(_ context.Context, run models.ScheduledRun) error {
+	f.runs = append(f.runs, run)
+	return nil
+}
+
+func (f *fakeStore) RecordScheduledRun(_ context.Context, _ models.ScheduledRun) error {
+	return nil
+}
+
+func (f *fakeStore) SaveAirtimeTransaction(_ context.Context, _ *telcom.AirtimeResponse) error {
+	return nil
+}
+
+func (f *fakeStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (f *fakeStore) GetVirtualNuban(_ context.Context, name string) (models.AccountDetails, error) {
+	return models.AccountDetails{User_ID: "user-" + name, VirtualAccountID: "nuban-" + name}, nil
+}
+
+func (f *fakeStore) GetBalance(_ context.Context, virtualNuban string) (float64, error) {
+	return f.balances[virtualNuban], nil
+}
+
+func (f *fakeStore) UpdateBalance(_ context.Context, virtualNuban string, balance float64) error {
+	f.balances[virtualNuban] = balance
+	return nil
+}
+
+func (f *fakeStore) SaveLedgerEntry(_ context.Context, entry models.LedgerEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func TestCreate(t *testing.T) {
+	var tests = []struct {
+		name      string
+		order     models.ScheduledOrder
+		wantErr   error
+		wantPhone string
+	}{
+		{
+			name: "Test airtime number kept in local format",
+			order: models.ScheduledOrder{Product: ProductAirtime, Cadence: "@daily", MaxAmount: 500,
+				Airtime: &telcom.AirtimeInfo{Network: "01", Amount: "500", Phone_no: "+2348031234567"}},
+			wantPhone: "08031234567",
+		},
+		{
+			name: "Test airtime amount out of range",
+			order: models.ScheduledOrder{Product: ProductAirtime, Cadence: "@daily", MaxAmount: 500,
+				Airtime: &telcom.AirtimeInfo{Network: "01", Amount: "10", Phone_no: "08031234567"}},
+			wantErr: ErrInvalidDetails,
+		},
+		{
+			name: "Test airtime invalid number",
+			order: models.ScheduledOrder{Product: ProductAirtime, Cadence: "@daily", MaxAmount: 500,
+				Airtime: &telcom.AirtimeInfo{Network: "01", Amount: "500", Phone_no: "0803123"}},
+			wantErr: phone.ErrInvalidNumber,
+		},
+		{
+			name: "Test data invalid number",
+			order: models.ScheduledOrder{Product: ProductData, Cadence: "@daily", MaxAmount: 500,
+				Data: &telcom.DataInfo{Network: 1, Plan: 7, Mobile_Num: "12345"}},
+			wantErr: phone.ErrInvalidNumber,
+		},
+		{
+			name: "Test electricity invalid meter",
+			order: models.ScheduledOrder{Product: ProductElectricity, Cadence: "@monthly", MaxAmount: 5000,
+				Electricity: &models.ElectricInfo{DiscoType: "ikeja-electric", Meter_No: "123", Meter_Type: "prepaid", Amount: 5000}},
+			wantErr: ErrInvalidDetails,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			s := NewScheduler(&Options{Store: store, Logger: zap.NewNop()})
+
+			order, err := s.Create(context.Background(), &models.User{ID: "user-ada", Username: "ada"}, tt.order)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, store.orders)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, store.orders, 1)
+			assert.Equal(t, tt.wantPhone, order.Airtime.Phone_no)
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	var tests = []struct {
+		name        string
+		status      int
+		response    string
+		wantStatus  string
+		wantBalance float64
+	}{
+		{
+			name:        "Test delivered",
+			status:      http.StatusOK,
+			response:    `{"success":"true","network":"MTN","airtimeamount":500,"mobileno":"08031234567","status":"successful"}`,
+			wantStatus:  models.RunSuccess,
+			wantBalance: 500,
+		},
+		{
+			name:        "Test rejected is refunded",
+			status:      http.StatusOK,
+			response:    `{"success":"false","message":"transaction failed"}`,
+			wantStatus:  models.RunFailed,
+			wantBalance: 1000,
+		},
+		{
+			name:        "Test unanswered is kept",
+			status:      http.StatusBadGateway,
+			response:    `<html>bad gateway</html>`,
+			wantStatus:  models.RunUnconfirmed,
+			wantBalance: 500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			t.Cleanup(srv.Close)
+			client := httpclient.New(&httpclient.Options{Name: t.Name(), BaseURL: srv.URL, Backoff: time.Millisecond})
+
+			store := &fakeStore{balances: map[string]float64{"nuban-ada": 1000}}
+			s := NewScheduler(&Options{
+				Store:   store,
+				Wallet:  wallet.NewWallet(store, zap.NewNop()),
+				Airtime: vtu.NewAirtimeConn(store, zap.NewNop(), client),
+				Logger:  zap.NewNop(),
+			})
+
+			now := time.Now()
+			s.execute(context.Background(), models.ScheduledOrder{
+				ID: "order-1", UserID: "user-ada", Username: "ada", Product: ProductAirtime, Cadence: "@daily",
+				MaxAmount: 500, Status: models.ScheduleActive, NextRun: now,
+				Airtime: &telcom.AirtimeInfo{Network: "01", Amount: "500", Phone_no: "08031234567"},
+			}, now)
+
+			require.Len(t, store.runs, 1)
+			assert.Equal(t, tt.wantStatus, store.runs[0].Status)
+			assert.Equal(t, tt.wantBalance, store.balances["nuban-ada"])
+			// the run is debited and refunded under its own order id, not the scheduled order's
+			require.NotEmpty(t, store.runs[0].PurchaseID)
+			for _, entry := range store.entries {
+				assert.Equal(t, store.runs[0].PurchaseID, entry.Reference)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package smsclient
+
+import "github.com/aremxyplug-be/db/models"
+
+// SMSClient interface
+type SMSClient interface {
+	Send(sms *models.Message) error
+}
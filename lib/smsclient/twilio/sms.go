@@ -1,82 +1,72 @@
-//package smsclient
-//
-//import (
-//	"encoding/json"
-//	"fmt"
-//	"math/rand"
-//	"net/http"
-//	"time"
-//
-//	"github.com/go-chi/chi"
-//	"github.com/twilio/twilio-go"
-//	"github.com/twilio/twilio-go/rest/api/v2010/account/message"
-//	"github.com/twinj/uuid"
-//)
-//
-//var (
-//	twilioAccountSid = "your_twilio_account_sid"
-//	twilioAuthToken  = "your_twilio_auth_token"
-//)
-//
-//var otpDataStore = make(map[string]OTPData)
-//
-//func generateOTP() string {
-//	rand.Seed(time.Now().UnixNano())
-//	return fmt.Sprintf("%06d", rand.Intn(1000000))
-//}
-//
-//func createOTPHandler(w http.ResponseWriter, r *http.Request) {
-//	phoneNumber := r.FormValue("phone_number")
-//
-//	otpID := uuid.NewV4().String()
-//	otp := generateOTP()
-//
-//	otpDataStore[otpID] = OTPData{
-//		ID:       otpID,
-//		OTP:      otp,
-//		Verified: false,
-//	}
-//
-//	client := twilio.NewRestClient(twilioAccountSid, twilioAuthToken)
-//	messageService := client.ApiV2010.CreateMessage(message.CreateMessageParams{
-//		To:   twilio.String(phoneNumber),
-//		From: twilio.String("your_twilio_phone_number"),
-//		Body: twilio.String(fmt.Sprintf("Your OTP is: %s", otp)),
-//	})
-//
-//	if messageService != nil {
-//		w.WriteHeader(http.StatusCreated)
-//		w.Header().Set("Content-Type", "application/json")
-//		json.NewEncoder(w).Encode(map[string]string{"message": "OTP sent successfully"})
-//	} else {
-//		w.WriteHeader(http.StatusInternalServerError)
-//		w.Header().Set("Content-Type", "application/json")
-//		json.NewEncoder(w).Encode(map[string]string{"message": "Failed to send OTP"})
-//	}
-//}
-//
-//func verifyOTPHandler(w http.ResponseWriter, r *http.Request) {
-//	otpID := chi.URLParam(r, "otpID")
-//	otp := r.FormValue("otp")
-//
-//	storedOTPData, found := otpDataStore[otpID]
-//	if !found {
-//		w.WriteHeader(http.StatusNotFound)
-//		w.Header().Set("Content-Type", "application/json")
-//		json.NewEncoder(w).Encode(map[string]string{"error": "OTP not found"})
-//		return
-//	}
-//
-//	if storedOTPData.OTP == otp && !storedOTPData.Verified {
-//		storedOTPData.Verified = true
-//		otpDataStore[otpID] = storedOTPData
-//
-//		w.WriteHeader(http.StatusOK)
-//		w.Header().Set("Content-Type", "application/json")
-//		json.NewEncoder(w).Encode(map[string]string{"message": "OTP verified successfully"})
-//	} else {
-//		w.WriteHeader(http.StatusUnauthorized)
-//		w.Header().Set("Content-Type", "application/json")
-//		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid OTP"})
-//	}
-//}
+package twilio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/smsclient"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	twilioAPIURL     = "https://api.twilio.com/2010-04-01"
+	messagesEndpoint = "/Accounts/%s/Messages.json"
+)
+
+// Ensure implementation of SMSClient interface
+var _ smsclient.SMSClient = (*smsClient)(nil)
+
+type smsClient struct {
+	RESTClient *resty.Client
+	config     config.Twilio
+}
+
+// ErrorResponse payload definition
+type ErrorResponse struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+}
+
+// Send sends the body of the message to the message target using the twilio messaging service
+func (s *smsClient) Send(message *models.Message) error {
+	if message == nil {
+		return errors.New("message it's empty")
+	}
+	if message.Target == "" {
+		return errors.New("message has no recipient")
+	}
+
+	var errorResponse ErrorResponse
+	response, err := s.RESTClient.R().
+		SetFormData(map[string]string{
+			"To":                  message.Target,
+			"MessagingServiceSid": s.config.ServiceID,
+			"Body":                message.Body,
+		}).
+		SetError(&errorResponse).
+		Post(fmt.Sprintf(messagesEndpoint, s.config.AccountSID))
+	if err != nil {
+		return err
+	}
+	if response.IsError() {
+		return fmt.Errorf("twilio call response error with code: %d, message: %s", errorResponse.Code, errorResponse.Message)
+	}
+
+	return nil
+}
+
+// New return a new instance of a Twilio definition for SMSClient interface
+func New(cfg config.Twilio) smsclient.SMSClient {
+	restClient := resty.New()
+	restClient.SetBaseURL(twilioAPIURL)
+	restClient.SetBasicAuth(cfg.AccountSID, cfg.AuthToken)
+	restClient.SetHeader("Accept", "application/json")
+
+	return &smsClient{
+		RESTClient: restClient,
+		config:     cfg,
+	}
+}
@@ -0,0 +1,122 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aremxyplug-be/db/models"
+)
+
+// Format is the file format a statement is exported in.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	PDF  Format = "pdf"
+	XLSX Format = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("statement format must be csv, pdf or xlsx")
+
+// columns of the statement table, in every format.
+var columns = []string{"Date", "Type", "Description", "Reference", "Amount", "Balance"}
+
+const dateTimeLayout = "2006-01-02 15:04"
+
+// ParseFormat reads the format a statement was requested in, PDF when none was.
+func ParseFormat(v string) (Format, error) {
+	switch f := Format(strings.ToLower(v)); f {
+	case "":
+		return PDF, nil
+	case CSV, PDF, XLSX:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/pdf"
+}
+
+// Filename is the name the statement is downloaded or attached as.
+func Filename(st models.Statement, f Format) string {
+	return fmt.Sprintf("statement-%s-%s.%s", st.From.In(location).Format("20060102"), st.To.In(location).Format("20060102"), f)
+}
+
+// Export writes the statement to w in the format f.
+func Export(w io.Writer, st models.Statement, f Format) error {
+	switch f {
+	case CSV:
+		return exportCSV(w, st)
+	case PDF:
+		return exportPDF(w, st)
+	case XLSX:
+		return exportXLSX(w, st)
+	}
+	return ErrUnknownFormat
+}
+
+// Attachment exports the statement as an email attachment.
+func Attachment(st models.Statement, f Format) (models.Attachment, error) {
+	var buf bytes.Buffer
+	if err := Export(&buf, st, f); err != nil {
+		return models.Attachment{}, err
+	}
+
+	return models.Attachment{
+		Name:        Filename(st, f),
+		Content:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+		ContentType: f.ContentType(),
+	}, nil
+}
+
+// exportCSV writes the entries between an opening and a closing balance row, amounts are plain numbers so
+// spreadsheets can sum them.
+func exportCSV(w io.Writer, st models.Statement) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{
+		columns,
+		{st.From.In(location).Format(dateTimeLayout), "", "Opening balance", "", "", amount(st.OpeningBalance)},
+	}
+	for _, entry := range st.Entries {
+		rows = append(rows, []string{
+			entry.CreatedAt.In(location).Format(dateTimeLayout),
+			entry.Type,
+			text(entry.Description),
+			text(entry.Reference),
+			amount(entry.Amount),
+			amount(entry.Balance),
+		})
+	}
+	rows = append(rows, []string{st.To.In(location).Format(dateTimeLayout), "", "Closing balance", "", "", amount(st.ClosingBalance)})
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// text keeps spreadsheets from reading a description, which may come from a sender's account name, as
+// a formula.
+func text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package statement
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfWidth  = 297.0 // A4 landscape, in mm
+	pdfHeight = 210.0
+	pdfMargin = 12.0
+	pdfRow    = 7.0
+)
+
+// widths of the columns, they add up to the width between the margins.
+var pdfColumns = []float64{34, 24, 97, 50, 34, 34}
+
+func exportPDF(w io.Writer, st models.Statement) error {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.SetTitle("Account statement", true)
+	pdf.SetCreator(receipt.Brand, true)
+	// a statement of a closed period exported twice is the same file
+	pdf.SetCreationDate(st.To)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	content := pdfWidth - 2*pdfMargin
+	period := st.From.In(location).Format("02 Jan 2006") + " - " + st.To.In(location).Format("02 Jan 2006")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(pdfHeight - pdfMargin)
+		pdf.SetTextColor(107, 107, 107)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(content/2, 4, tr(receipt.Brand+" account statement, "+period), "", 0, "L", false, 0, "")
+		pdf.CellFormat(content/2, 4, "Page "+strconv.Itoa(pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFillColor(int(receipt.BrandColor.R), int(receipt.BrandColor.G), int(receipt.BrandColor.B))
+	pdf.Rect(0, 0, pdfWidth, 28, "F")
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetXY(pdfMargin, 7)
+	pdf.CellFormat(content, 9, receipt.Brand, "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(content, 6, "Account statement, "+period, "", 1, "L", false, 0, "")
+
+	pdf.SetY(36)
+	summary := []receipt.Field{
+		{Label: "Opening balance", Value: receipt.FormatAmount(st.OpeningBalance)},
+		{Label: "Total credits", Value: receipt.FormatAmount(st.TotalCredits)},
+		{Label: "Total debits", Value: receipt.FormatAmount(st.TotalDebits)},
+		{Label: "Closing balance", Value: receipt.FormatAmount(st.ClosingBalance)},
+	}
+	width := content / float64(len(summary))
+	pdf.SetTextColor(107, 107, 107)
+	pdf.SetFont("Helvetica", "", 9)
+	for _, field := range summary {
+		pdf.CellFormat(width, 5, field.Label, "", 0, "L", false, 0, "")
+	}
+	pdf.Ln(5)
+	pdf.SetTextColor(34, 34, 34)
+	pdf.SetFont("Helvetica", "B", 13)
+	for _, field := range summary {
+		pdf.CellFormat(width, 8, field.Value, "", 0, "L", false, 0, "")
+	}
+	pdf.Ln(14)
+
+	tableHeader(pdf)
+	pdf.SetDrawColor(221, 221, 221)
+	for _, entry := range st.Entries {
+		if pdf.GetY()+pdfRow > pdfHeight-pdfMargin-6 {
+			pdf.AddPage()
+			tableHeader(pdf)
+			pdf.SetDrawColor(221, 221, 221)
+		}
+
+		values := []string{
+			entry.CreatedAt.In(location).Format(dateTimeLayout),
+			entry.Type,
+			fit(pdf, tr(entry.Description), pdfColumns[2]),
+			fit(pdf, tr(entry.Reference), pdfColumns[3]),
+			receipt.FormatAmount(entry.Amount),
+			receipt.FormatAmount(entry.Balance),
+		}
+		for i, value := range values {
+			align := "L"
+			if i >= 4 {
+				align = "R"
+			}
+			pdf.CellFormat(pdfColumns[i], pdfRow, value, "B", 0, align, false, 0, "")
+		}
+		pdf.Ln(pdfRow)
+	}
+	if len(st.Entries) == 0 {
+		pdf.SetTextColor(107, 107, 107)
+		pdf.CellFormat(content, pdfRow*2, "No transactions in this period.", "", 1, "C", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+func tableHeader(pdf *gofpdf.Fpdf) {
+	pdf.SetFillColor(243, 240, 248)
+	pdf.SetTextColor(34, 34, 34)
+	pdf.SetFont("Helvetica", "B", 9)
+	for i, column := range columns {
+		align := "L"
+		if i >= 4 {
+			align = "R"
+		}
+		pdf.CellFormat(pdfColumns[i], pdfRow, column, "", 0, align, true, 0, "")
+	}
+	pdf.Ln(pdfRow)
+	pdf.SetFont("Helvetica", "", 9)
+}
+
+// fit shortens s with an ellipsis so it fits in a column of the width.
+func fit(pdf *gofpdf.Fpdf, s string, width float64) string {
+	width -= 2 * pdf.GetCellMargin()
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,220 @@
+// Package statement compiles the ledger of the users' wallets into account statements, exports them as
+// CSV, PDF and Excel files and emails them every month.
+package statement
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"github.com/aremxyplug-be/lib/receipt"
+	"go.uber.org/zap"
+)
+
+const (
+	// Alias is the postmark template the statements are emailed with.
+	Alias = "account-statement"
+
+	// MaxPeriod is the longest period a statement can cover.
+	MaxPeriod = 366 * 24 * time.Hour
+
+	defaultInterval = time.Hour
+)
+
+var (
+	ErrInvalidPeriod = errors.New("statement period must end after it starts and cover at most a year")
+	ErrNoEmail       = errors.New("user has no email address to send the statement to")
+)
+
+// location is the time zone statements are dated in, a month is a calendar month there.
+var location = time.FixedZone("WAT", 60*60)
+
+// Statements generates the account statements of the users.
+type Statements struct {
+	db          db.DataStore
+	emailClient emailclient.EmailClient
+	idGenerator idgenerator.IdGenerator
+	logger      *zap.Logger
+
+	// sent is the last period every statement was sent for by this instance, so the worker does not
+	// look for users again until the next month.
+	sent string
+}
+
+func NewStatements(store db.DataStore, emailClient emailclient.EmailClient, logger *zap.Logger) *Statements {
+	return &Statements{
+		db:          store,
+		emailClient: emailClient,
+		idGenerator: idgenerator.New(),
+		logger:      logger,
+	}
+}
+
+// Generate compiles the statement of userID from from to to, both included.
+func (s *Statements) Generate(ctx context.Context, userID string, from, to time.Time) (models.Statement, error) {
+	if !to.After(from) || to.Sub(from) > MaxPeriod {
+		return models.Statement{}, ErrInvalidPeriod
+	}
+
+	opening, err := s.db.GetBalanceAt(ctx, userID, from)
+	if err != nil {
+		return models.Statement{}, s.logAndReturnError("failed to get opening balance", err)
+	}
+
+	entries, err := s.db.GetLedgerEntries(ctx, userID, from, to)
+	if err != nil {
+		return models.Statement{}, s.logAndReturnError("failed to get ledger entries", err)
+	}
+
+	return compile(userID, from, to, opening, entries), nil
+}
+
+func compile(userID string, from, to time.Time, opening float64, entries []models.LedgerEntry) models.Statement {
+	st := models.Statement{
+		UserID:         userID,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Entries:        entries,
+	}
+	for _, entry := range entries {
+		if entry.Amount > 0 {
+			st.TotalCredits += entry.Amount
+		} else {
+			st.TotalDebits -= entry.Amount
+		}
+	}
+	if len(entries) > 0 {
+		st.ClosingBalance = entries[len(entries)-1].Balance
+	}
+
+	return st
+}
+
+// Email sends the statement to the user with the file in format f attached.
+func (s *Statements) Email(ctx context.Context, user *models.User, st models.Statement, f Format) error {
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+
+	attachment, err := Attachment(st, f)
+	if err != nil {
+		return s.logAndReturnError("failed to render statement", err)
+	}
+
+	name := user.FullName
+	if name == "" {
+		name = user.Username
+	}
+	message := models.Message{
+		ID:         s.idGenerator.Generate(),
+		Target:     user.Email,
+		Type:       models.EMAIL_MESSAGE_TYPE,
+		Title:      "Account Statement",
+		TemplateID: Alias,
+		DataMap: map[string]string{
+			"Name":           name,
+			"From":           st.From.In(location).Format("02 Jan 2006"),
+			"To":             st.To.In(location).Format("02 Jan 2006"),
+			"OpeningBalance": receipt.FormatAmount(st.OpeningBalance),
+			"ClosingBalance": receipt.FormatAmount(st.ClosingBalance),
+			"TotalCredits":   receipt.FormatAmount(st.TotalCredits),
+			"TotalDebits":    receipt.FormatAmount(st.TotalDebits),
+		},
+		Attachments: []models.Attachment{attachment},
+		Ts:          time.Now().Unix(),
+	}
+	if err := s.emailClient.Send(&message); err != nil {
+		return s.logAndReturnError("failed to send statement email", err)
+	}
+
+	return nil
+}
+
+// Run sends the statements of the previous month every interval until ctx is cancelled. Statements are
+// claimed before they are sent, so each one goes out once however many instances run it.
+func (s *Statements) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SendMonthly(ctx, time.Now())
+		}
+	}
+}
+
+// SendMonthly emails a PDF statement of the month before now to every user whose wallet moved in it.
+// A statement that fails after it was claimed is logged and not retried.
+func (s *Statements) SendMonthly(ctx context.Context, now time.Time) {
+	from, to := previousMonth(now)
+	period := from.Format("2006-01")
+	if s.sent == period {
+		return
+	}
+
+	users, err := s.db.GetLedgerUsers(ctx, from, to)
+	if err != nil {
+		s.logger.Error("failed to get users with ledger entries", zap.String("period", period), zap.Error(err))
+		return
+	}
+
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return
+		}
+
+		claimed, err := s.db.ClaimStatement(ctx, userID, period)
+		if err != nil {
+			s.logger.Error("failed to claim statement", zap.String("user_id", userID), zap.String("period", period), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.send(ctx, userID, from, to); err != nil {
+			s.logger.Error("failed to send monthly statement", zap.String("user_id", userID), zap.String("period", period), zap.Error(err))
+		}
+	}
+
+	s.sent = period
+}
+
+func (s *Statements) send(ctx context.Context, userID string, from, to time.Time) error {
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	st, err := s.Generate(ctx, userID, from, to)
+	if err != nil {
+		return err
+	}
+
+	return s.Email(ctx, user, st, PDF)
+}
+
+// previousMonth returns the first and last instant of the calendar month before the one of now.
+func previousMonth(now time.Time) (time.Time, time.Time) {
+	now = now.In(location)
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	return to.AddDate(0, -1, 0), to.Add(-time.Nanosecond)
+}
+
+func (s *Statements) logAndReturnError(msg string, err error) error {
+	s.logger.Error(msg, zap.Error(err))
+	return err
+}
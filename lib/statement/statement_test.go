@@ -0,0 +1,279 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the ledger and the claimed statements in memory.
+type fakeStore struct {
+	db.DataStore
+	entries []models.LedgerEntry
+	users   map[string]*models.User
+	claims  map[string]bool
+}
+
+func (f *fakeStore) GetLedgerEntries(_ context.Context, userID string, from, to time.Time) ([]models.LedgerEntry, error) {
+	var res []models.LedgerEntry
+	for _, entry := range f.entries {
+		if entry.UserID == userID && !entry.CreatedAt.Before(from) && !entry.CreatedAt.After(to) {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) GetBalanceAt(_ context.Context, userID string, t time.Time) (float64, error) {
+	balance := 0.0
+	for _, entry := range f.entries {
+		if entry.UserID == userID && entry.CreatedAt.Before(t) {
+			balance = entry.Balance
+		}
+	}
+	return balance, nil
+}
+
+func (f *fakeStore) GetLedgerUsers(_ context.Context, from, to time.Time) ([]string, error) {
+	seen := map[string]bool{}
+	var users []string
+	for _, entry := range f.entries {
+		if !seen[entry.UserID] && !entry.CreatedAt.Before(from) && !entry.CreatedAt.After(to) {
+			seen[entry.UserID] = true
+			users = append(users, entry.UserID)
+		}
+	}
+	return users, nil
+}
+
+func (f *fakeStore) ClaimStatement(_ context.Context, userID, period string) (bool, error) {
+	if f.claims[userID+"/"+period] {
+		return false, nil
+	}
+	f.claims[userID+"/"+period] = true
+	return true, nil
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	return f.users[id], nil
+}
+
+type fakeEmailClient struct {
+	sent []*models.Message
+}
+
+func (f *fakeEmailClient) Send(email *models.Message) error {
+	f.sent = append(f.sent, email)
+	return nil
+}
+
+func at(day, hour int) time.Time {
+	return time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC)
+}
+
+func newStore() *fakeStore {
+	return &fakeStore{
+		entries: []models.LedgerEntry{
+			{UserID: "user-1", Type: models.EntryDeposit, Amount: 5000, Balance: 5000, CreatedAt: time.Date(2024, 4, 28, 9, 0, 0, 0, time.UTC)},
+			{UserID: "user-1", Type: models.EntryPurchase, Amount: -1200, Balance: 3800, Description: "Airtime", CreatedAt: at(3, 10)},
+			{UserID: "user-1", Type: models.EntryRefund, Amount: 200, Balance: 4000, Description: "=HYPERLINK(\"x\")", CreatedAt: at(4, 10)},
+			{UserID: "user-1", Type: models.EntryTransfer, Amount: -1000, Balance: 3000, Reference: "77", CreatedAt: at(9, 12)},
+			{UserID: "user-1", Type: models.EntryFee, Amount: -50, Balance: 2950, Reference: "77", CreatedAt: at(9, 12)},
+			{UserID: "user-2", Type: models.EntryDeposit, Amount: 900, Balance: 900, CreatedAt: at(20, 8)},
+		},
+		users: map[string]*models.User{
+			"user-1": {ID: "user-1", FullName: "Ada Obi", Email: "ada@example.com"},
+			"user-2": {ID: "user-2", Username: "tunde"},
+		},
+		claims: map[string]bool{},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var tests = []struct {
+		name     string
+		userID   string
+		from, to time.Time
+		want     models.Statement
+		entries  int
+		wantErr  error
+	}{
+		{
+			name:    "Test month with movements",
+			userID:  "user-1",
+			from:    at(1, 0),
+			to:      at(31, 22),
+			want:    models.Statement{OpeningBalance: 5000, ClosingBalance: 2950, TotalCredits: 200, TotalDebits: 2250},
+			entries: 4,
+		},
+		{
+			name:   "Test period without movements",
+			userID: "user-1",
+			from:   at(10, 0),
+			to:     at(11, 0),
+			want:   models.Statement{OpeningBalance: 2950, ClosingBalance: 2950},
+		},
+		{
+			name:    "Test period ending before it starts",
+			userID:  "user-1",
+			from:    at(11, 0),
+			to:      at(10, 0),
+			wantErr: ErrInvalidPeriod,
+		},
+		{
+			name:    "Test period longer than a year",
+			userID:  "user-1",
+			from:    at(1, 0).AddDate(-2, 0, 0),
+			to:      at(1, 0),
+			wantErr: ErrInvalidPeriod,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStatements(newStore(), &fakeEmailClient{}, zap.NewNop())
+
+			got, err := s.Generate(context.Background(), tt.userID, tt.from, tt.to)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+			assert.Equal(t, tt.want.OpeningBalance, got.OpeningBalance)
+			assert.Equal(t, tt.want.ClosingBalance, got.ClosingBalance)
+			assert.Equal(t, tt.want.TotalCredits, got.TotalCredits)
+			assert.Equal(t, tt.want.TotalDebits, got.TotalDebits)
+			assert.Len(t, got.Entries, tt.entries)
+		})
+	}
+}
+
+func TestSendMonthly(t *testing.T) {
+	store := newStore()
+	emails := &fakeEmailClient{}
+	s := NewStatements(store, emails, zap.NewNop())
+
+	s.SendMonthly(context.Background(), time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC))
+
+	// user-2 has no email address, the statement is claimed and not sent
+	if assert.Len(t, emails.sent, 1) {
+		message := emails.sent[0]
+		assert.Equal(t, "ada@example.com", message.Target)
+		assert.Equal(t, Alias, message.TemplateID)
+		assert.Equal(t, "NGN 2,950.00", message.DataMap["ClosingBalance"])
+		assert.Equal(t, "statement-20240501-20240531.pdf", message.Attachments[0].Name)
+	}
+	assert.True(t, store.claims["user-1/2024-05"])
+	assert.True(t, store.claims["user-2/2024-05"])
+
+	// a second instance finds the statements claimed
+	NewStatements(store, emails, zap.NewNop()).SendMonthly(context.Background(), time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC))
+	assert.Len(t, emails.sent, 1)
+}
+
+func TestPreviousMonth(t *testing.T) {
+	var tests = []struct {
+		name     string
+		now      time.Time
+		wantFrom string
+		wantTo   string
+	}{
+		{name: "Test middle of a month", now: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC), wantFrom: "2024-02-01 00:00", wantTo: "2024-02-29 23:59"},
+		{name: "Test january", now: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), wantFrom: "2023-12-01 00:00", wantTo: "2023-12-31 23:59"},
+		// 23:30 UTC on the last day of the month is already the next month in Lagos
+		{name: "Test end of a month in UTC", now: time.Date(2024, 4, 30, 23, 30, 0, 0, time.UTC), wantFrom: "2024-04-01 00:00", wantTo: "2024-04-30 23:59"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := previousMonth(tt.now)
+			assert.Equal(t, tt.wantFrom, from.In(location).Format(dateTimeLayout))
+			assert.Equal(t, tt.wantTo, to.In(location).Format(dateTimeLayout))
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	s := NewStatements(newStore(), &fakeEmailClient{}, zap.NewNop())
+	st, err := s.Generate(context.Background(), "user-1", at(1, 0), at(31, 22))
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		name   string
+		format Format
+		check  func(t *testing.T, content []byte)
+	}{
+		{name: "Test csv", format: CSV, check: func(t *testing.T, content []byte) {
+			rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+			assert.NoError(t, err)
+			if assert.Len(t, rows, 7) {
+				assert.Equal(t, columns, rows[0])
+				assert.Equal(t, []string{"Opening balance", "5000.00"}, []string{rows[1][2], rows[1][5]})
+				assert.Equal(t, "-1200.00", rows[2][4])
+				assert.True(t, strings.HasPrefix(rows[3][2], "'="), "formula not escaped")
+				assert.Equal(t, []string{"Closing balance", "2950.00"}, []string{rows[6][2], rows[6][5]})
+			}
+		}},
+		{name: "Test pdf", format: PDF, check: func(t *testing.T, content []byte) {
+			assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+		}},
+		{name: "Test xlsx", format: XLSX, check: func(t *testing.T, content []byte) {
+			f, err := excelize.OpenReader(bytes.NewReader(content))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer f.Close()
+
+			rows, err := f.GetRows(sheet)
+			assert.NoError(t, err)
+			assert.Len(t, rows, 12)
+			closing, err := f.GetCellValue(sheet, "B6")
+			assert.NoError(t, err)
+			assert.Equal(t, "2,950.00", closing)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachment, err := Attachment(st, tt.format)
+			assert.NoError(t, err)
+			assert.Equal(t, "statement-20240501-20240531."+string(tt.format), attachment.Name)
+			assert.Equal(t, tt.format.ContentType(), attachment.ContentType)
+
+			content, err := base64.StdEncoding.DecodeString(attachment.Content)
+			assert.NoError(t, err)
+			tt.check(t, content)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	var tests = []struct {
+		name    string
+		value   string
+		want    Format
+		wantErr error
+	}{
+		{name: "Test default", value: "", want: PDF},
+		{name: "Test upper case", value: "XLSX", want: XLSX},
+		{name: "Test csv", value: "csv", want: CSV},
+		{name: "Test unknown", value: "json", wantErr: ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormat(tt.value)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package statement
+
+import (
+	"io"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/xuri/excelize/v2"
+)
+
+const sheet = "Statement"
+
+// cellStyle is a style applied to the cells from from to to.
+type cellStyle struct {
+	from, to string
+	style    int
+}
+
+func exportXLSX(w io.Writer, st models.Statement) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	moneyFormat := "#,##0.00"
+	money, err := f.NewStyle(&excelize.Style{CustomNumFmt: &moneyFormat})
+	if err != nil {
+		return err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{
+		{receipt.Brand + " account statement"},
+		{"Period", st.From.In(location).Format("02 Jan 2006") + " - " + st.To.In(location).Format("02 Jan 2006")},
+		{"Opening balance", st.OpeningBalance},
+		{"Total credits", st.TotalCredits},
+		{"Total debits", st.TotalDebits},
+		{"Closing balance", st.ClosingBalance},
+		{},
+	}
+	header := len(rows) + 1
+	titles := make([]interface{}, len(columns))
+	for i, column := range columns {
+		titles[i] = column
+	}
+	rows = append(rows, titles)
+	for _, entry := range st.Entries {
+		rows = append(rows, []interface{}{
+			entry.CreatedAt.In(location).Format(dateTimeLayout),
+			entry.Type,
+			entry.Description,
+			entry.Reference,
+			entry.Amount,
+			entry.Balance,
+		})
+	}
+
+	for i, row := range rows {
+		if err := f.SetSheetRow(sheet, cellName(1, i+1), &row); err != nil {
+			return err
+		}
+	}
+
+	styles := []cellStyle{
+		{"A1", "A1", bold},
+		{"B3", "B6", money},
+		{cellName(1, header), cellName(len(columns), header), bold},
+	}
+	if len(st.Entries) > 0 {
+		styles = append(styles, cellStyle{cellName(5, header+1), cellName(6, len(rows)), money})
+	}
+	for _, s := range styles {
+		if err := f.SetCellStyle(sheet, s.from, s.to, s.style); err != nil {
+			return err
+		}
+	}
+	if err := f.SetColWidth(sheet, "A", "A", 18); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "C", "D", 36); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "E", "F", 14); err != nil {
+		return err
+	}
+
+	return f.Write(w)
+}
+
+func cellName(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
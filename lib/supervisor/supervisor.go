@@ -0,0 +1,149 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type State string
+
+const (
+	StateIdle       State = "idle"
+	StateRunning    State = "running"
+	StateRestarting State = "restarting"
+	StateStopped    State = "stopped"
+
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Supervisor runs the background workers. A worker that panics or returns before it is stopped is
+// restarted with a backoff, and Stop cancels every worker and waits for them to return.
+type Supervisor struct {
+	mu      sync.RWMutex
+	workers []*worker
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	logger  *zap.Logger
+}
+
+type worker struct {
+	name  string
+	run   func(ctx context.Context)
+	state State
+}
+
+func New(logger *zap.Logger) *Supervisor {
+	return &Supervisor{logger: logger}
+}
+
+// Add registers a worker, run should block until ctx is done. Workers added after Start are not run.
+func (s *Supervisor) Add(name string, run func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.workers = append(s.workers, &worker{name: name, run: run, state: StateIdle})
+}
+
+// Start runs every worker in its own goroutine until ctx is done or Stop is called.
+func (s *Supervisor) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	s.cancel = cancel
+	workers := s.workers
+	s.mu.Unlock()
+
+	for _, w := range workers {
+		s.wg.Add(1)
+		go s.supervise(ctx, w)
+	}
+}
+
+// Stop cancels the workers and waits for them to return, or for ctx to be done.
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.mu.RLock()
+	cancel := s.cancel
+	s.mu.RUnlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop: %w", ctx.Err())
+	}
+}
+
+// Status returns the state of every worker by name.
+func (s *Supervisor) Status() map[string]State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := make(map[string]State, len(s.workers))
+	for _, w := range s.workers {
+		status[w.name] = w.state
+	}
+	return status
+}
+
+func (s *Supervisor) supervise(ctx context.Context, w *worker) {
+	defer s.wg.Done()
+	defer s.setState(w, StateStopped)
+
+	backoff := minBackoff
+	for {
+		s.setState(w, StateRunning)
+		started := time.Now()
+		s.runOnce(ctx, w)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		// a worker that ran for a while before failing starts over from the smallest backoff.
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		s.setState(w, StateRestarting)
+		s.logger.Warn("restarting worker", zap.String("worker", w.name), zap.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *Supervisor) runOnce(ctx context.Context, w *worker) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("worker panicked", zap.String("worker", w.name), zap.Any("panic", r), zap.Stack("stack"))
+		}
+	}()
+
+	w.run(ctx)
+}
+
+func (s *Supervisor) setState(w *worker, state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.state = state
+}
@@ -0,0 +1,63 @@
+package supervisor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSupervisor(t *testing.T) {
+	t.Run("Test worker runs until stopped", func(t *testing.T) {
+		s := New(zap.NewNop())
+		s.Add("blocking", func(ctx context.Context) {
+			<-ctx.Done()
+		})
+		assert.Equal(t, map[string]State{"blocking": StateIdle}, s.Status())
+
+		s.Start(context.Background())
+		assert.Eventually(t, func() bool {
+			return s.Status()["blocking"] == StateRunning
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, s.Stop(context.Background()))
+		assert.Equal(t, StateStopped, s.Status()["blocking"])
+	})
+
+	t.Run("Test panicking worker is restarted", func(t *testing.T) {
+		var runs atomic.Int32
+		s := New(zap.NewNop())
+		s.Add("panics", func(ctx context.Context) {
+			if runs.Add(1) == 1 {
+				panic("boom")
+			}
+			<-ctx.Done()
+		})
+
+		s.Start(context.Background())
+		assert.Eventually(t, func() bool {
+			return runs.Load() == 2 && s.Status()["panics"] == StateRunning
+		}, 3*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, s.Stop(context.Background()))
+	})
+
+	t.Run("Test stop times out on a stuck worker", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		s := New(zap.NewNop())
+		s.Add("stuck", func(ctx context.Context) {
+			<-release
+		})
+		s.Start(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, s.Stop(ctx), context.DeadlineExceeded)
+	})
+}
@@ -2,69 +2,103 @@ package airtime
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/phone"
 	"github.com/aremxyplug-be/lib/randomgen"
 	"go.uber.org/zap"
 )
 
 var (
-	api   = os.Getenv("EASYACCESS")
-	token = os.Getenv("EASYACCESS_AUTH")
+	// networks maps the network codes used by easyaccess to network names.
+	networks = map[string]string{
+		"01": phone.MTN,
+		"02": phone.Glo,
+		"03": phone.Airtel,
+		"04": phone.NineMob,
+	}
 )
 
+// NetworkName returns the network name of an easyaccess network code. Names are returned as they are.
+func NetworkName(code string) string {
+	if name, ok := networks[code]; ok {
+		return name
+	}
+	return code
+}
+
+// NetworkCode returns the easyaccess network code of a network name.
+func NetworkCode(name string) (string, bool) {
+	name = phone.NormaliseNetwork(name)
+	for code, network := range networks {
+		if network == name {
+			return code, true
+		}
+	}
+	return "", false
+}
+
 type AirtimeConn struct {
 	logger *zap.Logger
 	db     db.TelcomStore
+	client *httpclient.Client
 }
 
-func NewAirtimeConn(store db.TelcomStore, logger *zap.Logger) *AirtimeConn {
+func NewAirtimeConn(store db.TelcomStore, logger *zap.Logger, client *httpclient.Client) *AirtimeConn {
 	return &AirtimeConn{
 		logger: logger,
 		db:     store,
+		client: client,
 	}
 }
 
-func (a *AirtimeConn) BuyAirtime(airtime telcom.AirtimeInfo) (*telcom.AirtimeResponse, error) {
-
-	id, err := randomgen.GenerateOrderID()
-	if err != nil {
-		a.logger.Error("unable to generate orderID", zap.Any("error:", "failed to generate orderID"))
+func (a *AirtimeConn) BuyAirtime(ctx context.Context, airtime telcom.AirtimeInfo) (*telcom.AirtimeResponse, error) {
+	// the amount was validated by the handler
+	price, _ := strconv.ParseFloat(airtime.Amount, 64)
+	if err := a.client.CheckFloat(price); err != nil {
 		return nil, err
 	}
-	resp, err := a.buy(airtime)
+
+	id := airtime.OrderID
+	if id == 0 {
+		var err error
+		if id, err = randomgen.GenerateOrderID(); err != nil {
+			a.logger.Error("unable to generate orderID", zap.Any("error:", "failed to generate orderID"))
+			return nil, err
+		}
+	}
+	resp, err := a.buy(ctx, airtime)
 	if err != nil {
 		a.logger.Error("error returned from server", zap.Any("error:", err))
-		return nil, err
+		return nil, httpclient.Unconfirmed(err)
 	}
 	if resp.Body == nil {
 		a.logger.Error("empty resp body", zap.String("error:", "response body is nil!"))
-		return nil, errors.New("empty response body")
+		return nil, httpclient.Unconfirmed(errors.New("empty response body"))
 	}
-	log.Println(resp.Status)
 	defer resp.Body.Close()
 
 	apiResponse := telcom.AirtimeApiResponse{}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
 		if err == io.EOF {
-			return nil, logAndReturnError(a.logger, "Empty response from server")
+			return nil, httpclient.Unconfirmed(logAndReturnError(a.logger, "Empty response from server", err))
 		}
-		return nil, logAndReturnError(a.logger, "Error returned from server")
+		return nil, httpclient.Unconfirmed(logAndReturnError(a.logger, "Error returned from server", err))
 	}
 
 	// check to see if the buy was successful. The response is printed to the log
 	if apiResponse.Success_Response == "false" {
-		log.Print(apiResponse.Message)
+		a.logger.Warn("airtime purchase failed", zap.String("message", apiResponse.Message))
 		return nil, errors.New("failed to buy airtime")
 	}
 
@@ -73,6 +107,7 @@ func (a *AirtimeConn) BuyAirtime(airtime telcom.AirtimeInfo) (*telcom.AirtimeRes
 	product := apiResponse.Network + " " + airtime.Product
 
 	result := &telcom.AirtimeResponse{
+		UserID:          airtime.UserID,
 		OrderID:         id,
 		Amount:          amount,
 		Network:         apiResponse.Network,
@@ -86,16 +121,16 @@ func (a *AirtimeConn) BuyAirtime(airtime telcom.AirtimeInfo) (*telcom.AirtimeRes
 		TransactionID:   transactionID,
 	}
 
-	// save transaction
-	if err := a.saveTransaction(result); err != nil {
-		return result, logAndReturnError(a.logger, "error saving transaction, an error occurred")
+	// save transaction, the airtime was sent so a cancelled request must not lose the record
+	if err := a.saveTransaction(context.WithoutCancel(ctx), result); err != nil {
+		return result, httpclient.Unconfirmed(logAndReturnError(a.logger, "error saving transaction, an error occurred", err))
 	}
 
 	return result, nil
 }
 
-func (a *AirtimeConn) GetTransactionDetail(id string) (telcom.AirtimeResponse, error) {
-	result, err := a.getTransacationDetails(id)
+func (a *AirtimeConn) GetTransactionDetail(ctx context.Context, userID, id string) (telcom.AirtimeResponse, error) {
+	result, err := a.getTransacationDetails(ctx, userID, id)
 	if err != nil {
 		return telcom.AirtimeResponse{}, err
 	}
@@ -103,8 +138,8 @@ func (a *AirtimeConn) GetTransactionDetail(id string) (telcom.AirtimeResponse, e
 	return result, nil
 }
 
-func (a *AirtimeConn) QueryTransaction(id string) (*telcom.AirtimeResponse, error) {
-	resp, err := a.queryTransaction(id)
+func (a *AirtimeConn) QueryTransaction(ctx context.Context, id string) (*telcom.AirtimeResponse, error) {
+	resp, err := a.queryTransaction(ctx, id)
 	if err != nil {
 		return &telcom.AirtimeResponse{}, err
 	}
@@ -122,26 +157,27 @@ func (a *AirtimeConn) QueryTransaction(id string) (*telcom.AirtimeResponse, erro
 
 }
 
-func (a *AirtimeConn) GetUserTransaction(username string) ([]telcom.AirtimeResponse, error) {
-	resp, err := a.getAllTransactions(username)
+func (a *AirtimeConn) GetUserTransaction(ctx context.Context, userID string, opts db.ListOptions) ([]telcom.AirtimeResponse, string, error) {
+	opts.UserID = userID
+	resp, next, err := a.getAllTransactions(ctx, opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return resp, nil
+	return resp, next, nil
 }
 
-func (a *AirtimeConn) GetAllTransactions() ([]telcom.AirtimeResponse, error) {
-	result, err := a.getAllTransactions("")
+func (a *AirtimeConn) GetAllTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.AirtimeResponse, string, error) {
+	result, next, err := a.getAllTransactions(ctx, opts)
 	if err != nil {
 		a.logger.Error("Database error try again...", zap.Error(err))
-		return nil, errors.New("Database request error: " + err.Error())
+		return nil, "", errors.New("Database request error: " + err.Error())
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (a *AirtimeConn) buy(data telcom.AirtimeInfo) (*http.Response, error) {
+func (a *AirtimeConn) buy(ctx context.Context, data telcom.AirtimeInfo) (*http.Response, error) {
 
 	formdata := url.Values{
 		"network":      {data.Network},
@@ -151,17 +187,15 @@ func (a *AirtimeConn) buy(data telcom.AirtimeInfo) (*http.Response, error) {
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s.php", api, "airtime")
+	url := fmt.Sprintf("/%s.php", "airtime")
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("AuthorizationToken", token)
 	req.Header.Set("cache-control", "no-cache")
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -169,35 +203,32 @@ func (a *AirtimeConn) buy(data telcom.AirtimeInfo) (*http.Response, error) {
 	return resp, nil
 }
 
-func (a *AirtimeConn) saveTransaction(detail *telcom.AirtimeResponse) error {
-	err := a.db.SaveAirtimeTransaction(detail)
+func (a *AirtimeConn) saveTransaction(ctx context.Context, detail *telcom.AirtimeResponse) error {
+	err := a.db.SaveAirtimeTransaction(ctx, detail)
 	return err
 }
 
-func (a *AirtimeConn) getTransacationDetails(id string) (telcom.AirtimeResponse, error) {
-	result, err := a.db.GetAirtimeTransactionDetails(id)
+func (a *AirtimeConn) getTransacationDetails(ctx context.Context, userID, id string) (telcom.AirtimeResponse, error) {
+	result, err := a.db.GetAirtimeTransactionDetails(ctx, userID, id)
 	return result, err
 }
 
-func (a *AirtimeConn) getAllTransactions(username string) ([]telcom.AirtimeResponse, error) {
-	results, err := a.db.GetAllAirtimeTransactions(username)
-	return results, err
+func (a *AirtimeConn) getAllTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.AirtimeResponse, string, error) {
+	return a.db.GetAllAirtimeTransactions(ctx, opts)
 }
 
-func (a *AirtimeConn) queryTransaction(id string) (*http.Response, error) {
+func (a *AirtimeConn) queryTransaction(ctx context.Context, id string) (*http.Response, error) {
 
 	var buf bytes.Buffer
 	json.NewEncoder(&buf).Encode(&id)
 
-	req, err := http.NewRequest("POST", api+"query_transaction.php", &buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/query_transaction.php", &buf)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", token)
 	req.Header.Set("cache-control", "no-cache")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -205,9 +236,9 @@ func (a *AirtimeConn) queryTransaction(id string) (*http.Response, error) {
 	return resp, nil
 }
 
-func logAndReturnError(logger *zap.Logger, errorMsg string) error {
-	logger.Error(errorMsg)
-	return errors.New(errorMsg)
+func logAndReturnError(logger *zap.Logger, errorMsg string, err error) error {
+	logger.Error(errorMsg, zap.Error(err))
+	return fmt.Errorf("%s: %w", errorMsg, err)
 }
 
 // international airtime
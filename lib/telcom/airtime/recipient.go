@@ -1,17 +1,24 @@
 package airtime
 
-import "github.com/aremxyplug-be/db/models/telcom"
-
-func (a *AirtimeConn) SaveRecipient(userID string, data telcom.Recipient) error {
-	if err := a.db.SaveTelcomRecipient(userID, data); err != nil {
+import (
+	"context"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/phone"
+)
+
+func (a *AirtimeConn) SaveRecipient(ctx context.Context, userID string, data telcom.Recipient) error {
+	if err := normaliseRecipient(&data); err != nil {
+		return err
+	}
+	if err := a.db.SaveTelcomRecipient(ctx, userID, data); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (a *AirtimeConn) GetRecipients(username string) (telcom.TelcomRecipient, error) {
-	resp, err := a.db.GetTelcomRecipients(username)
+func (a *AirtimeConn) GetRecipients(ctx context.Context, username string) (telcom.TelcomRecipient, error) {
+	resp, err := a.db.GetTelcomRecipients(ctx, username)
 	if err != nil {
 		return telcom.TelcomRecipient{}, err
 	}
@@ -19,18 +26,35 @@ func (a *AirtimeConn) GetRecipients(username string) (telcom.TelcomRecipient, er
 	return resp, nil
 }
 
-func (a *AirtimeConn) UpdateRecipient(userID string, data telcom.Recipient) error {
-	if err := a.db.EditTelcomRecipient(userID, data); err != nil {
+func (a *AirtimeConn) UpdateRecipient(ctx context.Context, userID string, data telcom.Recipient) error {
+	if err := normaliseRecipient(&data); err != nil {
+		return err
+	}
+	if err := a.db.EditTelcomRecipient(ctx, userID, data); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (a *AirtimeConn) DeleteRecipient(recipientID int, userID string) error {
-	if err := a.db.DeleteTelcomRecipient(recipientID, userID); err != nil {
+func (a *AirtimeConn) DeleteRecipient(ctx context.Context, recipientID int, userID string) error {
+	if err := a.db.DeleteTelcomRecipient(ctx, recipientID, userID); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// normaliseRecipient stores the phone number in the local format and fills in the network from its prefix
+// when none was given.
+func normaliseRecipient(data *telcom.Recipient) error {
+	result, err := phone.Validate(data.Phone_no, NetworkName(data.Network))
+	if err != nil {
+		return err
+	}
+
+	data.Phone_no = result.Local
+	data.Network = result.Selected
+
+	return nil
+}
@@ -2,69 +2,81 @@ package data
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
 	"github.com/aremxyplug-be/lib/randomgen"
+	"github.com/aremxyplug-be/lib/telcom/plans"
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 )
 
-var (
-	api   = os.Getenv("DONTECH")
-	token = "Token " + os.Getenv("DONTECH_AUTH")
-	vtapi = os.Getenv("VTPASS_SANDBOX")
-	pk    = os.Getenv("APIKey")
-	sk    = os.Getenv("SK")
-)
-
 type DataConn struct {
-	Dbconn db.TelcomStore
-	Logger *zap.Logger
+	Dbconn  db.TelcomStore
+	Logger  *zap.Logger
+	dontech *httpclient.Client
+	vtpass  *httpclient.Client
 }
 
-func NewData(DbConn db.TelcomStore, logger *zap.Logger) *DataConn {
+func NewData(DbConn db.TelcomStore, logger *zap.Logger, dontech, vtpass *httpclient.Client) *DataConn {
 	return &DataConn{
-		Dbconn: DbConn,
-		Logger: logger,
+		Dbconn:  DbConn,
+		Logger:  logger,
+		dontech: dontech,
+		vtpass:  vtpass,
 	}
 }
 
 // BuyData makes a call to the api to initiate a purchase
-func (d *DataConn) BuyData(data telcom.DataInfo) (*telcom.DataResult, error) {
+func (d *DataConn) BuyData(ctx context.Context, data telcom.DataInfo) (*telcom.DataResult, error) {
 	data.Ported_number = true
 
+	plan, err := d.validatePlan(ctx, plans.ProviderDontech, strconv.Itoa(data.Plan))
+	if err != nil {
+		return nil, err
+	}
+	if plan.NetworkID != data.Network {
+		return nil, ErrNetworkMismatch
+	}
+	if err := d.dontech.CheckFloat(plan.CostPrice); err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(&data); err != nil {
 		return nil, d.logAndReturnError("unable to encode data", err)
 	}
-	id, err := randomgen.GenerateOrderID()
-	if err != nil {
-		d.Logger.Error("Could not generate orderID...", zap.Error(err))
-		return nil, d.logAndReturnError("Could not generate orderID", err)
+	id := data.OrderID
+	if id == 0 {
+		if id, err = randomgen.GenerateOrderID(); err != nil {
+			d.Logger.Error("Could not generate orderID...", zap.Error(err))
+			return nil, d.logAndReturnError("Could not generate orderID", err)
+		}
 	}
 
-	req, err := http.NewRequest("POST", api+"/data/", &buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/data/", &buf)
 	if err != nil {
 		return nil, err
 	}
 	//req.Header.Set("Access-Control-Allow-Origin", "*")
-	req.Header.Add("Authorization", token)
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := d.dontech.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, httpclient.Unconfirmed(err))
 	}
 	defer resp.Body.Close()
 
@@ -75,9 +87,9 @@ func (d *DataConn) BuyData(data telcom.DataInfo) (*telcom.DataResult, error) {
 
 		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
 			if err == io.EOF {
-				return nil, d.logAndReturnError("Empty response body retured from server", err)
+				return nil, httpclient.Unconfirmed(d.logAndReturnError("Empty response body retured from server", err))
 			}
-			return nil, d.logAndReturnError("error while decoding json", err)
+			return nil, httpclient.Unconfirmed(d.logAndReturnError("error while decoding json", err))
 		}
 
 		transactionID := randomgen.GenerateTransactionID("dat")
@@ -87,7 +99,8 @@ func (d *DataConn) BuyData(data telcom.DataInfo) (*telcom.DataResult, error) {
 			ReferenceNumber: apiResponse.Ident,
 			Plan_Amount:     apiResponse.Plan_amount,
 			PlanName:        apiResponse.Plan_Name,
-			CreatedAt:       time.Now().String(),
+			CreatedAt:       time.Now(),
+			UserID:          data.UserID,
 			OrderID:         id,
 			Username:        data.Username,
 			TransactionID:   transactionID,
@@ -95,25 +108,42 @@ func (d *DataConn) BuyData(data telcom.DataInfo) (*telcom.DataResult, error) {
 			Name:            data.Name,
 			ApiID:           apiResponse.Id,
 		}
-		if err := d.saveTransacation(result); err != nil {
+		// the plan has been bought, so it is recorded even if the client has gone away
+		if err := d.saveTransacation(context.WithoutCancel(ctx), result); err != nil {
 			d.Logger.Error("Database error try again...", zap.Error(err))
-			return nil, errors.New("Database Insert Error...")
+			return nil, httpclient.Unconfirmed(errors.New("Database Insert Error..."))
 		}
 
 		return result, nil
 	} else {
 		d.Logger.Error("Api Call Error: %s", zap.String("status", fmt.Sprint((resp.Status))))
-		body, err := json.Marshal(resp.Body)
-		if err != nil {
-			return nil, err
+		// a server error does not say whether the plan was sent
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, fmt.Errorf("%w: %w", ErrProviderFailed, httpclient.Unconfirmed(errors.New(resp.Status)))
 		}
-		log.Print(string(body))
-		return nil, fmt.Errorf("%v", resp.Status)
+		return nil, fmt.Errorf("%w: %v", ErrProviderFailed, resp.Status)
 	}
 
 }
 
-func (d *DataConn) BuySpecData(data telcom.SpectranetInfo) (*telcom.SpectranetResult, error) {
+func (d *DataConn) BuySpecData(ctx context.Context, data telcom.SpectranetInfo) (*telcom.SpectranetResult, error) {
+
+	plan, err := d.validatePlan(ctx, plans.ProviderVTpass, data.Plan)
+	if err != nil {
+		return nil, err
+	}
+	if plan.ServiceID != data.Network {
+		return nil, ErrNetworkMismatch
+	}
+	// the provider is paid the price it listed, not what the client sent.
+	data.Amount = naira(plan.CostPrice)
+	if err := d.vtpass.CheckFloat(plan.CostPrice); err != nil {
+		return nil, err
+	}
+	pins, err := strconv.Atoi(strings.TrimSpace(data.No_of_Pins))
+	if err != nil || pins < 1 {
+		return nil, ErrInvalidPins
+	}
 
 	data.RequestID = randomgen.GenerateRequestID()
 	orderid, err := randomgen.GenerateOrderID()
@@ -121,10 +151,10 @@ func (d *DataConn) BuySpecData(data telcom.SpectranetInfo) (*telcom.SpectranetRe
 		return nil, d.logAndReturnError("unable to generate orderid", err)
 	}
 	transactionID := randomgen.GenerateTransactionID("dat")
-	resp, err := d.buySpecData(data)
+	resp, err := d.buySpecData(ctx, data)
 	if err != nil {
 		d.Logger.Error("error returned from server", zap.Any("error:", err))
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrProviderFailed, err)
 	}
 	defer resp.Body.Close()
 
@@ -143,16 +173,17 @@ func (d *DataConn) BuySpecData(data telcom.SpectranetInfo) (*telcom.SpectranetRe
 		Plan:            data.Plan,
 		Phone_Number:    trans_content.Phone_Number,
 		No_of_Pins:      trans_content.Quantity,
-		Amount:          trans_content.Amount,
+		Amount:          naira(plan.Price) * pins,
 		ProductDesc:     trans_content.Type,
 		Description:     data.Product,
 		TranscationID:   transactionID,
+		UserID:          data.UserID,
 		OrderID:         orderid,
 		ReferenceNumber: trans_content.TransactionID,
 		RequestID:       apiResponse.RequestID,
 	}
 
-	if err := d.saveTransacation(result); err != nil {
+	if err := d.saveTransacation(context.WithoutCancel(ctx), result); err != nil {
 		return nil, d.logAndReturnError("error while saving to database", err)
 	}
 
@@ -162,7 +193,19 @@ func (d *DataConn) BuySpecData(data telcom.SpectranetInfo) (*telcom.SpectranetRe
 
 }
 
-func (d *DataConn) BuySmileData(data telcom.SmileInfo) (*telcom.SmileResult, error) {
+func (d *DataConn) BuySmileData(ctx context.Context, data telcom.SmileInfo) (*telcom.SmileResult, error) {
+
+	plan, err := d.validatePlan(ctx, plans.ProviderVTpass, data.Product_plan)
+	if err != nil {
+		return nil, err
+	}
+	if plan.ServiceID != data.Product {
+		return nil, ErrNetworkMismatch
+	}
+	data.Amount = naira(plan.CostPrice)
+	if err := d.vtpass.CheckFloat(plan.CostPrice); err != nil {
+		return nil, err
+	}
 
 	data.RequestID = randomgen.GenerateRequestID()
 	orderid, err := randomgen.GenerateOrderID()
@@ -170,9 +213,10 @@ func (d *DataConn) BuySmileData(data telcom.SmileInfo) (*telcom.SmileResult, err
 		return nil, d.logAndReturnError("unable to generate orderid", err)
 	}
 	transactionID := randomgen.GenerateTransactionID("dat")
-	resp, err := d.buySmileData(data)
+	resp, err := d.buySmileData(ctx, data)
 	if err != nil {
-		return nil, d.logAndReturnError("error returned from server", err)
+		d.Logger.Error("error returned from server", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrProviderFailed, err)
 	}
 
 	defer resp.Body.Close()
@@ -192,17 +236,18 @@ func (d *DataConn) BuySmileData(data telcom.SmileInfo) (*telcom.SmileResult, err
 		ProductPlan:     trans_content.Product_Desc,
 		Email:           data.Email,
 		AccountID:       data.AccountID,
-		Phone_Number:    data.AccountID,
-		Amount:          trans_content.Amount,
+		Phone_Number:    data.Phone_Number,
+		Amount:          naira(plan.Price),
 		Product:         trans_content.Type,
 		Description:     trans_content.Product_Desc,
 		TranscationID:   transactionID,
+		UserID:          data.UserID,
 		OrderID:         orderid,
 		ReferenceNumber: trans_content.TransactionID,
 		RequestID:       apiResponse.RequestID,
 	}
 
-	if err := d.saveTransacation(result); err != nil {
+	if err := d.saveTransacation(context.WithoutCancel(ctx), result); err != nil {
 		return nil, d.logAndReturnError("error while saving to database", err)
 	}
 
@@ -210,9 +255,9 @@ func (d *DataConn) BuySmileData(data telcom.SmileInfo) (*telcom.SmileResult, err
 }
 
 // GetTransactionDetail takes a  id and returns the details of the transaction
-func (d *DataConn) GetTransactionDetail(id string) (telcom.DataResult, error) {
+func (d *DataConn) GetTransactionDetail(ctx context.Context, userID, id string) (telcom.DataResult, error) {
 	resp := telcom.DataResult{}
-	res, err := d.getTransactionDetails(id)
+	res, err := d.getTransactionDetails(ctx, userID, id)
 	if err != nil {
 		return resp, d.logAndReturnError("error while communicating with database", err)
 	}
@@ -220,29 +265,28 @@ func (d *DataConn) GetTransactionDetail(id string) (telcom.DataResult, error) {
 	return res, nil
 }
 
-// GetUserTransactions return all the data transactions associated to a user
-func (d *DataConn) GetUserTransactions(username string) ([]telcom.DataResult, error) {
+// GetUserTransactions returns a page of the data transactions associated to a user and the cursor of the next page
+func (d *DataConn) GetUserTransactions(ctx context.Context, userID string, opts db.ListOptions) ([]telcom.DataResult, string, error) {
 
-	res, err := d.getAllTransactions(username)
+	opts.UserID = userID
+	res, next, err := d.getAllTransactions(ctx, opts)
 	if err != nil {
-		return res, d.logAndReturnError("error while communicating with database", err)
+		return nil, "", d.logAndReturnError("error while communicating with database", err)
 	}
 
-	return res, err
+	return res, next, nil
 }
 
 // PingUser is a test function to ping the api
-func (d *DataConn) PingUser(w http.ResponseWriter) (*http.Response, error) {
+func (d *DataConn) PingUser(ctx context.Context, w http.ResponseWriter) (*http.Response, error) {
 
-	req, err := http.NewRequest("GET", api+"/user/", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", "/user/", nil)
 	req.Header.Set("Access-Control-Allow-Origin", "*")
-	req.Header.Set("Authorization", "Token "+token)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := d.dontech.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -250,28 +294,25 @@ func (d *DataConn) PingUser(w http.ResponseWriter) (*http.Response, error) {
 
 	statusCode := res.StatusCode
 
-	log.Println(api)
-
 	log.Println("StatusCode: ", statusCode)
 
 	return res, nil
 }
 
-// GetAllTransactions returns a list of all data transactions.
-func (d *DataConn) GetAllTransactions() ([]telcom.DataResult, error) {
-	var user string
-	result, err := d.getAllTransactions(user)
+// GetAllTransactions returns a page of all data transactions and the cursor of the next page.
+func (d *DataConn) GetAllTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.DataResult, string, error) {
+	result, next, err := d.getAllTransactions(ctx, opts)
 	if err != nil {
 		d.Logger.Error("Database error try again...", zap.Error(err))
-		return nil, errors.New("Database request error: " + err.Error())
+		return nil, "", errors.New("Database request error: " + err.Error())
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (d *DataConn) GetSpecTransDetails(requestID string) (telcom.SpectranetResult, error) {
+func (d *DataConn) GetSpecTransDetails(ctx context.Context, userID, requestID string) (telcom.SpectranetResult, error) {
 	resp := telcom.SpectranetResult{}
-	res, err := d.getSpecDataDetails(requestID)
+	res, err := d.getSpecDataDetails(ctx, userID, requestID)
 	if err != nil {
 		return resp, d.logAndReturnError("error while communicating with database", err)
 	}
@@ -279,31 +320,31 @@ func (d *DataConn) GetSpecTransDetails(requestID string) (telcom.SpectranetResul
 	return res, nil
 }
 
-func (d *DataConn) GetSpecUserTransactions(username string) ([]telcom.SpectranetResult, error) {
+func (d *DataConn) GetSpecUserTransactions(ctx context.Context, userID string, opts db.ListOptions) ([]telcom.SpectranetResult, string, error) {
 
-	res, err := d.getAllSpecTransactions(username)
+	opts.UserID = userID
+	res, next, err := d.getAllSpecTransactions(ctx, opts)
 	if err != nil {
 		d.Logger.Error("Database error try again...", zap.Error(err))
-		return nil, errors.New("database request error: " + err.Error())
+		return nil, "", errors.New("database request error: " + err.Error())
 	}
 
-	return res, err
+	return res, next, nil
 }
 
-func (d *DataConn) GetAllSpecTransactions() ([]telcom.SpectranetResult, error) {
-	var user string
-	result, err := d.getAllSpecTransactions(user)
+func (d *DataConn) GetAllSpecTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.SpectranetResult, string, error) {
+	result, next, err := d.getAllSpecTransactions(ctx, opts)
 	if err != nil {
 		d.Logger.Error("Database error try again...", zap.Error(err))
-		return nil, errors.New("Database request error: " + err.Error())
+		return nil, "", errors.New("Database request error: " + err.Error())
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (d *DataConn) GetSmileTransDetails(requestID string) (telcom.SmileResult, error) {
+func (d *DataConn) GetSmileTransDetails(ctx context.Context, userID, requestID string) (telcom.SmileResult, error) {
 	resp := telcom.SmileResult{}
-	res, err := d.getSmileDataDetails(requestID)
+	res, err := d.getSmileDataDetails(ctx, userID, requestID)
 	if err != nil {
 		// write error
 		d.Logger.Error("Database error try again...", zap.Error(err))
@@ -313,44 +354,42 @@ func (d *DataConn) GetSmileTransDetails(requestID string) (telcom.SmileResult, e
 	return res, nil
 }
 
-func (d *DataConn) GetSmileUserTransactions(username string) ([]telcom.SmileResult, error) {
+func (d *DataConn) GetSmileUserTransactions(ctx context.Context, userID string, opts db.ListOptions) ([]telcom.SmileResult, string, error) {
 
-	res, err := d.getAllSmileTransactions(username)
+	opts.UserID = userID
+	res, next, err := d.getAllSmileTransactions(ctx, opts)
 	if err != nil {
 		// write error
 		d.Logger.Error("Database error try again...", zap.Error(err))
-		return nil, errors.New("database request error: " + err.Error())
+		return nil, "", errors.New("database request error: " + err.Error())
 	}
 
-	return res, err
+	return res, next, nil
 }
 
-func (d *DataConn) GetAllSmileTransactions() ([]telcom.SmileResult, error) {
-	var user string
-	result, err := d.getAllSmileTransactions(user)
+func (d *DataConn) GetAllSmileTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.SmileResult, string, error) {
+	result, next, err := d.getAllSmileTransactions(ctx, opts)
 	if err != nil {
 		d.Logger.Error("Database error try again...", zap.Error(err))
-		return nil, errors.New("Database request error: " + err.Error())
+		return nil, "", errors.New("Database request error: " + err.Error())
 	}
 
-	return result, nil
+	return result, next, nil
 }
 
-func (d *DataConn) QueryTransaction(id int) error {
+func (d *DataConn) QueryTransaction(ctx context.Context, id int) error {
 
 	pid := strconv.Itoa(id)
 
-	req, err := http.NewRequest("POST", api+"/data/"+pid, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/data/"+pid, nil)
 	req.Header.Set("Access-Control-Allow-Origin", "*")
-	req.Header.Add("Authorization", "Token "+token)
 	req.Header.Add("Content-Type", "application/json")
 	if err != nil {
 		// return err
 		return err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := d.dontech.Do(req)
 	if err != nil {
 		return err
 	}
@@ -365,29 +404,27 @@ func (d *DataConn) QueryTransaction(id int) error {
 
 }
 
-func (d *DataConn) buySmileData(data telcom.SmileInfo) (*http.Response, error) {
+func (d *DataConn) buySmileData(ctx context.Context, data telcom.SmileInfo) (*http.Response, error) {
 
 	formdata := url.Values{
 		"request_id":     {data.RequestID},
 		"serviceID":      {data.Product},
 		"billersCode":    {data.AccountID},
 		"variation_code": {data.Product_plan},
+		"amount":         {strconv.Itoa(data.Amount)},
 		"phone":          {data.Phone_Number},
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", vtapi, "pay")
+	url := "/pay"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("api-key", pk)
-	req.Header.Set("secret-key", sk)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := d.vtpass.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -395,7 +432,7 @@ func (d *DataConn) buySmileData(data telcom.SmileInfo) (*http.Response, error) {
 	return resp, nil
 }
 
-func (d *DataConn) buySpecData(data telcom.SpectranetInfo) (*http.Response, error) {
+func (d *DataConn) buySpecData(ctx context.Context, data telcom.SpectranetInfo) (*http.Response, error) {
 
 	amount := strconv.Itoa(data.Amount)
 
@@ -410,18 +447,15 @@ func (d *DataConn) buySpecData(data telcom.SpectranetInfo) (*http.Response, erro
 	}
 
 	body := bytes.NewBufferString(formdata.Encode())
-	url := fmt.Sprintf("%s/%s", vtapi, "pay")
+	url := "/pay"
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("api-key", pk)
-	req.Header.Set("secret-key", sk)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := d.vtpass.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -429,46 +463,74 @@ func (d *DataConn) buySpecData(data telcom.SpectranetInfo) (*http.Response, erro
 	return resp, nil
 }
 
+// Price returns the catalogue price of the plan in data.
+func (d *DataConn) Price(ctx context.Context, data telcom.DataInfo) (float64, error) {
+	plan, err := d.validatePlan(ctx, plans.ProviderDontech, strconv.Itoa(data.Plan))
+	if err != nil {
+		return 0, err
+	}
+	if plan.NetworkID != data.Network {
+		return 0, ErrNetworkMismatch
+	}
+
+	return plan.Price, nil
+}
+
+// validatePlan checks that a plan being bought is in the data plan catalogue.
+func (d *DataConn) validatePlan(ctx context.Context, provider, providerCode string) (telcom.DataPlan, error) {
+	plan, err := d.Dbconn.GetDataPlan(ctx, provider, providerCode)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return telcom.DataPlan{}, ErrUnknownPlan
+		}
+		return telcom.DataPlan{}, d.logAndReturnError("error while communicating with database", err)
+	}
+
+	return plan, nil
+}
+
+// naira rounds a catalogue price to the whole naira the vtpass amounts are in.
+func naira(price float64) int {
+	return int(math.Round(price))
+}
+
 // saveTranscation saves the details of a transaction to database
-func (d *DataConn) saveTransacation(details interface{}) error {
-	err := d.Dbconn.SaveDataTransaction(details)
+func (d *DataConn) saveTransacation(ctx context.Context, details interface{}) error {
+	err := d.Dbconn.SaveDataTransaction(ctx, details)
 	return err
 }
 
 // getTransacationDetails returns the details of a transaction
-func (d *DataConn) getTransactionDetails(id string) (telcom.DataResult, error) {
-	result, err := d.Dbconn.GetDataTransactionDetails(id)
+func (d *DataConn) getTransactionDetails(ctx context.Context, userID, id string) (telcom.DataResult, error) {
+	result, err := d.Dbconn.GetDataTransactionDetails(ctx, userID, id)
 	return result, err
 }
 
-// getAllTransaction returns all transactions, if an empty string is passed, it returns all transaction in the database
-func (d *DataConn) getAllTransactions(username string) ([]telcom.DataResult, error) {
-	results, err := d.Dbconn.GetAllDataTransactions(username)
-	return results, err
+// getAllTransaction returns a page of transactions, if opts has no username it pages through all transactions in the database
+func (d *DataConn) getAllTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.DataResult, string, error) {
+	return d.Dbconn.GetAllDataTransactions(ctx, opts)
 }
 
 // get transactions history
-func (d *DataConn) getSpecDataDetails(requestID string) (telcom.SpectranetResult, error) {
-	result, err := d.Dbconn.GetSpecTransDetails(requestID)
+func (d *DataConn) getSpecDataDetails(ctx context.Context, userID, requestID string) (telcom.SpectranetResult, error) {
+	result, err := d.Dbconn.GetSpecTransDetails(ctx, userID, requestID)
 	return result, err
 }
 
-func (d *DataConn) getAllSpecTransactions(username string) ([]telcom.SpectranetResult, error) {
-	result, err := d.Dbconn.GetAllSpecDataTransactions(username)
-	return result, err
+func (d *DataConn) getAllSpecTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.SpectranetResult, string, error) {
+	return d.Dbconn.GetAllSpecDataTransactions(ctx, opts)
 }
 
-func (d *DataConn) getSmileDataDetails(id string) (telcom.SmileResult, error) {
-	result, err := d.Dbconn.GetSmileTransDetails(id)
+func (d *DataConn) getSmileDataDetails(ctx context.Context, userID, id string) (telcom.SmileResult, error) {
+	result, err := d.Dbconn.GetSmileTransDetails(ctx, userID, id)
 	return result, err
 }
 
-func (d *DataConn) getAllSmileTransactions(username string) ([]telcom.SmileResult, error) {
-	result, err := d.Dbconn.GetAllSmileDataTransactions(username)
-	return result, err
+func (d *DataConn) getAllSmileTransactions(ctx context.Context, opts db.ListOptions) ([]telcom.SmileResult, string, error) {
+	return d.Dbconn.GetAllSmileDataTransactions(ctx, opts)
 }
 
 func (d *DataConn) logAndReturnError(errorMsg string, err error) error {
 	d.Logger.Error(errorMsg, zap.Error(err))
-	return errors.New(errorMsg)
+	return fmt.Errorf("%s: %w", errorMsg, err)
 }
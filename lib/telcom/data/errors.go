@@ -0,0 +1,10 @@
+package data
+
+import "errors"
+
+var (
+	ErrUnknownPlan     = errors.New("plan is not in the data plan catalogue")
+	ErrNetworkMismatch = errors.New("plan does not belong to the selected network")
+	ErrProviderFailed  = errors.New("data provider could not complete the purchase")
+	ErrInvalidPins     = errors.New("number of pins must be a whole number")
+)
@@ -2,6 +2,7 @@ package edu
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,40 +10,38 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
+	"time"
 
 	"github.com/aremxyplug-be/db"
 	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/httpclient"
 	"github.com/aremxyplug-be/lib/randomgen"
 	"go.uber.org/zap"
 )
 
-var (
-	api   = os.Getenv("EASYACCESS")
-	token = os.Getenv("EASYACCESS_AUTH")
-)
-
 type EduConn struct {
 	db     db.UtilitiesStore
 	logger *zap.Logger
+	client *httpclient.Client
 }
 
-func NewEdu(DbConn db.UtilitiesStore, logger *zap.Logger) *EduConn {
+func NewEdu(DbConn db.UtilitiesStore, logger *zap.Logger, client *httpclient.Client) *EduConn {
 	return &EduConn{
 		db:     DbConn,
 		logger: logger,
+		client: client,
 	}
 }
 
-func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, error) {
+func (edu *EduConn) BuyEduPin(ctx context.Context, eduInfo models.EduInfo) (*models.EduResponse, error) {
 
 	examType := eduInfo.Exam_Type
 	pinNumber := strconv.Itoa(eduInfo.Quantity)
 
-	resp, err := edu.buyPin(examType, pinNumber)
+	resp, err := edu.buyPin(ctx, examType, pinNumber)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrProviderFailed, err)
 	}
 
 	id, err := randomgen.GenerateOrderID()
@@ -53,7 +52,7 @@ func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, erro
 	}
 
 	if resp.Body == nil {
-		return nil, errors.New("response body is nil")
+		return nil, fmt.Errorf("%w: response body is nil", ErrProviderFailed)
 	}
 	defer resp.Body.Close()
 	apiResponse := models.EduApiResponse{}
@@ -62,13 +61,11 @@ func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, erro
 		log.Println(err)
 		return nil, errors.New("could not unmarshal response body")
 	}
-	log.Println(string(body))
-	log.Println("Status: ", resp.Status)
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
 		if err == io.EOF {
 			log.Println("No response from body")
 			// edu.logger.Error("Empty response body", zap.Error(err))
-			return nil, errors.New("empty response from server")
+			return nil, fmt.Errorf("%w: empty response from server", ErrProviderFailed)
 		} else {
 			log.Println("other error:", err)
 			// edu.logger.Error("error returned from server: ", zap.Error(err))
@@ -76,7 +73,6 @@ func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, erro
 		}
 
 	}
-	log.Printf("%+v", apiResponse)
 
 	/*
 		err = json.NewDecoder(resp.Body).Decode(&apiResponse)
@@ -94,7 +90,7 @@ func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, erro
 
 	if apiResponse.Success_Response == "false" {
 		log.Println(apiResponse.Message)
-		return nil, errors.New("failed while purchasing edu pin")
+		return nil, fmt.Errorf("%w: %s", ErrProviderFailed, apiResponse.Message)
 	}
 
 	transactionID := randomgen.GenerateTransactionID("edu")
@@ -126,16 +122,17 @@ func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, erro
 		Product:         eduInfo.Exam_Type,
 		Status:          apiResponse.Status,
 		Description:     apiResponse.Message,
+		UserID:          eduInfo.UserID,
 		OrderID:         id,
 		Pin_Generated:   pinGenerated,
-		CreatedAt:       apiResponse.Date,
+		CreatedAt:       time.Now(),
 		TransactionID:   transactionID,
 	}
 
 	log.Printf("%+v", result)
 
-	// write to database
-	if err := edu.saveTransaction(result); err != nil {
+	// write to database, the pins are paid for even if the request was cancelled
+	if err := edu.saveTransaction(context.WithoutCancel(ctx), result); err != nil {
 		edu.logger.Error("Database error try again...", zap.Error(err))
 		return nil, errors.New("database insert error")
 	}
@@ -144,9 +141,9 @@ func (edu *EduConn) BuyEduPin(eduInfo models.EduInfo) (*models.EduResponse, erro
 
 }
 
-func (edu *EduConn) QueryTransaction(id string) (*models.EduResponse, error) {
+func (edu *EduConn) QueryTransaction(ctx context.Context, id string) (*models.EduResponse, error) {
 
-	resp, err := edu.queryTransaction(id)
+	resp, err := edu.queryTransaction(ctx, id)
 	if err != nil {
 		// return and check error
 		return &models.EduResponse{}, err
@@ -161,10 +158,10 @@ func (edu *EduConn) QueryTransaction(id string) (*models.EduResponse, error) {
 
 }
 
-func (edu *EduConn) GetTransactionDetail(id string) (models.EduResponse, error) {
+func (edu *EduConn) GetTransactionDetail(ctx context.Context, userID, id string) (models.EduResponse, error) {
 
 	resp := models.EduResponse{}
-	result, err := edu.getTransactionDetails(id)
+	result, err := edu.getTransactionDetails(ctx, userID, id)
 	if err != nil {
 		edu.logger.Error("Database error try again...", zap.Error(err))
 		return resp, errors.New("Database request error: " + err.Error())
@@ -173,28 +170,33 @@ func (edu *EduConn) GetTransactionDetail(id string) (models.EduResponse, error)
 	return result, nil
 }
 
-func (edu *EduConn) GetAllTransaction(user string) ([]models.EduResponse, error) {
-	resp, err := edu.db.GetAllEduTransactions(user)
+// GetUserTransactions returns a page of the transactions of a user and the cursor of the next page.
+func (edu *EduConn) GetUserTransactions(ctx context.Context, userID string, opts db.ListOptions) ([]models.EduResponse, string, error) {
+	opts.UserID = userID
+	return edu.GetAllTransaction(ctx, opts)
+}
+
+// GetAllTransaction returns a page of the transactions selected by opts and the cursor of the next page.
+func (edu *EduConn) GetAllTransaction(ctx context.Context, opts db.ListOptions) ([]models.EduResponse, string, error) {
+	resp, next, err := edu.db.GetAllEduTransactions(ctx, opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return resp, nil
+	return resp, next, nil
 
 }
 
-func (edu *EduConn) Ping() (*http.Response, error) {
+func (edu *EduConn) Ping(ctx context.Context) (*http.Response, error) {
 
-	req, err := http.NewRequest("GET", api+"/wallet_balance.php", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", "/wallet_balance.php", nil)
 	req.Header.Set("cache-control", "no-cache")
 	req.Header.Set("Access-Control-Allow-Origin", "*")
-	req.Header.Set("AuthorizationToken", token)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := edu.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -202,14 +204,12 @@ func (edu *EduConn) Ping() (*http.Response, error) {
 
 	statusCode := res.StatusCode
 
-	log.Println(api)
-
 	log.Println("StatusCode: ", statusCode)
 
 	return res, nil
 }
 
-func (edu *EduConn) buyPin(examType string, pinNumber string) (*http.Response, error) {
+func (edu *EduConn) buyPin(ctx context.Context, examType string, pinNumber string) (*http.Response, error) {
 
 	formdata := url.Values{
 		"no_of_pins": {pinNumber},
@@ -217,18 +217,16 @@ func (edu *EduConn) buyPin(examType string, pinNumber string) (*http.Response, e
 
 	body := bytes.NewBufferString(formdata.Encode())
 
-	url := fmt.Sprintf("%s/%s_v2.php", api, examType)
+	url := fmt.Sprintf("/%s_v2.php", examType)
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("AuthorizationToken", token)
 	req.Header.Set("cache-control", "no-cache")
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := edu.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +234,7 @@ func (edu *EduConn) buyPin(examType string, pinNumber string) (*http.Response, e
 	return resp, nil
 }
 
-func (edu *EduConn) saveTransaction(detail *models.EduResponse) error {
+func (edu *EduConn) saveTransaction(ctx context.Context, detail *models.EduResponse) error {
 
 	if edu == nil {
 		return errors.New("edu is nil")
@@ -250,7 +248,7 @@ func (edu *EduConn) saveTransaction(detail *models.EduResponse) error {
 
 	log.Println(detail)
 
-	err := edu.db.SaveEduTransaction(detail)
+	err := edu.db.SaveEduTransaction(ctx, detail)
 	if err != nil {
 		return err
 	}
@@ -258,20 +256,18 @@ func (edu *EduConn) saveTransaction(detail *models.EduResponse) error {
 	return nil
 }
 
-func (edu *EduConn) queryTransaction(id string) (*http.Response, error) {
+func (edu *EduConn) queryTransaction(ctx context.Context, id string) (*http.Response, error) {
 
 	var buf bytes.Buffer
 	json.NewEncoder(&buf).Encode(&id)
 
-	req, err := http.NewRequest("POST", api+"query_transaction.php", &buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/query_transaction.php", &buf)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", token)
 	req.Header.Set("cache-control", "no-cache")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := edu.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -279,9 +275,9 @@ func (edu *EduConn) queryTransaction(id string) (*http.Response, error) {
 	return resp, nil
 }
 
-func (edu *EduConn) getTransactionDetails(id string) (models.EduResponse, error) {
+func (edu *EduConn) getTransactionDetails(ctx context.Context, userID, id string) (models.EduResponse, error) {
 
-	res, err := edu.db.GetEduTransactionDetails(id)
+	res, err := edu.db.GetEduTransactionDetails(ctx, userID, id)
 	if err != nil {
 		edu.logger.Error("Error getting details from database...", zap.Error(err))
 		return models.EduResponse{}, errors.New("database error")
@@ -0,0 +1,5 @@
+package edu
+
+import "errors"
+
+var ErrProviderFailed = errors.New("exam pin provider could not complete the purchase")
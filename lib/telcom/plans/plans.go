@@ -0,0 +1,341 @@
+package plans
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"go.uber.org/zap"
+)
+
+const (
+	ProviderDontech = "dontech"
+	ProviderVTpass  = "vtpass"
+
+	defaultSyncInterval = 6 * time.Hour
+)
+
+var (
+	ErrUnknownNetwork = errors.New("unknown network")
+
+	// dontechNetworks maps the network ids used by Dontech to our network names.
+	dontechNetworks = map[int]string{
+		1: "mtn",
+		2: "glo",
+		3: "9mobile",
+		4: "airtel",
+	}
+
+	// vtpassServices maps the VTpass serviceIDs we resell to our network names.
+	vtpassServices = map[string]string{
+		"smile-direct": "smile",
+		"spectranet":   "spectranet",
+	}
+
+	networks = map[string]bool{
+		"mtn": true, "glo": true, "9mobile": true, "airtel": true, "smile": true, "spectranet": true,
+	}
+
+	sizePattern     = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s?(TB|GB|MB)`)
+	validityPattern = regexp.MustCompile(`(?i)(\d+)\s?(days?|hrs?|hours?|weeks?|months?|years?)`)
+)
+
+type Catalogue struct {
+	db      db.TelcomStore
+	logger  *zap.Logger
+	markup  float64
+	dontech *httpclient.Client
+	vtpass  *httpclient.Client
+}
+
+// NewCatalogue creates a catalogue that adds markup percent to the provider prices.
+func NewCatalogue(store db.TelcomStore, logger *zap.Logger, dontech, vtpass *httpclient.Client, markup float64) *Catalogue {
+	return &Catalogue{
+		db:      store,
+		logger:  logger,
+		markup:  markup,
+		dontech: dontech,
+		vtpass:  vtpass,
+	}
+}
+
+// DontechNetwork returns the network name of a dontech network id.
+func DontechNetwork(id int) string {
+	return dontechNetworks[id]
+}
+
+// DontechNetworkID returns the dontech network id of a network name.
+func DontechNetworkID(name string) (int, bool) {
+	for id, network := range dontechNetworks {
+		if network == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// Run syncs the catalogue immediately and then on every tick of interval until ctx is done.
+func (c *Catalogue) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil {
+			c.logger.Error("data plan sync failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pulls the plan lists from every provider and saves them to the catalogue.
+// A provider failing does not stop the others from syncing.
+func (c *Catalogue) Sync(ctx context.Context) error {
+	var errs []error
+
+	dontechPlans, err := c.fetchDontechPlans(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("dontech: %w", err))
+	} else if err := c.db.SaveDataPlans(ctx, ProviderDontech, dontechPlans); err != nil {
+		errs = append(errs, fmt.Errorf("dontech: %w", err))
+	}
+
+	vtpassPlans := []telcom.DataPlan{}
+	vtpassFailed := false
+	for serviceID, network := range vtpassServices {
+		plans, err := c.fetchVTpassPlans(ctx, serviceID, network)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("vtpass %s: %w", serviceID, err))
+			vtpassFailed = true
+			continue
+		}
+		vtpassPlans = append(vtpassPlans, plans...)
+	}
+	// only replace the vtpass plans when every service synced, otherwise the failed service is wiped out.
+	if !vtpassFailed {
+		if err := c.db.SaveDataPlans(ctx, ProviderVTpass, vtpassPlans); err != nil {
+			errs = append(errs, fmt.Errorf("vtpass: %w", err))
+		}
+	}
+
+	c.logger.Info("data plan sync completed", zap.Int("dontech", len(dontechPlans)), zap.Int("vtpass", len(vtpassPlans)))
+
+	return errors.Join(errs...)
+}
+
+// ListPlans returns the plans in the catalogue for a network, if network is empty all plans are returned.
+func (c *Catalogue) ListPlans(ctx context.Context, network string) ([]telcom.DataPlan, error) {
+	network = strings.ToLower(strings.TrimSpace(network))
+	if network != "" && !networks[network] {
+		return nil, ErrUnknownNetwork
+	}
+
+	plans, err := c.db.GetDataPlans(ctx, network)
+	if err != nil {
+		c.logger.Error("failed to get data plans", zap.Error(err))
+		return nil, errors.New("failed to get data plans")
+	}
+
+	return plans, nil
+}
+
+type dontechUser struct {
+	Dataplans map[string]struct {
+		All []dontechPlan `json:"ALL"`
+	} `json:"Dataplans"`
+}
+
+type dontechPlan struct {
+	ID            int         `json:"id"`
+	DataplanID    string      `json:"dataplan_id"`
+	Network       int         `json:"network"`
+	PlanType      string      `json:"plan_type"`
+	PlanNetwork   string      `json:"plan_network"`
+	MonthValidate string      `json:"month_validate"`
+	Plan          string      `json:"plan"`
+	PlanAmount    json.Number `json:"plan_amount"`
+}
+
+func (c *Catalogue) fetchDontechPlans(ctx context.Context) ([]telcom.DataPlan, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "/user/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := c.dontech.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	user := dontechUser{}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	plans := []telcom.DataPlan{}
+	for _, group := range user.Dataplans {
+		for _, p := range group.All {
+			network, ok := dontechNetworks[p.Network]
+			if !ok {
+				continue
+			}
+			cost, err := p.PlanAmount.Float64()
+			if err != nil {
+				continue
+			}
+
+			code := strconv.Itoa(p.ID)
+			name := strings.TrimSpace(fmt.Sprintf("%s %s %s", p.PlanNetwork, p.PlanType, p.Plan))
+			plans = append(plans, telcom.DataPlan{
+				ID:           ProviderDontech + ":" + code,
+				Network:      network,
+				Provider:     ProviderDontech,
+				ProviderCode: code,
+				NetworkID:    p.Network,
+				Name:         name,
+				PlanType:     p.PlanType,
+				Size:         normaliseSize(p.Plan),
+				Validity:     normaliseValidity(p.MonthValidate),
+				CostPrice:    cost,
+				Price:        c.price(cost),
+			})
+		}
+	}
+
+	return plans, nil
+}
+
+type vtpassVariations struct {
+	ResponseDescription string `json:"response_description"`
+	Content             struct {
+		ServiceID  string            `json:"serviceID"`
+		Varations  []vtpassVariation `json:"varations"`
+		Variations []vtpassVariation `json:"variations"`
+	} `json:"content"`
+}
+
+type vtpassVariation struct {
+	VariationCode   string      `json:"variation_code"`
+	Name            string      `json:"name"`
+	VariationAmount json.Number `json:"variation_amount"`
+	FixedPrice      string      `json:"fixedPrice"`
+}
+
+func (c *Catalogue) fetchVTpassPlans(ctx context.Context, serviceID, network string) ([]telcom.DataPlan, error) {
+	url := fmt.Sprintf("/%s?serviceID=%s", "service-variations", serviceID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.vtpass.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	apiResponse := vtpassVariations{}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, err
+	}
+	if apiResponse.ResponseDescription != "000" {
+		return nil, fmt.Errorf("unexpected response %q", apiResponse.ResponseDescription)
+	}
+
+	// VTpass spells the field "varations" on most services.
+	variations := apiResponse.Content.Varations
+	if len(variations) == 0 {
+		variations = apiResponse.Content.Variations
+	}
+
+	plans := []telcom.DataPlan{}
+	for _, v := range variations {
+		cost, err := v.VariationAmount.Float64()
+		if err != nil {
+			continue
+		}
+
+		plans = append(plans, telcom.DataPlan{
+			ID:           ProviderVTpass + ":" + v.VariationCode,
+			Network:      network,
+			Provider:     ProviderVTpass,
+			ProviderCode: v.VariationCode,
+			ServiceID:    serviceID,
+			Name:         v.Name,
+			Size:         normaliseSize(v.Name),
+			Validity:     normaliseValidity(v.Name),
+			CostPrice:    cost,
+			Price:        c.price(cost),
+		})
+	}
+
+	return plans, nil
+}
+
+// price adds our markup to the provider's price and rounds up to the nearest naira.
+func (c *Catalogue) price(cost float64) float64 {
+	return math.Ceil(cost * (1 + c.markup/100))
+}
+
+func normaliseSize(s string) string {
+	match := sizePattern.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	size, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(size, 'f', -1, 64) + strings.ToUpper(match[2])
+}
+
+func normaliseValidity(s string) string {
+	match := validityPattern.FindStringSubmatch(s)
+	if match == nil {
+		return strings.TrimSpace(s)
+	}
+	unit := strings.ToLower(match[2])
+	switch {
+	case strings.HasPrefix(unit, "h"):
+		unit = "hours"
+	case strings.HasPrefix(unit, "d"):
+		unit = "days"
+	case strings.HasPrefix(unit, "w"):
+		unit = "weeks"
+	case strings.HasPrefix(unit, "m"):
+		unit = "months"
+	case strings.HasPrefix(unit, "y"):
+		unit = "years"
+	}
+	if match[1] == "1" {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	return match[1] + " " + unit
+}
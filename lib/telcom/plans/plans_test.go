@@ -0,0 +1,157 @@
+package plans
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps the plans saved for each provider.
+type fakeStore struct {
+	db.TelcomStore
+	saved map[string][]telcom.DataPlan
+}
+
+func (f *fakeStore) SaveDataPlans(_ context.Context, provider string, plans []telcom.DataPlan) error {
+	f.saved[provider] = plans
+	return nil
+}
+
+func newClient(t *testing.T, handler http.HandlerFunc) *httpclient.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return httpclient.New(&httpclient.Options{Name: t.Name(), BaseURL: srv.URL, Retries: 0, Backoff: time.Millisecond})
+}
+
+func TestNormaliseSize(t *testing.T) {
+	var tests = []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "Test gigabytes", in: "1.5GB", want: "1.5GB"},
+		{name: "Test space and lower case", in: "500 mb", want: "500MB"},
+		{name: "Test whole number drops decimals", in: "2.0GB SME", want: "2GB"},
+		{name: "Test size inside a vtpass name", in: "Smile 10GB Bigga - 30 days", want: "10GB"},
+		{name: "Test terabytes", in: "1TB Annual", want: "1TB"},
+		{name: "Test no size", in: "Unlimited Lite", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normaliseSize(tt.in))
+		})
+	}
+}
+
+func TestNormaliseValidity(t *testing.T) {
+	var tests = []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "Test days", in: "30 days", want: "30 days"},
+		{name: "Test single day", in: "1 Day", want: "1 day"},
+		{name: "Test hours abbreviated", in: "24hrs", want: "24 hours"},
+		{name: "Test weeks", in: "2 Weeks", want: "2 weeks"},
+		{name: "Test month inside a vtpass name", in: "Smile 10GB Bigga - 1 Month", want: "1 month"},
+		{name: "Test years", in: "1 year", want: "1 year"},
+		{name: "Test unknown format is kept", in: " Monthly ", want: "Monthly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normaliseValidity(tt.in))
+		})
+	}
+}
+
+func TestPrice(t *testing.T) {
+	var tests = []struct {
+		name   string
+		markup float64
+		cost   float64
+		want   float64
+	}{
+		{name: "Test no markup", cost: 250, want: 250},
+		{name: "Test markup rounds up", markup: 2.5, cost: 299, want: 307},
+		{name: "Test kobo cost rounds up", cost: 249.01, want: 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Catalogue{markup: tt.markup}
+			assert.Equal(t, tt.want, c.price(tt.cost))
+		})
+	}
+}
+
+const dontechUserBody = `{"Dataplans":{"MTN_PLAN":{"ALL":[
+	{"id":7,"network":1,"plan_type":"SME","plan_network":"MTN","month_validate":"30 days","plan":"1.0GB","plan_amount":"245.50"},
+	{"id":8,"network":9,"plan_type":"SME","plan_network":"NEW","month_validate":"30 days","plan":"1GB","plan_amount":"100"}
+]}}}`
+
+func TestSync(t *testing.T) {
+	var tests = []struct {
+		name string
+		// failSpectranet makes the vtpass spectranet service fail
+		failSpectranet bool
+		wantVTpass     bool
+	}{
+		{name: "Test every provider synced", wantVTpass: true},
+		{name: "Test vtpass kept when a service fails", failSpectranet: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dontech := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(dontechUserBody))
+			})
+			vtpass := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("serviceID") {
+				case "smile-direct":
+					w.Write([]byte(`{"response_description":"000","content":{"varations":[
+						{"variation_code":"516","name":"Smile 10GB Bigga - 30 days","variation_amount":"4999.00"}]}}`))
+				case "spectranet":
+					if tt.failSpectranet {
+						w.Write([]byte(`{"response_description":"020"}`))
+						return
+					}
+					w.Write([]byte(`{"response_description":"000","content":{"variations":[
+						{"variation_code":"spec-7000","name":"7,000 Naira - 1 Month","variation_amount":"7000"}]}}`))
+				}
+			})
+			store := &fakeStore{saved: map[string][]telcom.DataPlan{}}
+			c := NewCatalogue(store, zap.NewNop(), dontech, vtpass, 10)
+
+			err := c.Sync(context.Background())
+			assert.Equal(t, tt.failSpectranet, err != nil)
+
+			// plans of unknown networks are skipped
+			require.Len(t, store.saved[ProviderDontech], 1)
+			plan := store.saved[ProviderDontech][0]
+			assert.Equal(t, "dontech:7", plan.ID)
+			assert.Equal(t, "mtn", plan.Network)
+			assert.Equal(t, 1, plan.NetworkID)
+			assert.Equal(t, "1GB", plan.Size)
+			assert.Equal(t, "30 days", plan.Validity)
+			assert.Equal(t, 245.5, plan.CostPrice)
+			assert.Equal(t, float64(271), plan.Price)
+
+			vtpassPlans, ok := store.saved[ProviderVTpass]
+			assert.Equal(t, tt.wantVTpass, ok)
+			if tt.wantVTpass {
+				assert.Len(t, vtpassPlans, 2)
+			}
+		})
+	}
+}
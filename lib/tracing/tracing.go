@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	tracerName = "github.com/aremxyplug-be"
+)
+
+// Setup installs the global tracer provider and propagator. The returned func flushes the spans still
+// buffered and stops the exporter, it must be called on shutdown.
+func Setup(ctx context.Context, cfg config.Tracing, env string) (func(context.Context) error, error) {
+	// incoming trace context is propagated even when spans are not exported, so the trace ids callers
+	// send still show up in logs and error responses.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: creating %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.DeploymentEnvironment(env),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: building resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the application, spans are dropped until Setup installs an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// TraceID returns the id of the trace in ctx, or an empty string when ctx carries no trace.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// CorrelationID returns the trace id of the request in ctx, or its chi request id when tracing is disabled
+// and the caller did not send a trace.
+func CorrelationID(ctx context.Context) string {
+	if id := TraceID(ctx); id != "" {
+		return id
+	}
+	return middleware.GetReqID(ctx)
+}
+
+// Fields returns the zap fields identifying the span in ctx, so log lines can be joined to their trace.
+func Fields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
+
+// Logger returns logger with the trace and span ids of ctx attached.
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
@@ -0,0 +1,214 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	terror "github.com/aremxyplug-be/lib/errors"
+	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	meterRegex = regexp.MustCompile(`^\d{11,13}$`)
+	// dstv and gotv smartcards are 10 digits, startimes are 10 or 11 and showmax uses the phone number
+	iucRegex = regexp.MustCompile(`^\d{10,11}$`)
+)
+
+// FieldError is a single failed rule on a request field.
+type FieldError = terror.FieldError
+
+// New returns a validator with the custom purchase rules registered. Fields are reported by their json names.
+//
+//	network     easyaccess network code or network name on strings, dontech network id on ints
+//	meter       11 to 13 digit meter number
+//	iuc         10 or 11 digit smartcard number
+//	amount      numeric range on a string, int or float field, e.g. amount=50-50000
+//	quantity    same as amount for whole counts, several ranges or values are space separated, e.g. quantity=1-4 10
+func New() *validator.Validate {
+	validate := validator.New()
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	validate.RegisterValidation("network", network)
+	validate.RegisterValidation("meter", matches(meterRegex))
+	validate.RegisterValidation("iuc", matches(iucRegex))
+	validate.RegisterValidation("amount", numberRange(false))
+	validate.RegisterValidation("quantity", numberRange(true))
+
+	return validate
+}
+
+// Errors turns the error returned by the validator into field errors. Other errors are returned as a single
+// field error with an empty field.
+func Errors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []FieldError{{Message: err.Error()}}
+	}
+
+	fieldErrs := make([]FieldError, 0, len(validationErrs))
+	for _, e := range validationErrs {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   e.Field(),
+			Rule:    e.Tag(),
+			Message: message(e),
+		})
+	}
+
+	return fieldErrs
+}
+
+func message(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required", "required_if":
+		return fmt.Sprintf("%s is required", e.Field())
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", e.Field())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", e.Field(), strings.ReplaceAll(e.Param(), " ", ", "))
+	case "network":
+		return fmt.Sprintf("%s is not a supported network", e.Field())
+	case "meter":
+		return fmt.Sprintf("%s must be an 11 to 13 digit meter number", e.Field())
+	case "iuc":
+		return fmt.Sprintf("%s must be a 10 or 11 digit smartcard number", e.Field())
+	case "amount", "quantity":
+		kind := "a number"
+		if e.Tag() == "quantity" {
+			kind = "a whole number"
+		}
+		ranges, _ := parseRanges(e.Param())
+		allowed := make([]string, 0, len(ranges))
+		for _, r := range ranges {
+			if r.min == r.max {
+				allowed = append(allowed, format(r.min))
+				continue
+			}
+			allowed = append(allowed, fmt.Sprintf("between %s and %s", format(r.min), format(r.max)))
+		}
+		return fmt.Sprintf("%s must be %s %s", e.Field(), kind, strings.Join(allowed, " or "))
+	case "min", "max", "len":
+		return fmt.Sprintf("%s must have %s %s", e.Field(), e.Tag(), e.Param())
+	}
+
+	return fmt.Sprintf("%s failed the %s rule", e.Field(), e.Tag())
+}
+
+func network(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		_, ok := vtu.NetworkCode(vtu.NetworkName(field.String()))
+		return ok
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return plans.DontechNetwork(int(field.Int())) != ""
+	}
+
+	return false
+}
+
+func matches(re *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return fl.Field().Kind() == reflect.String && re.MatchString(fl.Field().String())
+	}
+}
+
+// numberRange checks that a numeric or numeric string field is within one of the space separated "min-max"
+// ranges or values of the param, and is a whole number when whole is set.
+func numberRange(whole bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		ranges, err := parseRanges(fl.Param())
+		if err != nil {
+			panic(fmt.Sprintf("validation: bad range %q on %s: %v", fl.Param(), fl.FieldName(), err))
+		}
+
+		value, ok := number(fl.Field(), whole)
+		if !ok {
+			return false
+		}
+		for _, r := range ranges {
+			if value >= r.min && value <= r.max {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// number returns the value of a numeric or numeric string field, whole rejects fractions.
+func number(field reflect.Value, whole bool) (float64, bool) {
+	var value float64
+	switch field.Kind() {
+	case reflect.String:
+		s := strings.TrimSpace(field.String())
+		if whole {
+			n, err := strconv.Atoi(s)
+			return float64(n), err == nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false
+		}
+		value = v
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = field.Float()
+		if whole && value != math.Trunc(value) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	return value, true
+}
+
+type numRange struct {
+	min, max float64
+}
+
+func parseRanges(param string) ([]numRange, error) {
+	fields := strings.Fields(param)
+	if len(fields) == 0 {
+		return nil, errors.New("range must be min-max")
+	}
+
+	ranges := make([]numRange, 0, len(fields))
+	for _, field := range fields {
+		lower, upper, ok := strings.Cut(field, "-")
+		if !ok {
+			upper = lower
+		}
+		min, err := strconv.ParseFloat(lower, 64)
+		if err != nil {
+			return nil, err
+		}
+		max, err := strconv.ParseFloat(upper, 64)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, numRange{min: min, max: max})
+	}
+
+	return ranges, nil
+}
+
+func format(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
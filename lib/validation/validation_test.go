@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	validate := New()
+
+	var tests = []struct {
+		name string
+		body interface{}
+		want []FieldError
+	}{
+		{
+			name: "Test valid airtime",
+			body: &telcom.AirtimeInfo{Network: "01", Amount: "100", Phone_no: "08031234567"},
+		},
+		{
+			name: "Test airtime network name",
+			body: &telcom.AirtimeInfo{Network: "Airtel", Amount: "100", Phone_no: "08021234567"},
+		},
+		{
+			name: "Test airtime bad network and amount",
+			body: &telcom.AirtimeInfo{Network: "09", Amount: "20", Phone_no: "08031234567"},
+			want: []FieldError{
+				{Field: "network", Rule: "network", Message: "network is not a supported network"},
+				{Field: "amount", Rule: "amount", Message: "amount must be a number between 50 and 50000"},
+			},
+		},
+		{
+			name: "Test airtime amount not a number",
+			body: &telcom.AirtimeInfo{Network: "01", Amount: "ten", Phone_no: "08031234567"},
+			want: []FieldError{{Field: "amount", Rule: "amount", Message: "amount must be a number between 50 and 50000"}},
+		},
+		{
+			name: "Test data unknown network id",
+			body: &telcom.DataInfo{Network: 42, Plan: 1, Mobile_Num: "08031234567"},
+			want: []FieldError{{Field: "network", Rule: "network", Message: "network is not a supported network"}},
+		},
+		{
+			name: "Test spectranet pins",
+			body: &telcom.SpectranetInfo{Network: "spectranet", Plan: "500", Phone_Number: "08031234567", No_of_Pins: "11"},
+			want: []FieldError{{Field: "no_of_pins", Rule: "quantity", Message: "no_of_pins must be a whole number between 1 and 10"}},
+		},
+		{
+			name: "Test spectranet fraction of a pin",
+			body: &telcom.SpectranetInfo{Network: "spectranet", Plan: "500", Phone_Number: "08031234567", No_of_Pins: "2.5"},
+			want: []FieldError{{Field: "no_of_pins", Rule: "quantity", Message: "no_of_pins must be a whole number between 1 and 10"}},
+		},
+		{
+			name: "Test edu missing quantity",
+			body: &models.EduInfo{Exam_Type: "waec", Phone_Number: "08031234567"},
+			want: []FieldError{{Field: "quantity", Rule: "required", Message: "quantity is required"}},
+		},
+		{
+			name: "Test edu ten pins",
+			body: &models.EduInfo{Exam_Type: "waec", Phone_Number: "08031234567", Quantity: 10},
+		},
+		{
+			name: "Test edu pins between four and ten",
+			body: &models.EduInfo{Exam_Type: "waec", Phone_Number: "08031234567", Quantity: 7},
+			want: []FieldError{{Field: "quantity", Rule: "quantity", Message: "quantity must be a whole number between 1 and 4 or 10"}},
+		},
+		{
+			name: "Test valid electricity",
+			body: &models.ElectricInfo{DiscoType: "ikeja-electric", Meter_No: "1234567890123", Meter_Type: "prepaid", Amount: 1000},
+		},
+		{
+			name: "Test electricity bad meter",
+			body: &models.ElectricInfo{DiscoType: "ikeja-electric", Meter_No: "1234-5678", Meter_Type: "token", Amount: 100},
+			want: []FieldError{
+				{Field: "meter_no", Rule: "meter", Message: "meter_no must be an 11 to 13 digit meter number"},
+				{Field: "meter_type", Rule: "oneof", Message: "meter_type must be one of prepaid, postpaid"},
+				{Field: "amount", Rule: "amount", Message: "amount must be a number between 500 and 500000"},
+			},
+		},
+		{
+			name: "Test tv change without package",
+			body: &models.TvInfo{DecoderType: "dstv", SmartCard_Number: "1234567890", SubType: "change"},
+			want: []FieldError{{Field: "package", Rule: "required_if", Message: "package is required"}},
+		},
+		{
+			name: "Test tv bad iuc",
+			body: &models.TvInfo{DecoderType: "gotv", SmartCard_Number: "12345"},
+			want: []FieldError{{Field: "iuc_number", Rule: "iuc", Message: "iuc_number must be a 10 or 11 digit smartcard number"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.body)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, Errors(err))
+		})
+	}
+}
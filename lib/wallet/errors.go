@@ -0,0 +1,9 @@
+package wallet
+
+import "errors"
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient balance to carry out the transaction")
+	ErrNoAccount         = errors.New("user does not have a virtual account")
+	ErrInvalidAmount     = errors.New("amount must be greater than zero")
+)
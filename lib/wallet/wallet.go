@@ -0,0 +1,243 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/idgenerator"
+	"go.uber.org/zap"
+)
+
+// Wallet debits and credits a user's balance. Balance changes for the same account are serialised
+// so that concurrent payments cannot both spend the same naira, and each one reads and writes the
+// balance in a transaction so that other instances of the service cannot interleave with it.
+type Wallet struct {
+	db          db.BankStore
+	logger      *zap.Logger
+	idGenerator idgenerator.IdGenerator
+
+	mu       sync.Mutex
+	locks    map[string]*sync.Mutex
+	watchers []BalanceWatcher
+}
+
+// BalanceWatcher is told the balance of a user before and after a debit.
+type BalanceWatcher func(ctx context.Context, userID string, before, after float64)
+
+// Watch calls fn after every successful debit, it must be called before the wallet is used.
+func (w *Wallet) Watch(fn BalanceWatcher) {
+	w.watchers = append(w.watchers, fn)
+}
+
+func NewWallet(store db.BankStore, logger *zap.Logger) *Wallet {
+	return &Wallet{
+		db:          store,
+		logger:      logger,
+		idGenerator: idgenerator.New(),
+		locks:       map[string]*sync.Mutex{},
+	}
+}
+
+// Movement describes a change of a balance in the ledger, Type is one of the models.Entry types.
+type Movement struct {
+	Type        string
+	Reference   string
+	Description string
+}
+
+// Balance returns the balance of the user's virtual account.
+func (w *Wallet) Balance(ctx context.Context, username string) (float64, error) {
+	account, err := w.account(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+
+	bal, err := w.db.GetBalance(ctx, account.VirtualAccountID)
+	if err != nil {
+		return 0, w.logAndReturnError("failed to get balance", err)
+	}
+
+	return bal, nil
+}
+
+// Line is one line of a debit or credit on the user's statement.
+type Line struct {
+	Amount float64
+	Movement
+}
+
+// Debit removes amount from the user's balance, it fails with ErrInsufficientFunds when the balance is too low.
+func (w *Wallet) Debit(ctx context.Context, username string, amount float64, movement Movement) error {
+	return w.DebitWith(ctx, username, []Line{{Amount: amount, Movement: movement}}, nil)
+}
+
+// DebitWith removes the total of the lines from the user's balance like Debit, recording each line in the
+// ledger, and runs with in the same transaction so what the money pays for is saved with the debit or not
+// at all.
+func (w *Wallet) DebitWith(ctx context.Context, username string, lines []Line, with func(ctx context.Context) error) error {
+	amount, err := total(lines)
+	if err != nil {
+		return err
+	}
+
+	account, err := w.account(ctx, username)
+	if err != nil {
+		return err
+	}
+	nuban := account.VirtualAccountID
+
+	lock := w.lock(nuban)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var before float64
+	err = w.db.WithTransaction(ctx, func(ctx context.Context) error {
+		bal, err := w.db.GetBalance(ctx, nuban)
+		if err != nil {
+			return w.logAndReturnError("failed to get balance", err)
+		}
+		if bal < amount {
+			return ErrInsufficientFunds
+		}
+		before = bal
+
+		if err := w.db.UpdateBalance(ctx, nuban, bal-amount); err != nil {
+			return w.logAndReturnError("failed to update balance", err)
+		}
+
+		for _, line := range lines {
+			bal -= line.Amount
+			if err := w.record(ctx, account.User_ID, -line.Amount, bal, line.Movement); err != nil {
+				return err
+			}
+		}
+
+		if with != nil {
+			return with(ctx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, watch := range w.watchers {
+		watch(ctx, account.User_ID, before, before-amount)
+	}
+	return nil
+}
+
+// Credit adds amount to the user's balance, it is used for refunds when a paid for purchase fails.
+func (w *Wallet) Credit(ctx context.Context, username string, amount float64, movement Movement) error {
+	return w.CreditWith(ctx, username, []Line{{Amount: amount, Movement: movement}}, nil)
+}
+
+// CreditWith adds the total of the lines to the user's balance like Credit and runs with in the same
+// transaction.
+func (w *Wallet) CreditWith(ctx context.Context, username string, lines []Line, with func(ctx context.Context) error) error {
+	amount, err := total(lines)
+	if err != nil {
+		return err
+	}
+
+	account, err := w.account(ctx, username)
+	if err != nil {
+		return err
+	}
+	nuban := account.VirtualAccountID
+
+	lock := w.lock(nuban)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return w.db.WithTransaction(ctx, func(ctx context.Context) error {
+		bal, err := w.db.GetBalance(ctx, nuban)
+		if err != nil {
+			return w.logAndReturnError("failed to get balance", err)
+		}
+
+		if err := w.db.UpdateBalance(ctx, nuban, bal+amount); err != nil {
+			return w.logAndReturnError("failed to update balance", err)
+		}
+
+		for _, line := range lines {
+			bal += line.Amount
+			if err := w.record(ctx, account.User_ID, line.Amount, bal, line.Movement); err != nil {
+				return err
+			}
+		}
+
+		if with != nil {
+			return with(ctx)
+		}
+		return nil
+	})
+}
+
+func total(lines []Line) (float64, error) {
+	var amount float64
+	for _, line := range lines {
+		if line.Amount <= 0 {
+			return 0, ErrInvalidAmount
+		}
+		amount += line.Amount
+	}
+	if amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	return amount, nil
+}
+
+// record saves the movement in the ledger, it is called in the transaction that changed the balance.
+func (w *Wallet) record(ctx context.Context, userID string, amount, balance float64, movement Movement) error {
+	entry := models.LedgerEntry{
+		ID:          w.idGenerator.Generate(),
+		UserID:      userID,
+		Type:        movement.Type,
+		Amount:      amount,
+		Balance:     balance,
+		Reference:   movement.Reference,
+		Description: movement.Description,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := w.db.SaveLedgerEntry(ctx, entry); err != nil {
+		return w.logAndReturnError("failed to record ledger entry", err)
+	}
+
+	return nil
+}
+
+func (w *Wallet) account(ctx context.Context, username string) (models.AccountDetails, error) {
+	account, err := w.db.GetVirtualNuban(ctx, username)
+	if err != nil {
+		return models.AccountDetails{}, w.logAndReturnError("failed to get virtual account", err)
+	}
+	if account.VirtualAccountID == "" {
+		return models.AccountDetails{}, ErrNoAccount
+	}
+
+	return account, nil
+}
+
+func (w *Wallet) lock(nuban string) *sync.Mutex {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lock, ok := w.locks[nuban]
+	if !ok {
+		lock = &sync.Mutex{}
+		w.locks[nuban] = lock
+	}
+
+	return lock
+}
+
+func (w *Wallet) logAndReturnError(errorMsg string, err error) error {
+	w.logger.Error(errorMsg, zap.Error(err))
+	return fmt.Errorf("%s: %w", errorMsg, err)
+}
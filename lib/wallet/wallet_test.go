@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeStore keeps balances in memory, writes made outside WithTransaction fail the test.
+type fakeStore struct {
+	db.BankStore
+	t        *testing.T
+	balances map[string]float64
+	entries  []models.LedgerEntry
+	inTx     bool
+	// err fails every balance read
+	err error
+}
+
+func (f *fakeStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.inTx = true
+	defer func() { f.inTx = false }()
+
+	snapshot, entries := map[string]float64{}, len(f.entries)
+	for k, v := range f.balances {
+		snapshot[k] = v
+	}
+	if err := fn(ctx); err != nil {
+		f.balances, f.entries = snapshot, f.entries[:entries]
+		return err
+	}
+	return nil
+}
+
+func (f *fakeStore) GetVirtualNuban(_ context.Context, name string) (models.AccountDetails, error) {
+	return models.AccountDetails{User_ID: "user-" + name, VirtualAccountID: "nuban-" + name}, nil
+}
+
+func (f *fakeStore) GetBalance(_ context.Context, virtualNuban string) (float64, error) {
+	return f.balances[virtualNuban], f.err
+}
+
+func (f *fakeStore) UpdateBalance(_ context.Context, virtualNuban string, balance float64) error {
+	assert.True(f.t, f.inTx, "balance updated outside a transaction")
+	f.balances[virtualNuban] = balance
+	return nil
+}
+
+func (f *fakeStore) SaveLedgerEntry(_ context.Context, entry models.LedgerEntry) error {
+	assert.True(f.t, f.inTx, "ledger entry saved outside a transaction")
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func TestWallet(t *testing.T) {
+	var tests = []struct {
+		name   string
+		credit bool
+		amount float64
+		want   float64
+		// wantEntry is the signed amount recorded in the ledger, 0 when nothing is recorded
+		wantEntry float64
+		wantErr   error
+	}{
+		{name: "Test debit", amount: 300, want: 700, wantEntry: -300},
+		{name: "Test debit above balance", amount: 1500, want: 1000, wantErr: ErrInsufficientFunds},
+		{name: "Test credit", credit: true, amount: 250, want: 1250, wantEntry: 250},
+		{name: "Test invalid amount", amount: -5, want: 1000, wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{t: t, balances: map[string]float64{"nuban-ada": 1000}}
+			w := NewWallet(store, zap.NewNop())
+			var watched []float64
+			w.Watch(func(_ context.Context, userID string, before, after float64) {
+				assert.Equal(t, "user-ada", userID)
+				watched = append(watched, before, after)
+			})
+
+			movement := Movement{Type: models.EntryPurchase, Reference: "order-1"}
+			var err error
+			if tt.credit {
+				err = w.Credit(context.Background(), "ada", tt.amount, movement)
+			} else {
+				err = w.Debit(context.Background(), "ada", tt.amount, movement)
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, store.balances["nuban-ada"])
+			if tt.wantEntry < 0 {
+				assert.Equal(t, []float64{1000, tt.want}, watched)
+			} else {
+				assert.Empty(t, watched, "only debits are watched")
+			}
+			if tt.wantEntry == 0 {
+				assert.Empty(t, store.entries)
+				return
+			}
+			if assert.Len(t, store.entries, 1) {
+				entry := store.entries[0]
+				assert.Equal(t, "user-ada", entry.UserID)
+				assert.Equal(t, tt.wantEntry, entry.Amount)
+				assert.Equal(t, tt.want, entry.Balance)
+				assert.Equal(t, "order-1", entry.Reference)
+				assert.NotEmpty(t, entry.ID)
+			}
+		})
+	}
+}
+
+func TestDebitWith(t *testing.T) {
+	var tests = []struct {
+		name        string
+		withErr     error
+		want        float64
+		wantEntries []float64
+	}{
+		{name: "Test lines recorded with the record", want: 650, wantEntries: []float64{-300, -50}},
+		{name: "Test failed record undoes the debit", withErr: errors.New("duplicate transfer"), want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{t: t, balances: map[string]float64{"nuban-ada": 1000}}
+			w := NewWallet(store, zap.NewNop())
+
+			lines := []Line{
+				{Amount: 300, Movement: Movement{Type: models.EntryTransfer, Reference: "42"}},
+				{Amount: 50, Movement: Movement{Type: models.EntryFee, Reference: "42"}},
+			}
+			err := w.DebitWith(context.Background(), "ada", lines, func(ctx context.Context) error {
+				assert.True(t, store.inTx, "record saved outside the debit")
+				return tt.withErr
+			})
+
+			if tt.withErr != nil {
+				assert.ErrorIs(t, err, tt.withErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, store.balances["nuban-ada"])
+			var amounts []float64
+			for _, entry := range store.entries {
+				amounts = append(amounts, entry.Amount)
+			}
+			assert.Equal(t, tt.wantEntries, amounts)
+			if len(store.entries) == 2 {
+				// each line shows the balance after it
+				assert.Equal(t, float64(700), store.entries[0].Balance)
+				assert.Equal(t, float64(650), store.entries[1].Balance)
+			}
+		})
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := &fakeStore{t: t, balances: map[string]float64{"nuban-ada": 1000}, err: context.Canceled}
+	w := NewWallet(store, zap.NewNop())
+
+	// a cancellation is told apart from a failure
+	err := w.Debit(ctx, "ada", 300, Movement{Type: models.EntryPurchase, Reference: "order-1"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = w.Balance(ctx, "ada")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1000.0, store.balances["nuban-ada"])
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// SignatureHeader carries the signature anchor puts on the events it sends.
+const SignatureHeader = "x-anchor-signature"
+
+// events of a transfer the bank did not pay, its money has to go back to the user.
+const (
+	EventTransferFailed   = "nip.transfer.failed"
+	EventTransferReversed = "nip.transfer.reversed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrNoReference      = errors.New("webhook event has no transfer reference")
+)
+
+// AnchorEvent is the part of an anchor event the refunds need.
+type AnchorEvent struct {
+	ID        string
+	Type      string
+	Reference string // reference we sent with the transfer, our transaction id
+	Reason    string
+}
+
+type anchorPayload struct {
+	Data struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Attributes struct {
+			Reason string `json:"reason"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Included []struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Reference     string `json:"reference"`
+			FailureReason string `json:"failureReason"`
+		} `json:"attributes"`
+	} `json:"included"`
+}
+
+// VerifyAnchor reports whether signature is the base64 HMAC-SHA1 of body keyed with secret. Nothing
+// verifies without a secret.
+func VerifyAnchor(body []byte, signature, secret string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// ParseAnchorEvent reads an anchor event. The reference is only looked up for the transfer failure
+// events, the other events are returned with their type for the caller to ignore.
+func ParseAnchorEvent(body []byte) (AnchorEvent, error) {
+	payload := anchorPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return AnchorEvent{}, err
+	}
+
+	event := AnchorEvent{ID: payload.Data.ID, Type: payload.Data.Type, Reason: payload.Data.Attributes.Reason}
+	if !event.TransferFailed() {
+		return event, nil
+	}
+
+	for _, included := range payload.Included {
+		if included.Attributes.Reference == "" {
+			continue
+		}
+		event.Reference = included.Attributes.Reference
+		if event.Reason == "" {
+			event.Reason = included.Attributes.FailureReason
+		}
+		return event, nil
+	}
+	return AnchorEvent{}, ErrNoReference
+}
+
+// TransferFailed reports whether the event is a transfer the bank did not pay.
+func (e AnchorEvent) TransferFailed() bool {
+	return e.Type == EventTransferFailed || e.Type == EventTransferReversed
+}
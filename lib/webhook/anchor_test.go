@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(body, secret string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyAnchor(t *testing.T) {
+	body := `{"data":{"id":"evt-1"}}`
+
+	var tests = []struct {
+		name      string
+		signature string
+		secret    string
+		want      bool
+	}{
+		{name: "Test valid signature", signature: sign(body, "s3cret"), secret: "s3cret", want: true},
+		{name: "Test signed with another secret", signature: sign(body, "other"), secret: "s3cret"},
+		{name: "Test no signature", secret: "s3cret"},
+		{name: "Test no secret configured", signature: sign(body, ""), secret: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, VerifyAnchor([]byte(body), tt.signature, tt.secret))
+		})
+	}
+}
+
+func TestParseAnchorEvent(t *testing.T) {
+	var tests = []struct {
+		name    string
+		body    string
+		want    AnchorEvent
+		wantErr error
+	}{
+		{
+			name: "Test transfer failed",
+			body: `{"data":{"id":"evt-1","type":"nip.transfer.failed","attributes":{}},
+				"included":[{"type":"NIP_TRANSFER","attributes":{"reference":"TX-2","failureReason":"account closed"}}]}`,
+			want: AnchorEvent{ID: "evt-1", Type: EventTransferFailed, Reference: "TX-2", Reason: "account closed"},
+		},
+		{
+			name: "Test other event",
+			body: `{"data":{"id":"evt-2","type":"nip.transfer.successful"}}`,
+			want: AnchorEvent{ID: "evt-2", Type: "nip.transfer.successful"},
+		},
+		{
+			name:    "Test failed transfer without reference",
+			body:    `{"data":{"id":"evt-3","type":"nip.transfer.reversed"},"included":[]}`,
+			wantErr: ErrNoReference,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAnchorEvent([]byte(tt.body))
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db/models"
 	"github.com/aremxyplug-be/db/mongo"
 	"github.com/aremxyplug-be/lib/auth"
 	auth_pin "github.com/aremxyplug-be/lib/auth/pin"
@@ -15,50 +20,218 @@ import (
 	"github.com/aremxyplug-be/lib/bank/transfer"
 	elect "github.com/aremxyplug-be/lib/bills/electricity"
 	"github.com/aremxyplug-be/lib/bills/tvsub"
+	"github.com/aremxyplug-be/lib/bulk"
+	"github.com/aremxyplug-be/lib/dispute"
 	"github.com/aremxyplug-be/lib/emailclient/postmark"
+	"github.com/aremxyplug-be/lib/float"
+	"github.com/aremxyplug-be/lib/health"
+	"github.com/aremxyplug-be/lib/httpclient"
 	zapLogger "github.com/aremxyplug-be/lib/logger"
+	"github.com/aremxyplug-be/lib/notification"
 	otpgen "github.com/aremxyplug-be/lib/otp_gen"
 	pointredeem "github.com/aremxyplug-be/lib/point-redeem"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/aremxyplug-be/lib/reconcile"
 	"github.com/aremxyplug-be/lib/referral"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/scheduler"
+	"github.com/aremxyplug-be/lib/smsclient/twilio"
+	"github.com/aremxyplug-be/lib/statement"
+	"github.com/aremxyplug-be/lib/supervisor"
 	vtu "github.com/aremxyplug-be/lib/telcom/airtime"
 	"github.com/aremxyplug-be/lib/telcom/data"
 	"github.com/aremxyplug-be/lib/telcom/edu"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/tracing"
+	"github.com/aremxyplug-be/lib/wallet"
 	httpSrv "github.com/aremxyplug-be/server/http"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.uber.org/zap"
 )
 
 func main() {
 	logger := zapLogger.New()
-	secrets := config.GetSecrets()
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		logger.Fatal("failed to load configuration", zap.Error(err))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.Mongo, logger, os.Args[2:]); err != nil {
+			logger.Fatal("migrate failed", zap.Error(err))
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, cfg.Server.Env)
+	if err != nil {
+		logger.Fatal("failed to set up tracing", zap.Error(err))
+	}
 
 	// Get data store
-	store, client, err := mongo.New(secrets.MongdbUrl, secrets.DbName, logger)
+	store, client, err := mongo.New(cfg.Mongo, logger)
 	if err != nil {
 		logger.Fatal("failed to open mongodb", zap.Error(err))
 	}
 
+	// the schema is not migrated on start up, several instances would race to apply the same migration
+	if pending, err := mongo.NewMigrator(client.Database(cfg.Mongo.Database), logger).Pending(context.Background()); err != nil {
+		logger.Error("failed to check schema migrations", zap.Error(err))
+	} else if pending > 0 {
+		logger.Warn("schema migrations are pending, run `migrate up`", zap.Int("pending", pending))
+	}
+
 	// setup email client
-	emailClient := postmark.New(secrets)
+	emailClient := postmark.New(cfg.Postmark)
+	smsClient := twilio.New(cfg.Twilio)
+	providers := httpclient.NewProviders(cfg.Providers, logger)
+
+	// there is no push provider yet, until one is added here push only reaches the inbox
+	notifier := notification.NewNotifier(&notification.Options{
+		Store: store,
+		Senders: map[models.MessageType]notification.Sender{
+			models.EMAIL_MESSAGE_TYPE: emailClient,
+			models.SMS_MESSAGE_TYPE:   smsClient,
+		},
+		LowBalanceThreshold: cfg.Features.LowBalanceThreshold,
+		Logger:              logger,
+	})
+
 	otp := otpgen.NewOTP(store)
-	data := data.NewData(store, logger)
-	edu := edu.NewEdu(store, logger)
-	vtu := vtu.NewAirtimeConn(store, logger)
-	tvSub := tvsub.NewTvConn(store, logger)
-	electSub := elect.NewElectricConn(store, logger)
-	auth := auth.NewAuthConn(secrets)
-	virtualAcc := bankacc.NewBankConfig(store, logger)
+	data := data.NewData(store, logger, providers.Dontech, providers.VTpass)
+	edu := edu.NewEdu(store, logger, providers.EasyAccess)
+	vtu := vtu.NewAirtimeConn(store, logger, providers.EasyAccess)
+	tvSub := tvsub.NewTvConn(store, logger, providers.VTpass, cfg.Features.TVPackageCacheTTL)
+	electSub := elect.NewElectricConn(store, logger, notifier, providers.VTpass)
+	auth := auth.NewAuthConn(cfg.JWT)
+	virtualAcc := bankacc.NewBankConfig(store, logger, providers.Anchor, cfg.Providers.Anchor)
 	bankTransc := transactions.NewTransaction(store)
-	bankTrf := transfer.NewConfig(store, logger)
-	bankDep := deposit.NewDepositConfig(store, logger)
 	ref := referral.NewRefConfig(store)
 	point := pointredeem.NewPointConfig(store)
 	pin := auth_pin.NewPinConfig(logger, store)
+	planCatalogue := plans.NewCatalogue(store, logger, providers.Dontech, providers.VTpass, cfg.Features.DataPlanMarkup)
+
+	bankDep := deposit.NewDepositConfig(store, logger, providers.Anchor, notifier)
+
+	userWallet := wallet.NewWallet(store, logger)
+	userWallet.Watch(notifier.BalanceChanged)
+	bankTrf := transfer.NewConfig(store, userWallet, logger, providers.Anchor, cfg.Providers.Anchor)
+	orderScheduler := scheduler.NewScheduler(&scheduler.Options{
+		Store:       store,
+		Wallet:      userWallet,
+		Airtime:     vtu,
+		Data:        data,
+		TvSub:       tvSub,
+		Electricity: electSub,
+		EmailClient: emailClient,
+		SMSClient:   smsClient,
+		Logger:      logger,
+	})
+
+	bulkPurchase := bulk.NewBulk(&bulk.Options{
+		Store:   store,
+		Wallet:  userWallet,
+		Airtime: vtu,
+		Data:    data,
+		Workers: cfg.Features.BulkWorkers,
+		Logger:  logger,
+	})
+
+	receipts := receipt.NewReceipts(store)
+	statements := statement.NewStatements(store, emailClient, logger)
+	floats := float.NewMonitor(&float.Options{
+		Store:            store,
+		Providers:        providers,
+		DepositAccountID: cfg.Providers.Anchor.DepositAccountID,
+		Config:           cfg.Float,
+		EmailClient:      emailClient,
+		Logger:           logger,
+	})
+	reconciler := reconcile.NewReconciler(&reconcile.Options{
+		Store:     store,
+		Providers: providers,
+		Logger:    logger,
+	})
+	refunds := refund.NewRefunds(&refund.Options{
+		Store:             store,
+		Wallet:            userWallet,
+		EmailClient:       emailClient,
+		ApprovalThreshold: cfg.Features.RefundApprovalThreshold,
+		Providers:         providers,
+		Logger:            logger,
+	})
+	disputes := dispute.NewDisputes(&dispute.Options{
+		Store:       store,
+		Refunds:     refunds,
+		Providers:   providers,
+		EmailClient: emailClient,
+		SLA:         cfg.Features.DisputeSLA,
+		Logger:      logger,
+	})
+
+	// background workers, the supervisor restarts them if they fail and stops them on shutdown.
+	workers := supervisor.New(logger)
+	if cfg.Features.PlanSync {
+		// keep the data plan catalogue in sync with the providers
+		workers.Add("plan-sync", func(ctx context.Context) {
+			planCatalogue.Run(ctx, cfg.Features.PlanSyncInterval)
+		})
+	}
+	if cfg.Features.Scheduler {
+		// run the users' scheduled orders as they fall due
+		workers.Add("scheduled-payments", func(ctx context.Context) {
+			orderScheduler.Run(ctx, cfg.Features.SchedulerInterval)
+		})
+	}
+	if cfg.Features.DepositSync {
+		// credit payments into the users' virtual accounts
+		workers.Add("deposit-sync", func(ctx context.Context) {
+			bankDep.Run(ctx, cfg.Features.DepositSyncInterval)
+		})
+	}
+	if cfg.Features.Statements {
+		// email the users the statements of their wallets for the month just ended
+		workers.Add("monthly-statements", func(ctx context.Context) {
+			statements.Run(ctx, cfg.Features.StatementsInterval)
+		})
+	}
+	if cfg.Features.FloatMonitor {
+		// watch our balances at the providers and alert when one runs low
+		workers.Add("float-monitor", func(ctx context.Context) {
+			floats.Run(ctx, cfg.Features.FloatMonitorInterval)
+		})
+	}
+	if cfg.Features.Reconciliation {
+		// match yesterday's transactions with the reports of the providers that have an api for them
+		workers.Add("daily-reconciliation", func(ctx context.Context) {
+			reconciler.Run(ctx, cfg.Features.ReconciliationInterval)
+		})
+	}
+	if cfg.Features.Requery {
+		// ask the providers about the purchases paid from the wallets and refund the failed ones
+		workers.Add("requery", func(ctx context.Context) {
+			refunds.Run(ctx, cfg.Features.RequeryInterval)
+		})
+		// ask anchor about the transfers that were not confirmed when they were sent
+		workers.Add("transfer-requery", func(ctx context.Context) {
+			bankTrf.Run(ctx, cfg.Features.RequeryInterval)
+		})
+	}
+
+	// buy the rows of the bulk purchases, resuming the ones stopped by the last shutdown
+	workers.Add("bulk-purchases", func(ctx context.Context) {
+		bulkPurchase.Run(ctx, cfg.Features.BulkInterval)
+	})
+
+	checker := health.New(func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
+	}, workers)
 
 	config := httpSrv.ServerConfig{
 		Store:       store,
 		EmailClient: emailClient,
 		Logger:      logger,
-		Secrets:     secrets,
+		Config:      cfg,
 		DataClient:  data,
 		EduClient:   edu,
 		Vtu:         vtu,
@@ -73,18 +246,62 @@ func main() {
 		Referral:    ref,
 		Point:       point,
 		Pin:         pin,
+		Plans:       planCatalogue,
+		Scheduler:   orderScheduler,
+		Bulk:        bulkPurchase,
+		Receipts:    receipts,
+		Statements:  statements,
+		Floats:      floats,
+		Reconciler:  reconciler,
+		Refunds:     refunds,
+		Disputes:    disputes,
+		Notifier:    notifier,
+		Health:      checker,
 	}
 
-	httpRouter := httpSrv.MountServer(config)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workers.Start(ctx)
+
 	// Start HTTP server
-	httpAddr := fmt.Sprintf(":%s", secrets.AppPort)
-	logger.Info(fmt.Sprintf("HTTP service running on %v.", httpAddr))
-	if err := http.ListenAndServe(httpAddr, httpRouter); err != nil {
-		logger.With(zap.Error(err)).Fatal("start http server")
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%s", cfg.Server.Port),
+		Handler:      httpSrv.MountServer(config),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("HTTP service running on %v.", server.Addr))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		logger.Error("http server stopped", zap.Error(err))
+	case <-ctx.Done():
+		logger.Info("shutdown signal received, draining...")
+		checker.Drain()
+		time.Sleep(cfg.Server.DrainPeriod)
 	}
+
 	logger.Info("closing application...")
-	if err := client.Disconnect(context.Background()); err != nil {
-		logger.Fatal("failed to disconnect from database", zap.Error(err))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shut down http server", zap.Error(err))
+	}
+	if err := workers.Stop(shutdownCtx); err != nil {
+		logger.Error("failed to stop workers", zap.Error(err))
+	}
+	if err := client.Disconnect(shutdownCtx); err != nil {
+		logger.Error("failed to disconnect from database", zap.Error(err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", zap.Error(err))
 	}
 }
 
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db/mongo"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate runs the migrate command, `migrate up` applies the pending migrations, `migrate down [steps]`
+// rolls back the last steps migrations (one by default) and `migrate status` lists them.
+func runMigrate(cfg config.Mongo, logger *zap.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	_, client, err := mongo.New(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open mongodb: %w", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	migrator := mongo.NewMigrator(client.Database(cfg.Database), logger)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		logger.Info("migrations applied", zap.Int("count", applied))
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		logger.Info("migrations rolled back", zap.Int("count", rolledBack))
+		return err
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Description, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
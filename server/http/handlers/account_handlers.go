@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
 	"net/http"
 
 	"github.com/aremxyplug-be/db/models"
@@ -12,9 +15,7 @@ func (handler *HttpHandler) VirtualAccount(w http.ResponseWriter, r *http.Reques
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -27,33 +28,25 @@ func (handler *HttpHandler) VirtualAccount(w http.ResponseWriter, r *http.Reques
 		user := *userDetails
 
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": "invalid JSON request"}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("invalid JSON request"))
 			return
 		}
 		// get the user;s other infomation at this point and then associate the BVN field to this point
 
 		if data.Bvn == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": "bvn is required"}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("bvn is required"))
 			return
 		}
 
 		user.BVN = data.Bvn
-		_, err := handler.virtualAcc.VirtualAccount(user)
+		_, err := handler.virtualAcc.VirtualAccount(r.Context(), user)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"error": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		if err := handler.store.UpdateBVNField(user); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		if err := handler.store.UpdateBVNField(r.Context(), user); err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
@@ -71,11 +64,9 @@ func (handler *HttpHandler) VirtualAccount(w http.ResponseWriter, r *http.Reques
 	if r.Method == "GET" {
 
 		userID := userDetails.ID
-		virtualNuban, err := handler.getVirtualAccDetails(userID)
+		virtualNuban, err := handler.getVirtualAccDetails(r.Context(), userID)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		response := responseFormat.CustomResponse{
@@ -89,8 +80,8 @@ func (handler *HttpHandler) VirtualAccount(w http.ResponseWriter, r *http.Reques
 
 }
 
-func (handler *HttpHandler) getVirtualAccDetails(id string) (models.AccountDetails, error) {
-	acc_details, err := handler.store.GetVirtualNuban(id)
+func (handler *HttpHandler) getVirtualAccDetails(ctx context.Context, id string) (models.AccountDetails, error) {
+	acc_details, err := handler.store.GetVirtualNuban(ctx, id)
 	if err != nil {
 		handler.logger.Error(err.Error())
 		return models.AccountDetails{}, err
@@ -1,24 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/aremxyplug-be/lib/bank/transfer"
+	"github.com/aremxyplug-be/lib/errorvalues"
 	"net/http"
 
 	"github.com/aremxyplug-be/db/models"
 	"github.com/aremxyplug-be/lib/balance"
+	"github.com/aremxyplug-be/lib/metrics"
 	"github.com/aremxyplug-be/lib/responseFormat"
 	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func (handler *HttpHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -27,43 +30,28 @@ func (handler *HttpHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 		// first decode the request body
 		info := models.TransferInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
 
-		bal, err := handler.getBalance(userDetails.ID)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		if err := transfer.CheckKYCLimit(userDetails, info.Amount, handler.config.Features.KYCTransferLimit); err != nil {
+			handler.writeError(w, r, errorvalues.KYCLimitErr, err)
 			return
 		}
 
-		newBal, valid, err := handler.checkTransfer(bal, info.Amount)
-		if !valid || err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		resp, err := handler.bankTrf.TransferToBank(info)
+		// once the wallet is debited the transfer must be sent or reversed even if the client goes away, so
+		// the rest of the request is not bound to its cancellation. The wallet reports the balance change.
+		ctx := context.WithoutCancel(r.Context())
+		info.UserID = userDetails.ID
+		resp, err := handler.bankTrf.TransferToBank(ctx, userDetails.Username, info)
+		metrics.Transfer(info.Amount, err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.notify(ctx, r, transferNotification(userDetails.ID, info, err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 
 		}
-
-		if err := handler.updateBalance(userDetails.ID, newBal); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+		handler.notify(ctx, r, transferNotification(userDetails.ID, info, nil))
 
 		// if successfull return the Transfer receipt, otherwise return the error
 
@@ -73,27 +61,33 @@ func (handler *HttpHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "GET" {
-		resp, err := handler.bankTranc.GetTransferHistory(userDetails.Username)
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		resp, next, err := handler.bankTranc.GetTransferHistory(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
+			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"data": resp}}
-		json.NewEncoder(w).Encode(response)
+		writePage(w, "data", resp, next)
 	}
 
 }
 
 func (handler *HttpHandler) GetTransferDetails(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
-	resp, err := handler.bankTranc.GetTransferDetails(id)
+	resp, err := handler.bankTranc.GetTransferDetails(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -107,44 +101,50 @@ func (handler *HttpHandler) GetTransferDetails(w http.ResponseWriter, r *http.Re
 
 // Admin handler function
 func (handler *HttpHandler) GetTransferHistory(w http.ResponseWriter, r *http.Request) {
-	trsf, err := handler.bankTranc.GetTransferHistory("")
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	trsf, next, err := handler.bankTranc.GetTransferHistory(r.Context(), opts.UserID, opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	// if a parameter is provided it should return just that deposit with the id.
-	w.WriteHeader(http.StatusOK)
-	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"transfers": trsf}}
-	json.NewEncoder(w).Encode(response)
+	writePage(w, "transfers", trsf, next)
 }
 
 func (handler *HttpHandler) GetAllBankTransactions(w http.ResponseWriter, r *http.Request) {
 	// should call the fuction for loading all the  bank transactions
-	transactions, err := handler.bankTranc.GetAllTransactionHistory()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	transactions, next, err := handler.bankTranc.GetAllTransactionHistory(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"transactions": transactions}}
-	json.NewEncoder(w).Encode(response)
+	writePage(w, "transactions", transactions, next)
 
 	//  return both transfer and deposit history
 }
 
 func (handler *HttpHandler) GetDepositDetail(w http.ResponseWriter, r *http.Request) {
 
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
-	resp, err := handler.bankTranc.GetDepositDetails(id)
+	resp, err := handler.bankTranc.GetDepositDetails(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	// should be call the function to get the transfer history.
@@ -159,48 +159,46 @@ func (handler *HttpHandler) GetDepositHistory(w http.ResponseWriter, r *http.Req
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	opts, ok := handler.listOptions(w, r)
+	if !ok {
 		return
 	}
 
-	dept, err := handler.bankTranc.GetDepositHistory(userDetails.Username)
+	dept, next, err := handler.bankTranc.GetDepositHistory(r.Context(), userDetails.ID, opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	// if a parameter is provided it should return just that deposit with the id.
-	w.WriteHeader(http.StatusOK)
-	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"deposits": dept}}
-	json.NewEncoder(w).Encode(response)
+	writePage(w, "deposits", dept, next)
 	// return successful and deposit history, if an error is encountered, return the error
 }
 
 func (handler *HttpHandler) GetAllDepositHistory(w http.ResponseWriter, r *http.Request) {
-	dept, err := handler.bankTranc.GetDepositHistory("")
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	dept, next, err := handler.bankTranc.GetDepositHistory(r.Context(), opts.UserID, opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"deposits": dept}}
-	json.NewEncoder(w).Encode(response)
+	writePage(w, "deposits", dept, next)
 }
 
 func (handler *HttpHandler) GetBanks(w http.ResponseWriter, r *http.Request) {
 
-	err := handler.bankTrf.ListBanks()
+	err := handler.bankTrf.ListBanks(r.Context())
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -209,23 +207,22 @@ func (handler *HttpHandler) GetBanks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// DepositAccount creates a deposit account for the business at anchor and returns it, to be used by admin.
 func (handler *HttpHandler) DepositAccount(w http.ResponseWriter, r *http.Request) {
-	err := handler.virtualAcc.CreateDepositAccount()
+	account, err := handler.virtualAcc.CreateDepositAccount(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success"}
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"account": account}}
 	json.NewEncoder(w).Encode(response)
 }
 
 // Call this fucction before payments.
 func (handler *HttpHandler) checkPayment(bal, payValue float64) (newBal float64, canPay bool, err error) {
-	paymentERROR := errors.New("insufficient funds to complete payment")
+	paymentERROR := fmt.Errorf("%w, could not complete payment", balance.ErrInsufficientBalance)
 
 	valid, err := balance.CanPay(bal, payValue)
 	if !valid || err != nil {
@@ -239,7 +236,7 @@ func (handler *HttpHandler) checkPayment(bal, payValue float64) (newBal float64,
 
 func (handler *HttpHandler) checkTransfer(bal, amount float64) (newBal float64, canTrsf bool, err error) {
 
-	transferERROR := errors.New("insufficient funds to complete transfer")
+	transferERROR := fmt.Errorf("%w, could not complete transfer", balance.ErrInsufficientBalance)
 
 	valid, err := balance.CanTransfer(bal, amount)
 	if !valid || err != nil {
@@ -253,13 +250,13 @@ func (handler *HttpHandler) checkTransfer(bal, amount float64) (newBal float64,
 
 }
 
-func (handler *HttpHandler) updateBalance(id string, newBalance float64) error {
+func (handler *HttpHandler) updateBalance(ctx context.Context, id string, newBalance float64) error {
 
-	virtualNuban, err := handler.getVirtualNuban(id)
+	virtualNuban, err := handler.getVirtualNuban(ctx, id)
 	if err != nil {
 		return err
 	}
-	if err := handler.bankTranc.UpdateBalance(virtualNuban, newBalance); err != nil {
+	if err := handler.bankTranc.UpdateBalance(ctx, virtualNuban, newBalance); err != nil {
 		return err
 	}
 
@@ -267,8 +264,8 @@ func (handler *HttpHandler) updateBalance(id string, newBalance float64) error {
 
 }
 
-func (handler *HttpHandler) getVirtualNuban(id string) (string, error) {
-	acc_details, err := handler.store.GetVirtualNuban(id)
+func (handler *HttpHandler) getVirtualNuban(ctx context.Context, id string) (string, error) {
+	acc_details, err := handler.store.GetVirtualNuban(ctx, id)
 	if err != nil {
 		handler.logger.Error(err.Error())
 		return "", err
@@ -277,14 +274,14 @@ func (handler *HttpHandler) getVirtualNuban(id string) (string, error) {
 	return acc_details.VirtualAccountID, nil
 }
 
-func (handler *HttpHandler) refreshBalance(name string) error {
-	virtualNuban, err := handler.getVirtualNuban(name)
+func (handler *HttpHandler) refreshBalance(ctx context.Context, name string) error {
+	account, err := handler.store.GetVirtualNuban(ctx, name)
 	if err != nil {
 		handler.logger.Error(err.Error())
 		return err
 	}
 
-	if err := handler.bankDep.Deposit(virtualNuban); err != nil {
+	if err := handler.bankDep.Deposit(ctx, account); err != nil {
 		handler.logger.Error(err.Error())
 		return err
 	}
@@ -292,9 +289,9 @@ func (handler *HttpHandler) refreshBalance(name string) error {
 	return nil
 }
 
-func (handler *HttpHandler) getBalance(virtualNuban string) (balance float64, err error) {
+func (handler *HttpHandler) getBalance(ctx context.Context, virtualNuban string) (balance float64, err error) {
 
-	bal, err := handler.bankTranc.GetBalance(virtualNuban)
+	bal, err := handler.bankTranc.GetBalance(ctx, virtualNuban)
 	if err != nil {
 		return 0, err
 	}
@@ -309,12 +306,15 @@ func (handler *HttpHandler) GetUserDetails(r *http.Request) (user *models.User,
 
 	claim, err := handler.jwt.ValidateToken(token)
 	if err != nil {
-		return nil, fmt.Errorf("could not get user's details: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
 	}
 
-	userDetails, err := handler.store.GetUserByID(claim.ID)
+	userDetails, err := handler.store.GetUserByID(r.Context(), claim.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: user %s not found", ErrUnauthenticated, claim.ID)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("could not get user's details: %v", err)
+		return nil, fmt.Errorf("could not get user's details: %w", err)
 	}
 
 	return userDetails, nil
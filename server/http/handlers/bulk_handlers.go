@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	terror "github.com/aremxyplug-be/lib/errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"net/http"
+	"strings"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/bulk"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// maxUploadSize is the largest csv file accepted for a bulk purchase.
+const maxUploadSize = 1 << 20
+
+// BulkAirtime buys airtime for every row of a json list or uploaded csv file.
+func (handler *HttpHandler) BulkAirtime(w http.ResponseWriter, r *http.Request) {
+	handler.createBulk(w, r, bulk.ProductAirtime)
+}
+
+// BulkData buys data for every row of a json list or uploaded csv file.
+func (handler *HttpHandler) BulkData(w http.ResponseWriter, r *http.Request) {
+	handler.createBulk(w, r, bulk.ProductData)
+}
+
+func (handler *HttpHandler) createBulk(w http.ResponseWriter, r *http.Request, product string) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	rows, err := readBulkRows(w, r, product)
+	if err != nil {
+		handler.log(r).Error("Reading bulk rows", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	batch, err := handler.bulk.Create(r.Context(), userDetails, product, rows)
+	if errors.Is(err, bulk.ErrInvalidRows) {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err, terror.WithFields(rowErrors(batch.Rows)...))
+		return
+	}
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	response := responseFormat.CustomResponse{Status: http.StatusAccepted, Message: "success", Data: map[string]interface{}{"batch_id": batch.ID, "total": batch.Total, "rows": len(batch.Rows)}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// rowErrors returns a field error for every invalid row of a batch.
+func rowErrors(rows []models.BulkRow) []terror.FieldError {
+	fieldErrs := []terror.FieldError{}
+	for _, row := range rows {
+		if row.Status == models.RowInvalid {
+			fieldErrs = append(fieldErrs, terror.FieldError{Field: fmt.Sprintf("rows[%d]", row.Row), Rule: "row", Message: row.Error})
+		}
+	}
+	return fieldErrs
+}
+
+// readBulkRows reads the rows from a csv file in the "file" field of a multipart form, or from a json list.
+func readBulkRows(w http.ResponseWriter, r *http.Request, product string) ([]models.BulkRow, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("could not read csv file: %v", err)
+		}
+		defer file.Close()
+
+		return bulk.ParseCSV(file, product)
+	}
+
+	rows := []models.BulkRow{}
+	if err := json.NewDecoder(r.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetBulkBatch returns the status of a batch and each of its rows.
+func (handler *HttpHandler) GetBulkBatch(w http.ResponseWriter, r *http.Request) {
+	batch, ok := handler.getBulkBatch(w, r)
+	if !ok {
+		return
+	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"batch": batch}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// BulkBatchReport downloads the per row status of a batch as csv.
+func (handler *HttpHandler) BulkBatchReport(w http.ResponseWriter, r *http.Request) {
+	batch, ok := handler.getBulkBatch(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "bulk-"+batch.ID+".csv"))
+	if err := bulk.WriteReport(w, batch); err != nil {
+		handler.log(r).Error("error writing bulk report", zap.String("batch_id", batch.ID), zap.Error(err))
+	}
+}
+
+func (handler *HttpHandler) getBulkBatch(w http.ResponseWriter, r *http.Request) (models.BulkBatch, bool) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return models.BulkBatch{}, false
+	}
+
+	batch, err := handler.bulk.Get(r.Context(), chi.URLParam(r, "id"), userDetails.Username)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return models.BulkBatch{}, false
+	}
+
+	return batch, true
+}
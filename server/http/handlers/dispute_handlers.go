@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aremxyplug-be/lib/dispute"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxDisputeSize is the largest ticket accepted, its attachments and form fields together.
+const maxDisputeSize = dispute.MaxAttachments*dispute.MaxAttachmentSize + 1<<20
+
+type commentRequest struct {
+	Comment string `json:"comment"`
+}
+
+type disputeStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+type disputeRefundRequest struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+}
+
+// OpenDispute opens a ticket against one of the user's transactions. The ticket is sent as a multipart
+// form with its files in the "attachments" field, or as json without attachments.
+func (handler *HttpHandler) OpenDispute(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	ticket, attachments, err := readDispute(w, r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	result, err := handler.disputes.Open(r.Context(), userDetails.ID, ticket, attachments)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeDispute(w, http.StatusCreated, result)
+}
+
+func readDispute(w http.ResponseWriter, r *http.Request) (dispute.Ticket, []dispute.Attachment, error) {
+	ticket := dispute.Ticket{}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := json.NewDecoder(r.Body).Decode(&ticket)
+		return ticket, nil, err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxDisputeSize)
+	if err := r.ParseMultipartForm(maxDisputeSize); err != nil {
+		return ticket, nil, fmt.Errorf("could not read dispute form: %v", err)
+	}
+	ticket.TransactionID = r.FormValue("transaction_id")
+	ticket.Category = r.FormValue("category")
+	ticket.Description = r.FormValue("description")
+
+	var attachments []dispute.Attachment
+	for _, header := range r.MultipartForm.File["attachments"] {
+		file, err := header.Open()
+		if err != nil {
+			return ticket, nil, fmt.Errorf("could not read attachment %s: %v", header.Filename, err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return ticket, nil, fmt.Errorf("could not read attachment %s: %v", header.Filename, err)
+		}
+		attachments = append(attachments, dispute.Attachment{Name: header.Filename, Data: data})
+	}
+
+	return ticket, attachments, nil
+}
+
+// GetDisputes returns a page of the user's tickets, in one status with the status query parameter.
+func (handler *HttpHandler) GetDisputes(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	opts, ok := handler.listOptions(w, r)
+	if !ok {
+		return
+	}
+	opts.UserID = userDetails.ID
+
+	disputes, next, err := handler.disputes.List(r.Context(), opts)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writePage(w, "disputes", disputes, next)
+}
+
+// GetDispute returns one of the user's tickets with its timeline.
+func (handler *HttpHandler) GetDispute(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	result, err := handler.disputes.Get(r.Context(), userDetails.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeDispute(w, http.StatusOK, result)
+}
+
+// CommentOnDispute adds the user's comment to one of their tickets.
+func (handler *HttpHandler) CommentOnDispute(w http.ResponseWriter, r *http.Request) {
+	handler.commentOnDispute(w, r, false)
+}
+
+// GetDisputeAttachment downloads an attachment of one of the user's tickets.
+func (handler *HttpHandler) GetDisputeAttachment(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	handler.writeDisputeAttachment(w, r, userDetails.ID)
+}
+
+// GetSupportDisputes returns a page of all the tickets for support, of one user with the user_id query
+// parameter and in one status with status.
+func (handler *HttpHandler) GetSupportDisputes(w http.ResponseWriter, r *http.Request) {
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	disputes, next, err := handler.disputes.List(r.Context(), opts)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writePage(w, "disputes", disputes, next)
+}
+
+// GetSupportDispute returns any ticket for support.
+func (handler *HttpHandler) GetSupportDispute(w http.ResponseWriter, r *http.Request) {
+	result, err := handler.disputes.Get(r.Context(), "", chi.URLParam(r, "id"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeDispute(w, http.StatusOK, result)
+}
+
+// GetSupportDisputeAttachment downloads an attachment of any ticket for support.
+func (handler *HttpHandler) GetSupportDisputeAttachment(w http.ResponseWriter, r *http.Request) {
+	handler.writeDisputeAttachment(w, r, "")
+}
+
+// SupportCommentOnDispute adds a comment of support to a ticket, the user is emailed it.
+func (handler *HttpHandler) SupportCommentOnDispute(w http.ResponseWriter, r *http.Request) {
+	handler.commentOnDispute(w, r, true)
+}
+
+// SetDisputeStatus moves a ticket to another status.
+func (handler *HttpHandler) SetDisputeStatus(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := disputeStatusRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	result, err := handler.disputes.SetStatus(r.Context(), userDetails, chi.URLParam(r, "id"), req.Status, req.Note)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeDispute(w, http.StatusOK, result)
+}
+
+// RequeryDispute asks the provider for the status of the disputed transaction.
+func (handler *HttpHandler) RequeryDispute(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	result, requery, err := handler.disputes.Requery(r.Context(), userDetails, chi.URLParam(r, "id"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"dispute": result, "requery": requery}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// RefundDispute requests a refund of the disputed transaction, of all of it when no amount is given.
+func (handler *HttpHandler) RefundDispute(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := disputeRefundRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	result, refund, err := handler.disputes.Refund(r.Context(), userDetails, chi.URLParam(r, "id"), req.Amount, req.Note)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"dispute": result, "refund": refund}}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (handler *HttpHandler) commentOnDispute(w http.ResponseWriter, r *http.Request, staff bool) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := commentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	result, err := handler.disputes.Comment(r.Context(), userDetails, chi.URLParam(r, "id"), staff, req.Comment)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeDispute(w, http.StatusOK, result)
+}
+
+func (handler *HttpHandler) writeDisputeAttachment(w http.ResponseWriter, r *http.Request, userID string) {
+	file, err := handler.disputes.Attachment(r.Context(), userID, chi.URLParam(r, "id"), chi.URLParam(r, "attachmentID"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Data)
+}
+
+func writeDispute(w http.ResponseWriter, status int, result interface{}) {
+	w.WriteHeader(status)
+	response := responseFormat.CustomResponse{Status: status, Message: "success", Data: map[string]interface{}{"dispute": result}}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/lib/balance"
+	bankacc "github.com/aremxyplug-be/lib/bank/bank_acc"
+	"github.com/aremxyplug-be/lib/bank/deposit"
+	"github.com/aremxyplug-be/lib/bank/transfer"
+	elect "github.com/aremxyplug-be/lib/bills/electricity"
+	"github.com/aremxyplug-be/lib/bills/tvsub"
+	"github.com/aremxyplug-be/lib/bulk"
+	"github.com/aremxyplug-be/lib/dispute"
+	terror "github.com/aremxyplug-be/lib/errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/float"
+	"github.com/aremxyplug-be/lib/httpclient"
+	"github.com/aremxyplug-be/lib/notification"
+	"github.com/aremxyplug-be/lib/phone"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/aremxyplug-be/lib/reconcile"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/requery"
+	"github.com/aremxyplug-be/lib/scheduler"
+	"github.com/aremxyplug-be/lib/statement"
+	telcomdata "github.com/aremxyplug-be/lib/telcom/data"
+	"github.com/aremxyplug-be/lib/telcom/edu"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/tracing"
+	"github.com/aremxyplug-be/lib/wallet"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// ErrUnauthenticated is returned by GetUserDetails when the request has no valid token or its user is gone.
+var ErrUnauthenticated = errors.New("could not authenticate the user")
+
+// domainErrors maps the errors returned by the lib packages to error codes, the first match wins.
+var domainErrors = []struct {
+	err  error
+	code int
+}{
+	{ErrUnauthenticated, errorvalues.InvalidTokenErr},
+	{db.ErrInvalidCursor, errorvalues.InvalidRequestErr},
+
+	{wallet.ErrInsufficientFunds, errorvalues.InsufficientFundsErr},
+	{balance.ErrInsufficientBalance, errorvalues.InsufficientFundsErr},
+	{wallet.ErrNoAccount, errorvalues.CustomerNotFound},
+	{wallet.ErrInvalidAmount, errorvalues.InvalidRequestErr},
+
+	{transfer.ErrKYCLimit, errorvalues.KYCLimitErr},
+	{transfer.ErrTransferRejected, errorvalues.ProviderErr},
+	{transfer.ErrTransferUnconfirmed, errorvalues.TimeoutErr},
+	{transfer.ErrAccountValidationFailed, errorvalues.InvalidRequestErr},
+	{transfer.ErrAPIConnectionFailed, errorvalues.ProviderErr},
+	{transfer.ErrCounterpartyCreationFailed, errorvalues.ProviderErr},
+	{deposit.ErrAPIConnectionFailed, errorvalues.ProviderErr},
+	{deposit.ErrCounterpartyCreationFailed, errorvalues.ProviderErr},
+	{deposit.ErrEmptyVirtualNuban, errorvalues.CustomerNotFound},
+	{bankacc.ErrAccountValidationFailed, errorvalues.InvalidRequestErr},
+	{bankacc.ErrAPIConnectionFailed, errorvalues.ProviderErr},
+	{bankacc.ErrCounterpartyCreationFailed, errorvalues.ProviderErr},
+	{bankacc.ErrCreatingDepositAccount, errorvalues.ProviderErr},
+
+	{phone.ErrInvalidNumber, errorvalues.InvalidPhoneNumberError},
+	{phone.ErrUnknownNetwork, errorvalues.InvalidRequestErr},
+	{plans.ErrUnknownNetwork, errorvalues.InvalidRequestErr},
+	{telcomdata.ErrUnknownPlan, errorvalues.InvalidRequestErr},
+	{telcomdata.ErrNetworkMismatch, errorvalues.InvalidRequestErr},
+	{telcomdata.ErrInvalidPins, errorvalues.InvalidRequestErr},
+	{telcomdata.ErrProviderFailed, errorvalues.ProviderErr},
+	{edu.ErrProviderFailed, errorvalues.ProviderErr},
+	{float.ErrUnknownProvider, errorvalues.InvalidRequestErr},
+	{reconcile.ErrUnknownProvider, errorvalues.InvalidRequestErr},
+	{reconcile.ErrNoAPI, errorvalues.InvalidRequestErr},
+	{reconcile.ErrInvalidDate, errorvalues.InvalidRequestErr},
+	{reconcile.ErrInvalidCSV, errorvalues.InvalidRequestErr},
+	{reconcile.ErrNoResolution, errorvalues.InvalidRequestErr},
+	{reconcile.ErrExceptionNotFound, errorvalues.DatabaseNotFoundError},
+	{receipt.ErrUnknownFormat, errorvalues.InvalidRequestErr},
+	{receipt.ErrUnknownTransaction, errorvalues.DatabaseNotFoundError},
+	{tvsub.ErrUnknownProvider, errorvalues.InvalidRequestErr},
+	{tvsub.ErrUnknownPackage, errorvalues.InvalidRequestErr},
+	{tvsub.ErrInvalidSmartCard, errorvalues.InvalidRequestErr},
+	{tvsub.ErrInvalidSubType, errorvalues.InvalidRequestErr},
+	{tvsub.ErrRenewalUnavailable, errorvalues.InvalidRequestErr},
+	{tvsub.ErrProviderFailed, errorvalues.ProviderErr},
+	{elect.ErrMeterRequired, errorvalues.InvalidRequestErr},
+	{elect.ErrInvalidMeter, errorvalues.InvalidRequestErr},
+	{elect.ErrProviderFailed, errorvalues.ProviderErr},
+
+	{scheduler.ErrOrderNotFound, errorvalues.DatabaseNotFoundError},
+	{scheduler.ErrInvalidStatus, errorvalues.ConflictErr},
+	{scheduler.ErrInvalidCadence, errorvalues.InvalidRequestErr},
+	{scheduler.ErrInvalidProduct, errorvalues.InvalidRequestErr},
+	{scheduler.ErrMissingDetails, errorvalues.InvalidRequestErr},
+	{scheduler.ErrInvalidDetails, errorvalues.InvalidRequestErr},
+	{scheduler.ErrInvalidDates, errorvalues.InvalidRequestErr},
+	{scheduler.ErrInvalidMaxAmount, errorvalues.InvalidRequestErr},
+	{bulk.ErrBatchNotFound, errorvalues.DatabaseNotFoundError},
+	{bulk.ErrNoRows, errorvalues.InvalidRequestErr},
+	{bulk.ErrTooManyRows, errorvalues.InvalidRequestErr},
+	{bulk.ErrInvalidRows, errorvalues.InvalidRequestErr},
+	{bulk.ErrInvalidCSV, errorvalues.InvalidRequestErr},
+	{bulk.ErrInvalidProduct, errorvalues.InvalidRequestErr},
+	{statement.ErrInvalidPeriod, errorvalues.InvalidRequestErr},
+	{statement.ErrUnknownFormat, errorvalues.InvalidRequestErr},
+	{statement.ErrNoEmail, errorvalues.InvalidRequestErr},
+	{refund.ErrTransactionNotFound, errorvalues.DatabaseNotFoundError},
+	{refund.ErrRefundNotFound, errorvalues.DatabaseNotFoundError},
+	{refund.ErrNotRefundable, errorvalues.InvalidRequestErr},
+	{refund.ErrInvalidAmount, errorvalues.InvalidRequestErr},
+	{refund.ErrReasonRequired, errorvalues.InvalidRequestErr},
+	{refund.ErrRefundExists, errorvalues.ConflictErr},
+	{refund.ErrAlreadyReturned, errorvalues.ConflictErr},
+	{refund.ErrNotFailed, errorvalues.InvalidRequestErr},
+	{refund.ErrNotDebited, errorvalues.InvalidRequestErr},
+	{refund.ErrInvalidStatus, errorvalues.ConflictErr},
+	{dispute.ErrTransactionNotFound, errorvalues.DatabaseNotFoundError},
+	{dispute.ErrDisputeNotFound, errorvalues.DatabaseNotFoundError},
+	{dispute.ErrAttachmentNotFound, errorvalues.DatabaseNotFoundError},
+	{dispute.ErrInvalidCategory, errorvalues.InvalidRequestErr},
+	{dispute.ErrDescriptionRequired, errorvalues.InvalidRequestErr},
+	{dispute.ErrTooManyAttachments, errorvalues.InvalidRequestErr},
+	{dispute.ErrInvalidAttachment, errorvalues.InvalidRequestErr},
+	{dispute.ErrCommentRequired, errorvalues.InvalidRequestErr},
+	{requery.ErrNoRequery, errorvalues.InvalidRequestErr},
+	{requery.ErrFailed, errorvalues.ProviderErr},
+	{dispute.ErrDisputeExists, errorvalues.ConflictErr},
+	{dispute.ErrInvalidStatus, errorvalues.ConflictErr},
+	{dispute.ErrDisputeClosed, errorvalues.ConflictErr},
+	{dispute.ErrNotStaff, errorvalues.ForbiddenErr},
+	{notification.ErrNotificationNotFound, errorvalues.DatabaseNotFoundError},
+	{notification.ErrInvalidStatus, errorvalues.InvalidRequestErr},
+
+	{httpclient.ErrCircuitOpen, errorvalues.ProviderErr},
+	{httpclient.ErrInsufficientFloat, errorvalues.ProviderErr},
+	{mongo.ErrNoDocuments, errorvalues.DatabaseNotFoundError},
+}
+
+// writeError writes err as a Terror with the trace id of the request, or its request id when it is not traced. Cancelled and timed out
+// requests get their own codes, otherwise the code is taken from err when it is a Terror or a known domain error, or the given code is used.
+// Only Terrors and domain errors the client can act on carry their own detail, the others get the message of their code so that store
+// and provider errors are only logged.
+func (handler *HttpHandler) writeError(w http.ResponseWriter, r *http.Request, code int, err error, optionalAttrs ...terror.TerrorOptionalAttrs) {
+	var detail string
+
+	var t *terror.Terror
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = errorvalues.RequestCanceledErr
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		code = errorvalues.TimeoutErr
+	case errors.As(err, &t):
+		code, detail = t.Code(), t.Detail()
+	default:
+		for _, domainErr := range domainErrors {
+			if errors.Is(err, domainErr.err) {
+				code, detail = domainErr.code, domainDetail(err, domainErr.err, domainErr.code)
+				break
+			}
+		}
+	}
+	if detail == "" {
+		detail = errorvalues.Message(code)
+	}
+
+	traceID := tracing.CorrelationID(r.Context())
+	optionalAttrs = append(optionalAttrs, terror.WithTraceID(traceID), terror.WithInstance(r.URL.Path))
+	t = errorvalues.New(code, detail, optionalAttrs...)
+
+	if t.Status() >= http.StatusInternalServerError {
+		handler.log(r).Error("request failed", zap.String("trace_id", traceID), zap.String("path", r.URL.Path), zap.Int("code", code), zap.Error(err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(t.Status())
+	w.Write([]byte(t.Error()))
+}
+
+// domainDetail returns the detail of err matched to the domain error target. A client error carries what the caller got
+// wrong, e.g. the line of a bad csv, a failure on the provider's or the store's side is only named.
+func domainDetail(err, target error, code int) string {
+	if target == mongo.ErrNoDocuments {
+		return ""
+	}
+	if errorvalues.Status(code) >= http.StatusInternalServerError {
+		return target.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aremxyplug-be/lib/bulk"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	telcomdata "github.com/aremxyplug-be/lib/telcom/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+func TestWriteError(t *testing.T) {
+	var tests = []struct {
+		name       string
+		code       int
+		err        error
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:       "Test store error is not shown",
+			code:       errorvalues.InternalServerError,
+			err:        fmt.Errorf("failed to get balance: %w", errors.New("connection() error occurred during connection handshake")),
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: errorvalues.Message(errorvalues.InternalServerError),
+		},
+		{
+			name:       "Test not found",
+			code:       errorvalues.InternalServerError,
+			err:        fmt.Errorf("failed to get order: %w", mongo.ErrNoDocuments),
+			wantStatus: http.StatusNotFound,
+			wantDetail: errorvalues.Message(errorvalues.DatabaseNotFoundError),
+		},
+		{
+			name:       "Test unauthenticated",
+			code:       errorvalues.InternalServerError,
+			err:        fmt.Errorf("%w: token is expired", ErrUnauthenticated),
+			wantStatus: http.StatusUnauthorized,
+			wantDetail: "could not authenticate the user: token is expired",
+		},
+		{
+			name:       "Test provider error is only named",
+			code:       errorvalues.InternalServerError,
+			err:        fmt.Errorf("%w: 500 Internal Server Error from 10.0.0.3", telcomdata.ErrProviderFailed),
+			wantStatus: http.StatusBadGateway,
+			wantDetail: telcomdata.ErrProviderFailed.Error(),
+		},
+		{
+			name:       "Test client error keeps its detail",
+			code:       errorvalues.InternalServerError,
+			err:        fmt.Errorf("%w: missing %q column", bulk.ErrInvalidCSV, "phone"),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: fmt.Sprintf("%v: missing \"phone\" column", bulk.ErrInvalidCSV),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &HttpHandler{logger: zap.NewNop()}
+			w := httptest.NewRecorder()
+			handler.writeError(w, httptest.NewRequest(http.MethodGet, "/api/v1/balance", nil), tt.code, tt.err)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			var body struct {
+				Error struct {
+					Detail string `json:"detail"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantDetail, body.Error.Detail)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
 
 	// "fmt"
 	"net/http"
@@ -105,17 +107,13 @@ func (handler *HttpHandler) addPoints(w http.ResponseWriter, r *http.Request) {
 	// TODO: call the addPoints method after the neccessary conditions has been met
 	user, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
 	var points int
-	if err := handler.point.UpdatePoints(user.ID, points); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+	if err := handler.point.UpdatePoints(r.Context(), user.ID, points); err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 }
@@ -124,9 +122,7 @@ func (handler *HttpHandler) Pin(w http.ResponseWriter, r *http.Request) {
 
 	user, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -138,9 +134,7 @@ func (handler *HttpHandler) Pin(w http.ResponseWriter, r *http.Request) {
 
 		newPin := newPinInput{}
 		if err := json.NewDecoder(r.Body).Decode(&newPin); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
 
@@ -149,13 +143,13 @@ func (handler *HttpHandler) Pin(w http.ResponseWriter, r *http.Request) {
 			Pin:    newPin.Pin,
 		}
 
-		if err := handler.pin.SavePin(pin); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		if err := handler.pin.SavePin(r.Context(), pin); err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
+		handler.notifySecurity(r, user.ID, "Transaction pin set", "A transaction pin was set on your account.")
+
 		w.WriteHeader(http.StatusCreated)
 		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "success", Data: map[string]interface{}{"msg": "user pin created successfully"}}
 		json.NewEncoder(w).Encode(response)
@@ -171,19 +165,17 @@ func (handler *HttpHandler) Pin(w http.ResponseWriter, r *http.Request) {
 		updatePin := userPin{}
 
 		if err := json.NewDecoder(r.Body).Decode(&updatePin); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
 
-		if err := handler.pin.UpdatePin(user.ID, updatePin.Pin); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		if err := handler.pin.UpdatePin(r.Context(), user.ID, updatePin.Pin); err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
+		handler.notifySecurity(r, user.ID, "Transaction pin changed", "Your transaction pin was changed, contact support if it was not you.")
+
 		w.WriteHeader(http.StatusOK)
 		response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"msg": "user pin updated successfully"}}
 		json.NewEncoder(w).Encode(response)
@@ -195,9 +187,7 @@ func (handler *HttpHandler) Pin(w http.ResponseWriter, r *http.Request) {
 func (handler *HttpHandler) VerifyPIN(w http.ResponseWriter, r *http.Request) {
 	user, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -208,26 +198,20 @@ func (handler *HttpHandler) VerifyPIN(w http.ResponseWriter, r *http.Request) {
 	pin := userPin{}
 
 	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
-	valid, err := handler.pin.VerifyPin(user.ID, pin.Pin)
+	valid, err := handler.pin.VerifyPin(r.Context(), user.ID, pin.Pin)
 	if err != nil {
 		if !valid {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 	}
 
 	if !valid {
-		w.WriteHeader(http.StatusBadRequest)
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": "incorrect pin"}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("incorrect pin"))
 		return
 	}
 
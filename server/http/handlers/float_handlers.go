@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/go-chi/chi/v5"
+)
+
+// floatHistoryPeriod is the history returned when no from is given.
+const floatHistoryPeriod = 7 * 24 * time.Hour
+
+// GetProviderFloats returns the last balance checked at every provider.
+func (handler *HttpHandler) GetProviderFloats(w http.ResponseWriter, r *http.Request) {
+	balances, err := handler.floats.Latest(r.Context())
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"floats": balances}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetProviderFloatHistory returns the balances checked at a provider between the from and to query
+// parameters, the last seven days by default.
+func (handler *HttpHandler) GetProviderFloatHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, err := parseTime(query.Get("from"), false)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("from must be a date (2006-01-02) or a time (RFC 3339)"))
+		return
+	}
+	to, err := parseTime(query.Get("to"), true)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("to must be a date (2006-01-02) or a time (RFC 3339)"))
+		return
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-floatHistoryPeriod)
+	}
+
+	balances, err := handler.floats.History(r.Context(), chi.URLParam(r, "provider"), from, to)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"floats": balances}}
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,7 +16,6 @@ import (
 	"github.com/aremxyplug-be/lib/errorvalues"
 	"github.com/aremxyplug-be/lib/responseFormat"
 	"github.com/aremxyplug-be/types/dto"
-	"github.com/go-chi/render"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
@@ -38,37 +39,25 @@ func (handler *HttpHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 
 	// validate the request body
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-
 		//response := responseFormat.RespondWithError(w, http.StatusBadRequest, err.Error())
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
-	if !handler.isValidNewUser(user) {
-		response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "email exist", Data: map[string]interface{}{"data": "user already exist"}}
-		json.NewEncoder(w).Encode(response)
+	if !handler.isValidNewUser(r.Context(), user) {
+		handler.writeError(w, r, errorvalues.DuplicatedCustomerEmailError, errors.New("user already exist"))
 		return
 	}
 
 	// use the validator library to validate required fields
-	if validationErr := validate.Struct(&user); validationErr != nil {
-		w.WriteHeader(http.StatusBadRequest)
-
-		response := responseFormat.CustomResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    map[string]interface{}{"data": validationErr.Error()},
-		}
-		json.NewEncoder(w).Encode(response)
+	if !handler.validateRequest(w, r, &user) {
 		return
 	}
 	timestamp := handler.timeHelper.Now().Unix()
 	userId := handler.idGenerator.Generate()
 	hashedPassword, err := handler.encrypt.GenerateFromPassword(user.Password)
 	if err != nil {
-		handler.logger.Error("fail to generate password", zap.Error(err))
+		handler.log(r).Error("fail to generate password", zap.Error(err))
 		return
 	}
 
@@ -90,11 +79,9 @@ func (handler *HttpHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		HasPin:         false,
 	}
 
-	err = handler.store.SaveUser(newUser)
+	err = handler.store.SaveUser(r.Context(), newUser)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -109,26 +96,20 @@ func (handler *HttpHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// validate the request body
 	if err := json.NewDecoder(r.Body).Decode(&userlogin); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
-	user, err := handler.store.GetUserByUsernameOrEmail(userlogin.Email, userlogin.Username)
+	user, err := handler.store.GetUserByUsernameOrEmail(r.Context(), userlogin.Email, userlogin.Username)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		response := responseFormat.CustomResponse{Status: http.StatusNotFound, Message: "user not found", Data: map[string]interface{}{"data": "user not found"}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.CustomerNotFound, errors.New("user not found"))
 		return
 	}
 	hashedPassword := user.Password
 
 	ok := handler.encrypt.ComparePasscode(userlogin.Password, hashedPassword)
 	if !ok {
-		handler.logger.Error("store validating password")
-		w.WriteHeader(http.StatusUnauthorized)
-		response := responseFormat.CustomResponse{Status: http.StatusUnauthorized, Message: "error", Data: map[string]interface{}{"data": "password incorrect"}}
-		json.NewEncoder(w).Encode(response)
+		handler.log(r).Error("store validating password")
+		handler.writeError(w, r, errorvalues.InvalidAuthenticationError, errors.New("password incorrect"))
 		return
 	}
 
@@ -142,10 +123,8 @@ func (handler *HttpHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	jwtToken, err := handler.jwt.GenerateTokenWithExpiration(claims, handler.authTokenDuration)
 	if err != nil {
-		handler.logger.Error("fail to generate token", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.log(r).Error("fail to generate token", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	userResponse := dto.UserResponse{
@@ -157,17 +136,17 @@ func (handler *HttpHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	refreshToken, err := handler.jwt.GenerateTokenWithExpiration(refreshTokenClaims, handler.refreshTokenDuration)
 	if err != nil {
-		handler.logger.Error("fail to generate refresh token", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.log(r).Error("fail to generate refresh token", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
+	handler.notifySecurity(r, user.ID, "New login", "A new login to your account was made, reset your password if it was not you.")
+
 	hasPin := user.HasPin
 
 	if !hasPin {
-		handler.logger.Warn("pin not yet set", zap.Any("userID", user.ID))
+		handler.log(r).Warn("pin not yet set", zap.Any("userID", user.ID))
 		w.Header().Set("Authorization", jwtToken)
 		w.WriteHeader(http.StatusAccepted)
 		response := responseFormat.CustomResponse{Status: http.StatusAccepted, Message: "success", Data: map[string]interface{}{"msg": "user's pin not set"}}
@@ -183,7 +162,7 @@ func (handler *HttpHandler) Login(w http.ResponseWriter, r *http.Request) {
 				json.NewEncoder(w).Encode(response)
 				return
 			}
-			handler.logger.Error("failed to load user's balance", zap.Error(err))
+			handler.log(r).Error("failed to load user's balance", zap.Error(err))
 			w.WriteHeader(http.StatusInternalServerError)
 			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
 			json.NewEncoder(w).Encode(response)
@@ -206,15 +185,14 @@ func (handler *HttpHandler) ForgotPassword(w http.ResponseWriter, r *http.Reques
 
 	// validate the request body
 	if err := json.NewDecoder(r.Body).Decode(&userlogin); err != nil {
-		respondWithError(w, http.StatusBadRequest, "error", err)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
 	// Checking if the user exists (replace with your actual user lookup logic)
-	user, err := handler.store.GetUserByEmail(userlogin.Email)
+	user, err := handler.store.GetUserByEmail(r.Context(), userlogin.Email)
 	if err != nil || user == nil {
-		render.Status(r, http.StatusUnauthorized)
-		render.JSON(w, r, map[string]string{"error": "Sorry, this user does not exist"})
+		handler.writeError(w, r, errorvalues.CustomerNotFound, errors.New("sorry, this user does not exist"))
 		return
 	}
 
@@ -224,8 +202,8 @@ func (handler *HttpHandler) ForgotPassword(w http.ResponseWriter, r *http.Reques
 
 	token, err := handler.jwt.GenerateToken(claims)
 	if err != nil {
-		handler.logger.Error("fail to generate token", zap.Error(err))
-		respondWithError(w, http.StatusInternalServerError, "error", err)
+		handler.log(r).Error("fail to generate token", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	//var uri string
@@ -255,11 +233,11 @@ func (handler *HttpHandler) ForgotPassword(w http.ResponseWriter, r *http.Reques
 	err = handler.emailClient.Send(&message)
 	fmt.Println("email sent")
 	if err != nil {
-		handler.logger.Error("error sending password reset email", zap.String("target", user.Email), zap.Error(err))
-		respondWithError(w, http.StatusInternalServerError, "error", err)
+		handler.log(r).Error("error sending password reset email", zap.String("target", user.Email), zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
-	handler.logger.Info("password reset email sent", zap.String("target", user.Email))
+	handler.log(r).Info("password reset email sent", zap.String("target", user.Email))
 	w.WriteHeader(http.StatusOK)
 	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"msg": "email sent successfully"}}
 	json.NewEncoder(w).Encode(response)
@@ -274,10 +252,8 @@ func (handler *HttpHandler) ValidateToken(w http.ResponseWriter, r *http.Request
 
 	_, err := handler.jwt.ValidateToken(token)
 	if err != nil {
-		handler.logger.Error("failed to validate token", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": "link either invalid or expired, request for a new link"}}
-		json.NewEncoder(w).Encode(response)
+		handler.log(r).Error("failed to validate token", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InvalidTokenErr, errors.New("link either invalid or expired, request for a new link"))
 		return
 	}
 
@@ -287,6 +263,23 @@ func (handler *HttpHandler) ValidateToken(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
+// RequireStaff lets only support and admin users through to the routes it guards. The role is read from
+// the stored user on every request, so taking it away applies at once.
+func (handler *HttpHandler) RequireStaff(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userDetails, err := handler.GetUserDetails(r)
+		if err != nil {
+			handler.writeError(w, r, errorvalues.InvalidTokenErr, err)
+			return
+		}
+		if !userDetails.IsStaff() {
+			handler.writeError(w, r, errorvalues.ForbiddenErr, errors.New("only support staff can access this route"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // ResetPassword
 func (handler *HttpHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	//params := chi.URLParam(r, "token")
@@ -305,22 +298,21 @@ func (handler *HttpHandler) ResetPassword(w http.ResponseWriter, r *http.Request
 	json.NewDecoder(r.Body).Decode(&newPassword)
 	hashedPassword, err := handler.encrypt.GenerateFromPassword(newPassword.Password)
 	if err != nil {
-		handler.logger.Error("error hashing password", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": "something unexpected occured, please try again"}}
-		json.NewEncoder(w).Encode(response)
+		handler.log(r).Error("error hashing password", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, errors.New("something unexpected occured, please try again"))
 		return
 	}
 	newPassword.Password = string(hashedPassword)
 
-	err = handler.store.UpdateUserPassword(email, newPassword.Password)
+	err = handler.store.UpdateUserPassword(r.Context(), email, newPassword.Password)
 	if err != nil {
-		handler.logger.Error("failed to update password", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.log(r).Error("failed to update password", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
+	if user, err := handler.store.GetUserByEmail(r.Context(), email); err == nil {
+		handler.notifySecurity(r, user.ID, "Password changed", "Your password was changed, contact support if it was not you.")
+	}
 	w.WriteHeader(http.StatusCreated)
 	response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "success", Data: map[string]interface{}{"data": "Password updated successfully"}}
 	json.NewEncoder(w).Encode(response)
@@ -331,14 +323,14 @@ func (handler *HttpHandler) SendOTP(w http.ResponseWriter, r *http.Request) {
 
 	// Decode and validate the request body
 	if err := json.NewDecoder(r.Body).Decode(&userLogin); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
 	// Retrieve user by email
-	user, err := handler.store.GetUserByEmail(userLogin.Email)
+	user, err := handler.store.GetUserByEmail(r.Context(), userLogin.Email)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "User not found", nil)
+		handler.writeError(w, r, errorvalues.CustomerNotFound, errors.New("user not found"))
 		return
 	}
 
@@ -346,22 +338,22 @@ func (handler *HttpHandler) SendOTP(w http.ResponseWriter, r *http.Request) {
 	action := getLastPathSegment(r.URL.Path)
 	switch action {
 	case "signup":
-		if err := handler.sendOTP(user, "Sign-Up Verification", verifyEmailAlias); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Error sending verification OTP", err)
+		if err := handler.sendOTP(r.Context(), user, "Sign-Up Verification", verifyEmailAlias); err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, fmt.Errorf("error sending verification OTP: %w", err))
 			return
 		}
 		respondWithSuccess(w, http.StatusOK, "success", "Verification email sent successfully")
 
 	case "signin":
-		if err := handler.sendOTP(user, "Sign-in Verification", signInVerification); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Error sending sign-in OTP", err)
+		if err := handler.sendOTP(r.Context(), user, "Sign-in Verification", signInVerification); err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, fmt.Errorf("error sending sign-in OTP: %w", err))
 			return
 		}
 		respondWithSuccess(w, http.StatusOK, "success", "Sign-in email sent successfully")
 
 	case "resetpassword":
-		if err := handler.sendOTP(user, "Password OTP", PasswordOTPAlias); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Error sending password reset OTP", err)
+		if err := handler.sendOTP(r.Context(), user, "Password OTP", PasswordOTPAlias); err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, fmt.Errorf("error sending password reset OTP: %w", err))
 			return
 		}
 		respondWithSuccess(w, http.StatusCreated, "success", "Password reset email sent successfully")
@@ -379,26 +371,20 @@ func (handler *HttpHandler) VerifyOTP(w http.ResponseWriter, r *http.Request) {
 
 	// validate the request body
 	if err := json.NewDecoder(r.Body).Decode(&Otp); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
 	email := r.URL.Query().Get("email")
-	valid, err := handler.otp.ValidateOTP(Otp.OTP, email)
+	valid, err := handler.otp.ValidateOTP(r.Context(), Otp.OTP, email)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
 	if !valid {
-		w.WriteHeader(http.StatusBadRequest)
 		log.Println("otp verification failed at validation")
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": "otp verification failed"}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("otp verification failed"))
 		return
 	}
 
@@ -408,25 +394,25 @@ func (handler *HttpHandler) VerifyOTP(w http.ResponseWriter, r *http.Request) {
 		data := map[string]interface{}{"data": email}
 		respondWithSuccess(w, http.StatusOK, "otp verification successful", data)
 	case "signup":
-		user, err := handler.store.VerifyUser(email)
+		user, err := handler.store.VerifyUser(r.Context(), email)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "error", err)
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		err = handler.sendOTP(user, "verify-email", welcomeMessage)
+		err = handler.sendOTP(r.Context(), user, "verify-email", welcomeMessage)
 		if err != nil {
-			handler.logger.Error("error sending email verification otp", zap.String("target", user.Email), zap.Error(err))
-			respondWithError(w, http.StatusInternalServerError, "error", err)
+			handler.log(r).Error("error sending email verification otp", zap.String("target", user.Email), zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
 		data := map[string]interface{}{"data": email}
 		respondWithSuccess(w, http.StatusOK, "otp verification successful", data)
 	case "resetpassword":
-		user, err := handler.store.GetUserByEmail(email)
+		user, err := handler.store.GetUserByEmail(r.Context(), email)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "error", err)
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
@@ -436,8 +422,8 @@ func (handler *HttpHandler) VerifyOTP(w http.ResponseWriter, r *http.Request) {
 
 		jwtToken, err := handler.jwt.GenerateTokenWithExpiration(claims, handler.authTokenDuration)
 		if err != nil {
-			handler.logger.Error("fail to generate token", zap.Error(err))
-			respondWithError(w, http.StatusInternalServerError, "error", err)
+			handler.log(r).Error("fail to generate token", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
@@ -464,8 +450,8 @@ func (handler *HttpHandler) validateToken(token string) (isValid bool, response
 	}
 }
 
-func (handler *HttpHandler) sendOTP(user *models.User, title string, templateID string) error {
-	otp, err := handler.otp.GenerateOTP(user.Email)
+func (handler *HttpHandler) sendOTP(ctx context.Context, user *models.User, title string, templateID string) error {
+	otp, err := handler.otp.GenerateOTP(ctx, user.Email)
 	if err != nil {
 		return err
 	}
@@ -501,10 +487,7 @@ func (handler *HttpHandler) Testtoken(w http.ResponseWriter, r *http.Request) {
 
 	// validate the request body
 	if err := json.NewDecoder(r.Body).Decode(&tokenIn); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-
-		response := responseFormat.CustomResponse{Status: http.StatusBadRequest, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 		return
 	}
 
@@ -513,9 +496,9 @@ func (handler *HttpHandler) Testtoken(w http.ResponseWriter, r *http.Request) {
 	fmt.Println(claims)
 }
 
-func (handler *HttpHandler) isValidNewUser(user models.User) bool {
+func (handler *HttpHandler) isValidNewUser(ctx context.Context, user models.User) bool {
 
-	userDetails, err := handler.store.GetUserByEmail(user.Email)
+	userDetails, err := handler.store.GetUserByEmail(ctx, user.Email)
 	if err != nil {
 		switch err {
 		case mongodb.ErrNoDocuments:
@@ -537,9 +520,9 @@ func (handler *HttpHandler) isValidNewUser(user models.User) bool {
 // PingUser pings the api with client credentials. It not used.
 func (handler *HttpHandler) PingUser(w http.ResponseWriter, r *http.Request) {
 
-	res, err := handler.dataClient.PingUser(w)
+	res, err := handler.dataClient.PingUser(r.Context(), w)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, errorvalues.ProviderErr, err)
 		return
 	}
 
@@ -555,21 +538,6 @@ func getLastPathSegment(path string) string {
 	return parts[len(parts)-1]
 }
 
-// Helper function to respond with error
-func respondWithError(w http.ResponseWriter, statusCode int, message string, err error) {
-	w.WriteHeader(statusCode)
-	data := map[string]interface{}{"message": message}
-	if err != nil {
-		data["error"] = err.Error()
-	}
-	response := responseFormat.CustomResponse{
-		Status:  statusCode,
-		Message: "error",
-		Data:    data,
-	}
-	json.NewEncoder(w).Encode(response)
-}
-
 // Helper function to respond with success
 func respondWithSuccess(w http.ResponseWriter, statusCode int, message string, datamsg interface{}) {
 	w.WriteHeader(statusCode)
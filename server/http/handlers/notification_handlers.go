@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/notification"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type preferencesRequest struct {
+	Email bool `json:"email"`
+	SMS   bool `json:"sms"`
+	Push  bool `json:"push"`
+}
+
+// GetNotifications returns a page of the user's notifications with how many are unread, the status query
+// parameter selects the unread or read ones.
+func (handler *HttpHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	opts, ok := handler.listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	messages, next, err := handler.notifier.List(r.Context(), userDetails.ID, opts)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+	unread, err := handler.notifier.UnreadCount(r.Context(), userDetails.ID)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{
+		"notifications": messages, "unread_count": unread, "next_cursor": next,
+	}}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (handler *HttpHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	unread, err := handler.notifier.UnreadCount(r.Context(), userDetails.ID)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"unread_count": unread}}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (handler *HttpHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	if err := handler.notifier.MarkRead(r.Context(), userDetails.ID, chi.URLParam(r, "id")); err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"data": "notification marked read"}}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (handler *HttpHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	count, err := handler.notifier.MarkAllRead(r.Context(), userDetails.ID)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"marked": count}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// NotificationPreferences returns the channels the user receives notifications on with GET and sets them
+// with PUT, the inbox keeps every notification whatever they choose.
+func (handler *HttpHandler) NotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	var prefs models.NotificationPreferences
+	if r.Method == http.MethodPut {
+		req := preferencesRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+			return
+		}
+		prefs, err = handler.notifier.SetPreferences(r.Context(), models.NotificationPreferences{
+			UserID: userDetails.ID, Email: req.Email, SMS: req.SMS, Push: req.Push,
+		})
+	} else {
+		prefs, err = handler.notifier.Preferences(r.Context(), userDetails.ID)
+	}
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"data": prefs}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// notifyPurchase tells the user how their purchase of product went, a failure to notify is only logged.
+func (handler *HttpHandler) notifyPurchase(r *http.Request, userID, product string, err error) {
+	n := notification.Notification{
+		UserID:   userID,
+		Category: notification.CategoryTransaction,
+		Title:    "Purchase successful",
+		Body:     "Your " + product + " purchase was successful.",
+	}
+	if err != nil {
+		n.Title = "Purchase failed"
+		n.Body = "Your " + product + " purchase could not be completed."
+	}
+	handler.notify(r.Context(), r, n)
+}
+
+// notifySecurity alerts the user of a change to their account, it is always emailed.
+func (handler *HttpHandler) notifySecurity(r *http.Request, userID, title, body string) {
+	handler.notify(r.Context(), r, notification.Notification{
+		UserID:   userID,
+		Category: notification.CategorySecurity,
+		Title:    title,
+		Body:     body,
+	})
+}
+
+func (handler *HttpHandler) notify(ctx context.Context, r *http.Request, n notification.Notification) {
+	if _, err := handler.notifier.Notify(ctx, n); err != nil {
+		handler.log(r).Error("failed to notify user", zap.String("category", n.Category), zap.Error(err))
+	}
+}
+
+func transferNotification(userID string, info models.TransferInfo, err error) notification.Notification {
+	n := notification.Notification{
+		UserID:   userID,
+		Category: notification.CategoryTransaction,
+		Title:    "Transfer successful",
+		Body:     fmt.Sprintf("NGN %.2f has been sent to %s.", info.Amount, info.Account_Name),
+	}
+	if err != nil {
+		n.Title = "Transfer failed"
+		n.Body = fmt.Sprintf("Your transfer of NGN %.2f to %s could not be completed.", info.Amount, info.Account_Name)
+	}
+	return n
+}
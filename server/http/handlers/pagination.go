@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aremxyplug-be/db"
+	terror "github.com/aremxyplug-be/lib/errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/responseFormat"
+)
+
+// dateLayout is the layout of the from and to query parameters when they carry no time.
+const dateLayout = "2006-01-02"
+
+// listOptions reads the page and filters of a list request from its query parameters:
+// cursor, limit, from, to, status, min_amount and max_amount. On failure a 400 is written with every
+// invalid parameter.
+func (handler *HttpHandler) listOptions(w http.ResponseWriter, r *http.Request) (db.ListOptions, bool) {
+	opts, fields := parseListOptions(r)
+	if len(fields) == 0 {
+		return opts, true
+	}
+
+	handler.writeError(w, r, errorvalues.ValidationErr, errors.New("request has invalid query parameters"), terror.WithFields(fields...))
+	return db.ListOptions{}, false
+}
+
+// adminListOptions is listOptions for the admin lists, which can also be narrowed to one user with the
+// user_id query parameter.
+func (handler *HttpHandler) adminListOptions(w http.ResponseWriter, r *http.Request) (db.ListOptions, bool) {
+	opts, ok := handler.listOptions(w, r)
+	opts.UserID = r.URL.Query().Get("user_id")
+	return opts, ok
+}
+
+func parseListOptions(r *http.Request) (db.ListOptions, []terror.FieldError) {
+	query := r.URL.Query()
+	opts := db.ListOptions{
+		Cursor: query.Get("cursor"),
+		Status: query.Get("status"),
+	}
+	var fields []terror.FieldError
+	invalid := func(field, rule, message string) {
+		fields = append(fields, terror.FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	if opts.Cursor != "" {
+		if _, err := db.DecodeCursor(opts.Cursor); err != nil {
+			invalid("cursor", "cursor", "cursor must be the next_cursor of a previous page")
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > db.MaxPageSize {
+			invalid("limit", "range", "limit must be a number from 1 to "+strconv.Itoa(db.MaxPageSize))
+		}
+		opts.Limit = limit
+	}
+
+	var err error
+	if opts.From, err = parseTime(query.Get("from"), false); err != nil {
+		invalid("from", "date", "from must be a date (2006-01-02) or a time (RFC 3339)")
+	}
+	if opts.To, err = parseTime(query.Get("to"), true); err != nil {
+		invalid("to", "date", "to must be a date (2006-01-02) or a time (RFC 3339)")
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && opts.To.Before(opts.From) {
+		invalid("to", "gtefield", "to must not be before from")
+	}
+
+	if opts.MinAmount, err = parseAmount(query.Get("min_amount")); err != nil {
+		invalid("min_amount", "amount", "min_amount must be a positive number")
+	}
+	if opts.MaxAmount, err = parseAmount(query.Get("max_amount")); err != nil {
+		invalid("max_amount", "amount", "max_amount must be a positive number")
+	}
+	if opts.MinAmount > 0 && opts.MaxAmount > 0 && opts.MaxAmount < opts.MinAmount {
+		invalid("max_amount", "gtefield", "max_amount must not be less than min_amount")
+	}
+
+	return opts, fields
+}
+
+// parseTime parses an RFC 3339 time or a date, a date given as the end of a range covers the whole day.
+func parseTime(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(dateLayout, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+func parseAmount(v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	amount, err := strconv.ParseFloat(v, 64)
+	if err != nil || amount < 0 {
+		return 0, errors.New("invalid amount")
+	}
+	return amount, nil
+}
+
+// writePage writes a page of a list under key with the cursor of the next page, which is empty on the last page.
+func writePage(w http.ResponseWriter, key string, items interface{}, next string) {
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{key: items, "next_cursor": next}}
+	json.NewEncoder(w).Encode(response)
+}
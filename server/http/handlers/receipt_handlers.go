@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/go-chi/chi/v5"
+)
+
+// TransactionReceipt serves the receipt of a transaction of the user as a PDF, or as a PNG with format=png.
+func (handler *HttpHandler) TransactionReceipt(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	format, err := receipt.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	rec, err := handler.receipts.Get(r.Context(), userDetails.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	// rendered before anything is written so a failure can still be reported as an error
+	var buf bytes.Buffer
+	if err := rec.Render(&buf, format); err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", rec.Filename(format)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
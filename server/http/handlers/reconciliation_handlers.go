@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/reconcile"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxReportSize is the largest provider report accepted for reconciliation.
+const maxReportSize = 10 << 20
+
+type pullRequest struct {
+	Date string `json:"date"`
+}
+
+type resolveRequest struct {
+	Resolution string `json:"resolution"`
+}
+
+// GetReconciliations returns a page of the reconciliation reports, of one provider with the provider query
+// parameter.
+func (handler *HttpHandler) GetReconciliations(w http.ResponseWriter, r *http.Request) {
+	opts, ok := handler.listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	reports, next, err := handler.reconciler.Reports(r.Context(), opts, r.URL.Query().Get("provider"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writePage(w, "reconciliations", reports, next)
+}
+
+// PullReconciliation reconciles a day with the transactions fetched from the provider's api.
+func (handler *HttpHandler) PullReconciliation(w http.ResponseWriter, r *http.Request) {
+	req := pullRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	report, err := handler.reconciler.Pull(r.Context(), chi.URLParam(r, "provider"), req.Date)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeReconciliation(w, report)
+}
+
+// UploadReconciliation reconciles a day with the provider's csv report, sent in the "file" field of a
+// multipart form with the day in the "date" field.
+func (handler *HttpHandler) UploadReconciliation(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxReportSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, fmt.Errorf("could not read csv file: %v", err))
+		return
+	}
+	defer file.Close()
+
+	records, err := reconcile.ParseCSV(file)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	report, err := handler.reconciler.Reconcile(r.Context(), chi.URLParam(r, "provider"), r.FormValue("date"), records)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeReconciliation(w, report)
+}
+
+// GetReconExceptions returns a page of the transactions that did not reconcile. The status query parameter
+// selects open or resolved ones and provider the exceptions of one provider.
+func (handler *HttpHandler) GetReconExceptions(w http.ResponseWriter, r *http.Request) {
+	opts, ok := handler.listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	exceptions, next, err := handler.reconciler.Exceptions(r.Context(), opts, r.URL.Query().Get("provider"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writePage(w, "exceptions", exceptions, next)
+}
+
+// ResolveReconException closes an exception with a note of how finance settled it.
+func (handler *HttpHandler) ResolveReconException(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := resolveRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	if err := handler.reconciler.Resolve(r.Context(), chi.URLParam(r, "id"), req.Resolution, userDetails.Username); err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"message": "exception resolved"}}
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeReconciliation(w http.ResponseWriter, report interface{}) {
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"reconciliation": report}}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/aremxyplug-be/lib/webhook"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// maxWebhookSize is the largest event accepted from a provider.
+const maxWebhookSize = 1 << 20
+
+type refundNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// RequestRefund records a refund support asks for on behalf of a user, it is paid to the owner of the
+// transaction.
+func (handler *HttpHandler) RequestRefund(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := refund.Request{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+	req.Source = models.RefundSourceSupport
+	req.RequestedBy = userDetails.Username
+
+	result, err := handler.refunds.Request(r.Context(), req)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeRefund(w, http.StatusCreated, result)
+}
+
+// GetRefunds returns a page of the refunds, of one user with the user_id query parameter and in one
+// status with status.
+func (handler *HttpHandler) GetRefunds(w http.ResponseWriter, r *http.Request) {
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	refunds, next, err := handler.refunds.List(r.Context(), opts)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writePage(w, "refunds", refunds, next)
+}
+
+// GetRefund returns a refund with its status trail.
+func (handler *HttpHandler) GetRefund(w http.ResponseWriter, r *http.Request) {
+	result, err := handler.refunds.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeRefund(w, http.StatusOK, result)
+}
+
+// ApproveRefund approves a refund above the approval threshold and credits it to the user's wallet.
+func (handler *HttpHandler) ApproveRefund(w http.ResponseWriter, r *http.Request) {
+	handler.decideRefund(w, r, handler.refunds.Approve)
+}
+
+// RejectRefund turns down a refund pending approval, the note tells the user why.
+func (handler *HttpHandler) RejectRefund(w http.ResponseWriter, r *http.Request) {
+	handler.decideRefund(w, r, handler.refunds.Reject)
+}
+
+func (handler *HttpHandler) decideRefund(w http.ResponseWriter, r *http.Request, decide func(ctx context.Context, id, actor, note string) (models.Refund, error)) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := refundNoteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	result, err := decide(r.Context(), chi.URLParam(r, "id"), userDetails.Username, req.Note)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	writeRefund(w, http.StatusOK, result)
+}
+
+// AnchorWebhook receives the events anchor sends about our transfers and refunds the ones the bank did
+// not pay. Events are acknowledged once handled so anchor stops sending them again.
+func (handler *HttpHandler) AnchorWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	if !webhook.VerifyAnchor(body, r.Header.Get(webhook.SignatureHeader), handler.config.Providers.Anchor.WebhookSecret) {
+		handler.writeError(w, r, errorvalues.InvalidAuthenticationError, webhook.ErrInvalidSignature)
+		return
+	}
+
+	event, err := webhook.ParseAnchorEvent(body)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	if event.TransferFailed() {
+		_, err := handler.refunds.TransferFailed(r.Context(), event.Reference, event.Reason)
+		switch {
+		case errors.Is(err, refund.ErrRefundExists), errors.Is(err, refund.ErrAlreadyReturned), errors.Is(err, refund.ErrCreditFailed):
+			// sent again, reversed when the bank rejected it, or recorded as failed for support to follow up
+		case errors.Is(err, refund.ErrTransactionNotFound), errors.Is(err, refund.ErrNotDebited):
+			handler.log(r).Warn("failed transfer has nothing to refund", zap.String("event_id", event.ID), zap.String("reference", event.Reference), zap.Error(err))
+		case err != nil:
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"message": "event received"}}
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeRefund(w http.ResponseWriter, status int, result models.Refund) {
+	w.WriteHeader(status)
+	response := responseFormat.CustomResponse{Status: status, Message: "success", Data: map[string]interface{}{"refund": result}}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"net/http"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// ScheduledOrders creates a recurring order for the user(POST) and returns the user's scheduled orders(GET)
+func (handler *HttpHandler) ScheduledOrders(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	if r.Method == "POST" {
+		order := models.ScheduledOrder{}
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+			return
+		}
+		if !handler.validateRequest(w, r, &order) {
+			return
+		}
+		if order.Airtime != nil {
+			if _, ok := handler.checkPhone(w, r, order.Airtime.Phone_no, airtime.NetworkName(order.Airtime.Network)); !ok {
+				return
+			}
+		}
+		if order.Data != nil {
+			if _, ok := handler.checkPhone(w, r, order.Data.Mobile_Num, plans.DontechNetwork(order.Data.Network)); !ok {
+				return
+			}
+		}
+
+		res, err := handler.scheduler.Create(r.Context(), userDetails, order)
+		if err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "success", Data: map[string]interface{}{"order": res}}
+		json.NewEncoder(w).Encode(response)
+	}
+
+	if r.Method == "GET" {
+		res, err := handler.scheduler.List(r.Context(), userDetails.Username)
+		if err != nil {
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
+			return
+		}
+
+		response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"orders": res}}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetScheduledOrder returns one of the user's scheduled orders with its runs.
+func (handler *HttpHandler) GetScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+	id := chi.URLParam(r, "id")
+
+	order, err := handler.scheduler.Get(r.Context(), id, userDetails.Username)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+	runs, err := handler.scheduler.Runs(r.Context(), id, userDetails.Username)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"order": order, "runs": runs}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// PauseScheduledOrder stops a scheduled order from running until it is resumed.
+func (handler *HttpHandler) PauseScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	handler.updateScheduledOrder(w, r, handler.scheduler.Pause)
+}
+
+// ResumeScheduledOrder restarts a paused scheduled order.
+func (handler *HttpHandler) ResumeScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	handler.updateScheduledOrder(w, r, handler.scheduler.Resume)
+}
+
+// CancelScheduledOrder stops a scheduled order for good.
+func (handler *HttpHandler) CancelScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	handler.updateScheduledOrder(w, r, handler.scheduler.Cancel)
+}
+
+func (handler *HttpHandler) updateScheduledOrder(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, id, username string) (models.ScheduledOrder, error)) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	order, err := update(r.Context(), chi.URLParam(r, "id"), userDetails.Username)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"order": order}}
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/aremxyplug-be/db"
@@ -11,14 +12,26 @@ import (
 	"github.com/aremxyplug-be/lib/bank/transfer"
 	elect "github.com/aremxyplug-be/lib/bills/electricity"
 	"github.com/aremxyplug-be/lib/bills/tvsub"
+	"github.com/aremxyplug-be/lib/bulk"
+	"github.com/aremxyplug-be/lib/dispute"
 	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/float"
 	"github.com/aremxyplug-be/lib/key_generator"
+	"github.com/aremxyplug-be/lib/notification"
 	otpgen "github.com/aremxyplug-be/lib/otp_gen"
 	pointredeem "github.com/aremxyplug-be/lib/point-redeem"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/aremxyplug-be/lib/reconcile"
 	"github.com/aremxyplug-be/lib/referral"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/scheduler"
+	"github.com/aremxyplug-be/lib/statement"
 	"github.com/aremxyplug-be/lib/telcom/airtime"
 	"github.com/aremxyplug-be/lib/telcom/data"
 	"github.com/aremxyplug-be/lib/telcom/edu"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/tracing"
+	"github.com/aremxyplug-be/lib/validation"
 
 	"github.com/aremxyplug-be/config"
 	"github.com/aremxyplug-be/lib/encryptor"
@@ -26,7 +39,6 @@ import (
 	"github.com/aremxyplug-be/lib/timehelper"
 	tokengenerator "github.com/aremxyplug-be/lib/tokekngenerator"
 	uuidgenerator "github.com/aremxyplug-be/lib/uuidgeneraor"
-	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -39,14 +51,14 @@ const (
 	welcomeMessage     = "verify-email"
 )
 
-var validate = validator.New()
+var validate = validation.New()
 
 type HttpHandler struct {
 	logger               *zap.Logger
 	idGenerator          idgenerator.IdGenerator
 	timeHelper           timehelper.TimeHelper
 	store                db.DataStore
-	secrets              *config.Secrets
+	config               *config.Config
 	encrypt              encryptor.Encryptor
 	jwt                  tokengenerator.TokenGenerator
 	refreshTokenDuration time.Duration
@@ -66,6 +78,16 @@ type HttpHandler struct {
 	referral             *referral.RefConfig
 	point                *pointredeem.PointConfig
 	pin                  *auth_pin.PinConfig
+	plans                *plans.Catalogue
+	scheduler            *scheduler.Scheduler
+	bulk                 *bulk.Bulk
+	receipts             *receipt.Receipts
+	statements           *statement.Statements
+	floats               *float.Monitor
+	reconciler           *reconcile.Reconciler
+	refunds              *refund.Refunds
+	disputes             *dispute.Disputes
+	notifier             *notification.Notifier
 }
 
 type HandlerOptions struct {
@@ -76,7 +98,7 @@ type HandlerOptions struct {
 	VTU         *airtime.AirtimeConn
 	TvSub       *tvsub.TvConn
 	ElectSub    *elect.ElectricConn
-	Secrets     *config.Secrets
+	Config      *config.Config
 	EmailClient emailclient.EmailClient
 	Otp         *otpgen.OTPConn
 	VirtualAcc  *bankacc.BankConfig
@@ -86,19 +108,29 @@ type HandlerOptions struct {
 	Referral    *referral.RefConfig
 	Point       *pointredeem.PointConfig
 	Pin         *auth_pin.PinConfig
+	Plans       *plans.Catalogue
+	Scheduler   *scheduler.Scheduler
+	Bulk        *bulk.Bulk
+	Receipts    *receipt.Receipts
+	Statements  *statement.Statements
+	Floats      *float.Monitor
+	Reconciler  *reconcile.Reconciler
+	Refunds     *refund.Refunds
+	Disputes    *dispute.Disputes
+	Notifier    *notification.Notifier
 }
 
 func NewHttpHandler(opt *HandlerOptions) *HttpHandler {
 	refreshTokenDuration := calculateDefaultDuration(
 		tokengenerator.RefreshTokenDuration,
-		time.Duration(opt.Secrets.RefreshTokenDuration),
+		time.Duration(opt.Config.JWT.RefreshTokenDuration),
 	)
 	authTokenDuration := calculateDefaultDuration(
 		tokengenerator.AuthTokenDuration,
-		time.Duration(opt.Secrets.AuthTokenDuration),
+		time.Duration(opt.Config.JWT.AuthTokenDuration),
 	)
 
-	tokenGeneratorPublicKey, err := key_generator.GeneratePublicKey(opt.Secrets.JWTPublicKey)
+	tokenGeneratorPublicKey, err := key_generator.GeneratePublicKey(opt.Config.JWT.PublicKey)
 	if err != nil {
 		opt.Logger.Error(
 			"error parsing public key for token encryption",
@@ -106,7 +138,7 @@ func NewHttpHandler(opt *HandlerOptions) *HttpHandler {
 		)
 	}
 
-	tokenGeneratorPrivateKey, err := key_generator.GeneratePrivateKey(opt.Secrets.JWTPrivateKey)
+	tokenGeneratorPrivateKey, err := key_generator.GeneratePrivateKey(opt.Config.JWT.PrivateKey)
 	if err != nil {
 		opt.Logger.Error(
 			"error parsing private key for token encryption",
@@ -119,7 +151,7 @@ func NewHttpHandler(opt *HandlerOptions) *HttpHandler {
 		idGenerator: idgenerator.New(),
 		timeHelper:  timehelper.New(),
 		store:       opt.Store,
-		secrets:     opt.Secrets,
+		config:      opt.Config,
 		encrypt:     encryptor.NewEncryptor(),
 		jwt: tokengenerator.New(
 			tokenGeneratorPublicKey,
@@ -141,5 +173,20 @@ func NewHttpHandler(opt *HandlerOptions) *HttpHandler {
 		bankDep:              opt.BankDep,
 		pin:                  opt.Pin,
 		point:                opt.Point,
+		plans:                opt.Plans,
+		scheduler:            opt.Scheduler,
+		bulk:                 opt.Bulk,
+		receipts:             opt.Receipts,
+		statements:           opt.Statements,
+		floats:               opt.Floats,
+		reconciler:           opt.Reconciler,
+		refunds:              opt.Refunds,
+		disputes:             opt.Disputes,
+		notifier:             opt.Notifier,
 	}
 }
+
+// log returns the handler logger with the trace of r attached.
+func (handler *HttpHandler) log(r *http.Request) *zap.Logger {
+	return tracing.Logger(r.Context(), handler.logger)
+}
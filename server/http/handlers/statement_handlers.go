@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/aremxyplug-be/lib/statement"
+)
+
+// statementRequest is the period and format of a statement to email, dates are read like the from and to
+// query parameters.
+type statementRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Format string `json:"format"`
+}
+
+// AccountStatement returns the statement of the user's wallet for the from and to query parameters as json,
+// or as a csv, pdf or xlsx file with the format parameter. The period defaults to the current month.
+func (handler *HttpHandler) AccountStatement(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	query := r.URL.Query()
+	st, ok := handler.statement(w, r, userDetails, query.Get("from"), query.Get("to"))
+	if !ok {
+		return
+	}
+
+	if v := query.Get("format"); v == "" || strings.EqualFold(v, "json") {
+		w.WriteHeader(http.StatusOK)
+		response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"statement": st}}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	format, err := statement.ParseFormat(query.Get("format"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	// exported before anything is written so a failure can still be reported as an error
+	var buf bytes.Buffer
+	if err := statement.Export(&buf, st, format); err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statement.Filename(st, format)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// EmailStatement sends the statement of the user's wallet for a period to the user's email address, as a
+// PDF unless another format is asked for.
+func (handler *HttpHandler) EmailStatement(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	req := statementRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	format, err := statement.ParseFormat(req.Format)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return
+	}
+
+	st, ok := handler.statement(w, r, userDetails, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	if err := handler.statements.Email(r.Context(), userDetails, st, format); err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"message": "statement sent to " + userDetails.Email}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// statement generates the user's statement from from to to, the start of the current month and now when
+// they are empty. On failure the error is written.
+func (handler *HttpHandler) statement(w http.ResponseWriter, r *http.Request, user *models.User, from, to string) (models.Statement, bool) {
+	start, err := parseTime(from, false)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("from must be a date (2006-01-02) or a time (RFC 3339)"))
+		return models.Statement{}, false
+	}
+	end, err := parseTime(to, true)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("to must be a date (2006-01-02) or a time (RFC 3339)"))
+		return models.Statement{}, false
+	}
+
+	now := time.Now()
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+	if start.IsZero() {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+
+	st, err := handler.statements.Generate(r.Context(), user.ID, start, end)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return models.Statement{}, false
+	}
+
+	return st, true
+}
@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
 	"net/http"
 
 	"github.com/aremxyplug-be/db/models/telcom"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/phone"
 	"github.com/aremxyplug-be/lib/responseFormat"
+	"github.com/aremxyplug-be/lib/telcom/airtime"
+	"github.com/aremxyplug-be/lib/telcom/plans"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
@@ -16,9 +21,7 @@ func (handler *HttpHandler) Airtime(w http.ResponseWriter, r *http.Request) {
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	// id := userDetails.ID
@@ -27,17 +30,19 @@ func (handler *HttpHandler) Airtime(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		data := telcom.AirtimeInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
-			fmt.Fprintf(w, "%v", err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 
 		}
-		if len(data.Phone_no) != 11 {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(w, "Phone number must be %d digits, got %d. Check the phone number and try again.", 11, len(data.Phone_no))
+		if !handler.validateRequest(w, r, &data) {
+			return
+		}
+		number, ok := handler.checkPhone(w, r, data.Phone_no, airtime.NetworkName(data.Network))
+		if !ok {
 			return
 		}
+		data.Phone_no = number.Local
 		/*
 			bal, err := handler.getBalance(id)
 			if err != nil {
@@ -64,11 +69,13 @@ func (handler *HttpHandler) Airtime(w http.ResponseWriter, r *http.Request) {
 			}
 		*/
 		data.Username = username
-		res, err := handler.vtuClient.BuyAirtime(data)
+		data.UserID = userDetails.ID
+		res, err := handler.vtuClient.BuyAirtime(r.Context(), data)
+		metrics.Purchase("airtime", airtime.NetworkName(data.Network), err)
+		handler.notifyPurchase(r, userDetails.ID, "airtime", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintf(w, "An internal error occurred while purchasing data, please try again...\n %s\n", err)
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -79,45 +86,59 @@ func (handler *HttpHandler) Airtime(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		*/
+		res.Warning = number.Warning
 		json.NewEncoder(w).Encode(res)
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.vtuClient.GetUserTransaction(username)
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.vtuClient.GetUserTransaction(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
 	}
 }
 
 // GetAirtimeTransactions return all the airtime transactions in the database, to be used by admin.
 func (handler *HttpHandler) GetAirtimeTransactions(w http.ResponseWriter, r *http.Request) {
 
-	resp, err := handler.vtuClient.GetAllTransactions()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.vtuClient.GetAllTransactions(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Error geeting user's transaction", zap.Error(err))
-		fmt.Fprintln(w, "Error occurred while getting transactions")
+		handler.log(r).Error("Error geeting user's transaction", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
 }
 
 // GetAirtimeInfo returns the details of an airtime transaction.
 func (handler *HttpHandler) GetAirtimeInfo(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.dataClient.GetTransactionDetail(id)
+	res, err := handler.vtuClient.GetTransactionDetail(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction detail.")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -128,9 +149,7 @@ func (handler *HttpHandler) TelcomRecipient(w http.ResponseWriter, r *http.Reque
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	userID := userDetails.ID
@@ -138,18 +157,15 @@ func (handler *HttpHandler) TelcomRecipient(w http.ResponseWriter, r *http.Reque
 	if r.Method == "POST" {
 		data := telcom.Recipient{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("error decoding json payload", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.log(r).Error("error decoding json payload", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
 
-		if err := handler.vtuClient.SaveRecipient(userID, data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("failed while saving recipient", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		err := handler.vtuClient.SaveRecipient(r.Context(), userID, data)
+		if err != nil {
+			handler.log(r).Error("failed while saving recipient", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
@@ -162,18 +178,15 @@ func (handler *HttpHandler) TelcomRecipient(w http.ResponseWriter, r *http.Reque
 	if r.Method == "PUT" {
 		data := telcom.Recipient{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("error decoding json payload", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.log(r).Error("error decoding json payload", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
 
-		if err := handler.vtuClient.UpdateRecipient(userID, data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("failed while updating recipient", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		err := handler.vtuClient.UpdateRecipient(r.Context(), userID, data)
+		if err != nil {
+			handler.log(r).Error("failed while updating recipient", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
@@ -183,12 +196,10 @@ func (handler *HttpHandler) TelcomRecipient(w http.ResponseWriter, r *http.Reque
 	}
 
 	if r.Method == "GET" {
-		recipients, err := handler.vtuClient.GetRecipients(userID)
+		recipients, err := handler.vtuClient.GetRecipients(r.Context(), userID)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("failed to get recipients", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": "failed to retrieve recipients"}}
-			json.NewEncoder(w).Encode(response)
+			handler.log(r).Error("failed to get recipients", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, errors.New("failed to retrieve recipients"))
 			return
 		}
 
@@ -203,18 +214,14 @@ func (handler *HttpHandler) TelcomRecipient(w http.ResponseWriter, r *http.Reque
 			ID int `json:"id"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&recipient); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("error decoding json payload", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+			handler.log(r).Error("error decoding json payload", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
 
-		if err := handler.vtuClient.DeleteRecipient(recipient.ID, userID); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("failed to delete recipient", zap.Error(err))
-			response := responseFormat.CustomResponse{Status: http.StatusInternalServerError, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
+		if err := handler.vtuClient.DeleteRecipient(r.Context(), recipient.ID, userID); err != nil {
+			handler.log(r).Error("failed to delete recipient", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
@@ -230,9 +237,7 @@ func (handler *HttpHandler) Data(w http.ResponseWriter, r *http.Request) {
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	// id := userDetails.ID
@@ -241,12 +246,19 @@ func (handler *HttpHandler) Data(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		data := telcom.DataInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintf(w, "%v", err)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
 			return
 
 		}
+		if !handler.validateRequest(w, r, &data) {
+			return
+		}
+		number, ok := handler.checkPhone(w, r, data.Mobile_Num, plans.DontechNetwork(data.Network))
+		if !ok {
+			return
+		}
+		data.Mobile_Num = number.Local
 		/*
 			bal, err := handler.getBalance(id)
 			if err != nil {
@@ -273,11 +285,13 @@ func (handler *HttpHandler) Data(w http.ResponseWriter, r *http.Request) {
 			}
 		*/
 		data.Username = username
-		res, err := handler.dataClient.BuyData(data)
+		data.UserID = userDetails.ID
+		res, err := handler.dataClient.BuyData(r.Context(), data)
+		metrics.Purchase("data", plans.DontechNetwork(data.Network), err)
+		handler.notifyPurchase(r, userDetails.ID, "data", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintf(w, "An internal error occurred while purchasing data, please try again...")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -288,19 +302,24 @@ func (handler *HttpHandler) Data(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		*/
+		res.Warning = number.Warning
 		json.NewEncoder(w).Encode(res)
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.dataClient.GetUserTransactions(username)
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.dataClient.GetUserTransactions(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
 	}
 
 }
@@ -308,32 +327,55 @@ func (handler *HttpHandler) Data(w http.ResponseWriter, r *http.Request) {
 // GetDataInfo checks and returns the details of a given transaction.
 func (handler *HttpHandler) GetDataInfo(w http.ResponseWriter, r *http.Request) {
 
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	//id := r.URL.Query().Get("id")
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.dataClient.GetTransactionDetail(id)
+	res, err := handler.dataClient.GetTransactionDetail(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction detail.")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(res)
 }
 
+// DataPlans returns the data plans in the catalogue, filtered by the network query parameter when it is set.
+func (handler *HttpHandler) DataPlans(w http.ResponseWriter, r *http.Request) {
+	network := r.URL.Query().Get("network")
+
+	dataPlans, err := handler.plans.ListPlans(r.Context(), network)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"plans": dataPlans}}
+	json.NewEncoder(w).Encode(response)
+}
+
 // GetTransactions returns the list of transaction carried out in the server. It is for admins to view all transactions.
 func (handler *HttpHandler) GetDataTransactions(w http.ResponseWriter, r *http.Request) {
 
-	resp, err := handler.dataClient.GetAllTransactions()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.dataClient.GetAllTransactions(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintf(w, "Error getting users transactions records: %v", err)
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
 
 }
 
@@ -341,23 +383,27 @@ func (handler *HttpHandler) SpectranetData(w http.ResponseWriter, r *http.Reques
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	// id := userDetails.ID
-	username := userDetails.Username
 
 	if r.Method == "POST" {
 		data := telcom.SpectranetInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
-			fmt.Fprintf(w, "%v", err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 
 		}
+		if !handler.validateRequest(w, r, &data) {
+			return
+		}
+		number, ok := handler.checkPhone(w, r, data.Phone_Number, "")
+		if !ok {
+			return
+		}
+		data.Phone_Number = number.Local
 		/*
 			bal, err := handler.getBalance(id)
 			if err != nil {
@@ -383,11 +429,13 @@ func (handler *HttpHandler) SpectranetData(w http.ResponseWriter, r *http.Reques
 			}
 		*/
 
-		res, err := handler.dataClient.BuySpecData(data)
+		data.UserID = userDetails.ID
+		res, err := handler.dataClient.BuySpecData(r.Context(), data)
+		metrics.Purchase("data", "spectranet", err)
+		handler.notifyPurchase(r, userDetails.ID, "spectranet data", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintf(w, "An internal error occurred while purchasing data, please try again...")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -402,29 +450,38 @@ func (handler *HttpHandler) SpectranetData(w http.ResponseWriter, r *http.Reques
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.dataClient.GetSpecUserTransactions(username)
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.dataClient.GetSpecUserTransactions(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
 	}
 
 }
 
 func (handler *HttpHandler) GetSpecDataDetails(w http.ResponseWriter, r *http.Request) {
 
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	//id := r.URL.Query().Get("id")
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.dataClient.GetSpecTransDetails(id)
+	res, err := handler.dataClient.GetSpecTransDetails(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction detail.")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -434,38 +491,46 @@ func (handler *HttpHandler) GetSpecDataDetails(w http.ResponseWriter, r *http.Re
 // To be used by admin
 func (handler *HttpHandler) GetSpectranetTransactions(w http.ResponseWriter, r *http.Request) {
 
-	resp, err := handler.dataClient.GetAllSpecTransactions()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.dataClient.GetAllSpecTransactions(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Error geeting user's transaction", zap.Error(err))
-		fmt.Fprintln(w, "Error occurred while getting transactions")
+		handler.log(r).Error("Error geeting user's transaction", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
 }
 
 func (handler *HttpHandler) SmileData(w http.ResponseWriter, r *http.Request) {
 
 	userDetails, err := handler.GetUserDetails(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-		json.NewEncoder(w).Encode(response)
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 	//id := userDetails.ID
-	username := userDetails.Username
 
 	if r.Method == "POST" {
 		data := telcom.SmileInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
-			fmt.Fprintf(w, "%v", err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 
 		}
+		if !handler.validateRequest(w, r, &data) {
+			return
+		}
+		number, ok := handler.checkPhone(w, r, data.Phone_Number, "")
+		if !ok {
+			return
+		}
+		data.Phone_Number = number.Local
 		/*
 			bal, err := handler.getBalance(id)
 			if err != nil {
@@ -491,11 +556,13 @@ func (handler *HttpHandler) SmileData(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		*/
-		res, err := handler.dataClient.BuySmileData(data)
+		data.UserID = userDetails.ID
+		res, err := handler.dataClient.BuySmileData(r.Context(), data)
+		metrics.Purchase("data", "smile", err)
+		handler.notifyPurchase(r, userDetails.ID, "smile data", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintf(w, "An internal error occurred while purchasing data, please try again...")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -510,29 +577,38 @@ func (handler *HttpHandler) SmileData(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.dataClient.GetSmileUserTransactions(username)
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.dataClient.GetSmileUserTransactions(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
 	}
 
 }
 
 func (handler *HttpHandler) GetSmileDataDetails(w http.ResponseWriter, r *http.Request) {
 
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	//id := r.URL.Query().Get("id")
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.dataClient.GetSmileTransDetails(id)
+	res, err := handler.dataClient.GetSmileTransDetails(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction detail.")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -542,13 +618,32 @@ func (handler *HttpHandler) GetSmileDataDetails(w http.ResponseWriter, r *http.R
 // To be used by admin
 func (handler *HttpHandler) GetSmileTransactions(w http.ResponseWriter, r *http.Request) {
 
-	resp, err := handler.dataClient.GetAllSmileTransactions()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.dataClient.GetAllSmileTransactions(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Error geeting user's transaction", zap.Error(err))
-		fmt.Fprintln(w, "Error occurred while getting transactions")
+		handler.log(r).Error("Error geeting user's transaction", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
+}
+
+// checkPhone parses the phone number and checks it against the selected network, a 400 is written when
+// the number is not valid. A network that does not match the prefix is allowed as the number may be ported.
+func (handler *HttpHandler) checkPhone(w http.ResponseWriter, r *http.Request, number, network string) (phone.Result, bool) {
+	result, err := phone.Validate(number, network)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+		return phone.Result{}, false
+	}
+	if result.Ported {
+		handler.log(r).Warn("selected network does not match the phone number prefix", zap.String("phone", result.Local), zap.String("prefix_network", result.Network), zap.String("selected_network", result.Selected))
+	}
+
+	return result, true
 }
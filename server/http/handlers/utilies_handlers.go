@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
 	"net/http"
+	"strings"
 
 	"github.com/aremxyplug-be/db/models"
+	"github.com/aremxyplug-be/lib/metrics"
 	"github.com/aremxyplug-be/lib/responseFormat"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -13,28 +16,20 @@ import (
 
 // EduPins is use to carry out buying of education pins(POST) and returning all the transactions made by the user(GET)
 func (handler *HttpHandler) EduPins(w http.ResponseWriter, r *http.Request) {
-	/*
-		userDetails, err := handler.GetUserDetails(r)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-		id := userDetails.ID
-	*/
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	if r.Method == "POST" {
 		data := models.EduInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
-			fmt.Fprintf(w, "%v", err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 		}
-		if data.Quantity >= 5 && data.Quantity < 10 {
-			w.WriteHeader(http.StatusBadRequest)
-			handler.logger.Error("invalid number of buy pins")
-			fmt.Fprintf(w, "Invalid number of pins!! Pins between %d and %d are not allowed. Try again...", 5, 10)
+		if !handler.validateRequest(w, r, &data) {
 			return
 		}
 		/*
@@ -62,11 +57,13 @@ func (handler *HttpHandler) EduPins(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		*/
-		res, err := handler.eduClient.BuyEduPin(data)
+		data.UserID = userDetails.ID
+		res, err := handler.eduClient.BuyEduPin(r.Context(), data)
+		metrics.Purchase("edu", strings.ToLower(data.Exam_Type), err)
+		handler.notifyPurchase(r, userDetails.ID, data.Exam_Type+" pin", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintf(w, "An internal error occurred while purchasing %s pin, please try again...", data.Exam_Type)
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -81,30 +78,39 @@ func (handler *HttpHandler) EduPins(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.eduClient.QueryTransaction("id")
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.eduClient.GetUserTransactions(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
 	}
 
 }
 
-// GetEduInfo returns the details of an airtime transaction.
+// GetEduInfo returns the details of an education pin transaction.
 func (handler *HttpHandler) GetEduInfo(w http.ResponseWriter, r *http.Request) {
 
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	//id := r.URL.Query().Get("id")
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.dataClient.GetTransactionDetail(id)
+	res, err := handler.eduClient.GetTransactionDetail(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction detail.")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -114,37 +120,39 @@ func (handler *HttpHandler) GetEduInfo(w http.ResponseWriter, r *http.Request) {
 // To be used by admins to view transactions in the databases
 func (handler *HttpHandler) GetEduTransactions(w http.ResponseWriter, r *http.Request) {
 
-	resp, err := handler.eduClient.GetAllTransaction("user")
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.eduClient.GetAllTransaction(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Error geeting user's transaction", zap.Error(err))
-		fmt.Fprintln(w, "Error occurred while getting transactions")
+		handler.log(r).Error("Error geeting user's transaction", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
 }
 
 func (handler *HttpHandler) TVSubscriptions(w http.ResponseWriter, r *http.Request) {
-	/*
-		userDetails, err := handler.GetUserDetails(r)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-		id := userDetails.ID
-	*/
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	if r.Method == "POST" {
 		data := models.TvInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
-			fmt.Fprintf(w, "%v", err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
 			return
 
 		}
+		if !handler.validateRequest(w, r, &data) {
+			return
+		}
 		/*
 			bal, err := handler.getBalance(id)
 			if err != nil {
@@ -169,12 +177,14 @@ func (handler *HttpHandler) TVSubscriptions(w http.ResponseWriter, r *http.Reque
 				return
 			}
 		*/
-		res, err := handler.tvClient.BuySub(data)
+		data.UserID = userDetails.ID
+		res, err := handler.tvClient.BuySub(r.Context(), data)
+		metrics.Purchase("tv", data.DecoderType, err)
+		handler.notifyPurchase(r, userDetails.ID, data.DecoderType+" subscription", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
+			handler.log(r).Error("Api response error", zap.Error(err))
 			// change error message
-			fmt.Fprintf(w, "An internal error occurred while purchasing tv subscription, please try again...")
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -189,38 +199,84 @@ func (handler *HttpHandler) TVSubscriptions(w http.ResponseWriter, r *http.Reque
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.tvClient.GetUserTransactions("user")
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.tvClient.GetUserTransactions(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
+	}
+}
+
+// TvPackages returns the bouquets offered by the tv provider in the provider query parameter.
+func (handler *HttpHandler) TvPackages(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+
+	packages, err := handler.tvClient.Packages(r.Context(), provider)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"packages": packages}}
+	json.NewEncoder(w).Encode(response)
+}
+
+// VerifySmartCard returns the customer name, current bouquet, due date and renewal amount of a smartcard/IUC number.
+func (handler *HttpHandler) VerifySmartCard(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+	iucNumber := r.URL.Query().Get("iuc_number")
+	if iucNumber == "" {
+		handler.writeError(w, r, errorvalues.InvalidRequestErr, errors.New("iuc_number is required"))
+		return
+	}
+
+	card, err := handler.tvClient.VerifyCard(r.Context(), iucNumber, provider)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
 	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"customer": card}}
+	json.NewEncoder(w).Encode(response)
 }
 
 func (handler *HttpHandler) GetTvSubscriptions(w http.ResponseWriter, r *http.Request) {
-	resp, err := handler.tvClient.GetAllTransactions()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.tvClient.GetAllTransactions(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Error geeting user's transaction", zap.Error(err))
-		fmt.Fprintln(w, "Error occurred while getting transactions")
+		handler.log(r).Error("Error geeting user's transaction", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
 }
 
 func (handler *HttpHandler) GetTvSubDetails(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.tvClient.GetTransactionDetails(id)
+	res, err := handler.tvClient.GetTransactionDetails(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction details...")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
@@ -228,22 +284,20 @@ func (handler *HttpHandler) GetTvSubDetails(w http.ResponseWriter, r *http.Reque
 }
 
 func (handler *HttpHandler) ElectricBill(w http.ResponseWriter, r *http.Request) {
-	/*
-		userDetails, err := handler.GetUserDetails(r)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": err.Error()}}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-		id := userDetails.ID
-	*/
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	if r.Method == "POST" {
 		data := models.ElectricInfo{}
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Decoding JSON response", zap.Error(err))
-			fmt.Fprintf(w, "%v", err)
+			handler.log(r).Error("Decoding JSON response", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InvalidRequestErr, err)
+			return
+		}
+		if !handler.validateRequest(w, r, &data) {
 			return
 		}
 		/*
@@ -270,18 +324,18 @@ func (handler *HttpHandler) ElectricBill(w http.ResponseWriter, r *http.Request)
 				return
 			}
 		*/
+		data.UserID = userDetails.ID
 		if data.Amount < 1000 {
-			w.WriteHeader(http.StatusInternalServerError)
-			response := responseFormat.CustomResponse{Status: http.StatusCreated, Message: "error", Data: map[string]interface{}{"data": "amount is less than 1000"}}
-			json.NewEncoder(w).Encode(response)
+			handler.writeError(w, r, errorvalues.InternalServerError, errors.New("amount is less than 1000"))
 			return
 		}
-		res, err := handler.electClient.PayBill(data)
+		res, err := handler.electClient.PayBill(r.Context(), data)
+		metrics.Purchase("electricity", strings.ToLower(data.DiscoType), err)
+		handler.notifyPurchase(r, userDetails.ID, "electricity", err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
+			handler.log(r).Error("Api response error", zap.Error(err))
 			// change error message
-			fmt.Fprintf(w, "An internal error occurred while paying electricity bill, please try again...")
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 		/*
@@ -296,38 +350,65 @@ func (handler *HttpHandler) ElectricBill(w http.ResponseWriter, r *http.Request)
 	}
 
 	if r.Method == "GET" {
-		res, err := handler.electClient.GetUserTransactions("user")
+		opts, ok := handler.listOptions(w, r)
+		if !ok {
+			return
+		}
+
+		res, next, err := handler.electClient.GetUserTransactions(r.Context(), userDetails.ID, opts)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			handler.logger.Error("Api response error", zap.Error(err))
-			fmt.Fprintln(w, "Errror occurred while getting user's records")
+			handler.log(r).Error("Api response error", zap.Error(err))
+			handler.writeError(w, r, errorvalues.InternalServerError, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(res)
+		writePage(w, "transactions", res, next)
+	}
+}
+
+// VerifyMeter returns the customer name, address and meter type of a meter number so it can be confirmed before payment.
+func (handler *HttpHandler) VerifyMeter(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	meter, err := handler.electClient.VerifyMeter(r.Context(), query.Get("disco_type"), query.Get("meter_no"), query.Get("meter_type"))
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
 	}
+
+	response := responseFormat.CustomResponse{Status: http.StatusOK, Message: "success", Data: map[string]interface{}{"customer": meter}}
+	json.NewEncoder(w).Encode(response)
 }
 
 func (handler *HttpHandler) GetElectricBills(w http.ResponseWriter, r *http.Request) {
-	resp, err := handler.electClient.GetAllTransactions()
+	opts, ok := handler.adminListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	resp, next, err := handler.electClient.GetAllTransactions(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Error geeting user's transaction", zap.Error(err))
-		fmt.Fprintln(w, "Error occurred while getting transactions")
+		handler.log(r).Error("Error geeting user's transaction", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	writePage(w, "transactions", resp, next)
 }
 
 func (handler *HttpHandler) GetElectricBillDetails(w http.ResponseWriter, r *http.Request) {
+	userDetails, err := handler.GetUserDetails(r)
+	if err != nil {
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
 
-	res, err := handler.electClient.GetTransactionDetails(id)
+	res, err := handler.electClient.GetTransactionDetails(r.Context(), userDetails.ID, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		handler.logger.Error("Api response error", zap.Error(err))
-		fmt.Fprintln(w, "Error getting transaction detail.")
+		handler.log(r).Error("Api response error", zap.Error(err))
+		handler.writeError(w, r, errorvalues.InternalServerError, err)
 		return
 	}
 
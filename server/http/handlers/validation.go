@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	terror "github.com/aremxyplug-be/lib/errors"
+	"github.com/aremxyplug-be/lib/errorvalues"
+	"github.com/aremxyplug-be/lib/validation"
+)
+
+// validateRequest checks the request body against its validate tags. On failure a 400 is written with
+// every failed field so the client can show the errors next to the inputs.
+func (handler *HttpHandler) validateRequest(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	err := validate.Struct(body)
+	if err == nil {
+		return true
+	}
+
+	handler.writeError(w, r, errorvalues.ValidationErr, errors.New("request has invalid fields"), terror.WithFields(validation.Errors(err)...))
+	return false
+}
@@ -11,20 +11,32 @@ import (
 	"github.com/aremxyplug-be/lib/bank/transfer"
 	elect "github.com/aremxyplug-be/lib/bills/electricity"
 	"github.com/aremxyplug-be/lib/bills/tvsub"
+	"github.com/aremxyplug-be/lib/bulk"
+	"github.com/aremxyplug-be/lib/dispute"
 	"github.com/aremxyplug-be/lib/emailclient"
+	"github.com/aremxyplug-be/lib/float"
+	"github.com/aremxyplug-be/lib/health"
+	"github.com/aremxyplug-be/lib/metrics"
+	"github.com/aremxyplug-be/lib/notification"
 	otpgen "github.com/aremxyplug-be/lib/otp_gen"
 	pointredeem "github.com/aremxyplug-be/lib/point-redeem"
+	"github.com/aremxyplug-be/lib/receipt"
+	"github.com/aremxyplug-be/lib/reconcile"
 	"github.com/aremxyplug-be/lib/referral"
+	"github.com/aremxyplug-be/lib/refund"
+	"github.com/aremxyplug-be/lib/scheduler"
+	"github.com/aremxyplug-be/lib/statement"
 	"github.com/aremxyplug-be/lib/telcom/airtime"
 	"github.com/aremxyplug-be/lib/telcom/data"
 	"github.com/aremxyplug-be/lib/telcom/edu"
+	"github.com/aremxyplug-be/lib/telcom/plans"
+	"github.com/aremxyplug-be/lib/tracing"
 	"github.com/aremxyplug-be/server/http/handlers"
 
 	"github.com/aremxyplug-be/config"
 	"github.com/aremxyplug-be/db"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
 )
@@ -32,7 +44,7 @@ import (
 type ServerConfig struct {
 	Logger      *zap.Logger
 	Store       db.DataStore
-	Secrets     *config.Secrets
+	Config      *config.Config
 	EmailClient emailclient.EmailClient
 	DataClient  *data.DataConn
 	EduClient   *edu.EduConn
@@ -48,6 +60,17 @@ type ServerConfig struct {
 	Referral    *referral.RefConfig
 	Point       *pointredeem.PointConfig
 	Pin         *auth_pin.PinConfig
+	Plans       *plans.Catalogue
+	Scheduler   *scheduler.Scheduler
+	Bulk        *bulk.Bulk
+	Receipts    *receipt.Receipts
+	Statements  *statement.Statements
+	Floats      *float.Monitor
+	Reconciler  *reconcile.Reconciler
+	Refunds     *refund.Refunds
+	Disputes    *dispute.Disputes
+	Notifier    *notification.Notifier
+	Health      *health.Checker
 }
 
 func MountServer(config ServerConfig) *chi.Mux {
@@ -65,13 +88,15 @@ func MountServer(config ServerConfig) *chi.Mux {
 	router.Use(setJSONContentType)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
 	router.Use(middleware.Logger)
+	router.Use(metrics.Middleware)
 
 	// Get handlers
 	httpHandler := handlers.NewHttpHandler(&handlers.HandlerOptions{
 		Logger:      config.Logger,
 		Store:       config.Store,
-		Secrets:     config.Secrets,
+		Config:      config.Config,
 		EmailClient: config.EmailClient,
 		Data:        config.DataClient,
 		Edu:         config.EduClient,
@@ -86,11 +111,23 @@ func MountServer(config ServerConfig) *chi.Mux {
 		Referral:    config.Referral,
 		Point:       config.Point,
 		Pin:         config.Pin,
+		Plans:       config.Plans,
+		Scheduler:   config.Scheduler,
+		Bulk:        config.Bulk,
+		Receipts:    config.Receipts,
+		Statements:  config.Statements,
+		Floats:      config.Floats,
+		Reconciler:  config.Reconciler,
+		Refunds:     config.Refunds,
+		Disputes:    config.Disputes,
+		Notifier:    config.Notifier,
 	})
 
 	// Routes
-	// Health check
-	router.Get("/health", healthCheck)
+	// Health and readiness checks
+	router.Get("/health", config.Health.Health)
+	router.Get("/ready", config.Health.Ready)
+	router.Handle("/metrics", metrics.Handler())
 
 	router.Route("/api/v1", func(router chi.Router) {
 		// SignUp
@@ -111,9 +148,12 @@ func MountServer(config ServerConfig) *chi.Mux {
 
 		router.Get("/banks", httpHandler.GetBanks)
 
-		router.Get("/deposit", httpHandler.DepositAccount)
+		// events sent by the providers, verified by their signatures
+		router.Post("/webhooks/anchor", httpHandler.AnchorWebhook)
 
 		authRouter := router.With(config.Auth.Authorize)
+		// creates the business deposit account at anchor
+		authRouter.With(httpHandler.RequireStaff).Get("/deposit", httpHandler.DepositAccount)
 		// reset password
 		authRouter.Patch("/reset-password", httpHandler.ResetPassword)
 		// Data Routes
@@ -143,6 +183,23 @@ func MountServer(config ServerConfig) *chi.Mux {
 		extraRoutes(authRouter, httpHandler)
 
 		virtualAccRoutes(authRouter, httpHandler)
+
+		scheduleRoutes(authRouter, httpHandler)
+
+		bulkRoutes(authRouter, httpHandler)
+
+		transactionRoutes(authRouter, httpHandler)
+
+		statementRoutes(authRouter, httpHandler)
+
+		floatRoutes(authRouter, httpHandler)
+
+		reconciliationRoutes(authRouter, httpHandler)
+
+		refundRoutes(authRouter, httpHandler)
+
+		disputeRoutes(authRouter, httpHandler)
+		notificationRoutes(authRouter, httpHandler)
 		/*
 			transferMoneyRoutes(authRouter, httpHandler)
 
@@ -160,17 +217,13 @@ func setJSONContentType(next http.Handler) http.Handler {
 	})
 }
 
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	render.Status(r, http.StatusOK)
-	render.Data(w, r, []byte("Ok"))
-}
-
 func dataRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 	r.Route("/data", func(router chi.Router) {
 		router.Post("/", httpHandler.Data)
 		router.Get("/", httpHandler.Data)
 		router.Get("/{id}", httpHandler.GetDataInfo)
-		router.Get("/transactions", httpHandler.GetDataTransactions)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetDataTransactions)
+		router.Get("/plans", httpHandler.DataPlans)
 
 		router.Route("/recipient", func(route chi.Router) {
 			route.Post("/", httpHandler.TelcomRecipient)
@@ -186,7 +239,7 @@ func smileDataRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 		router.Post("/", httpHandler.SmileData)
 		router.Get("/", httpHandler.SmileData)
 		router.Get("/{id}", httpHandler.GetSmileDataDetails)
-		router.Get("/transactions", httpHandler.GetSmileTransactions)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetSmileTransactions)
 	})
 }
 
@@ -195,7 +248,7 @@ func spectranetDataRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 		router.Post("/", httpHandler.SpectranetData)
 		router.Get("/", httpHandler.SpectranetData)
 		router.Get("/{id}", httpHandler.GetSpecDataDetails)
-		router.Get("/transactions", httpHandler.GetSpectranetTransactions)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetSpectranetTransactions)
 	})
 }
 
@@ -203,8 +256,8 @@ func eduRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 	r.Route("/edu", func(router chi.Router) {
 		router.Post("/", httpHandler.EduPins)
 		router.Get("/", httpHandler.EduPins)
-		router.Get("/{id}", httpHandler.GetDataInfo)
-		router.Get("/transactions", httpHandler.GetEduTransactions)
+		router.Get("/{id}", httpHandler.GetEduInfo)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetEduTransactions)
 	})
 }
 
@@ -213,7 +266,7 @@ func airtimeRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 		router.Post("/", httpHandler.Airtime)
 		router.Get("/", httpHandler.Airtime)
 		router.Get("/{id}", httpHandler.GetAirtimeInfo)
-		router.Get("/transactions", httpHandler.GetAirtimeTransactions)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetAirtimeTransactions)
 
 		router.Route("/recipient", func(route chi.Router) {
 			route.Post("/", httpHandler.TelcomRecipient)
@@ -229,7 +282,103 @@ func tvSubscriptionRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 		router.Post("/", httpHandler.TVSubscriptions)
 		router.Get("/", httpHandler.TVSubscriptions)
 		router.Get("/{id}", httpHandler.GetTvSubDetails)
-		router.Get("/transactions", httpHandler.GetTvSubscriptions)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetTvSubscriptions)
+		router.Get("/packages", httpHandler.TvPackages)
+		router.Get("/verify", httpHandler.VerifySmartCard)
+	})
+}
+
+func scheduleRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/schedules", func(router chi.Router) {
+		router.Post("/", httpHandler.ScheduledOrders)
+		router.Get("/", httpHandler.ScheduledOrders)
+		router.Get("/{id}", httpHandler.GetScheduledOrder)
+		router.Post("/{id}/pause", httpHandler.PauseScheduledOrder)
+		router.Post("/{id}/resume", httpHandler.ResumeScheduledOrder)
+		router.Post("/{id}/cancel", httpHandler.CancelScheduledOrder)
+	})
+}
+
+func bulkRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/bulk", func(router chi.Router) {
+		router.Post("/airtime", httpHandler.BulkAirtime)
+		router.Post("/data", httpHandler.BulkData)
+		router.Get("/{id}", httpHandler.GetBulkBatch)
+		router.Get("/{id}/report", httpHandler.BulkBatchReport)
+	})
+}
+
+func transactionRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/transactions", func(router chi.Router) {
+		router.Get("/{id}/receipt", httpHandler.TransactionReceipt)
+	})
+}
+
+func statementRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/statements", func(router chi.Router) {
+		router.Get("/", httpHandler.AccountStatement)
+		router.Post("/email", httpHandler.EmailStatement)
+	})
+}
+
+func floatRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/providers/floats", func(router chi.Router) {
+		router.Use(httpHandler.RequireStaff)
+		router.Get("/", httpHandler.GetProviderFloats)
+		router.Get("/{provider}", httpHandler.GetProviderFloatHistory)
+	})
+}
+
+func reconciliationRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/reconciliations", func(router chi.Router) {
+		router.Use(httpHandler.RequireStaff)
+		router.Get("/", httpHandler.GetReconciliations)
+		router.Get("/exceptions", httpHandler.GetReconExceptions)
+		router.Post("/exceptions/{id}/resolve", httpHandler.ResolveReconException)
+		router.Post("/{provider}/pull", httpHandler.PullReconciliation)
+		router.Post("/{provider}/upload", httpHandler.UploadReconciliation)
+	})
+}
+
+func refundRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/refunds", func(router chi.Router) {
+		router.Use(httpHandler.RequireStaff)
+		router.Post("/", httpHandler.RequestRefund)
+		router.Get("/", httpHandler.GetRefunds)
+		router.Get("/{id}", httpHandler.GetRefund)
+		router.Post("/{id}/approve", httpHandler.ApproveRefund)
+		router.Post("/{id}/reject", httpHandler.RejectRefund)
+	})
+}
+
+func disputeRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/disputes", func(router chi.Router) {
+		router.Post("/", httpHandler.OpenDispute)
+		router.Get("/", httpHandler.GetDisputes)
+		router.Get("/{id}", httpHandler.GetDispute)
+		router.Post("/{id}/comments", httpHandler.CommentOnDispute)
+		router.Get("/{id}/attachments/{attachmentID}", httpHandler.GetDisputeAttachment)
+	})
+	r.Route("/support/disputes", func(router chi.Router) {
+		router.Use(httpHandler.RequireStaff)
+		router.Get("/", httpHandler.GetSupportDisputes)
+		router.Get("/{id}", httpHandler.GetSupportDispute)
+		router.Get("/{id}/attachments/{attachmentID}", httpHandler.GetSupportDisputeAttachment)
+		router.Post("/{id}/comments", httpHandler.SupportCommentOnDispute)
+		router.Post("/{id}/status", httpHandler.SetDisputeStatus)
+		router.Post("/{id}/requery", httpHandler.RequeryDispute)
+		router.Post("/{id}/refund", httpHandler.RefundDispute)
+	})
+}
+
+func notificationRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
+	r.Route("/notifications", func(router chi.Router) {
+		router.Get("/", httpHandler.GetNotifications)
+		router.Get("/unread-count", httpHandler.GetUnreadCount)
+		router.Post("/read", httpHandler.MarkAllNotificationsRead)
+		router.Post("/{id}/read", httpHandler.MarkNotificationRead)
+		router.Get("/preferences", httpHandler.NotificationPreferences)
+		router.Put("/preferences", httpHandler.NotificationPreferences)
 	})
 }
 
@@ -238,7 +387,8 @@ func electricityBillRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 		router.Post("/", httpHandler.ElectricBill)
 		router.Get("/", httpHandler.ElectricBill)
 		router.Get("/{id}", httpHandler.GetElectricBillDetails)
-		router.Get("/transactions", httpHandler.GetElectricBills)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetElectricBills)
+		router.Get("/verify", httpHandler.VerifyMeter)
 	})
 }
 
@@ -253,7 +403,7 @@ func bankRoutes(r chi.Router, httpHandler *handlers.HttpHandler) {
 			router.Get("/", httpHandler.GetDepositHistory)
 			router.Get("/{id}", httpHandler.GetDepositDetail)
 		})
-		router.Get("/transactions", httpHandler.GetAllBankTransactions)
+		router.With(httpHandler.RequireStaff).Get("/transactions", httpHandler.GetAllBankTransactions)
 	})
 }
 
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aremxyplug-be/config"
+	"github.com/aremxyplug-be/db"
+	"github.com/aremxyplug-be/db/models"
+	tokengenerator "github.com/aremxyplug-be/lib/tokekngenerator"
+	"github.com/aremxyplug-be/server/http/handlers"
+	"github.com/aremxyplug-be/types/dto"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	db.DataStore
+	user *models.User
+}
+
+func (f *fakeStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return f.user, nil
+}
+
+func TestTransactionRoutes_Staff(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	cfg := &config.Config{JWT: config.JWT{
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+	}}
+	httpHandler := handlers.NewHttpHandler(&handlers.HandlerOptions{
+		Logger: zap.NewNop(),
+		Store:  &fakeStore{user: &models.User{ID: "user-1"}},
+		Config: cfg,
+	})
+
+	router := chi.NewRouter()
+	dataRoutes(router, httpHandler)
+	smileDataRoutes(router, httpHandler)
+	spectranetDataRoutes(router, httpHandler)
+	eduRoutes(router, httpHandler)
+	airtimeRoutes(router, httpHandler)
+	tvSubscriptionRoutes(router, httpHandler)
+	electricityBillRoutes(router, httpHandler)
+	bankRoutes(router, httpHandler)
+
+	token, err := tokengenerator.New(&key.PublicKey, key).GenerateToken(dto.Claims{PersonId: "user-1"})
+	require.NoError(t, err)
+
+	var tests = []struct {
+		name string
+		path string
+	}{
+		{name: "Test data transactions", path: "/data/transactions"},
+		{name: "Test smile transactions", path: "/data/smile/transactions"},
+		{name: "Test spectranet transactions", path: "/data/spectranet/transactions"},
+		{name: "Test edu transactions", path: "/edu/transactions"},
+		{name: "Test airtime transactions", path: "/airtime/transactions"},
+		{name: "Test tv transactions", path: "/tvsub/transactions"},
+		{name: "Test electricity transactions", path: "/electric-bill/transactions"},
+		{name: "Test bank transactions", path: "/bank/transactions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path+"?user_id=user-2", nil)
+			req.Header.Set("Authorization", token)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+		})
+	}
+}